@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/opencrafts-io/gossip-monger/internal/app"
 	"github.com/opencrafts-io/gossip-monger/internal/config"
@@ -11,7 +14,12 @@ import (
 
 func main() {
 	var logger *slog.Logger
-	logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{}))
+	logOutput := os.Stdout
+	if len(os.Args) > 1 {
+		// A subcommand prints its result on stdout; keep logs off it.
+		logOutput = os.Stderr
+	}
+	logger = slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{}))
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -29,7 +37,21 @@ func main() {
 		panic(err)
 	}
 
-	if err = gossipMonger.Start(context.Background()); err != nil {
+	// "parked ..." triages the parked queue and exits instead of serving.
+	if len(os.Args) > 1 && os.Args[1] == "parked" {
+		if err := gossipMonger.RunParkedCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// SIGINT or SIGTERM shuts the server down and lets the consumers drain
+	// the messages they are handling.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err = gossipMonger.Start(ctx); err != nil {
 		logger.Error("Failed to start the epic gossip monger service.", slog.Any("error", err))
 		panic(err)
 	}
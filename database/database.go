@@ -1,7 +1,10 @@
 package database
 
 import (
+	"context"
 	"embed"
+	"fmt"
+	"io/fs"
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -31,3 +34,22 @@ func RunGooseMigrations(logger *slog.Logger, pool *pgxpool.Pool) {
 
 	logger.Info("Migrations ran and were completed successfully")
 }
+
+// MigrationVersions returns the schema version the database is at and the
+// latest migration embedded in this build. The database is behind while
+// current is lower than latest.
+func MigrationVersions(ctx context.Context, pool *pgxpool.Pool) (current, latest int64, err error) {
+	migrations, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	db := stdlib.OpenDBFromPool(pool)
+	defer db.Close()
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, migrations)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return provider.GetVersions(ctx)
+}
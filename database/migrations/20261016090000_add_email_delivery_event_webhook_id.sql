@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Resend delivers webhooks through Svix, which is at-least-once: the same
+-- svix-id can arrive more than once. Keeping it lets a redelivered webhook
+-- be ignored instead of recording the same bounce/complaint twice.
+ALTER TABLE email_delivery_events ADD COLUMN webhook_id TEXT;
+CREATE UNIQUE INDEX idx_delivery_events_webhook_id_recipient
+  ON email_delivery_events(webhook_id, recipient);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS idx_delivery_events_webhook_id_recipient;
+ALTER TABLE email_delivery_events DROP COLUMN IF EXISTS webhook_id;
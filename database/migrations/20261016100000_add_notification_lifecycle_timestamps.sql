@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- UpdateNotificationStatus and MarkNotificationAsRead have always written
+-- failed_at/read_at, but the columns were never created. dismissed_at is
+-- new, fed by OneSignal's dismissed webhook.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS read_at TIMESTAMP;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS dismissed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_onesignal_notification_id
+  ON notifications(onesignal_notification_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS idx_notifications_onesignal_notification_id;
+ALTER TABLE notifications DROP COLUMN IF EXISTS dismissed_at;
+ALTER TABLE notifications DROP COLUMN IF EXISTS failed_at;
+ALTER TABLE notifications DROP COLUMN IF EXISTS read_at;
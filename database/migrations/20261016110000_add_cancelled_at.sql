@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Set when a push.cancel / email.cancel event retracts a request before it
+-- went out. status moves to 'cancelled' at the same time.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+ALTER TABLE email_requests ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE email_requests DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE notifications DROP COLUMN IF EXISTS cancelled_at;
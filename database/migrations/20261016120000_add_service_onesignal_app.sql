@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- The OneSignal app a service's pushes go out through, for services that
+-- ship their own mobile app. Both NULL means the globally configured app.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS onesignal_app_id VARCHAR(255);
+ALTER TABLE services ADD COLUMN IF NOT EXISTS onesignal_rest_api_key TEXT;
+ALTER TABLE services ADD CONSTRAINT services_onesignal_app_complete
+  CHECK ((onesignal_app_id IS NULL) = (onesignal_rest_api_key IS NULL));
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE services DROP CONSTRAINT IF EXISTS services_onesignal_app_complete;
+ALTER TABLE services DROP COLUMN IF EXISTS onesignal_rest_api_key;
+ALTER TABLE services DROP COLUMN IF EXISTS onesignal_app_id;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Per-service email sending settings. allowed_sender_domains restricts the
+-- domains a service may send from; NULL or empty means the globally
+-- configured RESEND_ALLOWED_SENDER_DOMAINS. resend_api_key, if set, sends
+-- the service's email through its own Resend account instead of the shared
+-- one.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS allowed_sender_domains TEXT[];
+ALTER TABLE services ADD COLUMN IF NOT EXISTS resend_api_key TEXT;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE services DROP COLUMN IF EXISTS resend_api_key;
+ALTER TABLE services DROP COLUMN IF EXISTS allowed_sender_domains;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Indexes backing the keyset-paginated history queries the admin API runs:
+-- each one matches a query's filter followed by its (timestamp, id) order.
+CREATE INDEX IF NOT EXISTS idx_notifications_target_user_created
+  ON notifications(target_user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_type_created
+  ON notifications(notification_type, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_status_created
+  ON notifications(status, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_external_user_ids
+  ON notifications USING GIN (include_external_user_ids);
+CREATE INDEX IF NOT EXISTS idx_email_requests_service_received
+  ON email_requests(service_id, received_at DESC, id DESC);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS idx_email_requests_service_received;
+DROP INDEX IF EXISTS idx_notifications_external_user_ids;
+DROP INDEX IF EXISTS idx_notifications_status_created;
+DROP INDEX IF EXISTS idx_notifications_type_created;
+DROP INDEX IF EXISTS idx_notifications_target_user_created;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Audit trail of triage actions taken on gossip.parked.queue, from the CLI
+-- or the admin API. parked_message_id is NULL for a listing. body is kept
+-- for replay and discard so a discarded message can still be recovered.
+CREATE TABLE IF NOT EXISTS parked_message_actions (
+    id                    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action                TEXT NOT NULL
+                          CHECK (action IN ('list', 'inspect', 'replay', 'discard')),
+    actor                 TEXT NOT NULL,
+    parked_message_id     TEXT,
+    original_exchange     TEXT,
+    original_routing_key  TEXT,
+    body                  BYTEA,
+    succeeded             BOOLEAN NOT NULL,
+    error                 TEXT,
+    performed_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_parked_message_actions_message
+  ON parked_message_actions(parked_message_id, performed_at DESC);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE IF EXISTS parked_message_actions;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Which AMQP message an email request was last handled from, and on which
+-- attempt. queue_message_id is the publisher's request_id, not the AMQP
+-- message-id; rows from before this migration have no message-id and count
+-- as a first attempt.
+ALTER TABLE email_requests
+  ADD COLUMN IF NOT EXISTS amqp_message_id TEXT,
+  ADD COLUMN IF NOT EXISTS attempt_count INTEGER NOT NULL DEFAULT 1;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE email_requests
+  DROP COLUMN IF EXISTS attempt_count,
+  DROP COLUMN IF EXISTS amqp_message_id;
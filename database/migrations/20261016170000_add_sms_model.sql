@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- One row per gossip.sms.send event, keyed by the publisher's request_id
+-- like email_requests.
+CREATE TABLE IF NOT EXISTS sms_requests (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id       VARCHAR(255) NOT NULL REFERENCES services(id),
+
+    -- Routing: the delivery that last handled the request
+    queue_message_id TEXT NOT NULL UNIQUE,
+    exchange         VARCHAR(100) NOT NULL,
+    routing_key      VARCHAR(100) NOT NULL,
+    amqp_message_id  TEXT,
+    attempt_count    INTEGER NOT NULL DEFAULT 1,
+
+    to_numbers       TEXT[] NOT NULL,
+    body             TEXT NOT NULL,
+    sender_id        VARCHAR(50),
+
+    status           VARCHAR(50) NOT NULL DEFAULT 'received',
+    -- received | dispatched | failed | circuit_open | invalid
+    received_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    processed_at     TIMESTAMPTZ
+);
+
+-- Every attempt to hand an sms_request to the provider. recipients holds
+-- the provider's verdict per number.
+CREATE TABLE IF NOT EXISTS sms_dispatches (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sms_request_id   UUID NOT NULL REFERENCES sms_requests(id),
+    provider         VARCHAR(50) NOT NULL,
+
+    status           VARCHAR(50) NOT NULL,
+    -- sent | rejected | failed | circuit_open
+    http_status_code INT,
+    provider_error   TEXT,
+    recipients       JSONB,
+
+    dispatched_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sms_requests_service_received
+  ON sms_requests(service_id, received_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_sms_dispatches_sms_request_id
+  ON sms_dispatches(sms_request_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS idx_sms_dispatches_sms_request_id;
+DROP INDEX IF EXISTS idx_sms_requests_service_received;
+
+DROP TABLE IF EXISTS sms_dispatches;
+DROP TABLE IF EXISTS sms_requests;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Which provider handled a dispatch, and the id it knows the email by.
+-- Every dispatch before this migration went through Resend. resend_payload
+-- and resend_error keep their names: the payload is built in Resend's shape
+-- whichever provider sends it, and the error is that provider's.
+-- resend_email_id stays Resend-only, as webhooks are matched on it.
+ALTER TABLE email_dispatches
+  ADD COLUMN IF NOT EXISTS provider VARCHAR(50) NOT NULL DEFAULT 'resend',
+  ADD COLUMN IF NOT EXISTS provider_message_id TEXT;
+
+UPDATE email_dispatches
+SET provider_message_id = resend_email_id
+WHERE provider_message_id IS NULL AND resend_email_id IS NOT NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE email_dispatches
+  DROP COLUMN IF EXISTS provider_message_id,
+  DROP COLUMN IF EXISTS provider;
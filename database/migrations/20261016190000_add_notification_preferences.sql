@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Whether a user wants a type of notification on a channel. No row means
+-- yes; notification_type '*' covers every type on the channel, and a row
+-- for the type itself takes precedence over it. There is no foreign key to
+-- users for the reason given in ADR-0001: a preference may arrive before
+-- the user is synced from Verisafe.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id           UUID NOT NULL,
+    channel           VARCHAR(10) NOT NULL,
+    -- push | email | sms
+    notification_type VARCHAR(100) NOT NULL,
+    enabled           BOOLEAN NOT NULL,
+    source_service_id VARCHAR(255) NOT NULL,
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, channel, notification_type)
+);
+
+-- The notification_type an email was sent as, and the recipients it was
+-- not sent to because they opted out.
+ALTER TABLE email_requests
+  ADD COLUMN IF NOT EXISTS notification_type VARCHAR(100),
+  ADD COLUMN IF NOT EXISTS suppressed_addresses TEXT[];
+
+-- The target_user_id and include_external_user_ids a push was not sent to
+-- because they opted out.
+ALTER TABLE notifications
+  ADD COLUMN IF NOT EXISTS suppressed_user_ids TEXT[];
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE notifications
+  DROP COLUMN IF EXISTS suppressed_user_ids;
+
+ALTER TABLE email_requests
+  DROP COLUMN IF EXISTS suppressed_addresses,
+  DROP COLUMN IF EXISTS notification_type;
+
+DROP TABLE IF EXISTS notification_preferences;
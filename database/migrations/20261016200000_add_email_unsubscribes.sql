@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Recipients who used the unsubscribe link of a service's
+-- non-transactional email, and get none from it since. address is
+-- lowercased.
+CREATE TABLE IF NOT EXISTS email_unsubscribes (
+    service_id      VARCHAR(255) NOT NULL REFERENCES services(id),
+    address         TEXT NOT NULL,
+    unsubscribed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (service_id, address)
+);
+
+-- Whether the publisher marked the email transactional. Requests from
+-- before this migration weren't asked.
+ALTER TABLE email_requests
+  ADD COLUMN IF NOT EXISTS transactional BOOLEAN;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE email_requests
+  DROP COLUMN IF EXISTS transactional;
+
+DROP TABLE IF EXISTS email_unsubscribes;
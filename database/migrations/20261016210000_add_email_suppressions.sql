@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Addresses no email is sent to, from any service: ones that hard-bounced
+-- or whose recipient marked an email as spam, and ones an operator added
+-- by hand. address is bare and lowercased. dispatch_id is the send whose
+-- bounce or complaint added it.
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    address     TEXT PRIMARY KEY,
+    reason      VARCHAR(20) NOT NULL
+                CHECK (reason IN ('hard_bounce', 'complaint', 'manual')),
+    dispatch_id UUID REFERENCES email_dispatches(id),
+    note        TEXT,
+    created_by  TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_suppressions_created_at
+  ON email_suppressions(created_at DESC);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP TABLE IF EXISTS email_suppressions;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Users a request was addressed to by id, and the addresses or numbers
+-- the user directory resolved them to. to_addresses and to_numbers keep
+-- only what the publisher spelled out.
+ALTER TABLE email_requests
+  ADD COLUMN IF NOT EXISTS to_user_ids UUID[],
+  ADD COLUMN IF NOT EXISTS resolved_to_addresses TEXT[];
+
+ALTER TABLE sms_requests
+  ADD COLUMN IF NOT EXISTS to_user_ids UUID[],
+  ADD COLUMN IF NOT EXISTS resolved_to_numbers TEXT[];
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE sms_requests
+  DROP COLUMN IF EXISTS resolved_to_numbers,
+  DROP COLUMN IF EXISTS to_user_ids;
+
+ALTER TABLE email_requests
+  DROP COLUMN IF EXISTS resolved_to_addresses,
+  DROP COLUMN IF EXISTS to_user_ids;
@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- A notify.send event: one notification fanned out to several channels.
+-- The push and email it sends are ordinary notifications and
+-- email_requests rows pointing back here through notify_request_id.
+CREATE TABLE IF NOT EXISTS notify_requests (
+    id                     UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    service_id             VARCHAR(255) NOT NULL REFERENCES services(id),
+
+    -- Routing: the delivery that last handled the request
+    queue_message_id       TEXT NOT NULL UNIQUE,
+    exchange               VARCHAR(100) NOT NULL,
+    routing_key            VARCHAR(100) NOT NULL,
+    amqp_message_id        TEXT,
+    attempt_count          INTEGER NOT NULL DEFAULT 1,
+
+    to_user_ids            UUID[] NOT NULL,
+    -- Channels in the order they are tried.
+    channels               TEXT[] NOT NULL,
+    policy                 VARCHAR(20) NOT NULL,
+    -- all | first_success | fallback
+    fallback_after_minutes INTEGER,
+    -- The notify object as published, which later channels are built from.
+    payload                JSONB NOT NULL,
+
+    -- The index into channels a fallback sends on next, and when.
+    next_channel           INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at        TIMESTAMPTZ,
+
+    status                 VARCHAR(50) NOT NULL DEFAULT 'received',
+    -- received | pending | dispatching | sent | partial | suppressed | failed
+    received_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at             TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notify_requests_pending
+  ON notify_requests(next_attempt_at)
+  WHERE status IN ('pending', 'dispatching');
+
+ALTER TABLE notifications
+  ADD COLUMN IF NOT EXISTS notify_request_id UUID REFERENCES notify_requests(id);
+
+ALTER TABLE email_requests
+  ADD COLUMN IF NOT EXISTS notify_request_id UUID REFERENCES notify_requests(id);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_notify_request_id
+  ON notifications(notify_request_id);
+
+CREATE INDEX IF NOT EXISTS idx_email_requests_notify_request_id
+  ON email_requests(notify_request_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS idx_email_requests_notify_request_id;
+DROP INDEX IF EXISTS idx_notifications_notify_request_id;
+
+ALTER TABLE email_requests
+  DROP COLUMN IF EXISTS notify_request_id;
+
+ALTER TABLE notifications
+  DROP COLUMN IF EXISTS notify_request_id;
+
+DROP TABLE IF EXISTS notify_requests;
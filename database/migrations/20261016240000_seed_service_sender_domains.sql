@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- A service without allowed_sender_domains can no longer send email (it
+-- used to fall back to RESEND_ALLOWED_SENDER_DOMAINS). Give each existing
+-- service the domains it has already sent email from, so nothing that
+-- works today stops; any other domain has to be granted through the admin
+-- API.
+UPDATE services s
+SET allowed_sender_domains = used.domains
+FROM (
+    SELECT service_id,
+           array_agg(DISTINCT lower(substring(from_address FROM '@([^@<>[:space:]]+)>?[[:space:]]*$'))) AS domains
+    FROM email_requests
+    WHERE status = 'dispatched'
+      AND substring(from_address FROM '@([^@<>[:space:]]+)>?[[:space:]]*$') IS NOT NULL
+    GROUP BY service_id
+) used
+WHERE s.id = used.service_id
+  AND (s.allowed_sender_domains IS NULL OR cardinality(s.allowed_sender_domains) = 0);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+-- The seeded domains can't be told apart from ones granted since; they are
+-- left in place.
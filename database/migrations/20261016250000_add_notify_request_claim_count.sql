@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- How many times the scheduler has claimed a notify request since it last
+-- made progress. A request whose fallback keeps failing in a way that may
+-- pass later is settled once this passes MAX_RETRY_ATTEMPTS, instead of
+-- being claimed again forever.
+ALTER TABLE notify_requests
+  ADD COLUMN IF NOT EXISTS claim_count INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE notify_requests DROP COLUMN IF EXISTS claim_count;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- Why an email request failed before it reached a provider: a user in
+-- to_user_ids the directory doesn't know, or an email that isn't valid.
+-- Provider errors stay on the request's dispatches.
+ALTER TABLE email_requests
+  ADD COLUMN IF NOT EXISTS failure_reason TEXT;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE email_requests DROP COLUMN IF EXISTS failure_reason;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- The notification_type an SMS was sent as, and the numbers it was not
+-- sent to because their user opted out. An SMS every recipient opted out
+-- of gets the status 'suppressed'.
+ALTER TABLE sms_requests
+  ADD COLUMN IF NOT EXISTS notification_type VARCHAR(100),
+  ADD COLUMN IF NOT EXISTS suppressed_numbers TEXT[];
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE sms_requests
+  DROP COLUMN IF EXISTS suppressed_numbers,
+  DROP COLUMN IF EXISTS notification_type;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- NULLs are distinct in a unique index, so the (webhook_id, recipient)
+-- index let a redelivered webhook with no recipient be recorded again.
+-- Drop the copies it let through, keeping the first, then index the
+-- recipient as '' when it is missing so those collide too.
+DELETE FROM email_delivery_events e
+USING email_delivery_events first
+WHERE e.webhook_id = first.webhook_id
+  AND e.recipient IS NULL
+  AND first.recipient IS NULL
+  AND (first.recorded_at, first.id) < (e.recorded_at, e.id);
+
+DROP INDEX IF EXISTS idx_delivery_events_webhook_id_recipient;
+CREATE UNIQUE INDEX idx_delivery_events_webhook_id_recipient
+  ON email_delivery_events(webhook_id, COALESCE(recipient, ''));
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+-- The duplicates deleted on the way up are not restored.
+DROP INDEX IF EXISTS idx_delivery_events_webhook_id_recipient;
+CREATE UNIQUE INDEX idx_delivery_events_webhook_id_recipient
+  ON email_delivery_events(webhook_id, recipient);
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- A notify request's in-app notification: one row per user, which the
+-- user's inbox lists until they dismiss it. user_id is a Verisafe user id
+-- and, like notification_preferences.user_id, has no foreign key to users:
+-- an inbox entry doesn't wait on the user directory.
+CREATE TABLE IF NOT EXISTS in_app_notifications (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id           UUID NOT NULL,
+    service_id        VARCHAR(255) NOT NULL REFERENCES services(id),
+    notify_request_id UUID NOT NULL REFERENCES notify_requests(id),
+
+    notification_type VARCHAR(100),
+    title             TEXT NOT NULL,
+    body              TEXT NOT NULL,
+    url               TEXT,
+
+    status            VARCHAR(50) NOT NULL,
+    -- delivered | suppressed
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    read_at           TIMESTAMPTZ,
+    dismissed_at      TIMESTAMPTZ,
+
+    -- A retried notify request doesn't put a notification in an inbox twice.
+    UNIQUE (notify_request_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_in_app_notifications_inbox
+  ON in_app_notifications(user_id, created_at DESC, id DESC)
+  WHERE status = 'delivered' AND dismissed_at IS NULL;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS idx_in_app_notifications_inbox;
+
+DROP TABLE IF EXISTS in_app_notifications;
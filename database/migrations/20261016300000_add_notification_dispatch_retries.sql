@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- How many times the scheduler has put a scheduled push back after a
+-- dispatch that failed in a way that may pass later. Once this reaches
+-- MAX_RETRY_ATTEMPTS the push is left failed instead.
+ALTER TABLE notifications
+  ADD COLUMN IF NOT EXISTS dispatch_retries INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS dispatch_retries;
@@ -3,16 +3,22 @@
 -- it in place if this is a retry of the same queue_message_id (dead-lettered
 -- redelivery) — retrying must not hit the queue_message_id UNIQUE
 -- constraint as a fresh insert, or the retry mechanism would just fail
--- forever on the second attempt without ever reaching Resend again.
+-- forever on the second attempt without ever reaching Resend again. The
+-- delivery columns are overwritten too, so they describe the delivery that
+-- last handled the request.
 INSERT INTO email_requests (
   service_id,
   queue_message_id,
   exchange,
   routing_key,
+  amqp_message_id,
+  attempt_count,
 
   from_address,
   reply_to,
   to_addresses,
+  to_user_ids,
+  resolved_to_addresses,
   cc_addresses,
   bcc_addresses,
   subject,
@@ -22,13 +28,22 @@ INSERT INTO email_requests (
 
   template_id,
   template_vars,
+  notification_type,
+  transactional,
 
   status,
+  failure_reason,
   processed_at
 
-) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
 ON CONFLICT (queue_message_id) DO UPDATE SET
+  exchange = EXCLUDED.exchange,
+  routing_key = EXCLUDED.routing_key,
+  amqp_message_id = EXCLUDED.amqp_message_id,
+  attempt_count = EXCLUDED.attempt_count,
+  resolved_to_addresses = EXCLUDED.resolved_to_addresses,
   status = EXCLUDED.status,
+  failure_reason = EXCLUDED.failure_reason,
   processed_at = EXCLUDED.processed_at
 RETURNING *;
 
@@ -46,12 +61,18 @@ limit 1
 ;
 
 -- name: GetEmailRequestByService :many
--- Orders the time it was recieved ie the most previous
+-- Keyset-paginated, most recently received first. Pass the received_at and
+-- id of the last row of the previous page as the cursor, or NULLs for the
+-- first page.
 select *
 from email_requests
-where service_id = $1
-order by received_at desc
-limit $2 offset $3
+where service_id = sqlc.arg(service_id)
+  and (
+    sqlc.narg(cursor_received_at)::timestamptz is null
+    or (received_at, id) < (sqlc.narg(cursor_received_at)::timestamptz, sqlc.narg(cursor_id)::uuid)
+  )
+order by received_at desc, id desc
+limit sqlc.arg(page_size)
 ;
 
 -- name: GetEmailRequestByID :one
@@ -70,6 +91,27 @@ UPDATE email_requests
 RETURNING *;
 
 
+-- name: SetEmailRequestSuppressedAddresses :exec
+-- Records the recipients an email request's latest attempt left out, NULL
+-- if it left out none.
+UPDATE email_requests
+  SET suppressed_addresses = $2
+  WHERE id = $1;
+
+-- name: CancelEmailRequest :one
+-- Cancels an email whose last attempt failed and is waiting in the retry
+-- queue; Send skips a cancelled request when the retry arrives. A
+-- dispatched email can't be recalled. Only the service that sent the
+-- email may cancel it.
+UPDATE email_requests
+  SET status = 'cancelled',
+      cancelled_at = NOW()
+  WHERE queue_message_id = $1
+    AND service_id = $2
+    AND status IN ('failed', 'circuit_open')
+RETURNING *;
+
+
 -- name: CreateEmailDispatch :one
 -- Records an email dispatch to the email sending service for compliance
 -- purposes
@@ -79,6 +121,89 @@ INSERT INTO email_dispatches(
   resend_payload,
   status,
   http_status_code,
-  resend_error
-) VALUES ($1, $2, $3, $4, $5, $6)
+  resend_error,
+  provider,
+  provider_message_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
+
+-- name: GetEmailDispatchesByRequestID :many
+-- Every attempt to hand an email request to a provider, oldest first.
+select *
+from email_dispatches
+where email_request_id = $1
+order by dispatched_at asc, id asc
+;
+
+-- name: GetEmailDeliveryEventsByRequestID :many
+-- Every delivery event Resend reported for any of an email request's
+-- dispatches, in the order they happened.
+select e.*
+from email_delivery_events e
+join email_dispatches d on d.id = e.dispatch_id
+where d.email_request_id = $1
+order by e.occurred_at asc, e.id asc
+;
+
+-- name: GetEmailDispatchByResendEmailID :one
+-- Resolves the dispatch a Resend webhook is reporting on; resend_email_id is
+-- only ever set on a dispatch Resend actually accepted.
+select *
+from email_dispatches
+where resend_email_id = $1
+limit 1
+;
+
+-- name: CreateEmailDeliveryEvent :exec
+-- Records a Resend delivery event (delivered, bounced, complained, ...)
+-- against its dispatch. A redelivered webhook (same webhook_id and
+-- recipient, or no recipient either time) is silently ignored rather than
+-- recorded twice.
+INSERT INTO email_delivery_events(
+  dispatch_id,
+  resend_email_id,
+  event_type,
+  recipient,
+  raw_payload,
+  occurred_at,
+  webhook_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT DO NOTHING;
+
+-- name: CreateEmailUnsubscribe :exec
+-- Records that address unsubscribed from a service's non-transactional
+-- email. Unsubscribing twice keeps the first time.
+INSERT INTO email_unsubscribes (service_id, address)
+VALUES ($1, lower(@address::text))
+ON CONFLICT (service_id, address) DO NOTHING;
+
+-- name: ListUnsubscribedAddresses :many
+-- The addresses, of those given (lowercased), that unsubscribed from the
+-- service's non-transactional email.
+SELECT address
+FROM email_unsubscribes
+WHERE service_id = $1
+  AND address = ANY(@addresses::text[]);
+
+-- name: LinkEmailRequestToNotifyRequest :exec
+-- Marks the email sent under queue_message_id as one channel of a notify
+-- request.
+UPDATE email_requests
+SET notify_request_id = @notify_request_id
+WHERE queue_message_id = @queue_message_id;
+
+-- name: GetEmailRequestsByNotifyRequestID :many
+SELECT * FROM email_requests
+WHERE notify_request_id = $1
+ORDER BY received_at ASC;
+
+-- name: EmailRequestWasDelivered :one
+-- Whether Resend reported any sign that an email request reached its
+-- recipient's inbox.
+SELECT EXISTS (
+  SELECT 1
+  FROM email_delivery_events e
+  JOIN email_dispatches d ON d.id = e.dispatch_id
+  WHERE d.email_request_id = $1
+    AND e.event_type IN ('email.delivered', 'email.opened', 'email.clicked')
+);
@@ -0,0 +1,55 @@
+-- name: CreateInAppNotification :exec
+-- Puts a notify request's notification in a user's inbox. A retried notify
+-- request leaves the one already there as it is.
+INSERT INTO in_app_notifications (
+  user_id,
+  service_id,
+  notify_request_id,
+  notification_type,
+  title,
+  body,
+  url,
+  status
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (notify_request_id, user_id) DO NOTHING;
+
+-- name: GetInAppNotificationsByNotifyRequestID :many
+SELECT * FROM in_app_notifications
+WHERE notify_request_id = $1
+ORDER BY created_at, id;
+
+-- name: ListInboxNotifications :many
+-- A user's inbox: their delivered in-app notifications not yet dismissed,
+-- keyset-paginated, newest first. Pass the created_at and id of the last
+-- row of the previous page as the cursor, or NULLs for the first page.
+SELECT * FROM in_app_notifications
+WHERE user_id = sqlc.arg(user_id)
+  AND status = 'delivered'
+  AND dismissed_at IS NULL
+  AND (
+    sqlc.narg(cursor_created_at)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkInAppNotificationRead :one
+-- Marks one of a user's in-app notifications read. Reading it again keeps
+-- the first read_at.
+UPDATE in_app_notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1
+  AND user_id = $2
+  AND status = 'delivered'
+RETURNING *;
+
+-- name: DismissInAppNotification :one
+-- Takes one of a user's in-app notifications out of their inbox. A
+-- dismissed notification counts as read.
+UPDATE in_app_notifications
+SET dismissed_at = COALESCE(dismissed_at, NOW()),
+    read_at = COALESCE(read_at, NOW())
+WHERE id = $1
+  AND user_id = $2
+  AND status = 'delivered'
+RETURNING *;
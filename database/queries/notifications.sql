@@ -59,12 +59,15 @@ INSERT INTO notifications (
     onesignal_response,
     onesignal_error,
     queue_message_id,
-    status
+    status,
+    buttons,
+    web_buttons,
+    suppressed_user_ids
 
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
     $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39,
-    $40, $41, $42, $43, $44, $45, $46, $47, $48, $49, $50, $51, $52, $53, $54, $55
+    $40, $41, $42, $43, $44, $45, $46, $47, $48, $49, $50, $51, $52, $53, $54, $55, $56, $57, $58
 )
 ON CONFLICT (queue_message_id) DO UPDATE SET
     app_id = EXCLUDED.app_id,
@@ -121,6 +124,9 @@ ON CONFLICT (queue_message_id) DO UPDATE SET
     onesignal_response = EXCLUDED.onesignal_response,
     onesignal_error = EXCLUDED.onesignal_error,
     status = EXCLUDED.status,
+    buttons = EXCLUDED.buttons,
+    web_buttons = EXCLUDED.web_buttons,
+    suppressed_user_ids = EXCLUDED.suppressed_user_ids,
     updated_at = NOW()
 RETURNING *;
 
@@ -142,25 +148,40 @@ SELECT * FROM notifications
 WHERE onesignal_notification_id = $1;
 
 -- name: GetNotificationsByTargetUser :many
-SELECT * FROM notifications 
-WHERE target_user_id = $1
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3;
+-- Keyset-paginated, newest first. Pass the created_at and id of the last
+-- row of the previous page as the cursor, or NULLs for the first page.
+SELECT * FROM notifications
+WHERE target_user_id = sqlc.arg(target_user_id)
+  AND (
+    sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
 
 -- name: GetNotificationsByType :many
-SELECT * FROM notifications 
-WHERE notification_type = $1
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3;
+-- Keyset-paginated, newest first. Pass the created_at and id of the last
+-- row of the previous page as the cursor, or NULLs for the first page.
+SELECT * FROM notifications
+WHERE notification_type = sqlc.arg(notification_type)
+  AND (
+    sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
 
 -- name: GetNotificationsByStatus :many
-SELECT * FROM notifications 
-WHERE status = $1
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3;
+-- Keyset-paginated, newest first. Pass the created_at and id of the last
+-- row of the previous page as the cursor, or NULLs for the first page.
+SELECT * FROM notifications
+WHERE status = sqlc.arg(status)
+  AND (
+    sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
 
 -- name: GetPendingNotifications :many
 SELECT * FROM notifications 
@@ -169,12 +190,89 @@ WHERE status = 'pending'
 ORDER BY created_at ASC
 LIMIT $1;
 
+-- name: ClaimDueNotifications :many
+-- Claims scheduled notifications whose send_after has passed by flipping
+-- them to 'dispatching' in the same statement that selects them. SKIP
+-- LOCKED makes concurrent replicas claim disjoint rows instead of queueing
+-- behind each other, so no row is handed to two dispatchers. A row stuck in
+-- 'dispatching' past stale_after_seconds (its replica died mid-dispatch)
+-- is claimed again. send_after is stored as UTC wall-clock time.
+UPDATE notifications
+SET
+    status = 'dispatching',
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM notifications
+    WHERE (status = 'pending' AND send_after <= (NOW() AT TIME ZONE 'UTC'))
+       OR (status = 'dispatching'
+           AND updated_at < NOW() - make_interval(secs => @stale_after_seconds::int))
+    ORDER BY send_after ASC
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CancelPendingNotification :one
+-- Cancels a push that hasn't gone out yet: one waiting for its send_after,
+-- or one whose failed attempt is waiting in the retry queue. A push the
+-- scheduler has already claimed ('dispatching') is past cancelling. Only
+-- the service that sent the push may cancel it.
+UPDATE notifications
+SET
+    status = 'cancelled',
+    cancelled_at = NOW(),
+    updated_at = NOW()
+WHERE queue_message_id = $1
+  AND source_service_id = $2
+  AND status IN ('pending', 'failed', 'circuit_open')
+RETURNING *;
+
+-- name: MarkNotificationCancelled :exec
+-- Records a push OneSignal itself was holding as cancelled, once OneSignal
+-- has accepted the cancellation.
+UPDATE notifications
+SET
+    status = 'cancelled',
+    cancelled_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryScheduledNotification :exec
+-- Puts a claimed push whose dispatch failed in a way that may pass later
+-- back to 'pending', due again at send_after, and counts the retry.
+UPDATE notifications
+SET
+    status = 'pending',
+    send_after = $2,
+    dispatch_retries = dispatch_retries + 1,
+    updated_at = NOW()
+WHERE id = $1
+  AND status IN ('dispatching', 'failed');
+
+-- name: RescheduleNotification :one
+-- Moves the send_after of a push still waiting for the scheduler. Only a
+-- 'pending' row qualifies; send_after is stored as UTC wall-clock time.
+-- Only the service that sent the push may reschedule it.
+UPDATE notifications
+SET
+    send_after = $2,
+    updated_at = NOW()
+WHERE queue_message_id = $1
+  AND source_service_id = $3
+  AND status = 'pending'
+RETURNING *;
+
 -- name: GetNotificationsByExternalUserID :many
-SELECT * FROM notifications 
-WHERE $1 = ANY(include_external_user_ids)
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3;
+-- Keyset-paginated, newest first. Pass the created_at and id of the last
+-- row of the previous page as the cursor, or NULLs for the first page.
+SELECT * FROM notifications
+WHERE include_external_user_ids @> ARRAY[sqlc.arg(external_user_id)::text]
+  AND (
+    sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
 
 -- name: UpdateNotificationStatus :exec
 UPDATE notifications
@@ -191,9 +289,18 @@ SET
 WHERE id = $1;
 
 -- name: MarkNotificationAsRead :exec
+-- Keeps the first read time: OneSignal reports a click per device, and a
+-- later click must not move read_at forward.
+UPDATE notifications
+SET
+    read_at = COALESCE(read_at, NOW()),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkNotificationAsDismissed :exec
 UPDATE notifications
 SET
-    read_at = NOW(),
+    dismissed_at = COALESCE(dismissed_at, NOW()),
     updated_at = NOW()
 WHERE id = $1;
 
@@ -237,3 +344,15 @@ DELETE FROM notifications
 WHERE created_at < NOW() - INTERVAL '90 days'
   AND status IN ('delivered', 'failed');
 
+
+-- name: LinkNotificationToNotifyRequest :exec
+-- Marks the push sent under queue_message_id as one channel of a notify
+-- request.
+UPDATE notifications
+SET notify_request_id = @notify_request_id
+WHERE queue_message_id = @queue_message_id;
+
+-- name: GetNotificationsByNotifyRequestID :many
+SELECT * FROM notifications
+WHERE notify_request_id = $1
+ORDER BY created_at ASC;
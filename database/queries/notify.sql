@@ -0,0 +1,70 @@
+-- name: UpsertNotifyRequest :one
+-- Persists a notify request, or updates its delivery columns in place when
+-- the same queue_message_id is retried, the same way UpsertEmailRequest
+-- does.
+INSERT INTO notify_requests (
+  service_id,
+  queue_message_id,
+  exchange,
+  routing_key,
+  amqp_message_id,
+  attempt_count,
+
+  to_user_ids,
+  channels,
+  policy,
+  fallback_after_minutes,
+  payload
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (queue_message_id) DO UPDATE SET
+  exchange = EXCLUDED.exchange,
+  routing_key = EXCLUDED.routing_key,
+  amqp_message_id = EXCLUDED.amqp_message_id,
+  attempt_count = EXCLUDED.attempt_count,
+  updated_at = NOW()
+RETURNING *;
+
+-- name: GetNotifyRequestByQueueMessageID :one
+-- Used to detect a duplicate notify.send, like
+-- GetEmailRequestByQueueMessageID.
+SELECT * FROM notify_requests
+WHERE queue_message_id = $1
+LIMIT 1;
+
+-- name: GetNotifyRequestByID :one
+SELECT * FROM notify_requests
+WHERE id = $1;
+
+-- name: UpdateNotifyRequestProgress :exec
+-- Records where a notify request stands: its status, and for one waiting
+-- on a fallback, which channel is next and when. Progress resets
+-- claim_count.
+UPDATE notify_requests
+SET
+    status = $2,
+    next_channel = $3,
+    next_attempt_at = $4,
+    claim_count = 0,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: ClaimDueNotifyRequests :many
+-- Claims notify requests whose fallback is due by flipping them to
+-- 'dispatching', the way ClaimDueNotifications claims scheduled pushes. A
+-- row stuck in 'dispatching' past stale_after_seconds is claimed again;
+-- claim_count says how many times in a row it has been.
+UPDATE notify_requests
+SET
+    status = 'dispatching',
+    claim_count = claim_count + 1,
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM notify_requests
+    WHERE (status = 'pending' AND next_attempt_at <= NOW())
+       OR (status = 'dispatching'
+           AND updated_at < NOW() - make_interval(secs => @stale_after_seconds::int))
+    ORDER BY next_attempt_at ASC
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
@@ -0,0 +1,13 @@
+-- name: CreateParkedMessageAction :one
+-- Records a triage action on the parked queue, whether or not it succeeded.
+INSERT INTO parked_message_actions (
+  action,
+  actor,
+  parked_message_id,
+  original_exchange,
+  original_routing_key,
+  body,
+  succeeded,
+  error
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
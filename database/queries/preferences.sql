@@ -0,0 +1,76 @@
+-- name: UpsertNotificationPreference :exec
+-- Records a user's preference for a notification type on a channel. An
+-- update older than the one already stored is ignored, so a retried event
+-- can't undo a newer one.
+INSERT INTO notification_preferences (
+  user_id,
+  channel,
+  notification_type,
+  enabled,
+  source_service_id,
+  updated_at
+) VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, channel, notification_type) DO UPDATE SET
+  enabled = EXCLUDED.enabled,
+  source_service_id = EXCLUDED.source_service_id,
+  updated_at = EXCLUDED.updated_at
+WHERE notification_preferences.updated_at <= EXCLUDED.updated_at;
+
+-- name: DeleteNotificationPreference :exec
+-- Resets a preference to the default of sending, unless it was set after
+-- the reset was asked for.
+DELETE FROM notification_preferences
+WHERE user_id = $1
+  AND channel = $2
+  AND notification_type = $3
+  AND updated_at <= @reset_at;
+
+-- name: DeleteNotificationPreferencesByUserID :exec
+DELETE FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: ListOptedOutUserIDs :many
+-- The user_ids, of those given, that don't want notification_type on
+-- channel. A user's preference for the type itself wins over their '*'
+-- preference.
+SELECT user_id::text AS user_id
+FROM (
+  SELECT DISTINCT ON (user_id) user_id, enabled
+  FROM notification_preferences
+  WHERE channel = @channel
+    AND user_id::text = ANY(@user_ids::text[])
+    AND notification_type IN (@notification_type::varchar, '*')
+  ORDER BY user_id, notification_type = '*'
+) preference
+WHERE NOT enabled;
+
+-- name: ListOptedOutEmailAddresses :many
+-- The addresses, of those given, whose user doesn't want
+-- notification_type by email. Addresses are matched and returned
+-- lowercased.
+SELECT email
+FROM (
+  SELECT DISTINCT ON (u.id) lower(u.email)::text AS email, p.enabled
+  FROM users u
+  JOIN notification_preferences p ON p.user_id = u.id
+  WHERE lower(u.email) = ANY(@addresses::text[])
+    AND p.channel = 'email'
+    AND p.notification_type IN (@notification_type::varchar, '*')
+  ORDER BY u.id, p.notification_type = '*'
+) preference
+WHERE NOT enabled;
+
+-- name: ListOptedOutPhoneNumbers :many
+-- The numbers, of those given, whose user doesn't want notification_type
+-- by SMS. Numbers are matched as the user directory stores them.
+SELECT phone
+FROM (
+  SELECT DISTINCT ON (u.id) u.phone::text AS phone, p.enabled
+  FROM users u
+  JOIN notification_preferences p ON p.user_id = u.id
+  WHERE u.phone = ANY(@numbers::text[])
+    AND p.channel = 'sms'
+    AND p.notification_type IN (@notification_type::varchar, '*')
+  ORDER BY u.id, p.notification_type = '*'
+) preference
+WHERE NOT enabled;
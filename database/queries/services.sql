@@ -6,3 +6,35 @@ INSERT INTO services (id, name)
 VALUES ($1, $2)
 ON CONFLICT (id) DO UPDATE SET is_active = services.is_active
 RETURNING *;
+
+-- name: GetServiceOneSignalApp :one
+-- Looks up the OneSignal app a service has its own credentials for. No row,
+-- or NULL columns, mean the service uses the globally configured app.
+SELECT onesignal_app_id, onesignal_rest_api_key
+FROM services
+WHERE id = $1;
+
+-- name: GetOneSignalAppKey :one
+-- The REST API key some service holds for a OneSignal app, for calls about
+-- a push that went out through that app after the service that sent it
+-- moved to another.
+SELECT onesignal_rest_api_key::text
+FROM services
+WHERE onesignal_app_id = $1
+  AND onesignal_rest_api_key IS NOT NULL
+LIMIT 1;
+
+-- name: GetServiceSenderDomains :one
+-- The domains a service may send email from. NULL or empty means none.
+SELECT id, allowed_sender_domains
+FROM services
+WHERE id = $1;
+
+-- name: SetServiceSenderDomains :one
+-- Replaces the domains a service may send email from, registering the
+-- service if it hasn't sent anything yet so it can be set up beforehand.
+INSERT INTO services (id, name, allowed_sender_domains)
+VALUES (@id, @id, @allowed_sender_domains::text[])
+ON CONFLICT (id) DO UPDATE
+  SET allowed_sender_domains = EXCLUDED.allowed_sender_domains
+RETURNING id, allowed_sender_domains;
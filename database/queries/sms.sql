@@ -0,0 +1,67 @@
+-- name: UpsertSmsRequest :one
+-- Persists an SMS request, or updates it in place when the same
+-- queue_message_id is retried, the same way UpsertEmailRequest does.
+INSERT INTO sms_requests (
+  service_id,
+  queue_message_id,
+  exchange,
+  routing_key,
+  amqp_message_id,
+  attempt_count,
+
+  to_numbers,
+  to_user_ids,
+  body,
+  sender_id,
+  notification_type,
+
+  status,
+  processed_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (queue_message_id) DO UPDATE SET
+  exchange = EXCLUDED.exchange,
+  routing_key = EXCLUDED.routing_key,
+  amqp_message_id = EXCLUDED.amqp_message_id,
+  attempt_count = EXCLUDED.attempt_count,
+  status = EXCLUDED.status,
+  processed_at = EXCLUDED.processed_at
+RETURNING *;
+
+-- name: GetSmsRequestByQueueMessageID :one
+-- Used to detect a duplicate send before calling the provider, like
+-- GetEmailRequestByQueueMessageID.
+select *
+from sms_requests
+where queue_message_id = $1
+limit 1
+;
+
+-- name: SetSmsRequestResolvedNumbers :exec
+-- Records the numbers an SMS request's to_user_ids resolved to.
+UPDATE sms_requests
+  SET resolved_to_numbers = $2
+  WHERE id = $1;
+
+-- name: SetSmsRequestSuppressedNumbers :exec
+-- Records the numbers an SMS request was not sent to because their user
+-- opted out.
+UPDATE sms_requests
+  SET suppressed_numbers = $2
+  WHERE id = $1;
+
+-- name: UpdateSmsRequestStatusByID :exec
+UPDATE sms_requests
+  SET status = $2
+  WHERE id = $1;
+
+-- name: CreateSmsDispatch :one
+-- Records an attempt to hand an SMS request to the provider.
+INSERT INTO sms_dispatches(
+  sms_request_id,
+  provider,
+  status,
+  http_status_code,
+  provider_error,
+  recipients
+) VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
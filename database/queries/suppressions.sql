@@ -0,0 +1,32 @@
+-- name: CreateEmailSuppression :one
+-- Adds an address to the suppression list. An address already on it keeps
+-- its original entry, and no row is returned.
+INSERT INTO email_suppressions (
+  address,
+  reason,
+  dispatch_id,
+  note,
+  created_by
+) VALUES (lower(@address::text), @reason, @dispatch_id, @note, @created_by)
+ON CONFLICT (address) DO NOTHING
+RETURNING *;
+
+-- name: GetEmailSuppression :one
+SELECT * FROM email_suppressions
+WHERE address = lower(@address::text);
+
+-- name: ListEmailSuppressions :many
+-- The most recently suppressed addresses first.
+SELECT * FROM email_suppressions
+ORDER BY created_at DESC, address
+LIMIT @page_size;
+
+-- name: DeleteEmailSuppression :one
+DELETE FROM email_suppressions
+WHERE address = lower(@address::text)
+RETURNING *;
+
+-- name: ListSuppressedAddresses :many
+-- Which of addresses, bare and lowercased, are on the suppression list.
+SELECT address FROM email_suppressions
+WHERE address = ANY(@addresses::text[]);
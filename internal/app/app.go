@@ -6,35 +6,55 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"sync"
 	"time"
 
-	"github.com/OneSignal/onesignal-go-api/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/opencrafts-io/gossip-monger/database"
 	"github.com/opencrafts-io/gossip-monger/internal/broker"
 	"github.com/opencrafts-io/gossip-monger/internal/broker/consumers"
 	"github.com/opencrafts-io/gossip-monger/internal/config"
+	"github.com/opencrafts-io/gossip-monger/internal/metrics"
 	"github.com/opencrafts-io/gossip-monger/internal/middleware"
 	"github.com/opencrafts-io/gossip-monger/internal/repository"
 	"github.com/opencrafts-io/gossip-monger/internal/resilience"
 	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/opencrafts-io/gossip-monger/internal/tracing"
 	"github.com/resend/resend-go/v3"
 )
 
+// smsProviderTimeout bounds a call to the SMS provider.
+const smsProviderTimeout = 30 * time.Second
+
 type GossipMonger struct {
 	rabbitMQConn broker.Connection
 	pool         *pgxpool.Pool
 	config       *config.Config
 	logger       *slog.Logger
-	// Track all running consumers for graceful shutdown
-	consumerWg      sync.WaitGroup
+	// Track all running consumers for graceful shutdown and readiness
+	consumers       *consumerTracker
 	cancelConsumers context.CancelFunc
 
+	resendClient  *resend.Client
+	pushScheduler *service.PushScheduler
+	// notifyScheduler sends the fallback channel of notify requests once
+	// it falls due.
+	notifyScheduler *service.NotifyScheduler
+	// shutdownTracing flushes the spans still buffered for export
+	shutdownTracing func(context.Context) error
+
 	// Services
-	pushNotificationSvc service.PushNotificationService
-	userService         service.UserService
-	emailService        service.EmailService
+	pushNotificationSvc  service.PushNotificationService
+	userService          service.UserService
+	emailService         service.EmailService
+	smsService           service.SmsService
+	notifyService        service.NotifyService
+	preferenceService    service.PreferenceService
+	historyService       service.HistoryService
+	inboxService         service.InboxService
+	suppressionService   service.EmailSuppressionService
+	senderDomainService  service.SenderDomainService
+	parkedMessageService service.ParkedMessageService
+	healthService        service.HealthService
 }
 
 // Creates a new gossip-monger application ready to service requests
@@ -42,6 +62,16 @@ func NewGossipMongerApp(
 	logger *slog.Logger,
 	cfg *config.Config,
 ) (*GossipMonger, error) {
+	shutdownTracing, err := tracing.Setup(
+		context.Background(),
+		cfg.TracingConfig.OTLPEndpoint,
+		cfg.TracingConfig.ServiceName,
+		cfg.TracingConfig.SampleRatio,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	dbConfig, err := pgxpool.ParseConfig(fmt.Sprintf(
 		"postgresql://%s:%s@%s:%d/%s?sslmode=disable",
 		cfg.DatabaseConfig.DatabaseUser,
@@ -54,6 +84,7 @@ func NewGossipMongerApp(
 		return nil, err
 	}
 
+	dbConfig.ConnConfig.Tracer = tracing.NewPgxTracer()
 	dbConfig.MaxConns = cfg.DatabaseConfig.DatabasePoolMaxConnections
 	dbConfig.MinConns = cfg.DatabaseConfig.DatabasePoolMinConnections
 	dbConfig.MaxConnLifetime = time.Hour * time.Duration(
@@ -64,6 +95,12 @@ func NewGossipMongerApp(
 	if err != nil {
 		return nil, err
 	}
+	if err := metrics.RegisterPool(connPool); err != nil {
+		return nil, fmt.Errorf("failed to register database pool metrics: %w", err)
+	}
+	if err := metrics.RegisterBreakers(resilience.BreakerStates); err != nil {
+		return nil, fmt.Errorf("failed to register circuit breaker metrics: %w", err)
+	}
 
 	rabbitMQConnString := fmt.Sprintf("amqp://%s:%s@%s:%d/",
 		cfg.RabbitMQConfig.RabbitMQUser,
@@ -75,6 +112,8 @@ func NewGossipMongerApp(
 	rabbitMQConn, err := broker.NewRabbitMQConnection(
 		context.Background(),
 		rabbitMQConnString,
+		time.Duration(cfg.RabbitMQConfig.ReconnectMaxBackoffSeconds)*time.Second,
+		logger,
 	)
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -82,20 +121,6 @@ func NewGossipMongerApp(
 			err,
 		)
 	}
-	onesignalConfig := onesignal.NewConfiguration()
-	onesignalConfig.AddDefaultHeader("Authorization",
-		fmt.Sprintf("Basic %s", cfg.OneSignalConfig.RestAPIKey),
-	)
-	oneSignalService := onesignal.NewAPIClient(onesignalConfig)
-
-	if oneSignalService == nil {
-		logger.Error(
-			"Failed to initialize onesignal api client",
-			slog.Any("oneSignalService", oneSignalService),
-		)
-		panic("Failed to initialize one signal service")
-
-	}
 	breakerSettings := resilience.Settings{
 		ConsecutiveFailures: cfg.BreakerConfig.ConsecutiveFailures,
 		OpenTimeout: time.Duration(
@@ -108,37 +133,147 @@ func NewGossipMongerApp(
 	pnsvc := service.NewPushNotificationService(
 		querier,
 		logger,
-		oneSignalService,
+		service.OneSignalApp{
+			AppID:      cfg.OneSignalConfig.AppID,
+			RestAPIKey: cfg.OneSignalConfig.RestAPIKey,
+		},
 		breakerSettings,
 	)
 
-	resendClient := resend.NewClient(cfg.ResendConfig.ResendAPIKey)
+	pushScheduler := service.NewPushScheduler(
+		querier,
+		pnsvc,
+		time.Duration(cfg.SchedulerConfig.PollIntervalSeconds)*time.Second,
+		cfg.SchedulerConfig.BatchSize,
+		time.Duration(cfg.SchedulerConfig.ClaimTimeoutSeconds)*time.Second,
+		cfg.RabbitMQConfig.MaxRetryAttempts,
+		cfg.RabbitMQConfig.RetryDelays,
+		logger,
+	)
+
+	resendClient := service.NewResendClient(cfg.ResendConfig.ResendAPIKey)
+
+	var smtpProvider service.EmailProvider
+	if cfg.SMTPConfig.Host != "" {
+		smtpProvider = service.NewSMTPProvider(
+			cfg.SMTPConfig.Host,
+			cfg.SMTPConfig.Port,
+			cfg.SMTPConfig.Username,
+			cfg.SMTPConfig.Password,
+		)
+	}
 
 	emailService := service.NewEmailService(
 		connPool,
 		resendClient,
-		cfg.ResendConfig.AllowedSenderDomains,
+		smtpProvider,
+		service.UnsubscribeLinks{
+			BaseURL: cfg.UnsubscribeConfig.BaseURL,
+			Secret:  cfg.UnsubscribeConfig.Secret,
+		},
+		breakerSettings,
+		logger,
+	)
+
+	smsService := service.NewSmsService(
+		querier,
+		service.NewAfricasTalkingProvider(
+			cfg.AfricasTalkingConfig.BaseURL,
+			cfg.AfricasTalkingConfig.Username,
+			cfg.AfricasTalkingConfig.APIKey,
+			tracing.NewHTTPClient(smsProviderTimeout),
+		),
+		cfg.AfricasTalkingConfig.SenderID,
 		breakerSettings,
 		logger,
 	)
 
+	notifyService := service.NewNotifyService(
+		querier,
+		pnsvc,
+		emailService,
+		cfg.RabbitMQConfig.MaxRetryAttempts,
+		logger,
+	)
+
+	notifyScheduler := service.NewNotifyScheduler(
+		querier,
+		notifyService,
+		time.Duration(cfg.SchedulerConfig.PollIntervalSeconds)*time.Second,
+		cfg.SchedulerConfig.BatchSize,
+		time.Duration(cfg.SchedulerConfig.ClaimTimeoutSeconds)*time.Second,
+		logger,
+	)
+
 	userService := service.NewUserService(connPool, logger)
 
+	preferenceService := service.NewPreferenceService(querier, logger)
+
+	historyService := service.NewHistoryService(querier, logger)
+
+	inboxService := service.NewInboxService(querier, logger)
+
+	suppressionService := service.NewEmailSuppressionService(querier, logger)
+
+	senderDomainService := service.NewSenderDomainService(querier, logger)
+
+	parkedMessageService := service.NewParkedMessageService(
+		broker.NewParkedQueueBrowser(rabbitMQConn, logger),
+		querier,
+		logger,
+	)
+
+	consumers := newConsumerTracker(logger)
+
+	healthService := service.NewHealthService(
+		[]service.HealthCheck{
+			service.RabbitMQHealthCheck(rabbitMQConn),
+			service.DatabaseHealthCheck(connPool, logger),
+			service.MigrationsHealthCheck(
+				func(ctx context.Context) (int64, int64, error) {
+					return database.MigrationVersions(ctx, connPool)
+				},
+				logger,
+			),
+			service.ConsumersHealthCheck(consumers.stopped),
+			service.ProviderHealthCheck(),
+		},
+		logger,
+	)
+
 	return &GossipMonger{
-		rabbitMQConn:        rabbitMQConn,
-		pool:                connPool,
-		config:              cfg,
-		logger:              logger,
-		pushNotificationSvc: pnsvc,
-		userService:         userService,
-		emailService:        emailService,
+		rabbitMQConn:         rabbitMQConn,
+		pool:                 connPool,
+		config:               cfg,
+		logger:               logger,
+		consumers:            consumers,
+		resendClient:         resendClient,
+		shutdownTracing:      shutdownTracing,
+		pushScheduler:        pushScheduler,
+		notifyScheduler:      notifyScheduler,
+		pushNotificationSvc:  pnsvc,
+		userService:          userService,
+		emailService:         emailService,
+		smsService:           smsService,
+		notifyService:        notifyService,
+		preferenceService:    preferenceService,
+		historyService:       historyService,
+		inboxService:         inboxService,
+		suppressionService:   suppressionService,
+		senderDomainService:  senderDomainService,
+		parkedMessageService: parkedMessageService,
+		healthService:        healthService,
 	}, nil
 }
 
 func (gm *GossipMonger) Start(ctx context.Context) error {
 	database.RunGooseMigrations(gm.logger, gm.pool)
 
-	gm.startConsumers(ctx)
+	// Consumers are stopped by shutDown, after the HTTP server, so they
+	// get their own context rather than ctx.
+	consumerCtx, cancelConsumers := context.WithCancel(context.Background())
+	gm.cancelConsumers = cancelConsumers
+	gm.startConsumers(consumerCtx)
 
 	router := LoadRoutes(gm)
 
@@ -192,83 +327,121 @@ func (gm *GossipMonger) startConsumers(ctx context.Context) {
 		gm.config.RabbitMQConfig.RetryDelaySeconds,
 	) * time.Second
 
-	if err := broker.DeclareRetryTopology(
-		gm.rabbitMQConn,
-		retryDelay,
-		"gossip.topic.exchange",
-		[]string{"gossip.emails.send", "gossip.push.send"},
-	); err != nil {
+	declareRetryTopology := func() error {
+		return broker.DeclareRetryTopology(
+			gm.rabbitMQConn,
+			retryDelay,
+			gm.config.RabbitMQConfig.RetryDelays,
+			"gossip.topic.exchange",
+			[]string{
+				"gossip.emails.send",
+				"gossip.emails.cancel",
+				"gossip.push.send",
+				"gossip.push.cancel",
+				"gossip.push.reschedule",
+				"gossip.sms.send",
+				"gossip.notify.send",
+				"gossip.preferences.update",
+				"gossip.preferences.reset",
+			},
+		)
+	}
+	if err := declareRetryTopology(); err != nil {
 		gm.logger.Error(
 			"failed to declare retry topology, failed sends will not be retried",
 			slog.Any("error", err),
 		)
 	}
+	// Consumers re-declare their own queues when they restart; the retry
+	// topology has no consumer, so it is re-declared on reconnect.
+	gm.rabbitMQConn.OnReconnect(declareRetryTopology)
 
-	maxRetryAttempts := gm.config.RabbitMQConfig.MaxRetryAttempts
+	retryPolicy := broker.RetryPolicy{
+		MaxAttempts: gm.config.RabbitMQConfig.MaxRetryAttempts,
+		Delays:      gm.config.RabbitMQConfig.RetryDelays,
+	}
+	workerPool := broker.WorkerPoolConfig{
+		Workers: gm.config.RabbitMQConfig.ConsumerWorkers,
+		DrainTimeout: time.Duration(
+			gm.config.RabbitMQConfig.ConsumerDrainTimeoutSeconds,
+		) * time.Second,
+	}
 
 	pushNotificationConsumer := consumers.NewPushNotificationConsumer(
 		gm.rabbitMQConn,
 		gm.pushNotificationSvc,
-		maxRetryAttempts,
+		retryPolicy,
+		workerPool,
 		gm.logger,
 	)
 
 	userConsumer := consumers.NewUserConsumer(
 		gm.rabbitMQConn,
 		gm.userService,
+		workerPool,
 		gm.logger,
 	)
 
 	emailConsumer := consumers.NewEmailConsumer(
 		gm.rabbitMQConn,
 		gm.emailService,
-		maxRetryAttempts,
+		retryPolicy,
+		workerPool,
 		gm.logger,
 	)
 
-	gm.consumerWg.Add(1)
-	go func() {
-		defer gm.consumerWg.Done()
-		if err := pushNotificationConsumer.Start(ctx); err != nil {
-			gm.logger.Error(
-				"Push notification consumer stopped",
-				slog.Any("error", err),
-			)
-		}
-	}()
+	smsConsumer := consumers.NewSmsConsumer(
+		gm.rabbitMQConn,
+		gm.smsService,
+		retryPolicy,
+		workerPool,
+		gm.logger,
+	)
 
-	gm.consumerWg.Add(1)
-	go func() {
-		defer gm.consumerWg.Done()
-		if err := userConsumer.Start(ctx); err != nil {
-			gm.logger.Error(
-				"User events consumer stopped",
-				slog.Any("error", err),
-			)
-		}
-	}()
-	gm.consumerWg.Add(1)
-	go func() {
-		defer gm.consumerWg.Done()
-		if err := emailConsumer.Start(ctx); err != nil {
-			gm.logger.Error(
-				"Email events consumer stopped",
-				slog.Any("error", err),
-			)
-		}
-	}()
+	notifyConsumer := consumers.NewNotifyConsumer(
+		gm.rabbitMQConn,
+		gm.notifyService,
+		retryPolicy,
+		workerPool,
+		gm.logger,
+	)
+
+	preferenceConsumer := consumers.NewPreferenceConsumer(
+		gm.rabbitMQConn,
+		gm.preferenceService,
+		retryPolicy,
+		workerPool,
+		gm.logger,
+	)
+
+	gm.consumers.start(ctx, "push_notification_consumer", pushNotificationConsumer.Start)
+	gm.consumers.start(ctx, "user_consumer", userConsumer.Start)
+	gm.consumers.start(ctx, "email_consumer", emailConsumer.Start)
+	gm.consumers.start(ctx, "sms_consumer", smsConsumer.Start)
+	gm.consumers.start(ctx, "notify_consumer", notifyConsumer.Start)
+	gm.consumers.start(ctx, "preference_consumer", preferenceConsumer.Start)
+	gm.consumers.start(ctx, "push_scheduler", gm.pushScheduler.Start)
+	gm.consumers.start(ctx, "notify_scheduler", gm.notifyScheduler.Start)
 }
 
 func (gm *GossipMonger) shutDown() {
-	// Close the consumers
+	// Close the consumers. Each one drains the messages it is handling
+	// before it returns.
 	gm.logger.Info("Shutting down consumers...")
 	if gm.cancelConsumers != nil {
 		gm.cancelConsumers()
 	}
-	gm.consumerWg.Wait()
+	gm.consumers.wait()
 
 	// Close the rabbitmq connection
 	if err := gm.rabbitMQConn.Close(); err != nil {
 		panic(err)
 	}
+
+	// Export the spans of the last messages handled
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := gm.shutdownTracing(ctx); err != nil {
+		gm.logger.Error("failed to flush traces", slog.Any("error", err))
+	}
 }
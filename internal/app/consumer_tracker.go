@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// consumerTracker runs the long-lived background workers (consumers and
+// the push scheduler) and remembers which of them are still running, for
+// the readiness check.
+type consumerTracker struct {
+	logger *slog.Logger
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func newConsumerTracker(logger *slog.Logger) *consumerTracker {
+	return &consumerTracker{logger: logger, running: map[string]bool{}}
+}
+
+// start runs run in its own goroutine under name until it returns.
+func (ct *consumerTracker) start(
+	ctx context.Context,
+	name string,
+	run func(ctx context.Context) error,
+) {
+	ct.mu.Lock()
+	ct.running[name] = true
+	ct.mu.Unlock()
+
+	ct.wg.Add(1)
+	go func() {
+		defer ct.wg.Done()
+		err := run(ctx)
+
+		ct.mu.Lock()
+		ct.running[name] = false
+		ct.mu.Unlock()
+
+		if err != nil && !errors.Is(err, context.Canceled) {
+			ct.logger.Error("background worker stopped",
+				slog.String("worker", name),
+				slog.Any("error", err),
+			)
+		}
+	}()
+}
+
+// stopped returns the names of the workers that have returned, sorted.
+func (ct *consumerTracker) stopped() []string {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	var names []string
+	for name, running := range ct.running {
+		if !running {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// wait blocks until every worker has returned.
+func (ct *consumerTracker) wait() {
+	ct.wg.Wait()
+}
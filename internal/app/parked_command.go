@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// parkedUsage is printed for "parked" with no or an unknown action.
+const parkedUsage = `usage: gossip-monger parked <action> [flags] [id]
+
+actions:
+  list               print the oldest parked messages
+  inspect <id>       print one parked message, leaving it parked
+  replay <id>        send a parked message back to its original routing key
+  discard <id>       drop a parked message; its body is kept in the audit log
+
+flags:
+  -actor string      who is acting, recorded in the audit log (default "cli:$USER")
+  -limit int         how many messages list prints (default 50)
+`
+
+// RunParkedCommand runs the "parked" CLI subcommand against the parked
+// queue, writing its result to out as JSON. args are the arguments after
+// "parked". Every action is audited, as through the admin API.
+func (gm *GossipMonger) RunParkedCommand(
+	ctx context.Context,
+	args []string,
+	out io.Writer,
+) error {
+	defer gm.pool.Close()
+	defer gm.rabbitMQConn.Close()
+
+	if len(args) == 0 {
+		return errors.New(parkedUsage)
+	}
+	action := args[0]
+
+	flags := flag.NewFlagSet("parked "+action, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	actor := flags.String("actor", defaultCLIActor(), "")
+	limit := flags.Int("limit", 0, "")
+	if err := flags.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%w\n\n%s", err, parkedUsage)
+	}
+
+	var result any
+	var err error
+	switch action {
+	case "list":
+		result, err = gm.parkedMessageService.List(ctx, *actor, *limit)
+	case "inspect", "replay", "discard":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("parked %s takes exactly one message id\n\n%s", action, parkedUsage)
+		}
+		id := flags.Arg(0)
+		switch action {
+		case "inspect":
+			result, err = gm.parkedMessageService.Inspect(ctx, *actor, id)
+		case "replay":
+			result, err = gm.parkedMessageService.Replay(ctx, *actor, id)
+		default:
+			result, err = gm.parkedMessageService.Discard(ctx, *actor, id)
+		}
+	default:
+		return fmt.Errorf("unknown action %q\n\n%s", action, parkedUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func defaultCLIActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
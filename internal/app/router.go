@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/opencrafts-io/gossip-monger/internal/handlers"
+	"github.com/opencrafts-io/gossip-monger/internal/metrics"
+	"github.com/opencrafts-io/gossip-monger/internal/middleware"
 )
 
 func LoadRoutes(gm *GossipMonger) http.Handler {
@@ -11,6 +13,85 @@ func LoadRoutes(gm *GossipMonger) http.Handler {
 
 	ph := handlers.PingHandler{}
 
+	rwh := handlers.ResendWebhookHandler{
+		EmailService: gm.emailService,
+		Verifier:     gm.resendClient.Webhooks,
+		Secret:       gm.config.ResendConfig.WebhookSecret,
+		Logger:       gm.logger,
+	}
+
+	owh := handlers.OneSignalWebhookHandler{
+		PushNotificationService: gm.pushNotificationSvc,
+		Secret:                  gm.config.OneSignalConfig.WebhookSecret,
+		Logger:                  gm.logger,
+	}
+
+	hh := handlers.HistoryHandler{
+		HistoryService: gm.historyService,
+		Logger:         gm.logger,
+	}
+
+	ih := handlers.InboxHandler{
+		InboxService: gm.inboxService,
+		Logger:       gm.logger,
+	}
+
+	sh := handlers.EmailSuppressionHandler{
+		EmailSuppressionService: gm.suppressionService,
+		Logger:                  gm.logger,
+	}
+
+	sdh := handlers.SenderDomainHandler{
+		SenderDomainService: gm.senderDomainService,
+		Logger:              gm.logger,
+	}
+
+	pmh := handlers.ParkedMessageHandler{
+		ParkedMessageService: gm.parkedMessageService,
+		Logger:               gm.logger,
+	}
+
+	uh := handlers.UnsubscribeHandler{
+		EmailService: gm.emailService,
+		Logger:       gm.logger,
+	}
+
+	hch := handlers.HealthHandler{
+		HealthService: gm.healthService,
+	}
+
+	admin := middleware.BearerAuth(gm.config.AdminConfig.APIToken)
+	inbox := middleware.BearerAuth(gm.config.InboxConfig.APIToken)
+
 	router.HandleFunc("GET /ping", ph.Ping)
+	router.HandleFunc("GET /healthz", hch.Live)
+	router.HandleFunc("GET /readyz", hch.Ready)
+	router.Handle("GET /metrics", middleware.BearerAuth(gm.config.MetricsConfig.Token)(metrics.Handler()))
+	router.HandleFunc("POST /webhooks/resend", rwh.Handle)
+	router.HandleFunc("POST /webhooks/onesignal", owh.Handle)
+	router.HandleFunc("GET /unsubscribe", uh.Confirm)
+	router.HandleFunc("POST /unsubscribe", uh.Unsubscribe)
+
+	router.Handle("GET /v1/notifications", admin(http.HandlerFunc(hh.ListNotifications)))
+	router.Handle("GET /v1/services/{service_id}/emails", admin(http.HandlerFunc(hh.ListServiceEmails)))
+	router.Handle("GET /v1/emails/{id}", admin(http.HandlerFunc(hh.GetEmail)))
+	router.Handle("GET /v1/notify/{id}", admin(http.HandlerFunc(hh.GetNotifyRequest)))
+
+	router.Handle("GET /v1/users/{user_id}/inbox", inbox(http.HandlerFunc(ih.List)))
+	router.Handle("POST /v1/users/{user_id}/inbox/{id}/read", inbox(http.HandlerFunc(ih.MarkRead)))
+	router.Handle("POST /v1/users/{user_id}/inbox/{id}/dismiss", inbox(http.HandlerFunc(ih.Dismiss)))
+
+	router.Handle("GET /v1/email-suppressions", admin(http.HandlerFunc(sh.List)))
+	router.Handle("GET /v1/email-suppressions/{address}", admin(http.HandlerFunc(sh.Get)))
+	router.Handle("POST /v1/email-suppressions", admin(http.HandlerFunc(sh.Add)))
+	router.Handle("DELETE /v1/email-suppressions/{address}", admin(http.HandlerFunc(sh.Remove)))
+
+	router.Handle("GET /v1/services/{service_id}/sender-domains", admin(http.HandlerFunc(sdh.Get)))
+	router.Handle("PUT /v1/services/{service_id}/sender-domains", admin(http.HandlerFunc(sdh.Set)))
+
+	router.Handle("GET /v1/parked", admin(http.HandlerFunc(pmh.List)))
+	router.Handle("GET /v1/parked/{id}", admin(http.HandlerFunc(pmh.Inspect)))
+	router.Handle("POST /v1/parked/{id}/replay", admin(http.HandlerFunc(pmh.Replay)))
+	router.Handle("POST /v1/parked/{id}/discard", admin(http.HandlerFunc(pmh.Discard)))
 	return router
 }
@@ -2,59 +2,192 @@ package broker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// ErrNotConnected is returned by Channel while the connection to the broker
+// is down and being re-established.
+var ErrNotConnected = errors.New("not connected to RabbitMQ, reconnecting")
+
+// initialReconnectBackoff is the first wait before retrying a lost
+// connection; it doubles on each failed attempt up to the configured
+// maximum.
+const initialReconnectBackoff = 500 * time.Millisecond
+
 type Connection interface {
 	// Channel opens a new AMQP channel. amqp.Channel is not safe for
 	// concurrent RPC calls (Declare/Bind/Qos/Consume), so callers must not
 	// share a channel across goroutines — each consumer/publish operation
 	// should get its own via a fresh call to Channel().
 	Channel() (*amqp.Channel, error)
+	// OnReconnect registers hook to run each time the connection is
+	// re-established after being lost, e.g. to re-declare topology that
+	// nothing else declares. Hooks run in registration order.
+	OnReconnect(hook func() error)
 	Close() error
 	IsClosed() bool
 }
 
+// RabbitMQConnection is a connection to RabbitMQ that re-dials with
+// exponential backoff whenever the broker drops it, until Close is called.
+// Channels opened before a drop die with it; their owners open new ones
+// (Consumer.Consume does this on its own).
 type RabbitMQConnection struct {
-	conn *amqp.Connection
+	dsn        string
+	maxBackoff time.Duration
+	logger     *slog.Logger
+
+	mu      sync.RWMutex
+	conn    *amqp.Connection
+	hooks   []func() error
+	closing bool
+	done    chan struct{}
 }
 
+// NewRabbitMQConnection dials dsn. The initial dial is not retried, so a
+// misconfigured broker address fails startup; a connection lost after that
+// is re-dialed, waiting at most maxBackoff between attempts.
 func NewRabbitMQConnection(
 	ctx context.Context,
 	dsn string,
+	maxBackoff time.Duration,
+	logger *slog.Logger,
 ) (*RabbitMQConnection, error) {
 	conn, err := amqp.Dial(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
-	return &RabbitMQConnection{
-		conn: conn,
-	}, nil
+	rc := &RabbitMQConnection{
+		dsn:        dsn,
+		maxBackoff: max(maxBackoff, initialReconnectBackoff),
+		logger:     logger,
+		conn:       conn,
+		done:       make(chan struct{}),
+	}
+	go rc.watch(conn.NotifyClose(make(chan *amqp.Error, 1)))
+	return rc, nil
 }
 
 // Channel opens a new AMQP channel on the underlying connection. Each call
 // returns a distinct channel — callers own its lifecycle and must close it
 // when done.
 func (rc *RabbitMQConnection) Channel() (*amqp.Channel, error) {
-	ch, err := rc.conn.Channel()
+	rc.mu.RLock()
+	conn := rc.conn
+	rc.mu.RUnlock()
+
+	if conn == nil || conn.IsClosed() {
+		return nil, ErrNotConnected
+	}
+
+	ch, err := conn.Channel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create channel: %w", err)
 	}
 	return ch, nil
 }
 
-// Close closes the connection
+func (rc *RabbitMQConnection) OnReconnect(hook func() error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.hooks = append(rc.hooks, hook)
+}
+
+// Close closes the connection and stops any reconnection in progress.
 func (rc *RabbitMQConnection) Close() error {
-	if rc.conn != nil {
-		return rc.conn.Close()
+	rc.mu.Lock()
+	if rc.closing {
+		rc.mu.Unlock()
+		return nil
+	}
+	rc.closing = true
+	close(rc.done)
+	conn := rc.conn
+	rc.mu.Unlock()
+
+	if conn != nil && !conn.IsClosed() {
+		return conn.Close()
 	}
 	return nil
 }
 
-// IsClosed checks if the connection is closed
+// IsClosed reports whether there is currently no usable connection, either
+// because Close was called or because a reconnection is in progress.
 func (rc *RabbitMQConnection) IsClosed() bool {
-	return rc.conn == nil || rc.conn.IsClosed()
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.closing || rc.conn == nil || rc.conn.IsClosed()
+}
+
+// watch waits for a connection to close, as reported on closes, and unless
+// Close closed it, reconnects. closes must be registered with NotifyClose
+// before watch is started, or a drop in between would go unnoticed.
+func (rc *RabbitMQConnection) watch(closes chan *amqp.Error) {
+	closeErr := <-closes
+
+	rc.mu.RLock()
+	closing := rc.closing
+	rc.mu.RUnlock()
+	if closing {
+		return
+	}
+
+	rc.logger.Warn("lost connection to RabbitMQ, reconnecting",
+		"error", closeErr,
+	)
+	rc.reconnect()
+}
+
+// reconnect dials until it succeeds or Close is called, then runs the
+// reconnect hooks and resumes watching the new connection.
+func (rc *RabbitMQConnection) reconnect() {
+	backoff := initialReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		// Full jitter, so replicas don't all hit a recovering broker at
+		// once.
+		wait := time.Duration(rand.Int64N(int64(backoff))) + time.Millisecond
+		select {
+		case <-rc.done:
+			return
+		case <-time.After(wait):
+		}
+
+		conn, err := amqp.Dial(rc.dsn)
+		if err != nil {
+			rc.logger.Warn("failed to reconnect to RabbitMQ",
+				"attempt", attempt,
+				"error", err,
+			)
+			backoff = min(backoff*2, rc.maxBackoff)
+			continue
+		}
+
+		rc.mu.Lock()
+		if rc.closing {
+			rc.mu.Unlock()
+			conn.Close()
+			return
+		}
+		rc.conn = conn
+		hooks := append([]func() error(nil), rc.hooks...)
+		rc.mu.Unlock()
+
+		rc.logger.Info("reconnected to RabbitMQ", "attempts", attempt)
+		for _, hook := range hooks {
+			if err := hook(); err != nil {
+				rc.logger.Error("reconnect hook failed", "error", err)
+			}
+		}
+
+		go rc.watch(conn.NotifyClose(make(chan *amqp.Error, 1)))
+		return
+	}
 }
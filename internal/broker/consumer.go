@@ -4,8 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/opencrafts-io/gossip-monger/internal/metrics"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type ExchangeType string
@@ -16,8 +21,45 @@ const (
 	TopicExchangeType  ExchangeType = "topic"
 )
 
+// Delivery is a consumed message and where it came from, as handed to a
+// MessageHandler.
+type Delivery struct {
+	Body []byte
+	// Exchange and RoutingKey are those the message was last routed with.
+	// A message redelivered from a retry queue keeps its original routing
+	// key.
+	Exchange   string
+	RoutingKey string
+	// MessageID is the AMQP message-id property, empty if the publisher
+	// didn't set one. It is kept across retries.
+	MessageID string
+	Headers   amqp.Table
+	Timestamp time.Time
+	// Redelivered is set when the broker redelivers a message that was
+	// never acked, e.g. after a lost connection. Retries are new
+	// deliveries, and don't set it.
+	Redelivered bool
+	// Attempt is 1 for a message's first delivery, and one more for each
+	// time it has failed and been retried since.
+	Attempt int
+}
+
+// newDelivery wraps msg for a MessageHandler.
+func newDelivery(msg amqp.Delivery) Delivery {
+	return Delivery{
+		Body:        msg.Body,
+		Exchange:    msg.Exchange,
+		RoutingKey:  msg.RoutingKey,
+		MessageID:   msg.MessageId,
+		Headers:     msg.Headers,
+		Timestamp:   msg.Timestamp,
+		Redelivered: msg.Redelivered,
+		Attempt:     deathCount(msg.Headers) + 1,
+	}
+}
+
 // MessageHandler is the callback function that processes consumed messages
-type MessageHandler func(ctx context.Context, message []byte) error
+type MessageHandler func(ctx context.Context, delivery Delivery) error
 
 // MessageConsumer defines the contract for consuming messages from RabbitMQ
 type MessageConsumer interface {
@@ -34,39 +76,63 @@ type MessageConsumer interface {
 
 // Consumer implements MessageConsumer
 type Consumer struct {
-	conn             Connection
-	prefetchCount    int
-	maxRetryAttempts int
-	logger           slog.Logger
+	conn          Connection
+	prefetchCount int
+	retry         RetryPolicy
+	pool          WorkerPoolConfig
+	logger        slog.Logger
+	// publisher parks messages and sends them to retry tiers; a message is
+	// only acked off its queue once the broker has confirmed its copy.
+	publisher *Publisher
 }
 
 // NewConsumer creates a new Consumer instance with configurable prefetch count
 // prefetchCount limits the number of unacknowledged messages delivered to this consumer
 // Recommended: 1 for serial processing, higher for parallel processing
 //
-// maxRetryAttempts only matters for queues declared with a deadLetterExchange
-// (see Consume) — it's ignored otherwise.
+// pool sets how many of those messages are handled at once, and whether
+// any must stay in order. The prefetch count is raised to pool.Workers if
+// it is lower, since a worker without a message to handle sits idle.
+//
+// retry only matters for queues declared with a deadLetterExchange (see
+// Consume) — it's ignored otherwise.
 func NewConsumer(
 	conn Connection,
 	prefetchCount int,
-	maxRetryAttempts int,
+	retry RetryPolicy,
+	pool WorkerPoolConfig,
 	logger slog.Logger,
 ) *Consumer {
 	return &Consumer{
-		conn:             conn,
-		prefetchCount:    prefetchCount,
-		maxRetryAttempts: maxRetryAttempts,
-		logger:           logger,
+		conn:          conn,
+		prefetchCount: max(prefetchCount, pool.Workers),
+		retry:         retry,
+		pool:          pool,
+		logger:        logger,
+		publisher:     NewPublisher(conn, &logger),
 	}
 }
 
+// maxConsumeRestartBackoff caps the wait between attempts to restart a
+// consumer whose channel or connection was lost.
+const maxConsumeRestartBackoff = 30 * time.Second
+
 // Consume starts consuming messages from a queue and passes them to the
-// handler. If deadLetterExchange is non-empty, the queue is declared with
-// it as its x-dead-letter-exchange, and a handler error nacks the message
-// there (instead of discarding it) for automatic delayed redelivery — up to
-// maxRetryAttempts, after which it is routed to the parked queue instead of
-// being nacked again. Pass "" to keep the original discard-on-error
-// behavior (no DLX configured).
+// handler, until ctx is cancelled. Up to pool.Workers messages are handled
+// concurrently. Once ctx is cancelled no new message is handled; the ones
+// in flight get pool.DrainTimeout to finish and be acked before their own
+// context is cancelled, and Consume returns after they have. If the
+// channel or the connection under it is lost, Consume re-declares the
+// exchange, queue and binding and resumes consuming, backing off between
+// attempts while the broker is unreachable.
+//
+// If deadLetterExchange is non-empty, the queue is declared with it as its
+// x-dead-letter-exchange, and a handler error sends the message to its
+// next retry tier (see RetryPolicy) for delayed redelivery, falling back
+// to nacking it into deadLetterExchange if that fails. After
+// retry.MaxAttempts failures, or straight away for a PermanentError, it is
+// routed to the parked queue instead. Pass "" to keep the original
+// discard-on-error behavior (no DLX configured).
 func (c *Consumer) Consume(
 	ctx context.Context,
 	exchange string,
@@ -76,10 +142,57 @@ func (c *Consumer) Consume(
 	deadLetterExchange string,
 	handler MessageHandler,
 ) error {
+	backoff := initialReconnectBackoff
+	for {
+		started, err := c.consume(
+			ctx,
+			exchange,
+			exchangeType,
+			queue,
+			bindingKey,
+			deadLetterExchange,
+			handler,
+		)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if started {
+			// It got as far as consuming, so the broker was reachable:
+			// start the backoff over.
+			backoff = initialReconnectBackoff
+		}
+
+		c.logger.Warn("consumer interrupted, restarting",
+			"queue", queue,
+			"retry_in", backoff,
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConsumeRestartBackoff)
+	}
+}
+
+// consume runs one consuming session on a fresh channel: it declares the
+// topology, then handles messages until ctx is cancelled or the channel
+// closes, and waits for the messages in flight to finish. started reports
+// whether it got as far as consuming.
+func (c *Consumer) consume(
+	ctx context.Context,
+	exchange string,
+	exchangeType ExchangeType,
+	queue string,
+	bindingKey string,
+	deadLetterExchange string,
+	handler MessageHandler,
+) (started bool, err error) {
 	ch, err := c.conn.Channel()
 	if err != nil {
 		c.logger.Error("cannot consume messages", "error", err)
-		return err
+		return false, err
 	}
 	defer ch.Close()
 	err = ch.ExchangeDeclare(
@@ -92,7 +205,7 @@ func (c *Consumer) Consume(
 		nil,                  // arguments
 	)
 	if err != nil {
-		return fmt.Errorf("failed to declare exchange: %w", err)
+		return false, fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
 	var queueArgs amqp.Table
@@ -109,7 +222,7 @@ func (c *Consumer) Consume(
 		queueArgs, // arguments
 	)
 	if err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
+		return false, fmt.Errorf("failed to declare queue: %w", err)
 	}
 
 	err = ch.QueueBind(
@@ -120,7 +233,7 @@ func (c *Consumer) Consume(
 		nil,        // arguments
 	)
 	if err != nil {
-		return fmt.Errorf("failed to bind queue: %w", err)
+		return false, fmt.Errorf("failed to bind queue: %w", err)
 	}
 
 	err = ch.Qos(
@@ -134,100 +247,248 @@ func (c *Consumer) Consume(
 			"prefetch_count", c.prefetchCount,
 			"error", err,
 		)
-		return fmt.Errorf("failed to set QoS: %w", err)
+		return false, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
 	// Start consuming messages
+	consumerTag := "gossip-monger." + uuid.NewString()
 	msgs, err := ch.Consume(
-		queue, // queue name
-		"",    // consumer tag (auto-generated)
-		false, // auto-acknowledge
-		false, // exclusive
-		false, // no-local
-		false, // no-wait
-		nil,   // args
+		queue,       // queue name
+		consumerTag, // consumer tag
+		false,       // auto-acknowledge
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
 	)
 	if err != nil {
 		c.logger.Error("failed to start consuming",
 			"queue", queue,
 			"error", err,
 		)
-		return fmt.Errorf("failed to consume from queue: %w", err)
+		return false, fmt.Errorf("failed to consume from queue: %w", err)
 	}
 
-	c.logger.Info("consumer started", "queue", queue)
+	c.logger.Info("consumer started",
+		"queue", queue,
+		"workers", max(c.pool.Workers, 1),
+		"ordered", c.pool.OrderingKey != nil,
+	)
+
+	// Handlers run on a context that outlives ctx, so a message being
+	// handled at shutdown can finish instead of failing half-done. It is
+	// cancelled only if draining takes longer than the drain timeout.
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	workers := startWorkerPool(
+		c.pool.Workers,
+		c.pool.OrderingKey,
+		func(msg amqp.Delivery) {
+			c.handle(handlerCtx, msg, queue, deadLetterExchange, handler)
+		},
+	)
+	drain := func() {
+		workers.stop(c.pool.DrainTimeout, func() {
+			c.logger.Warn("in-flight messages did not finish in time, cancelling them",
+				"queue", queue,
+				"drain_timeout", c.pool.DrainTimeout,
+			)
+			cancelHandlers()
+		})
+	}
 
 	// Listen for messages
 	for {
 		select {
 		case <-ctx.Done():
+			// Stop the broker sending more; anything already sent but not
+			// yet handled is requeued when the channel closes.
+			if err := ch.Cancel(consumerTag, false); err != nil {
+				c.logger.Warn("failed to cancel consumer", "queue", queue, "error", err)
+			}
+			c.logger.Info("consumer stopping, draining in-flight messages", "queue", queue)
+			drain()
 			c.logger.Info("consumer stopped", "queue", queue)
-			return ctx.Err()
+			return true, ctx.Err()
 		case msg, ok := <-msgs:
 			if !ok {
 				c.logger.Error("message channel closed", "queue", queue)
-				return fmt.Errorf("message channel closed for queue: %s", queue)
+				// The messages in flight can't be acked any more; the
+				// broker redelivers them.
+				drain()
+				return true, fmt.Errorf("message channel closed for queue: %s", queue)
 			}
+			metrics.MessagesConsumed.WithLabelValues(queue).Inc()
+			// Returns false only when ctx is cancelled, which the next
+			// iteration handles.
+			workers.dispatch(ctx, msg)
+		}
+	}
+}
+
+// handle runs handler for msg and acks or nacks it. It is called from
+// several workers at once; amqp091 serialises the acks on the channel.
+func (c *Consumer) handle(
+	ctx context.Context,
+	msg amqp.Delivery,
+	queue string,
+	deadLetterExchange string,
+	handler MessageHandler,
+) {
+	ctx, span := startDeliverySpan(ctx, msg, queue)
+	defer span.End()
 
-			// Call the handler with the message
-			if err := handler(ctx, msg.Body); err != nil {
-				c.logger.Error("handler error",
-					"queue", queue,
-					"error", err,
-				)
-
-				if deadLetterExchange != "" && deathCount(msg.Headers) >= c.maxRetryAttempts {
-					if parkErr := c.park(ctx, msg.Body); parkErr != nil {
-						c.logger.Error("failed to park exhausted message, retrying instead",
-							"queue", queue,
-							"error", parkErr,
-						)
-						msg.Nack(false, false)
-					} else {
-						c.logger.Warn("message exhausted retry attempts, parked for manual triage",
-							"queue", queue,
-							"attempts", c.maxRetryAttempts,
-						)
-						msg.Ack(false)
-					}
-				} else {
-					msg.Nack(false, false)
-					// With a dead-letter-exchange configured, this is not a
-					// discard — RabbitMQ routes it into the retry flow.
-				}
-				// Continue consuming, don't stop on handler error
-			} else {
-				msg.Ack(false)
+	// Call the handler with the message
+	start := time.Now()
+	err := handler(ctx, newDelivery(msg))
+	outcome := metrics.OutcomeSuccess
+	if err != nil {
+		outcome = metrics.OutcomeError
+	}
+	metrics.HandlerDuration.WithLabelValues(queue, outcome).
+		Observe(time.Since(start).Seconds())
+	if err == nil {
+		c.ack(msg, queue)
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	c.logger.Error("handler error",
+		"queue", queue,
+		"error", err,
+	)
+
+	if deadLetterExchange == "" {
+		c.nack(msg, queue)
+		return
+	}
+
+	failures := deathCount(msg.Headers)
+	permanent := resilience.IsPermanent(err)
+	if permanent || failures >= c.retry.MaxAttempts {
+		if parkErr := c.park(ctx, msg, queue, err); parkErr != nil {
+			c.logger.Error("failed to park message, retrying instead",
+				"queue", queue,
+				"error", parkErr,
+			)
+			c.nack(msg, queue)
+		} else {
+			reason := metrics.ParkedExhausted
+			if permanent {
+				reason = metrics.ParkedPermanent
 			}
+			metrics.MessagesParked.WithLabelValues(queue, reason).Inc()
+			c.logger.Warn("message parked for manual triage",
+				"queue", queue,
+				"attempts", failures+1,
+				"permanent", permanent,
+			)
+			c.ack(msg, queue)
+		}
+		return
+	}
+
+	if len(c.retry.Delays) > 0 {
+		delay := c.retry.delayFor(failures + 1)
+		retryErr := c.retryLater(ctx, msg, failures+1, delay)
+		if retryErr == nil {
+			metrics.MessagesRetried.WithLabelValues(queue, retryTierName(delay)).Inc()
+			c.logger.Info("message sent for retry",
+				"queue", queue,
+				"attempt", failures+1,
+				"delay", delay,
+			)
+			c.ack(msg, queue)
+			return
 		}
+		c.logger.Error("failed to send message to its retry tier, using the fallback retry queue",
+			"queue", queue,
+			"delay", delay,
+			"error", retryErr,
+		)
 	}
+
+	// With a dead-letter-exchange configured, this is not a discard —
+	// RabbitMQ routes it into the fixed-delay retry queue.
+	c.nack(msg, queue)
+}
+
+// retryLater sends a copy of msg, which has now failed attempt times, to
+// the retry tier for delay. It returns nil once the broker has confirmed
+// the copy, so the caller may then ack the original.
+func (c *Consumer) retryLater(
+	ctx context.Context,
+	msg amqp.Delivery,
+	attempt int,
+	delay time.Duration,
+) error {
+	return c.publisher.PublishMessage(
+		ctx,
+		RetryTierExchange,
+		msg.RoutingKey,
+		retryPublishing(msg, attempt, delay),
+	)
 }
 
-// park republishes body verbatim to the parked queue, for a message that
-// has exhausted its retry attempts and needs manual triage rather than
-// another automatic redelivery.
-func (c *Consumer) park(ctx context.Context, body []byte) error {
-	publisher := NewPublisher(c.conn, &c.logger)
-	return publisher.PublishRaw(ctx, "", ParkedQueue, body)
+// ack and nack settle a single delivery. They fail if the channel it came
+// on has closed, in which case the broker has already requeued it.
+func (c *Consumer) ack(msg amqp.Delivery, queue string) {
+	metrics.MessagesAcked.WithLabelValues(queue).Inc()
+	if err := msg.Ack(false); err != nil {
+		c.logger.Warn("failed to ack message", "queue", queue, "error", err)
+	}
 }
 
-// deathCount returns how many times this message has previously been
-// dead-lettered for handler rejection (RabbitMQ's "rejected" reason),
-// derived from the standard x-death header. Redelivery cycles caused by the
-// retry queue's TTL expiry ("expired" reason) are not counted here — those
-// happen once per rejection and would otherwise double the count.
+func (c *Consumer) nack(msg amqp.Delivery, queue string) {
+	metrics.MessagesNacked.WithLabelValues(queue).Inc()
+	if err := msg.Nack(false, false); err != nil {
+		c.logger.Warn("failed to nack message", "queue", queue, "error", err)
+	}
+}
+
+// park republishes msg to the parked queue, for a message that has
+// exhausted its retry attempts and needs manual triage rather than another
+// automatic redelivery. The body is kept verbatim; headers record where it
+// came from and why it failed, so it can be replayed (see
+// ParkedQueueBrowser). It returns nil only once the broker has confirmed
+// the parked copy, so the caller may then ack the original.
+func (c *Consumer) park(ctx context.Context, msg amqp.Delivery, queue string, handlerErr error) error {
+	return c.publisher.PublishMessage(
+		ctx,
+		"",
+		ParkedQueue,
+		parkedPublishing(msg, queue, handlerErr, time.Now()),
+	)
+}
+
+// deathCount returns how many times this message has previously failed:
+// the count it carried when last sent to a retry tier (HeaderRetryCount),
+// plus the times it has since been dead-lettered for handler rejection
+// (RabbitMQ's "rejected" reason), derived from the standard x-death
+// header. Redelivery cycles caused by a retry queue's TTL expiry
+// ("expired" reason) are not counted here — those happen once per failure
+// and would otherwise double the count.
 func deathCount(headers amqp.Table) int {
+	total := 0
+	switch count := headers[HeaderRetryCount].(type) {
+	case int64:
+		total = int(count)
+	case int32:
+		total = int(count)
+	}
+
 	raw, ok := headers["x-death"]
 	if !ok {
-		return 0
+		return total
 	}
 
 	deaths, ok := raw.([]any)
 	if !ok {
-		return 0
+		return total
 	}
 
-	total := 0
 	for _, d := range deaths {
 		entry, ok := d.(amqp.Table)
 		if !ok {
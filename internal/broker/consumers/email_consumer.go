@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
 	"github.com/opencrafts-io/gossip-monger/internal/service"
 )
 
@@ -20,11 +21,14 @@ type EmailConsumer struct {
 func NewEmailConsumer(
 	conn broker.Connection,
 	emailService service.EmailService,
-	maxRetryAttempts int,
+	retry broker.RetryPolicy,
+	pool broker.WorkerPoolConfig,
 	logger *slog.Logger,
 ) *EmailConsumer {
+	// An email.cancel must not overtake the email.send it cancels.
+	pool.OrderingKey = emailOrderingKey
 	return &EmailConsumer{
-		consumer:     broker.NewConsumer(conn, 10, maxRetryAttempts, *logger),
+		consumer:     broker.NewConsumer(conn, 10, retry, pool, *logger),
 		emailService: emailService,
 		logger:       logger,
 	}
@@ -44,38 +48,61 @@ func (ec *EmailConsumer) Start(ctx context.Context) error {
 
 func (ec *EmailConsumer) handleMessage(
 	ctx context.Context,
-	message []byte,
+	delivery broker.Delivery,
 ) error {
 	var emailMsg service.EmailEvent
-	if err := json.Unmarshal(message, &emailMsg); err != nil {
+	if err := json.Unmarshal(delivery.Body, &emailMsg); err != nil {
 		ec.logger.Error(
 			"failed to unmarshal email message",
 			"error",
 			err,
 		)
-		return err
+		return resilience.Permanent(err)
 	}
 
 	if !strings.HasPrefix(emailMsg.Meta.SourceServiceID, "io.opencrafts.") {
-		return fmt.Errorf(
+		return resilience.Permanent(fmt.Errorf(
 			"wrong service id expected service id to be in the io.opencrafts namespace instead got '%s'",
 			emailMsg.Meta.SourceServiceID,
-		)
+		))
 	}
 
 	switch emailMsg.Meta.EventType {
 	case "email.send":
-		return ec.emailService.Send(ctx, emailMsg)
+		if service.IsReservedRequestID(emailMsg.Meta.RequestID) {
+			return resilience.Permanent(fmt.Errorf(
+				"request_id %q is reserved for email sent by gossip.notify.send",
+				emailMsg.Meta.RequestID,
+			))
+		}
+		return ec.emailService.Send(ctx, emailMsg, delivery)
+	case "email.cancel":
+		return ec.emailService.Cancel(ctx, emailMsg.Meta.SourceServiceID, emailMsg.Meta.TargetRequestID)
 	default:
 		ec.logger.Error(
 			"got wrong event metadata type",
 			slog.String("event_type", emailMsg.Meta.EventType),
 			slog.String("source_service", emailMsg.Meta.SourceServiceID),
 		)
-		return fmt.Errorf(
+		return resilience.Permanent(fmt.Errorf(
 			"wrong event metadata type: %s, source service %s",
 			emailMsg.Meta.EventType,
 			emailMsg.Meta.SourceServiceID,
-		)
+		))
+	}
+}
+
+// emailOrderingKey keys an email event by the request it is about: its own
+// request_id for email.send, the one it targets for email.cancel.
+func emailOrderingKey(message []byte) string {
+	var event struct {
+		Meta service.EmailEventMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
+	}
+	if event.Meta.TargetRequestID != "" {
+		return event.Meta.TargetRequestID
 	}
+	return event.Meta.RequestID
 }
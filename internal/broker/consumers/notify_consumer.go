@@ -0,0 +1,93 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+type NotifyConsumer struct {
+	consumer      broker.MessageConsumer
+	notifyService service.NotifyService
+	logger        *slog.Logger
+}
+
+func NewNotifyConsumer(
+	conn broker.Connection,
+	notifyService service.NotifyService,
+	retry broker.RetryPolicy,
+	pool broker.WorkerPoolConfig,
+	logger *slog.Logger,
+) *NotifyConsumer {
+	// Duplicates of a request are handled one after the other, so the
+	// second sees what the first sent.
+	pool.OrderingKey = notifyOrderingKey
+	return &NotifyConsumer{
+		consumer:      broker.NewConsumer(conn, 10, retry, pool, *logger),
+		notifyService: notifyService,
+		logger:        logger,
+	}
+}
+
+func (nc *NotifyConsumer) Start(ctx context.Context) error {
+	return nc.consumer.Consume(
+		ctx,
+		"gossip.topic.exchange",
+		broker.TopicExchangeType,
+		"gossip.notify.queue",
+		"gossip.notify.*",
+		broker.RetryExchange,
+		nc.handleMessage,
+	)
+}
+
+func (nc *NotifyConsumer) handleMessage(
+	ctx context.Context,
+	delivery broker.Delivery,
+) error {
+	var notifyMsg service.NotifyEvent
+	if err := json.Unmarshal(delivery.Body, &notifyMsg); err != nil {
+		nc.logger.Error("failed to unmarshal notify message", "error", err)
+		return resilience.Permanent(err)
+	}
+
+	if !strings.HasPrefix(notifyMsg.Meta.SourceServiceID, "io.opencrafts.") {
+		return resilience.Permanent(fmt.Errorf(
+			"wrong service id expected service id to be in the io.opencrafts namespace instead got '%s'",
+			notifyMsg.Meta.SourceServiceID,
+		))
+	}
+
+	switch notifyMsg.Meta.EventType {
+	case "notify.send":
+		return nc.notifyService.Send(ctx, notifyMsg, delivery)
+	default:
+		nc.logger.Error(
+			"got wrong event metadata type",
+			slog.String("event_type", notifyMsg.Meta.EventType),
+			slog.String("source_service", notifyMsg.Meta.SourceServiceID),
+		)
+		return resilience.Permanent(fmt.Errorf(
+			"wrong event metadata type: %s, source service %s",
+			notifyMsg.Meta.EventType,
+			notifyMsg.Meta.SourceServiceID,
+		))
+	}
+}
+
+// notifyOrderingKey keys a notify event by its request_id.
+func notifyOrderingKey(message []byte) string {
+	var event struct {
+		Meta service.NotifyEventMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
+	}
+	return event.Meta.RequestID
+}
@@ -0,0 +1,98 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+type PreferenceConsumer struct {
+	consumer          broker.MessageConsumer
+	preferenceService service.PreferenceService
+	logger            *slog.Logger
+}
+
+func NewPreferenceConsumer(
+	conn broker.Connection,
+	preferenceService service.PreferenceService,
+	retry broker.RetryPolicy,
+	pool broker.WorkerPoolConfig,
+	logger *slog.Logger,
+) *PreferenceConsumer {
+	// Changes to one user's preferences apply in the order they were made.
+	pool.OrderingKey = preferenceOrderingKey
+	return &PreferenceConsumer{
+		// Retried like a failed send: a lost opt-out means mail the user
+		// asked not to get.
+		consumer:          broker.NewConsumer(conn, 10, retry, pool, *logger),
+		preferenceService: preferenceService,
+		logger:            logger,
+	}
+}
+
+func (pc *PreferenceConsumer) Start(ctx context.Context) error {
+	return pc.consumer.Consume(
+		ctx,
+		"gossip.topic.exchange",
+		broker.TopicExchangeType,
+		"gossip.preferences.queue",
+		"gossip.preferences.*",
+		broker.RetryExchange,
+		pc.handleMessage,
+	)
+}
+
+func (pc *PreferenceConsumer) handleMessage(
+	ctx context.Context,
+	delivery broker.Delivery,
+) error {
+	var event service.PreferenceEvent
+	if err := json.Unmarshal(delivery.Body, &event); err != nil {
+		pc.logger.Error("failed to unmarshal preference event", "error", err)
+		return resilience.Permanent(err)
+	}
+
+	if !strings.HasPrefix(event.Meta.SourceServiceID, "io.opencrafts.") {
+		return resilience.Permanent(fmt.Errorf(
+			"wrong service id expected service id to be in the io.opencrafts namespace instead got '%s'",
+			event.Meta.SourceServiceID,
+		))
+	}
+
+	switch event.Meta.EventType {
+	case "preferences.update":
+		return pc.preferenceService.Update(ctx, event)
+	case "preferences.reset":
+		return pc.preferenceService.Reset(ctx, event)
+	default:
+		pc.logger.Error(
+			"got wrong event metadata type",
+			slog.String("event_type", event.Meta.EventType),
+			slog.String("source_service", event.Meta.SourceServiceID),
+		)
+		return resilience.Permanent(fmt.Errorf(
+			"wrong event metadata type: %s, source service %s",
+			event.Meta.EventType,
+			event.Meta.SourceServiceID,
+		))
+	}
+}
+
+// preferenceOrderingKey keys a preference event by the user it is about.
+func preferenceOrderingKey(message []byte) string {
+	var event struct {
+		Preference struct {
+			UserID string `json:"user_id"`
+		} `json:"preference"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
+	}
+	return event.Preference.UserID
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
 	"github.com/opencrafts-io/gossip-monger/internal/service"
 )
 
@@ -20,11 +21,15 @@ type PushNotificationConsumer struct {
 func NewPushNotificationConsumer(
 	conn broker.Connection,
 	notificationService service.PushNotificationService,
-	maxRetryAttempts int,
+	retry broker.RetryPolicy,
+	pool broker.WorkerPoolConfig,
 	logger *slog.Logger,
 ) *PushNotificationConsumer {
+	// A push.cancel or push.reschedule must not overtake the push.send it
+	// refers to.
+	pool.OrderingKey = pushOrderingKey
 	return &PushNotificationConsumer{
-		consumer:            broker.NewConsumer(conn, 10, maxRetryAttempts, *logger),
+		consumer:            broker.NewConsumer(conn, 10, retry, pool, *logger),
 		notificationService: notificationService,
 		logger:              logger,
 	}
@@ -44,42 +49,81 @@ func (pnc *PushNotificationConsumer) Start(ctx context.Context) error {
 
 func (pnc *PushNotificationConsumer) handleMessage(
 	ctx context.Context,
-	message []byte,
+	delivery broker.Delivery,
 ) error {
 	var notifMsg service.PushNotificationEvent
-	if err := json.Unmarshal(message, &notifMsg); err != nil {
+	if err := json.Unmarshal(delivery.Body, &notifMsg); err != nil {
 		pnc.logger.Error(
 			"failed to unmarshal notification message",
 			"error",
 			err,
 		)
-		return err
+		return resilience.Permanent(err)
 	}
 
 	if !strings.HasPrefix(notifMsg.Metadata.SourceServiceID, "io.opencrafts.") {
-		return fmt.Errorf(
+		return resilience.Permanent(fmt.Errorf(
 			"wrong service id expected service id to be in the io.opencrafts namespace instead got '%s'",
 			notifMsg.Metadata.SourceServiceID,
-		)
+		))
 	}
 
+	// The metadata's service id is the one checked above, so it's the one
+	// that decides which OneSignal app the push goes out through.
+	notifMsg.Notification.SourceServiceID = &notifMsg.Metadata.SourceServiceID
+
 	switch notifMsg.Metadata.EventType {
 	case "push.send":
+		if service.IsReservedRequestID(notifMsg.Metadata.RequestID) {
+			return resilience.Permanent(fmt.Errorf(
+				"request_id %q is reserved for pushes sent by gossip.notify.send",
+				notifMsg.Metadata.RequestID,
+			))
+		}
 		return pnc.notificationService.Send(
 			ctx,
 			notifMsg.Notification,
 			notifMsg.Metadata.RequestID,
 		)
+	case "push.cancel":
+		return pnc.notificationService.Cancel(
+			ctx,
+			notifMsg.Metadata.SourceServiceID,
+			notifMsg.Metadata.TargetRequestID,
+		)
+	case "push.reschedule":
+		return pnc.notificationService.Reschedule(
+			ctx,
+			notifMsg.Metadata.SourceServiceID,
+			notifMsg.Metadata.TargetRequestID,
+			notifMsg.Notification.SendAfter,
+		)
 	default:
 		pnc.logger.Error(
 			"Got wrong event metadata type",
 			slog.String("event_type", notifMsg.Metadata.EventType),
 			slog.String("source_service", notifMsg.Metadata.SourceServiceID),
 		)
-		return fmt.Errorf(
+		return resilience.Permanent(fmt.Errorf(
 			"wrong event metadata type: %s, source service %s",
 			notifMsg.Metadata.EventType,
 			notifMsg.Metadata.SourceServiceID,
-		)
+		))
+	}
+}
+
+// pushOrderingKey keys a push event by the request it is about: its own
+// request_id for push.send, the one it targets for push.cancel and
+// push.reschedule.
+func pushOrderingKey(message []byte) string {
+	var event struct {
+		Metadata service.PushNotificationEventMetaData `json:"metadata"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
+	}
+	if event.Metadata.TargetRequestID != "" {
+		return event.Metadata.TargetRequestID
 	}
+	return event.Metadata.RequestID
 }
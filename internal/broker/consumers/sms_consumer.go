@@ -0,0 +1,93 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+type SmsConsumer struct {
+	consumer   broker.MessageConsumer
+	smsService service.SmsService
+	logger     *slog.Logger
+}
+
+func NewSmsConsumer(
+	conn broker.Connection,
+	smsService service.SmsService,
+	retry broker.RetryPolicy,
+	pool broker.WorkerPoolConfig,
+	logger *slog.Logger,
+) *SmsConsumer {
+	// Duplicates of a request are handled one after the other, so the
+	// second sees the first already dispatched.
+	pool.OrderingKey = smsOrderingKey
+	return &SmsConsumer{
+		consumer:   broker.NewConsumer(conn, 10, retry, pool, *logger),
+		smsService: smsService,
+		logger:     logger,
+	}
+}
+
+func (sc *SmsConsumer) Start(ctx context.Context) error {
+	return sc.consumer.Consume(
+		ctx,
+		"gossip.topic.exchange",
+		broker.TopicExchangeType,
+		"gossip.sms.queue",
+		"gossip.sms.*",
+		broker.RetryExchange,
+		sc.handleMessage,
+	)
+}
+
+func (sc *SmsConsumer) handleMessage(
+	ctx context.Context,
+	delivery broker.Delivery,
+) error {
+	var smsMsg service.SmsEvent
+	if err := json.Unmarshal(delivery.Body, &smsMsg); err != nil {
+		sc.logger.Error("failed to unmarshal sms message", "error", err)
+		return resilience.Permanent(err)
+	}
+
+	if !strings.HasPrefix(smsMsg.Meta.SourceServiceID, "io.opencrafts.") {
+		return resilience.Permanent(fmt.Errorf(
+			"wrong service id expected service id to be in the io.opencrafts namespace instead got '%s'",
+			smsMsg.Meta.SourceServiceID,
+		))
+	}
+
+	switch smsMsg.Meta.EventType {
+	case "sms.send":
+		return sc.smsService.Send(ctx, smsMsg, delivery)
+	default:
+		sc.logger.Error(
+			"got wrong event metadata type",
+			slog.String("event_type", smsMsg.Meta.EventType),
+			slog.String("source_service", smsMsg.Meta.SourceServiceID),
+		)
+		return resilience.Permanent(fmt.Errorf(
+			"wrong event metadata type: %s, source service %s",
+			smsMsg.Meta.EventType,
+			smsMsg.Meta.SourceServiceID,
+		))
+	}
+}
+
+// smsOrderingKey keys an SMS event by its request_id.
+func smsOrderingKey(message []byte) string {
+	var event struct {
+		Meta service.SmsEventMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
+	}
+	return event.Meta.RequestID
+}
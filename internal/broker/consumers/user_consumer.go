@@ -19,12 +19,15 @@ type UserConsumer struct {
 func NewUserConsumer(
 	conn broker.Connection,
 	userService service.UserService,
+	pool broker.WorkerPoolConfig,
 	logger *slog.Logger,
 ) *UserConsumer {
+	// Changes to one user apply in the order Verisafe made them.
+	pool.OrderingKey = userOrderingKey
 	return &UserConsumer{
 		// No dead-letter exchange: user sync doesn't call a third-party API,
 		// so it keeps the original discard-on-error behavior.
-		consumer:    broker.NewConsumer(conn, 10, 0, *logger),
+		consumer:    broker.NewConsumer(conn, 10, broker.RetryPolicy{}, pool, *logger),
 		userService: userService,
 		logger:      logger,
 	}
@@ -44,10 +47,10 @@ func (uc *UserConsumer) Start(ctx context.Context) error {
 
 func (uc *UserConsumer) handleMessage(
 	ctx context.Context,
-	message []byte,
+	delivery broker.Delivery,
 ) error {
 	var event service.UserEvent
-	if err := json.Unmarshal(message, &event); err != nil {
+	if err := json.Unmarshal(delivery.Body, &event); err != nil {
 		uc.logger.Error("failed to unmarshal user event", "error", err)
 		return err
 	}
@@ -78,3 +81,16 @@ func (uc *UserConsumer) handleMessage(
 		)
 	}
 }
+
+// userOrderingKey keys a user event by the user it is about.
+func userOrderingKey(message []byte) string {
+	var event struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
+	}
+	return event.User.ID
+}
@@ -0,0 +1,364 @@
+package broker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers a parked message carries so it can be triaged and replayed to
+// where it came from.
+const (
+	HeaderParkedID           = "x-gossip-parked-id"
+	HeaderParkedAt           = "x-gossip-parked-at"
+	HeaderOriginalExchange   = "x-gossip-original-exchange"
+	HeaderOriginalRoutingKey = "x-gossip-original-routing-key"
+	HeaderOriginalQueue      = "x-gossip-original-queue"
+	// HeaderDeathHistory is a copy of the x-death header the message had
+	// when it was parked. RabbitMQ owns x-death itself, so it isn't
+	// republished under that name.
+	HeaderDeathHistory = "x-gossip-death-history"
+	HeaderLastError    = "x-gossip-last-error"
+	// HeaderReplayedFrom marks a message replayed out of the parked queue
+	// with the parked id it was replayed from.
+	HeaderReplayedFrom = "x-gossip-replayed-from"
+)
+
+// maxParkedErrorBytes caps how much of a handler error is kept on a parked
+// message.
+const maxParkedErrorBytes = 4096
+
+// MaxParkedScan is how many parked messages a single triage operation looks
+// through. Messages further back in the queue aren't reachable until the
+// ones ahead of them are replayed or discarded.
+const MaxParkedScan = 1000
+
+var (
+	// ErrParkedMessageNotFound is returned when no parked message within
+	// the first MaxParkedScan has the requested id.
+	ErrParkedMessageNotFound = errors.New("parked message not found")
+	// ErrParkedMessageNotReplayable is returned for a message parked before
+	// its original routing key was recorded.
+	ErrParkedMessageNotReplayable = errors.New(
+		"parked message has no original routing key to replay to",
+	)
+)
+
+// DeathRecord is one entry of a parked message's x-death history: how many
+// times it was dead-lettered from a queue, and why.
+type DeathRecord struct {
+	Queue       string     `json:"queue"`
+	Reason      string     `json:"reason"`
+	Count       int64      `json:"count"`
+	Exchange    string     `json:"exchange"`
+	RoutingKeys []string   `json:"routing_keys"`
+	Time        *time.Time `json:"time,omitempty"`
+}
+
+// ParkedMessage is a message sitting in the parked queue, decoded from the
+// headers Consumer.park sets.
+type ParkedMessage struct {
+	ID                 string        `json:"id"`
+	ParkedAt           *time.Time    `json:"parked_at,omitempty"`
+	OriginalExchange   string        `json:"original_exchange"`
+	OriginalRoutingKey string        `json:"original_routing_key"`
+	OriginalQueue      string        `json:"original_queue"`
+	LastError          string        `json:"last_error"`
+	DeathHistory       []DeathRecord `json:"death_history"`
+	// Body is the message body if it is valid JSON; BodyText holds it
+	// otherwise, e.g. for a message parked because it couldn't be parsed.
+	Body     json.RawMessage `json:"body,omitempty"`
+	BodyText string          `json:"body_text,omitempty"`
+
+	raw []byte
+}
+
+// RawBody returns the message body exactly as it was parked.
+func (pm ParkedMessage) RawBody() []byte {
+	return pm.raw
+}
+
+// parkedPublishing builds the message Consumer.park publishes for msg, which
+// failed in queue with handlerErr on its last attempt. The original headers
+// are kept, apart from x-death, so replaying restores them.
+func parkedPublishing(
+	msg amqp.Delivery,
+	queue string,
+	handlerErr error,
+	now time.Time,
+) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		if k != "x-death" {
+			headers[k] = v
+		}
+	}
+	if deaths, ok := msg.Headers["x-death"]; ok {
+		headers[HeaderDeathHistory] = deaths
+	}
+
+	lastError := ""
+	if handlerErr != nil {
+		lastError = handlerErr.Error()
+		if len(lastError) > maxParkedErrorBytes {
+			lastError = lastError[:maxParkedErrorBytes]
+		}
+	}
+
+	headers[HeaderParkedID] = uuid.NewString()
+	headers[HeaderParkedAt] = now.UTC()
+	headers[HeaderOriginalExchange] = msg.Exchange
+	headers[HeaderOriginalRoutingKey] = msg.RoutingKey
+	headers[HeaderOriginalQueue] = queue
+	headers[HeaderLastError] = lastError
+
+	contentType := msg.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  contentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.MessageId,
+		Timestamp:    msg.Timestamp,
+		Body:         msg.Body,
+	}
+}
+
+// replayPublishing builds the message that sends a parked message back to
+// its original routing key: the headers it had before it was parked, with
+// a fresh retry count.
+func replayPublishing(d amqp.Delivery, parkedID string) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		if k == "x-death" || strings.HasPrefix(k, "x-gossip-") {
+			continue
+		}
+		headers[k] = v
+	}
+	headers[HeaderReplayedFrom] = parkedID
+
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Timestamp:    d.Timestamp,
+		Body:         d.Body,
+	}
+}
+
+// parkedMessageFrom decodes a delivery from the parked queue. A message
+// parked before these headers existed is identified by a hash of its body.
+func parkedMessageFrom(d amqp.Delivery) ParkedMessage {
+	pm := ParkedMessage{
+		ID:                 headerString(d.Headers, HeaderParkedID),
+		OriginalExchange:   headerString(d.Headers, HeaderOriginalExchange),
+		OriginalRoutingKey: headerString(d.Headers, HeaderOriginalRoutingKey),
+		OriginalQueue:      headerString(d.Headers, HeaderOriginalQueue),
+		LastError:          headerString(d.Headers, HeaderLastError),
+		DeathHistory:       deathRecords(d.Headers[HeaderDeathHistory]),
+		raw:                d.Body,
+	}
+	if pm.ID == "" {
+		sum := sha256.Sum256(d.Body)
+		pm.ID = "legacy-" + hex.EncodeToString(sum[:8])
+	}
+	if parkedAt, ok := d.Headers[HeaderParkedAt].(time.Time); ok {
+		pm.ParkedAt = &parkedAt
+	}
+	if json.Valid(d.Body) {
+		pm.Body = json.RawMessage(d.Body)
+	} else {
+		pm.BodyText = string(d.Body)
+	}
+	return pm
+}
+
+func headerString(headers amqp.Table, key string) string {
+	s, _ := headers[key].(string)
+	return s
+}
+
+func deathRecords(raw any) []DeathRecord {
+	deaths, _ := raw.([]any)
+	records := make([]DeathRecord, 0, len(deaths))
+	for _, d := range deaths {
+		entry, ok := d.(amqp.Table)
+		if !ok {
+			continue
+		}
+		record := DeathRecord{
+			Queue:    headerString(entry, "queue"),
+			Reason:   headerString(entry, "reason"),
+			Exchange: headerString(entry, "exchange"),
+		}
+		switch count := entry["count"].(type) {
+		case int64:
+			record.Count = count
+		case int32:
+			record.Count = int64(count)
+		}
+		if keys, ok := entry["routing-keys"].([]any); ok {
+			for _, k := range keys {
+				if s, ok := k.(string); ok {
+					record.RoutingKeys = append(record.RoutingKeys, s)
+				}
+			}
+		}
+		if t, ok := entry["time"].(time.Time); ok {
+			record.Time = &t
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// ParkedQueueBrowser lists, replays and discards messages in the parked
+// queue.
+//
+// AMQP has no way to peek at a queue, so every operation takes up to
+// MaxParkedScan messages off it unacknowledged and puts back all but the
+// one it acted on by closing its channel. While an operation runs, the
+// messages it holds are invisible to any other; a concurrent operation may
+// report a message as not found.
+type ParkedQueueBrowser struct {
+	conn      Connection
+	publisher *Publisher
+	logger    *slog.Logger
+}
+
+// NewParkedQueueBrowser creates a browser over ParkedQueue.
+func NewParkedQueueBrowser(conn Connection, logger *slog.Logger) *ParkedQueueBrowser {
+	return &ParkedQueueBrowser{
+		conn:      conn,
+		publisher: NewPublisher(conn, logger),
+		logger:    logger,
+	}
+}
+
+// List returns up to limit parked messages, oldest first.
+func (b *ParkedQueueBrowser) List(ctx context.Context, limit int) ([]ParkedMessage, error) {
+	var messages []ParkedMessage
+	err := b.scan(ctx, func(d amqp.Delivery) (bool, error) {
+		messages = append(messages, parkedMessageFrom(d))
+		return len(messages) >= limit, nil
+	})
+	return messages, err
+}
+
+// Get returns the parked message with id, leaving it in the queue.
+func (b *ParkedQueueBrowser) Get(ctx context.Context, id string) (ParkedMessage, error) {
+	return b.find(ctx, id, nil)
+}
+
+// Replay republishes the parked message with id to its original exchange
+// and routing key, then removes it from the parked queue once the broker
+// has confirmed the republished copy. If the publish fails, or nothing is
+// bound to the routing key any more, the message stays parked.
+func (b *ParkedQueueBrowser) Replay(ctx context.Context, id string) (ParkedMessage, error) {
+	return b.find(ctx, id, func(pm ParkedMessage, d amqp.Delivery) error {
+		if pm.OriginalRoutingKey == "" {
+			return ErrParkedMessageNotReplayable
+		}
+		if err := b.publisher.PublishMessage(
+			ctx,
+			pm.OriginalExchange,
+			pm.OriginalRoutingKey,
+			replayPublishing(d, pm.ID),
+		); err != nil {
+			return fmt.Errorf("failed to republish parked message: %w", err)
+		}
+		b.logger.Info("replayed parked message",
+			"parked_id", pm.ID,
+			"exchange", pm.OriginalExchange,
+			"routing_key", pm.OriginalRoutingKey,
+		)
+		return d.Ack(false)
+	})
+}
+
+// Discard removes the parked message with id from the queue for good.
+func (b *ParkedQueueBrowser) Discard(ctx context.Context, id string) (ParkedMessage, error) {
+	return b.find(ctx, id, func(pm ParkedMessage, d amqp.Delivery) error {
+		b.logger.Info("discarded parked message", "parked_id", pm.ID)
+		return d.Ack(false)
+	})
+}
+
+// find scans for the message with id and, if act is non-nil, calls it with
+// the delivery. act acks the delivery to take it off the queue.
+func (b *ParkedQueueBrowser) find(
+	ctx context.Context,
+	id string,
+	act func(pm ParkedMessage, d amqp.Delivery) error,
+) (ParkedMessage, error) {
+	var found *ParkedMessage
+	err := b.scan(ctx, func(d amqp.Delivery) (bool, error) {
+		pm := parkedMessageFrom(d)
+		if pm.ID != id {
+			return false, nil
+		}
+		found = &pm
+		if act == nil {
+			return true, nil
+		}
+		return true, act(pm, d)
+	})
+	if err != nil {
+		if found != nil {
+			return *found, err
+		}
+		return ParkedMessage{}, err
+	}
+	if found == nil {
+		return ParkedMessage{}, fmt.Errorf("%w: %s", ErrParkedMessageNotFound, id)
+	}
+	return *found, nil
+}
+
+// scan gets messages off the parked queue one at a time, without acking
+// them, until visit returns true, the queue is empty or MaxParkedScan is
+// reached. Closing the channel returns every unacked message to the queue
+// in its original position.
+func (b *ParkedQueueBrowser) scan(
+	ctx context.Context,
+	visit func(d amqp.Delivery) (bool, error),
+) error {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	for range MaxParkedScan {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		d, ok, err := ch.Get(ParkedQueue, false)
+		if err != nil {
+			return fmt.Errorf("failed to get from parked queue: %w", err)
+		}
+		if !ok {
+			return nil
+		}
+
+		done, err := visit(d)
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package broker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exhaustedDelivery() amqp.Delivery {
+	return amqp.Delivery{
+		Exchange:    "gossip.topic.exchange",
+		RoutingKey:  "gossip.emails.send",
+		ContentType: "application/json",
+		MessageId:   "msg-1",
+		Headers: amqp.Table{
+			"traceparent": "00-abc-def-01",
+			"x-death": []any{
+				amqp.Table{
+					"queue":        "gossip.emails.queue",
+					"reason":       "rejected",
+					"count":        int64(5),
+					"exchange":     "gossip.topic.exchange",
+					"routing-keys": []any{"gossip.emails.send"},
+				},
+			},
+		},
+		Body: []byte(`{"email":{}}`),
+	}
+}
+
+func TestParkedPublishing_RecordsOriginAndFailure(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	pub := parkedPublishing(exhaustedDelivery(), "gossip.emails.queue", errors.New("resend said no"), now)
+
+	assert.Equal(t, "gossip.topic.exchange", pub.Headers[HeaderOriginalExchange])
+	assert.Equal(t, "gossip.emails.send", pub.Headers[HeaderOriginalRoutingKey])
+	assert.Equal(t, "gossip.emails.queue", pub.Headers[HeaderOriginalQueue])
+	assert.Equal(t, "resend said no", pub.Headers[HeaderLastError])
+	assert.Equal(t, now, pub.Headers[HeaderParkedAt])
+	assert.NotEmpty(t, pub.Headers[HeaderParkedID])
+	assert.Equal(t, "00-abc-def-01", pub.Headers["traceparent"], "original headers must be kept")
+	assert.NotContains(t, pub.Headers, "x-death", "x-death is RabbitMQ's to set")
+	assert.Len(t, pub.Headers[HeaderDeathHistory], 1)
+	assert.Equal(t, "msg-1", pub.MessageId)
+	assert.Equal(t, []byte(`{"email":{}}`), pub.Body)
+	require.NoError(t, pub.Headers.Validate())
+}
+
+func TestParkedMessageFrom_DecodesParkedDelivery(t *testing.T) {
+	pub := parkedPublishing(exhaustedDelivery(), "gossip.emails.queue", errors.New("boom"), time.Now())
+	parked := amqp.Delivery{Headers: pub.Headers, Body: pub.Body}
+
+	pm := parkedMessageFrom(parked)
+
+	assert.Equal(t, pub.Headers[HeaderParkedID], pm.ID)
+	assert.Equal(t, "gossip.emails.send", pm.OriginalRoutingKey)
+	assert.Equal(t, "boom", pm.LastError)
+	require.Len(t, pm.DeathHistory, 1)
+	assert.Equal(t, int64(5), pm.DeathHistory[0].Count)
+	assert.Equal(t, []string{"gossip.emails.send"}, pm.DeathHistory[0].RoutingKeys)
+	assert.JSONEq(t, `{"email":{}}`, string(pm.Body))
+	assert.NotNil(t, pm.ParkedAt)
+}
+
+func TestParkedMessageFrom_LegacyMessageGetsStableID(t *testing.T) {
+	legacy := amqp.Delivery{Body: []byte("not json")}
+
+	first, second := parkedMessageFrom(legacy), parkedMessageFrom(legacy)
+
+	assert.Equal(t, first.ID, second.ID)
+	assert.Contains(t, first.ID, "legacy-")
+	assert.Empty(t, first.OriginalRoutingKey)
+	assert.Equal(t, "not json", first.BodyText)
+	assert.Nil(t, first.Body)
+}
+
+func TestReplayPublishing_StripsParkingHeaders(t *testing.T) {
+	pub := parkedPublishing(exhaustedDelivery(), "gossip.emails.queue", errors.New("boom"), time.Now())
+	parked := amqp.Delivery{Headers: pub.Headers, Body: pub.Body, MessageId: pub.MessageId}
+
+	replay := replayPublishing(parked, "parked-1")
+
+	assert.Equal(t, amqp.Table{
+		"traceparent":      "00-abc-def-01",
+		HeaderReplayedFrom: "parked-1",
+	}, replay.Headers)
+	assert.Equal(t, "msg-1", replay.MessageId)
+	assert.Equal(t, pub.Body, replay.Body)
+}
@@ -3,13 +3,36 @@ package broker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-// MessagePublisher defines the contract for publishing messages to RabbitMQ
+var (
+	// ErrUnroutable is returned when the broker returned a mandatory
+	// message because no queue is bound to receive it.
+	ErrUnroutable = errors.New("message was returned unroutable")
+	// ErrNotConfirmed is returned when the broker negatively acknowledged a
+	// message, or the channel closed before it confirmed it either way.
+	ErrNotConfirmed = errors.New("message was not confirmed by the broker")
+)
+
+const (
+	// maxIdlePublishChannels is how many confirm-mode channels a Publisher
+	// keeps open between publishes. Concurrent publishes beyond that open
+	// extra channels and close them afterwards.
+	maxIdlePublishChannels = 8
+	// defaultConfirmTimeout bounds the wait for a confirm when the caller's
+	// context has no deadline of its own.
+	defaultConfirmTimeout = 10 * time.Second
+)
+
+// MessagePublisher defines the contract for publishing messages to RabbitMQ.
+// Every publish is mandatory and waits for the broker's confirm: a nil
+// error means the broker has taken responsibility for the message.
 type MessagePublisher interface {
 	Publish(
 		ctx context.Context,
@@ -24,12 +47,28 @@ type MessagePublisher interface {
 		exchange, routingKey string,
 		body []byte,
 	) error
+	// PublishMessage publishes msg as given, headers and properties
+	// included. Used where a message's headers must survive the hop, e.g.
+	// parking a delivery with the record of where it came from.
+	PublishMessage(
+		ctx context.Context,
+		exchange, routingKey string,
+		msg amqp.Publishing,
+	) error
+}
+
+// publishChannel is a channel in confirm mode, with the listener for
+// messages the broker returns as unroutable.
+type publishChannel struct {
+	ch      *amqp.Channel
+	returns chan amqp.Return
 }
 
-// Publisher implements MessagePublisher
+// Publisher implements MessagePublisher. It is safe for concurrent use.
 type Publisher struct {
 	conn   Connection
 	logger *slog.Logger
+	idle   chan *publishChannel
 }
 
 // NewPublisher creates a new Publisher instance
@@ -37,10 +76,12 @@ func NewPublisher(conn Connection, logger *slog.Logger) *Publisher {
 	return &Publisher{
 		conn:   conn,
 		logger: logger,
+		idle:   make(chan *publishChannel, maxIdlePublishChannels),
 	}
 }
 
-// Publish marshals the message to JSON and publishes it to the exchange
+// Publish marshals the message to JSON and publishes it to the exchange,
+// carrying the trace context in ctx.
 func (p *Publisher) Publish(
 	ctx context.Context,
 	exchange, routingKey string,
@@ -53,32 +94,42 @@ func (p *Publisher) Publish(
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	ch, err := p.conn.Channel()
-	if err != nil {
-		p.logger.Error("cannot publish message", "error", err)
-		return err
-	}
-	defer ch.Close()
+	return p.PublishMessage(ctx, exchange, routingKey, amqp.Publishing{
+		Headers:     injectTraceContext(ctx, nil),
+		ContentType: "application/json",
+		Body:        jsonBytes,
+	})
+}
 
-	// Publish the message
-	err = ch.PublishWithContext(
-		ctx,
-		exchange,   // exchange
-		routingKey, // routing key
-		false,      // mandatory
-		false,      // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        jsonBytes,
-		},
-	)
+// PublishRaw publishes body as-is, skipping JSON marshaling, carrying the
+// trace context in ctx.
+func (p *Publisher) PublishRaw(
+	ctx context.Context,
+	exchange, routingKey string,
+	body []byte,
+) error {
+	return p.PublishMessage(ctx, exchange, routingKey, amqp.Publishing{
+		Headers:     injectTraceContext(ctx, nil),
+		ContentType: "application/json",
+		Body:        body,
+	})
+}
+
+// PublishMessage publishes msg as given, headers included, and returns
+// once the broker has confirmed it. A message no queue is bound for is an ErrUnroutable error.
+func (p *Publisher) PublishMessage(
+	ctx context.Context,
+	exchange, routingKey string,
+	msg amqp.Publishing,
+) error {
+	err := p.publish(ctx, exchange, routingKey, msg)
 	if err != nil {
 		p.logger.Error("failed to publish message",
 			"exchange", exchange,
 			"routing_key", routingKey,
 			"error", err,
 		)
-		return fmt.Errorf("failed to publish message: %w", err)
+		return err
 	}
 
 	p.logger.Debug("message published",
@@ -88,42 +139,105 @@ func (p *Publisher) Publish(
 	return nil
 }
 
-// PublishRaw publishes body as-is, skipping JSON marshaling.
-func (p *Publisher) PublishRaw(
+func (p *Publisher) publish(
 	ctx context.Context,
 	exchange, routingKey string,
-	body []byte,
+	msg amqp.Publishing,
 ) error {
-	ch, err := p.conn.Channel()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultConfirmTimeout)
+		defer cancel()
+	}
+
+	pc, err := p.acquire()
 	if err != nil {
-		p.logger.Error("cannot publish message", "error", err)
 		return err
 	}
-	defer ch.Close()
 
-	err = ch.PublishWithContext(
+	confirm, err := pc.ch.PublishWithDeferredConfirmWithContext(
 		ctx,
 		exchange,
 		routingKey,
-		false, // mandatory
+		true,  // mandatory: an unroutable message comes back as a return
 		false, // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
+		msg,
 	)
 	if err != nil {
-		p.logger.Error("failed to publish raw message",
-			"exchange", exchange,
-			"routing_key", routingKey,
-			"error", err,
-		)
-		return fmt.Errorf("failed to publish raw message: %w", err)
+		p.discard(pc)
+		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
-	p.logger.Debug("raw message published",
-		"exchange", exchange,
-		"routing_key", routingKey,
-	)
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		// The confirm may still arrive; the channel can't be reused.
+		p.discard(pc)
+		return fmt.Errorf("timed out waiting for publish confirm: %w", err)
+	}
+
+	// The broker sends a return before the confirm for the same message,
+	// and the client library hands it over first, so it's already here.
+	select {
+	case ret, ok := <-pc.returns:
+		if ok {
+			p.release(pc)
+			return fmt.Errorf("%w: %d %s", ErrUnroutable, ret.ReplyCode, ret.ReplyText)
+		}
+		// Closed along with the channel; the confirm below is a nack.
+	default:
+	}
+
+	if !acked {
+		p.discard(pc)
+		return ErrNotConfirmed
+	}
+
+	p.release(pc)
 	return nil
 }
+
+// acquire takes an idle channel from the pool, or opens a new one.
+func (p *Publisher) acquire() (*publishChannel, error) {
+	for {
+		select {
+		case pc := <-p.idle:
+			if pc.ch.IsClosed() {
+				// It died with its connection; try the next one.
+				continue
+			}
+			return pc, nil
+		default:
+			return p.open()
+		}
+	}
+}
+
+func (p *Publisher) open() (*publishChannel, error) {
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("cannot publish message: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to put channel in confirm mode: %w", err)
+	}
+	return &publishChannel{
+		ch: ch,
+		// A publish waits for its own confirm before the channel is used
+		// again, so at most one return is ever pending.
+		returns: ch.NotifyReturn(make(chan amqp.Return, 1)),
+	}, nil
+}
+
+// release returns pc to the pool, closing it if the pool is full.
+func (p *Publisher) release(pc *publishChannel) {
+	select {
+	case p.idle <- pc:
+	default:
+		pc.ch.Close()
+	}
+}
+
+func (p *Publisher) discard(pc *publishChannel) {
+	pc.ch.Close()
+}
@@ -0,0 +1,98 @@
+//go:build integration
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher_ConfirmsRoutedMessage(t *testing.T) {
+	broker := brokerURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	queue := fmt.Sprintf("gossip.test.publish.%d", time.Now().UnixNano())
+	direct := directChannel(t, broker)
+	_, err := direct.QueueDeclare(queue, false, true, false, false, nil)
+	require.NoError(t, err)
+
+	conn, err := NewRabbitMQConnection(ctx, broker.String(), time.Second, testLogger())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	publisher := NewPublisher(conn, testLogger())
+	require.NoError(t, publisher.PublishRaw(ctx, "", queue, []byte(`{"n":1}`)))
+
+	d, ok, err := direct.Get(queue, true)
+	require.NoError(t, err)
+	require.True(t, ok, "confirmed message is not in the queue")
+	require.JSONEq(t, `{"n":1}`, string(d.Body))
+}
+
+func TestPublisher_UnroutableMessageIsAnError(t *testing.T) {
+	broker := brokerURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	conn, err := NewRabbitMQConnection(ctx, broker.String(), time.Second, testLogger())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	publisher := NewPublisher(conn, testLogger())
+	noSuchQueue := fmt.Sprintf("gossip.test.nowhere.%d", time.Now().UnixNano())
+	err = publisher.PublishRaw(ctx, "", noSuchQueue, []byte(`{}`))
+	require.ErrorIs(t, err, ErrUnroutable)
+
+	// The channel that saw the return goes back to the pool and must not
+	// report it again for the next message.
+	queue := fmt.Sprintf("gossip.test.publish.%d", time.Now().UnixNano())
+	direct := directChannel(t, broker)
+	_, err = direct.QueueDeclare(queue, false, true, false, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, publisher.PublishRaw(ctx, "", queue, []byte(`{}`)))
+}
+
+func TestPublisher_ConcurrentPublishesAllConfirm(t *testing.T) {
+	broker := brokerURL(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	queue := fmt.Sprintf("gossip.test.publish.%d", time.Now().UnixNano())
+	direct := directChannel(t, broker)
+	_, err := direct.QueueDeclare(queue, false, true, false, false, nil)
+	require.NoError(t, err)
+
+	conn, err := NewRabbitMQConnection(ctx, broker.String(), time.Second, testLogger())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	publisher := NewPublisher(conn, testLogger())
+	const n = 3 * maxIdlePublishChannels
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- publisher.PublishMessage(ctx, "", queue, amqp.Publishing{
+				Body: []byte(fmt.Sprint(i)),
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	q, err := direct.QueueDeclarePassive(queue, false, true, false, false, nil)
+	require.NoError(t, err)
+	require.Equal(t, n, q.Messages)
+}
@@ -0,0 +1,83 @@
+package broker
+
+import (
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers a message sent to a retry tier carries.
+const (
+	// HeaderRetryCount is how many times the message had failed when it
+	// was last sent to a retry tier.
+	HeaderRetryCount = "x-gossip-retry-count"
+	// HeaderRetryTier names the tier the message waits in; RetryTierExchange
+	// routes on it.
+	HeaderRetryTier = "x-gossip-retry-tier"
+)
+
+// RetryPolicy decides what happens to a message whose handler failed, on a
+// queue declared with a dead-letter exchange (see Consumer.Consume).
+type RetryPolicy struct {
+	// MaxAttempts is how many times a message may fail and be retried
+	// before it is parked instead.
+	MaxAttempts int
+	// Delays are the retry tiers: after its nth failure a message waits
+	// Delays[n-1] before it is redelivered, or the last delay once n runs
+	// past the end. Every delay must have been declared by
+	// DeclareRetryTopology. Empty means every retry goes through the
+	// fixed-delay RetryQueue.
+	Delays []time.Duration
+}
+
+// delayFor returns the tier delay for a message that has now failed
+// attempt times.
+func (p RetryPolicy) delayFor(attempt int) time.Duration {
+	return p.Delays[min(max(attempt, 1), len(p.Delays))-1]
+}
+
+// retryTierName names the tier for delay in queue names and
+// HeaderRetryTier, e.g. "10s", "1m", "1h".
+func retryTierName(delay time.Duration) string {
+	switch {
+	case delay%time.Hour == 0:
+		return fmt.Sprintf("%dh", delay/time.Hour)
+	case delay%time.Minute == 0:
+		return fmt.Sprintf("%dm", delay/time.Minute)
+	case delay%time.Second == 0:
+		return fmt.Sprintf("%ds", delay/time.Second)
+	default:
+		return fmt.Sprintf("%dms", delay/time.Millisecond)
+	}
+}
+
+// RetryTierQueue is the queue a message waits in for delay.
+func RetryTierQueue(delay time.Duration) string {
+	return "gossip.retry." + retryTierName(delay) + ".queue"
+}
+
+// retryPublishing builds the copy of msg that waits out its delay in the
+// tier for delay, having now failed attempt times. It is published with
+// msg's routing key, which the tier queue dead-letters it back under.
+func retryPublishing(msg amqp.Delivery, attempt int, delay time.Duration) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		// The failures x-death records so far are counted in the retry
+		// count from here on.
+		if k != "x-death" {
+			headers[k] = v
+		}
+	}
+	headers[HeaderRetryCount] = int64(attempt)
+	headers[HeaderRetryTier] = retryTierName(delay)
+
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.MessageId,
+		Timestamp:    msg.Timestamp,
+		Body:         msg.Body,
+	}
+}
@@ -0,0 +1,193 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/opencrafts-io/gossip-monger/internal/metrics"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_DelayForClimbsTheLadder(t *testing.T) {
+	policy := RetryPolicy{Delays: []time.Duration{
+		10 * time.Second, time.Minute, 10 * time.Minute, time.Hour,
+	}}
+
+	assert.Equal(t, 10*time.Second, policy.delayFor(1))
+	assert.Equal(t, time.Minute, policy.delayFor(2))
+	assert.Equal(t, 10*time.Minute, policy.delayFor(3))
+	assert.Equal(t, time.Hour, policy.delayFor(4))
+	// Past the last tier, the last delay repeats.
+	assert.Equal(t, time.Hour, policy.delayFor(9))
+}
+
+func TestRetryTierQueue_NamesTierByDelay(t *testing.T) {
+	assert.Equal(t, "gossip.retry.10s.queue", RetryTierQueue(10*time.Second))
+	assert.Equal(t, "gossip.retry.1m.queue", RetryTierQueue(time.Minute))
+	assert.Equal(t, "gossip.retry.90s.queue", RetryTierQueue(90*time.Second))
+	assert.Equal(t, "gossip.retry.1h.queue", RetryTierQueue(time.Hour))
+	assert.Equal(t, "gossip.retry.1500ms.queue", RetryTierQueue(1500*time.Millisecond))
+}
+
+func TestDeathCount_AddsRejectionsToRetryCount(t *testing.T) {
+	headers := amqp.Table{
+		HeaderRetryCount: int64(2),
+		"x-death": []any{
+			amqp.Table{"queue": "gossip.emails.queue", "reason": "rejected", "count": int64(1)},
+			amqp.Table{"queue": "gossip.retry.1m.queue", "reason": "expired", "count": int64(2)},
+		},
+	}
+
+	assert.Equal(t, 3, deathCount(headers))
+	assert.Equal(t, 0, deathCount(amqp.Table{}))
+}
+
+func TestConsumerHandle_PassesDeliveryMetadata(t *testing.T) {
+	c := NewConsumer(&unreachableConn{}, 10, RetryPolicy{MaxAttempts: 5}, WorkerPoolConfig{},
+		*slog.New(slog.NewTextHandler(io.Discard, nil)))
+	sent := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	// A message on its third attempt: sent to a retry tier once, then
+	// nacked to the fallback retry queue once.
+	msg := amqp.Delivery{
+		Acknowledger: &recordingAcker{},
+		DeliveryTag:  1,
+		Exchange:     "gossip.topic.exchange",
+		RoutingKey:   "gossip.emails.send",
+		MessageId:    "msg-1",
+		Timestamp:    sent,
+		Redelivered:  true,
+		Headers: amqp.Table{
+			HeaderRetryCount: int64(1),
+			"x-death": []any{
+				amqp.Table{"queue": "gossip.emails.queue", "reason": "rejected", "count": int64(1)},
+			},
+		},
+		Body: []byte(`{}`),
+	}
+
+	var got Delivery
+	c.handle(context.Background(), msg, "gossip.emails.queue", RetryExchange,
+		func(_ context.Context, d Delivery) error {
+			got = d
+			return nil
+		})
+
+	assert.Equal(t, "gossip.topic.exchange", got.Exchange)
+	assert.Equal(t, "gossip.emails.send", got.RoutingKey)
+	assert.Equal(t, "msg-1", got.MessageID)
+	assert.Equal(t, sent, got.Timestamp)
+	assert.True(t, got.Redelivered)
+	assert.Equal(t, 3, got.Attempt)
+	assert.Equal(t, []byte(`{}`), got.Body)
+}
+
+func TestRetryPublishing_CountsAttemptAndNamesTier(t *testing.T) {
+	msg := exhaustedDelivery()
+
+	pub := retryPublishing(msg, 6, time.Minute)
+
+	assert.Equal(t, int64(6), pub.Headers[HeaderRetryCount])
+	assert.Equal(t, "1m", pub.Headers[HeaderRetryTier])
+	assert.NotContains(t, pub.Headers, "x-death")
+	assert.Equal(t, "00-abc-def-01", pub.Headers["traceparent"])
+	assert.Equal(t, msg.Body, pub.Body)
+	assert.Equal(t, msg.MessageId, pub.MessageId)
+	assert.Equal(t, uint8(amqp.Persistent), pub.DeliveryMode)
+}
+
+// unreachableConn fails every attempt to open a channel, counting them.
+type unreachableConn struct {
+	Connection
+	channels int
+}
+
+func (c *unreachableConn) Channel() (*amqp.Channel, error) {
+	c.channels++
+	return nil, ErrNotConnected
+}
+
+func TestConsumerHandle_RoutesFailures(t *testing.T) {
+	tiers := RetryPolicy{MaxAttempts: 5, Delays: []time.Duration{10 * time.Second}}
+	fixed := RetryPolicy{MaxAttempts: 5}
+
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		retries int64
+		err     error
+		// publishes is how many publishes handle attempted: to a retry
+		// tier, or to the parked queue.
+		publishes int
+	}{
+		{
+			name:      "transient failure goes to its retry tier",
+			policy:    tiers,
+			err:       errors.New("resend is down"),
+			publishes: 1,
+		},
+		{
+			name:   "without tiers it is nacked to the fixed-delay retry queue",
+			policy: fixed,
+			err:    errors.New("resend is down"),
+		},
+		{
+			name:      "permanent failure is parked on the first attempt",
+			policy:    fixed,
+			err:       resilience.Permanent(errors.New("no heading")),
+			publishes: 1,
+		},
+		{
+			name:      "exhausted message is parked",
+			policy:    fixed,
+			retries:   5,
+			err:       errors.New("resend is down"),
+			publishes: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &unreachableConn{}
+			acker := &recordingAcker{}
+			c := NewConsumer(conn, 10, tt.policy, WorkerPoolConfig{},
+				*slog.New(slog.NewTextHandler(io.Discard, nil)))
+			msg := amqp.Delivery{
+				Acknowledger: acker,
+				DeliveryTag:  1,
+				Headers:      amqp.Table{HeaderRetryCount: tt.retries},
+			}
+
+			c.handle(context.Background(), msg, "q", RetryExchange,
+				func(context.Context, Delivery) error { return tt.err })
+
+			assert.Equal(t, tt.publishes, conn.channels)
+			// Every publish fails here, so the message always falls back to
+			// being nacked into the retry flow rather than lost.
+			require.Equal(t, []uint64{1}, acker.nacked)
+			assert.Empty(t, acker.acked)
+		})
+	}
+}
+
+func TestConsumerHandle_CountsOutcomes(t *testing.T) {
+	const queue = "gossip.metrics-test.queue"
+	acker := &recordingAcker{}
+	c := NewConsumer(&unreachableConn{}, 10, RetryPolicy{MaxAttempts: 5}, WorkerPoolConfig{},
+		*slog.New(slog.NewTextHandler(io.Discard, nil)))
+	succeed := func(context.Context, Delivery) error { return nil }
+	fail := func(context.Context, Delivery) error { return errors.New("boom") }
+
+	c.handle(context.Background(), amqp.Delivery{Acknowledger: acker, DeliveryTag: 1}, queue, RetryExchange, succeed)
+	c.handle(context.Background(), amqp.Delivery{Acknowledger: acker, DeliveryTag: 2}, queue, RetryExchange, fail)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.MessagesAcked.WithLabelValues(queue)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.MessagesNacked.WithLabelValues(queue)))
+}
@@ -13,8 +13,14 @@ const (
 	RetryExchange = "gossip.retry.exchange"
 	// RetryQueue holds a dead-lettered message for its configured delay,
 	// then RabbitMQ redelivers it (via its own x-dead-letter-exchange) back
-	// to whichever queue its original routing key maps to.
+	// to whichever queue its original routing key maps to. With retry tiers
+	// configured it only takes messages that couldn't be sent to their
+	// tier.
 	RetryQueue = "gossip.retry.queue"
+	// RetryTierExchange routes a message to its retry tier's queue by its
+	// HeaderRetryTier header, keeping its original routing key for the
+	// redelivery.
+	RetryTierExchange = "gossip.retry.tiers"
 	// ParkedQueue is the terminal destination for messages that exhausted
 	// their retry attempts. Never auto-consumed — for manual triage.
 	ParkedQueue = "gossip.parked.queue"
@@ -22,7 +28,8 @@ const (
 
 // DeclareRetryTopology declares the shared retry (delayed-requeue) queue and
 // the terminal parked queue used by consumers that opt into automatic
-// retry-with-backoff instead of dropping a failed message.
+// retry-with-backoff instead of dropping a failed message, and a queue for
+// each of the tierDelays a RetryPolicy may send a message to.
 //
 // sourceExchange is where a message is redelivered once its retry delay
 // elapses — the queues that dead-letter into RetryExchange must already be
@@ -31,6 +38,7 @@ const (
 func DeclareRetryTopology(
 	conn Connection,
 	retryDelay time.Duration,
+	tierDelays []time.Duration,
 	sourceExchange string,
 	routingKeys []string,
 ) error {
@@ -73,6 +81,44 @@ func DeclareRetryTopology(
 		}
 	}
 
+	if err := ch.ExchangeDeclare(
+		RetryTierExchange,
+		amqp.ExchangeHeaders,
+		true,  // durable
+		false, // auto-deleted
+		false, // internal
+		false, // no-wait
+		nil,   // arguments
+	); err != nil {
+		return fmt.Errorf("failed to declare retry tier exchange: %w", err)
+	}
+
+	// A tier's queue is named after its delay, so changing the delays
+	// declares new queues instead of conflicting with the old ones' TTL;
+	// the old ones empty themselves.
+	for _, delay := range tierDelays {
+		queue := RetryTierQueue(delay)
+		if _, err := ch.QueueDeclare(
+			queue,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			amqp.Table{
+				"x-message-ttl":          int64(delay / time.Millisecond),
+				"x-dead-letter-exchange": sourceExchange,
+			},
+		); err != nil {
+			return fmt.Errorf("failed to declare retry tier queue %q: %w", queue, err)
+		}
+		if err := ch.QueueBind(queue, "", RetryTierExchange, false, amqp.Table{
+			"x-match":       "all",
+			HeaderRetryTier: retryTierName(delay),
+		}); err != nil {
+			return fmt.Errorf("failed to bind retry tier queue %q: %w", queue, err)
+		}
+	}
+
 	if _, err := ch.QueueDeclare(
 		ParkedQueue,
 		true,  // durable
@@ -0,0 +1,123 @@
+package broker
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// KeyFunc returns the ordering key of a message body. Messages with the
+// same key are handled one at a time, in the order they were delivered;
+// messages with different keys may be handled concurrently. An empty key
+// means the message has no ordering constraint.
+type KeyFunc func(message []byte) string
+
+// WorkerPoolConfig configures how a Consumer handles the deliveries it has
+// been sent.
+type WorkerPoolConfig struct {
+	// Workers is how many deliveries are handled at once. Values below 1
+	// mean 1, i.e. serial handling.
+	Workers int
+	// OrderingKey, if set, keeps messages with the same key in order; see
+	// KeyFunc. Leave nil when ordering doesn't matter.
+	OrderingKey KeyFunc
+	// DrainTimeout is how long in-flight messages get to finish once the
+	// consumer is stopped, before their context is cancelled.
+	DrainTimeout time.Duration
+}
+
+// workerPool hands deliveries to a fixed set of goroutines.
+//
+// Without an ordering key every worker reads the same queue, so a delivery
+// goes to whichever worker is free. With one, each worker has its own queue
+// and a delivery goes to the worker its key hashes to, which keeps same-key
+// deliveries in order. A busy worker then holds up dispatch to the others
+// until it is free, so a slow key slows the consumer down rather than being
+// overtaken.
+type workerPool struct {
+	queues  []chan amqp.Delivery
+	key     KeyFunc
+	next    int
+	workers sync.WaitGroup
+}
+
+// startWorkerPool starts n workers, each calling handle for the deliveries
+// dispatched to it.
+func startWorkerPool(n int, key KeyFunc, handle func(amqp.Delivery)) *workerPool {
+	n = max(n, 1)
+	p := &workerPool{key: key}
+
+	queueCount := 1
+	if key != nil {
+		queueCount = n
+	}
+	p.queues = make([]chan amqp.Delivery, queueCount)
+	for i := range p.queues {
+		p.queues[i] = make(chan amqp.Delivery)
+	}
+
+	for i := range n {
+		queue := p.queues[i%queueCount]
+		p.workers.Add(1)
+		go func() {
+			defer p.workers.Done()
+			for d := range queue {
+				handle(d)
+			}
+		}()
+	}
+	return p
+}
+
+// dispatch waits for the right worker to take d. It returns false, leaving
+// d unhandled, if ctx is cancelled first.
+func (p *workerPool) dispatch(ctx context.Context, d amqp.Delivery) bool {
+	select {
+	case p.queues[p.queueFor(d.Body)] <- d:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (p *workerPool) queueFor(body []byte) int {
+	if len(p.queues) == 1 {
+		return 0
+	}
+	key := p.key(body)
+	if key == "" {
+		p.next = (p.next + 1) % len(p.queues)
+		return p.next
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+// stop lets the workers finish what they are handling and waits for them.
+// If they are not done within timeout, onTimeout is called, which should
+// make the remaining handlers return, and stop keeps waiting. dispatch must
+// not be called after stop.
+func (p *workerPool) stop(timeout time.Duration, onTimeout func()) {
+	for _, q := range p.queues {
+		close(q)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		onTimeout()
+		<-done
+	}
+}
@@ -0,0 +1,172 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func delivery(body string) amqp.Delivery {
+	return amqp.Delivery{Body: []byte(body)}
+}
+
+func TestWorkerPool_HandlesConcurrently(t *testing.T) {
+	const workers = 4
+	started := make(chan struct{}, workers)
+	release := make(chan struct{})
+
+	pool := startWorkerPool(workers, nil, func(amqp.Delivery) {
+		started <- struct{}{}
+		<-release
+	})
+
+	for range workers {
+		require.True(t, pool.dispatch(context.Background(), delivery("x")))
+	}
+	for range workers {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("deliveries were not handled concurrently")
+		}
+	}
+	close(release)
+	pool.stop(time.Second, func() { t.Fatal("drain timed out") })
+}
+
+func TestWorkerPool_KeepsSameKeyInOrder(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string][]string{}
+
+	// Bodies are "<key>:<seq>".
+	key := func(body []byte) string { return string(body[:1]) }
+	pool := startWorkerPool(4, key, func(d amqp.Delivery) {
+		// Give later deliveries of the same key a chance to overtake.
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		k := string(d.Body[:1])
+		seen[k] = append(seen[k], string(d.Body))
+	})
+
+	want := map[string][]string{}
+	for i := range 20 {
+		for _, k := range []string{"a", "b", "c"} {
+			body := k + ":" + string(rune('A'+i))
+			want[k] = append(want[k], body)
+			require.True(t, pool.dispatch(context.Background(), delivery(body)))
+		}
+	}
+	pool.stop(5*time.Second, func() { t.Fatal("drain timed out") })
+
+	assert.Equal(t, want, seen)
+}
+
+func TestWorkerPool_DispatchGivesUpWhenCancelled(t *testing.T) {
+	release := make(chan struct{})
+	pool := startWorkerPool(1, nil, func(amqp.Delivery) { <-release })
+	require.True(t, pool.dispatch(context.Background(), delivery("busy")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, pool.dispatch(ctx, delivery("waiting")))
+
+	close(release)
+	pool.stop(time.Second, func() {})
+}
+
+func TestWorkerPool_StopWaitsForInFlightWork(t *testing.T) {
+	finished := make(chan struct{})
+	pool := startWorkerPool(2, nil, func(amqp.Delivery) {
+		time.Sleep(50 * time.Millisecond)
+		close(finished)
+	})
+	require.True(t, pool.dispatch(context.Background(), delivery("x")))
+
+	pool.stop(time.Second, func() { t.Fatal("drain timed out") })
+
+	select {
+	case <-finished:
+	default:
+		t.Fatal("stop returned before the in-flight delivery finished")
+	}
+}
+
+func TestWorkerPool_StopCancelsAfterTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := startWorkerPool(1, nil, func(amqp.Delivery) { <-ctx.Done() })
+	require.True(t, pool.dispatch(context.Background(), delivery("stuck")))
+
+	timedOut := false
+	pool.stop(10*time.Millisecond, func() {
+		timedOut = true
+		cancel()
+	})
+
+	assert.True(t, timedOut)
+}
+
+// recordingAcker records how each delivery was settled.
+type recordingAcker struct {
+	mu      sync.Mutex
+	acked   []uint64
+	nacked  []uint64
+	requeue []bool
+}
+
+func (a *recordingAcker) Ack(tag uint64, _ bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked = append(a.acked, tag)
+	return nil
+}
+
+func (a *recordingAcker) Nack(tag uint64, _ bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacked = append(a.nacked, tag)
+	a.requeue = append(a.requeue, requeue)
+	return nil
+}
+
+func (a *recordingAcker) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func TestConsumerHandle_AcksSuccessAndNacksFailure(t *testing.T) {
+	acker := &recordingAcker{}
+	c := NewConsumer(nil, 10, RetryPolicy{MaxAttempts: 5}, WorkerPoolConfig{Workers: 2},
+		*slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ok := amqp.Delivery{Acknowledger: acker, DeliveryTag: 1, Body: []byte("ok")}
+	bad := amqp.Delivery{Acknowledger: acker, DeliveryTag: 2, Body: []byte("bad")}
+	handler := func(_ context.Context, d Delivery) error {
+		if string(d.Body) == "bad" {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	c.handle(context.Background(), ok, "q", RetryExchange, handler)
+	c.handle(context.Background(), bad, "q", RetryExchange, handler)
+
+	assert.Equal(t, []uint64{1}, acker.acked)
+	assert.Equal(t, []uint64{2}, acker.nacked)
+	// Not requeued: the queue's dead-letter exchange takes it to the retry
+	// queue.
+	assert.Equal(t, []bool{false}, acker.requeue)
+}
+
+func TestNewConsumer_PrefetchCoversWorkers(t *testing.T) {
+	c := NewConsumer(nil, 10, RetryPolicy{}, WorkerPoolConfig{Workers: 25},
+		*slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Equal(t, 25, c.prefetchCount)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -15,6 +16,40 @@ type Config struct {
 		Address string `envconfig:"GOSSIP_MONGER_ADDRESS"`
 	}
 
+	// AdminConfig configures the authenticated admin API under /v1/.
+	AdminConfig struct {
+		// APIToken is the bearer token admin API callers must present.
+		// Left empty, every admin request is rejected.
+		APIToken string `envconfig:"ADMIN_API_TOKEN"`
+	}
+
+	// MetricsConfig configures the Prometheus endpoint, /metrics.
+	MetricsConfig struct {
+		// Token is the bearer token a scraper must present. Left empty,
+		// every scrape is rejected.
+		Token string `envconfig:"METRICS_TOKEN"`
+	}
+
+	// InboxConfig configures the in-app inbox API under /v1/users/.
+	InboxConfig struct {
+		// APIToken is the bearer token the apps' backend must present when
+		// reading inboxes on its users' behalf. Left empty, every inbox
+		// request is rejected.
+		APIToken string `envconfig:"INBOX_API_TOKEN"`
+	}
+
+	// TracingConfig configures OpenTelemetry tracing.
+	TracingConfig struct {
+		// OTLPEndpoint is the OTLP/HTTP traces URL spans are exported to,
+		// e.g. "http://otel-collector:4318/v1/traces". Left empty, no spans
+		// are exported, but trace context is still passed on to providers.
+		OTLPEndpoint string `envconfig:"TRACING_OTLP_ENDPOINT"`
+		ServiceName  string `envconfig:"TRACING_SERVICE_NAME" default:"gossip-monger"`
+		// SampleRatio is the fraction of traces started here that are
+		// sampled. Traces continued from a publisher keep its decision.
+		SampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+	}
+
 	// Database configuration
 	DatabaseConfig struct {
 		DatabaseHost                      string `envconfig:"DB_HOST"`
@@ -35,12 +70,27 @@ type Config struct {
 		RabbitMQAddress string `envconfig:"RABBITMQ_ADDRESS"`
 		RabbitMQPort    int    `envconfig:"RABBITMQ_PORT"`
 
+		// RetryDelays are the retry tiers: how long a message waits before
+		// its first, second, ... redelivery. Failures past the last tier
+		// keep waiting the last delay.
+		RetryDelays []time.Duration `envconfig:"RETRY_DELAYS" default:"10s,1m,10m,1h"`
 		// RetryDelaySeconds is how long a failed message waits in the
-		// retry queue before being redelivered to its original queue.
+		// fallback retry queue, which only takes messages that couldn't be
+		// sent to their retry tier.
 		RetryDelaySeconds int `envconfig:"RETRY_DELAY_SECONDS" default:"30"`
 		// MaxRetryAttempts is how many times a message may be redelivered
 		// before it is routed to the parked queue for manual triage.
 		MaxRetryAttempts int `envconfig:"MAX_RETRY_ATTEMPTS" default:"5"`
+		// ReconnectMaxBackoffSeconds caps the wait between attempts to
+		// re-establish a lost broker connection.
+		ReconnectMaxBackoffSeconds int `envconfig:"RABBITMQ_RECONNECT_MAX_BACKOFF_SECONDS" default:"30"`
+		// ConsumerWorkers is how many messages each consumer handles at
+		// once.
+		ConsumerWorkers int `envconfig:"CONSUMER_WORKERS" default:"10"`
+		// ConsumerDrainTimeoutSeconds is how long messages being handled at
+		// shutdown get to finish before they are cancelled and left for
+		// redelivery.
+		ConsumerDrainTimeoutSeconds int `envconfig:"CONSUMER_DRAIN_TIMEOUT_SECONDS" default:"20"`
 	}
 
 	// BreakerConfig configures the circuit breakers guarding calls to
@@ -52,16 +102,68 @@ type Config struct {
 		HalfOpenMaxRequests uint32 `envconfig:"BREAKER_HALF_OPEN_MAX_REQUESTS" default:"1"`
 	}
 
+	// SchedulerConfig configures the worker that dispatches pushes held
+	// back by send_after once they fall due.
+	SchedulerConfig struct {
+		PollIntervalSeconds int `envconfig:"SCHEDULER_POLL_INTERVAL_SECONDS" default:"15"`
+		// BatchSize is how many due pushes one replica claims per poll.
+		BatchSize int32 `envconfig:"SCHEDULER_BATCH_SIZE" default:"50"`
+		// ClaimTimeoutSeconds is how long a claimed push may sit in
+		// "dispatching" before another replica assumes its claimant died
+		// and claims it again.
+		ClaimTimeoutSeconds int `envconfig:"SCHEDULER_CLAIM_TIMEOUT_SECONDS" default:"300"`
+	}
+
 	// OneSignal configuration
 	OneSignalConfig struct {
 		AppID      string `envconfig:"ONESIGNAL_APP_ID"`
 		RestAPIKey string `envconfig:"ONESIGNAL_REST_API_KEY"`
+		// WebhookSecret is the token OneSignal must present when calling
+		// /webhooks/onesignal. Left empty, every webhook is rejected.
+		WebhookSecret string `envconfig:"ONESIGNAL_WEBHOOK_SECRET"`
+	}
+
+	// AfricasTalkingConfig configures the SMS provider.
+	AfricasTalkingConfig struct {
+		Username string `envconfig:"AFRICASTALKING_USERNAME"`
+		APIKey   string `envconfig:"AFRICASTALKING_API_KEY"`
+		// SenderID is the alphanumeric sender id or short code SMS are sent
+		// from when an event names none. Left empty, Africa's Talking's
+		// shared sender is used.
+		SenderID string `envconfig:"AFRICASTALKING_SENDER_ID"`
+		// BaseURL is https://api.sandbox.africastalking.com for the
+		// sandbox.
+		BaseURL string `envconfig:"AFRICASTALKING_BASE_URL" default:"https://api.africastalking.com"`
 	}
 
 	// Resend configuration
 	ResendConfig struct {
-		ResendAPIKey         string   `envconfig:"RESEND_API_KEY"`
-		AllowedSenderDomains []string `envconfig:"RESEND_ALLOWED_SENDER_DOMAINS" default:"@posta.opencrafts.io"`
+		ResendAPIKey string `envconfig:"RESEND_API_KEY"`
+		// WebhookSecret is the signing secret ("whsec_...") of the Resend
+		// webhook pointed at /webhooks/resend. Left empty, every webhook is
+		// rejected.
+		WebhookSecret string `envconfig:"RESEND_WEBHOOK_SECRET"`
+	}
+
+	// UnsubscribeConfig configures the one-click unsubscribe links of
+	// non-transactional email. Left unset, only transactional email can
+	// be sent.
+	UnsubscribeConfig struct {
+		// BaseURL is where gossip-monger is publicly reachable; links
+		// point at BaseURL/unsubscribe.
+		BaseURL string `envconfig:"UNSUBSCRIBE_BASE_URL"`
+		// Secret signs the links, so they can't be forged for someone
+		// else's address.
+		Secret string `envconfig:"UNSUBSCRIBE_SECRET"`
+	}
+
+	// SMTPConfig configures the relay email fails over to while Resend's
+	// breaker is open. Left without a host, there is no failover.
+	SMTPConfig struct {
+		Host     string `envconfig:"SMTP_HOST"`
+		Port     int    `envconfig:"SMTP_PORT" default:"587"`
+		Username string `envconfig:"SMTP_USERNAME"`
+		Password string `envconfig:"SMTP_PASSWORD"`
 	}
 }
 
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+// EmailSuppressionHandler serves the admin endpoints for the email
+// suppression list. Authentication is applied by the router, not here.
+type EmailSuppressionHandler struct {
+	EmailSuppressionService service.EmailSuppressionService
+	Logger                  *slog.Logger
+}
+
+type addEmailSuppressionRequest struct {
+	Address string  `json:"address"`
+	Note    *string `json:"note"`
+}
+
+// List serves GET /v1/email-suppressions, the most recent suppressions
+// first.
+func (sh *EmailSuppressionHandler) List(w http.ResponseWriter, r *http.Request) {
+	pageSize, ok := parsePageSize(w, r)
+	if !ok {
+		return
+	}
+
+	suppressions, err := sh.EmailSuppressionService.List(r.Context(), pageSize)
+	if err != nil {
+		sh.writeServiceError(w, "failed to list email suppressions", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": suppressions})
+}
+
+// Get serves GET /v1/email-suppressions/{address}.
+func (sh *EmailSuppressionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	suppression, err := sh.EmailSuppressionService.Get(r.Context(), r.PathValue("address"))
+	if err != nil {
+		sh.writeServiceError(w, "failed to get email suppression", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, suppression)
+}
+
+// Add serves POST /v1/email-suppressions, suppressing the address in the
+// body.
+func (sh *EmailSuppressionHandler) Add(w http.ResponseWriter, r *http.Request) {
+	var body addEmailSuppressionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be a JSON object with an address")
+		return
+	}
+
+	suppression, err := sh.EmailSuppressionService.Add(r.Context(), actorOf(r), body.Address, body.Note)
+	if err != nil {
+		sh.writeServiceError(w, "failed to add email suppression", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, suppression)
+}
+
+// Remove serves DELETE /v1/email-suppressions/{address}, letting email go
+// to the address again.
+func (sh *EmailSuppressionHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	suppression, err := sh.EmailSuppressionService.Remove(r.Context(), actorOf(r), r.PathValue("address"))
+	if err != nil {
+		sh.writeServiceError(w, "failed to remove email suppression", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, suppression)
+}
+
+func (sh *EmailSuppressionHandler) writeServiceError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSuppressionAddress):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrEmailSuppressionNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrEmailSuppressionExists):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		sh.Logger.Error(message, slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmailSuppressionService embeds the service.EmailSuppressionService
+// interface as a nil value so tests only implement what they exercise.
+type fakeEmailSuppressionService struct {
+	service.EmailSuppressionService
+	add func(ctx context.Context, actor, address string, note *string) (repository.EmailSuppression, error)
+}
+
+func (f *fakeEmailSuppressionService) Add(
+	ctx context.Context,
+	actor, address string,
+	note *string,
+) (repository.EmailSuppression, error) {
+	return f.add(ctx, actor, address, note)
+}
+
+func serveAddSuppression(svc service.EmailSuppressionService, body string) *httptest.ResponseRecorder {
+	sh := &EmailSuppressionHandler{EmailSuppressionService: svc, Logger: testLogger()}
+	req := httptest.NewRequest(http.MethodPost, "/v1/email-suppressions", strings.NewReader(body))
+	req.Header.Set("X-Actor", "amina@opencrafts.io")
+
+	rec := httptest.NewRecorder()
+	sh.Add(rec, req)
+	return rec
+}
+
+func TestAddSuppression_PassesActorAndAddress(t *testing.T) {
+	var gotActor, gotAddress string
+	svc := &fakeEmailSuppressionService{
+		add: func(_ context.Context, actor, address string, _ *string) (repository.EmailSuppression, error) {
+			gotActor, gotAddress = actor, address
+			return repository.EmailSuppression{Address: address}, nil
+		},
+	}
+
+	rec := serveAddSuppression(svc, `{"address": "baraka@example.com"}`)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "amina@opencrafts.io", gotActor)
+	assert.Equal(t, "baraka@example.com", gotAddress)
+}
+
+func TestAddSuppression_MapsErrors(t *testing.T) {
+	for name, tt := range map[string]struct {
+		err  error
+		want int
+	}{
+		"invalid address":    {service.ErrInvalidSuppressionAddress, http.StatusBadRequest},
+		"already suppressed": {service.ErrEmailSuppressionExists, http.StatusConflict},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := &fakeEmailSuppressionService{
+				add: func(context.Context, string, string, *string) (repository.EmailSuppression, error) {
+					return repository.EmailSuppression{}, tt.err
+				},
+			}
+
+			rec := serveAddSuppression(svc, `{"address": "baraka@example.com"}`)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestAddSuppression_RejectsMalformedBody(t *testing.T) {
+	rec := serveAddSuppression(&fakeEmailSuppressionService{}, `not json`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+type HealthHandler struct {
+	HealthService service.HealthService
+}
+
+// Live reports that the process is up and serving HTTP. It checks no
+// dependencies, so an orchestrator only restarts the service when it is
+// wedged, not when RabbitMQ or Postgres is.
+//
+// GET /healthz
+func (hh *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// Ready reports whether the service should be sent traffic, with the state
+// of each component. It answers 503 while a critical component is failing.
+//
+// GET /readyz
+func (hh *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := hh.HealthService.Ready(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
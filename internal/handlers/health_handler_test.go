@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHealthService struct {
+	report service.HealthReport
+}
+
+func (f *fakeHealthService) Ready(context.Context) service.HealthReport {
+	return f.report
+}
+
+func TestReady_StatusCodeFollowsReport(t *testing.T) {
+	for name, tt := range map[string]struct {
+		status string
+		code   int
+	}{
+		"ready":     {service.HealthStatusReady, http.StatusOK},
+		"degraded":  {service.HealthStatusDegraded, http.StatusOK},
+		"not ready": {service.HealthStatusNotReady, http.StatusServiceUnavailable},
+	} {
+		t.Run(name, func(t *testing.T) {
+			hh := &HealthHandler{HealthService: &fakeHealthService{report: service.HealthReport{
+				Status: tt.status,
+				Components: map[string]service.ComponentHealth{
+					"rabbitmq": {Status: service.ComponentStatusOK, Critical: true},
+				},
+			}}}
+			rec := httptest.NewRecorder()
+
+			hh.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			require.Equal(t, tt.code, rec.Code)
+			var body service.HealthReport
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+			assert.Equal(t, tt.status, body.Status)
+			assert.Equal(t, service.ComponentStatusOK, body.Components["rabbitmq"].Status)
+		})
+	}
+}
+
+func TestLive_AlwaysOK(t *testing.T) {
+	hh := &HealthHandler{}
+	rec := httptest.NewRecorder()
+
+	hh.Live(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+// HistoryHandler serves the read-only admin endpoints under /v1/ that let
+// support staff see what was sent to whom. Authentication is applied by the
+// router, not here.
+type HistoryHandler struct {
+	HistoryService service.HistoryService
+	Logger         *slog.Logger
+}
+
+// ListNotifications serves GET /v1/notifications. Exactly one of the
+// target_user_id, external_user_id, notification_type or status query
+// parameters selects the notifications; cursor and limit page through them.
+func (hh *HistoryHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	pageSize, ok := parsePageSize(w, r)
+	if !ok {
+		return
+	}
+
+	filter := service.NotificationFilter{
+		ExternalUserID:   query.Get("external_user_id"),
+		NotificationType: query.Get("notification_type"),
+		Status:           query.Get("status"),
+	}
+	if raw := query.Get("target_user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "target_user_id must be a UUID")
+			return
+		}
+		filter.TargetUserID = &id
+	}
+
+	page, err := hh.HistoryService.ListNotifications(
+		r.Context(),
+		filter,
+		query.Get("cursor"),
+		pageSize,
+	)
+	if err != nil {
+		hh.writeServiceError(w, "failed to list notifications", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// ListServiceEmails serves GET /v1/services/{service_id}/emails, the email
+// requests a service has published, most recent first.
+func (hh *HistoryHandler) ListServiceEmails(w http.ResponseWriter, r *http.Request) {
+	pageSize, ok := parsePageSize(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := hh.HistoryService.ListEmailRequests(
+		r.Context(),
+		r.PathValue("service_id"),
+		r.URL.Query().Get("cursor"),
+		pageSize,
+	)
+	if err != nil {
+		hh.writeServiceError(w, "failed to list email requests", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// GetEmail serves GET /v1/emails/{id}: an email request with its dispatches
+// and delivery events.
+func (hh *HistoryHandler) GetEmail(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "id must be a UUID")
+		return
+	}
+
+	detail, err := hh.HistoryService.GetEmailRequest(r.Context(), id)
+	if err != nil {
+		hh.writeServiceError(w, "failed to get email request", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// GetNotifyRequest serves GET /v1/notify/{id}: a notify request with its
+// aggregate status and the push, emails and in-app notifications it sent.
+func (hh *HistoryHandler) GetNotifyRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "id must be a UUID")
+		return
+	}
+
+	detail, err := hh.HistoryService.GetNotifyRequest(r.Context(), id)
+	if err != nil {
+		hh.writeServiceError(w, "failed to get notify request", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// writeServiceError maps a HistoryService error to a response. Anything
+// other than a bad request or a missing record is logged and reported as a
+// 500 without its details.
+func (hh *HistoryHandler) writeServiceError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidNotificationFilter):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrEmailRequestNotFound):
+		writeError(w, http.StatusNotFound, "email request not found")
+	case errors.Is(err, service.ErrNotifyRequestNotFound):
+		writeError(w, http.StatusNotFound, "notify request not found")
+	default:
+		hh.Logger.Error(message, slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// parsePageSize reads the optional limit query parameter, writing a 400 and
+// returning false if it isn't a positive integer. Zero means the default.
+func parsePageSize(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return 0, true
+	}
+
+	limit, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil || limit <= 0 {
+		writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+		return 0, false
+	}
+	return int32(min(limit, int64(service.MaxPageSize))), true
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/opencrafts-io/gossip-monger/internal/middleware"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHistoryService embeds the service.HistoryService interface as a nil
+// value so tests only implement what they exercise.
+type fakeHistoryService struct {
+	service.HistoryService
+	listNotifications func(ctx context.Context, filter service.NotificationFilter, cursor string, pageSize int32) (service.Page[service.NotificationRecord], error)
+	getEmailRequest   func(ctx context.Context, id uuid.UUID) (service.EmailRequestDetail, error)
+	getNotifyRequest  func(ctx context.Context, id uuid.UUID) (service.NotifyRequestDetail, error)
+}
+
+func (f *fakeHistoryService) ListNotifications(
+	ctx context.Context,
+	filter service.NotificationFilter,
+	cursor string,
+	pageSize int32,
+) (service.Page[service.NotificationRecord], error) {
+	return f.listNotifications(ctx, filter, cursor, pageSize)
+}
+
+func (f *fakeHistoryService) GetEmailRequest(
+	ctx context.Context,
+	id uuid.UUID,
+) (service.EmailRequestDetail, error) {
+	return f.getEmailRequest(ctx, id)
+}
+
+func (f *fakeHistoryService) GetNotifyRequest(
+	ctx context.Context,
+	id uuid.UUID,
+) (service.NotifyRequestDetail, error) {
+	return f.getNotifyRequest(ctx, id)
+}
+
+const testAdminToken = "admin-test-token"
+
+// serveAdmin routes req through the same mux patterns and bearer auth as
+// the real router.
+func serveAdmin(svc service.HistoryService, req *http.Request) *httptest.ResponseRecorder {
+	hh := &HistoryHandler{HistoryService: svc, Logger: testLogger()}
+	admin := middleware.BearerAuth(testAdminToken)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /v1/notifications", admin(http.HandlerFunc(hh.ListNotifications)))
+	mux.Handle("GET /v1/emails/{id}", admin(http.HandlerFunc(hh.GetEmail)))
+	mux.Handle("GET /v1/notify/{id}", admin(http.HandlerFunc(hh.GetNotifyRequest)))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func adminRequest(target string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+func TestListNotifications_PassesFilterCursorAndLimit(t *testing.T) {
+	userID := uuid.New()
+	var gotFilter service.NotificationFilter
+	var gotCursor string
+	var gotPageSize int32
+	svc := &fakeHistoryService{
+		listNotifications: func(_ context.Context, filter service.NotificationFilter, cursor string, pageSize int32) (service.Page[service.NotificationRecord], error) {
+			gotFilter, gotCursor, gotPageSize = filter, cursor, pageSize
+			return service.Page[service.NotificationRecord]{
+				Items:      []service.NotificationRecord{{}},
+				NextCursor: "next",
+			}, nil
+		},
+	}
+
+	rec := serveAdmin(svc, adminRequest("/v1/notifications?target_user_id="+userID.String()+"&cursor=abc&limit=500"))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, gotFilter.TargetUserID)
+	assert.Equal(t, userID, *gotFilter.TargetUserID)
+	assert.Equal(t, "abc", gotCursor)
+	assert.Equal(t, service.MaxPageSize, gotPageSize)
+
+	var body struct {
+		Items      []json.RawMessage `json:"items"`
+		NextCursor string            `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Len(t, body.Items, 1)
+	assert.Equal(t, "next", body.NextCursor)
+}
+
+func TestListNotifications_BadRequests(t *testing.T) {
+	svc := &fakeHistoryService{
+		listNotifications: func(context.Context, service.NotificationFilter, string, int32) (service.Page[service.NotificationRecord], error) {
+			return service.Page[service.NotificationRecord]{}, service.ErrInvalidNotificationFilter
+		},
+	}
+
+	for _, target := range []string{
+		"/v1/notifications?status=sent&limit=0",
+		"/v1/notifications?status=sent&limit=ten",
+		"/v1/notifications?target_user_id=not-a-uuid",
+		"/v1/notifications",
+	} {
+		rec := serveAdmin(svc, adminRequest(target))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestAdminEndpoints_RequireBearerToken(t *testing.T) {
+	svc := &fakeHistoryService{}
+
+	for name, header := range map[string]string{
+		"missing": "",
+		"wrong":   "Bearer not-the-token",
+		"basic":   "Basic " + testAdminToken,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/notifications?status=sent", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+
+			rec := serveAdmin(svc, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		})
+	}
+}
+
+func TestGetEmail_UnknownID_Returns404(t *testing.T) {
+	svc := &fakeHistoryService{
+		getEmailRequest: func(context.Context, uuid.UUID) (service.EmailRequestDetail, error) {
+			return service.EmailRequestDetail{}, service.ErrEmailRequestNotFound
+		},
+	}
+
+	rec := serveAdmin(svc, adminRequest("/v1/emails/"+uuid.NewString()))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetEmail_ReturnsDispatchesAndEvents(t *testing.T) {
+	id := uuid.New()
+	svc := &fakeHistoryService{
+		getEmailRequest: func(_ context.Context, got uuid.UUID) (service.EmailRequestDetail, error) {
+			return service.EmailRequestDetail{
+				EmailRequest:   repository.EmailRequest{ID: got, Subject: "Your invoice"},
+				Dispatches:     []repository.EmailDispatch{{Status: "sent"}},
+				DeliveryEvents: []repository.EmailDeliveryEvent{{EventType: "email.delivered"}},
+			}, nil
+		},
+	}
+
+	rec := serveAdmin(svc, adminRequest("/v1/emails/"+id.String()))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, id.String(), body["id"])
+	assert.Equal(t, "Your invoice", body["subject"])
+	assert.Len(t, body["dispatches"], 1)
+	assert.Len(t, body["delivery_events"], 1)
+}
+
+func TestGetNotifyRequest_UnknownID_Returns404(t *testing.T) {
+	svc := &fakeHistoryService{
+		getNotifyRequest: func(context.Context, uuid.UUID) (service.NotifyRequestDetail, error) {
+			return service.NotifyRequestDetail{}, service.ErrNotifyRequestNotFound
+		},
+	}
+
+	rec := serveAdmin(svc, adminRequest("/v1/notify/"+uuid.NewString()))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetNotifyRequest_ReturnsStatusAndChildren(t *testing.T) {
+	id := uuid.New()
+	svc := &fakeHistoryService{
+		getNotifyRequest: func(_ context.Context, got uuid.UUID) (service.NotifyRequestDetail, error) {
+			return service.NotifyRequestDetail{
+				NotifyRequest: repository.NotifyRequest{ID: got, Policy: "fallback", Status: "sent"},
+				Notifications: []service.NotificationRecord{{}},
+				EmailRequests: []repository.EmailRequest{},
+			}, nil
+		},
+	}
+
+	rec := serveAdmin(svc, adminRequest("/v1/notify/"+id.String()))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, id.String(), body["id"])
+	assert.Equal(t, "sent", body["status"])
+	assert.Len(t, body["notifications"], 1)
+	assert.Empty(t, body["email_requests"])
+}
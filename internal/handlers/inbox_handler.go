@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+// InboxHandler serves the in-app inbox endpoints under /v1/users/, which
+// the apps call on a signed-in user's behalf. Authentication is applied by
+// the router, not here; the caller is trusted to pass only that user's id.
+type InboxHandler struct {
+	InboxService service.InboxService
+	Logger       *slog.Logger
+}
+
+// List serves GET /v1/users/{user_id}/inbox, the user's in-app
+// notifications, newest first.
+func (ih *InboxHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+	pageSize, ok := parsePageSize(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := ih.InboxService.List(r.Context(), userID, r.URL.Query().Get("cursor"), pageSize)
+	if err != nil {
+		ih.writeServiceError(w, "failed to list inbox", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// MarkRead serves POST /v1/users/{user_id}/inbox/{id}/read.
+func (ih *InboxHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseInboxIDs(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := ih.InboxService.MarkRead(r.Context(), userID, id)
+	if err != nil {
+		ih.writeServiceError(w, "failed to mark in-app notification read", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+// Dismiss serves POST /v1/users/{user_id}/inbox/{id}/dismiss.
+func (ih *InboxHandler) Dismiss(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := parseInboxIDs(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := ih.InboxService.Dismiss(r.Context(), userID, id)
+	if err != nil {
+		ih.writeServiceError(w, "failed to dismiss in-app notification", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+// writeServiceError maps an InboxService error to a response the way
+// HistoryHandler.writeServiceError does.
+func (ih *InboxHandler) writeServiceError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCursor):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrInAppNotificationNotFound):
+		writeError(w, http.StatusNotFound, "in-app notification not found")
+	default:
+		ih.Logger.Error(message, slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// parseUserID reads the user_id path value, writing a 400 and returning
+// false if it isn't a UUID.
+func parseUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "user_id must be a UUID")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// parseInboxIDs reads the user_id and id path values of an inbox entry.
+func parseInboxIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "id must be a UUID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, id, true
+}
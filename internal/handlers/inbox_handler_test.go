@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/opencrafts-io/gossip-monger/internal/middleware"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeInboxService embeds the service.InboxService interface as a nil
+// value so tests only implement what they exercise.
+type fakeInboxService struct {
+	service.InboxService
+	list     func(ctx context.Context, userID uuid.UUID, cursor string, pageSize int32) (service.Page[repository.InAppNotification], error)
+	markRead func(ctx context.Context, userID, id uuid.UUID) (repository.InAppNotification, error)
+	dismiss  func(ctx context.Context, userID, id uuid.UUID) (repository.InAppNotification, error)
+}
+
+func (f *fakeInboxService) List(
+	ctx context.Context,
+	userID uuid.UUID,
+	cursor string,
+	pageSize int32,
+) (service.Page[repository.InAppNotification], error) {
+	return f.list(ctx, userID, cursor, pageSize)
+}
+
+func (f *fakeInboxService) MarkRead(ctx context.Context, userID, id uuid.UUID) (repository.InAppNotification, error) {
+	return f.markRead(ctx, userID, id)
+}
+
+func (f *fakeInboxService) Dismiss(ctx context.Context, userID, id uuid.UUID) (repository.InAppNotification, error) {
+	return f.dismiss(ctx, userID, id)
+}
+
+const testInboxToken = "inbox-test-token"
+
+// serveInbox routes req through the same mux patterns and bearer auth as
+// the real router.
+func serveInbox(svc service.InboxService, req *http.Request) *httptest.ResponseRecorder {
+	ih := &InboxHandler{InboxService: svc, Logger: testLogger()}
+	inbox := middleware.BearerAuth(testInboxToken)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /v1/users/{user_id}/inbox", inbox(http.HandlerFunc(ih.List)))
+	mux.Handle("POST /v1/users/{user_id}/inbox/{id}/read", inbox(http.HandlerFunc(ih.MarkRead)))
+	mux.Handle("POST /v1/users/{user_id}/inbox/{id}/dismiss", inbox(http.HandlerFunc(ih.Dismiss)))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func inboxRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+testInboxToken)
+	return req
+}
+
+func TestInboxList_PassesUserCursorAndLimit(t *testing.T) {
+	userID := uuid.New()
+	var gotUserID uuid.UUID
+	var gotCursor string
+	var gotPageSize int32
+	svc := &fakeInboxService{
+		list: func(_ context.Context, userID uuid.UUID, cursor string, pageSize int32) (service.Page[repository.InAppNotification], error) {
+			gotUserID, gotCursor, gotPageSize = userID, cursor, pageSize
+			return service.Page[repository.InAppNotification]{Items: []repository.InAppNotification{}}, nil
+		},
+	}
+
+	rec := serveInbox(svc, inboxRequest(http.MethodGet, "/v1/users/"+userID.String()+"/inbox?cursor=abc&limit=10"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, userID, gotUserID)
+	assert.Equal(t, "abc", gotCursor)
+	assert.Equal(t, int32(10), gotPageSize)
+}
+
+func TestInbox_StatusCodes(t *testing.T) {
+	userID, id := uuid.New().String(), uuid.New().String()
+	for name, tt := range map[string]struct {
+		req  *http.Request
+		err  error
+		want int
+	}{
+		"read":          {inboxRequest(http.MethodPost, "/v1/users/"+userID+"/inbox/"+id+"/read"), nil, http.StatusOK},
+		"dismiss":       {inboxRequest(http.MethodPost, "/v1/users/"+userID+"/inbox/"+id+"/dismiss"), nil, http.StatusOK},
+		"not their own": {inboxRequest(http.MethodPost, "/v1/users/"+userID+"/inbox/"+id+"/read"), service.ErrInAppNotificationNotFound, http.StatusNotFound},
+		"bad user id":   {inboxRequest(http.MethodPost, "/v1/users/nobody/inbox/"+id+"/read"), nil, http.StatusBadRequest},
+		"bad id":        {inboxRequest(http.MethodPost, "/v1/users/"+userID+"/inbox/nothing/dismiss"), nil, http.StatusBadRequest},
+		"bad cursor":    {inboxRequest(http.MethodGet, "/v1/users/"+userID+"/inbox?cursor=x"), service.ErrInvalidCursor, http.StatusBadRequest},
+		"service fails": {inboxRequest(http.MethodGet, "/v1/users/"+userID+"/inbox"), errors.New("db down"), http.StatusInternalServerError},
+		"no token":      {httptest.NewRequest(http.MethodGet, "/v1/users/"+userID+"/inbox", nil), nil, http.StatusUnauthorized},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := &fakeInboxService{
+				list: func(context.Context, uuid.UUID, string, int32) (service.Page[repository.InAppNotification], error) {
+					return service.Page[repository.InAppNotification]{}, tt.err
+				},
+				markRead: func(context.Context, uuid.UUID, uuid.UUID) (repository.InAppNotification, error) {
+					return repository.InAppNotification{}, tt.err
+				},
+				dismiss: func(context.Context, uuid.UUID, uuid.UUID) (repository.InAppNotification, error) {
+					return repository.InAppNotification{}, tt.err
+				},
+			}
+
+			rec := serveInbox(svc, tt.req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
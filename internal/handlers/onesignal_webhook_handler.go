@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+// OneSignalWebhookHandler receives OneSignal notification lifecycle
+// webhooks (delivered, clicked, dismissed) and records them against the
+// notification they report on.
+type OneSignalWebhookHandler struct {
+	PushNotificationService service.PushNotificationService
+	// Secret is the shared token OneSignal is configured to send, either as
+	// "Authorization: Bearer <secret>" or as a ?token=<secret> query
+	// parameter for webhooks that cannot set custom headers. OneSignal does
+	// not sign its webhooks, so this is the only proof a call came from it.
+	Secret string
+	Logger *slog.Logger
+}
+
+func (oh *OneSignalWebhookHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if !oh.authorized(r) {
+		oh.Logger.Warn("rejected onesignal webhook with missing or invalid token")
+		writeError(w, http.StatusUnauthorized, "invalid webhook token")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	var event service.OneSignalWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.NotificationID == "" {
+		writeError(w, http.StatusBadRequest, "invalid webhook payload")
+		return
+	}
+
+	switch event.Event {
+	case service.OneSignalEventDisplayed,
+		service.OneSignalEventDelivered,
+		service.OneSignalEventClicked,
+		service.OneSignalEventDismissed:
+	default:
+		// Acknowledge events we don't track so OneSignal stops retrying.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = oh.PushNotificationService.RecordEvent(r.Context(), event)
+	switch {
+	case errors.Is(err, service.ErrNotificationNotFound):
+		// The delivered event can race the write of the OneSignal id onto
+		// the notification row; a non-2xx lets OneSignal retry it.
+		oh.Logger.Warn("onesignal webhook for unknown notification",
+			slog.String("onesignal_notification_id", event.NotificationID),
+			slog.String("event", event.Event),
+		)
+		writeError(w, http.StatusNotFound, "unknown notification id")
+	case err != nil:
+		oh.Logger.Error("failed to record onesignal webhook",
+			slog.String("onesignal_notification_id", event.NotificationID),
+			slog.Any("error", err),
+		)
+		writeError(w, http.StatusInternalServerError, "failed to record event")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// authorized reports whether r carries the configured webhook token. An
+// empty Secret rejects everything rather than accepting everything.
+func (oh *OneSignalWebhookHandler) authorized(r *http.Request) bool {
+	if oh.Secret == "" {
+		return false
+	}
+
+	token := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(oh.Secret)) == 1
+}
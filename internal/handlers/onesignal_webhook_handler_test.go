@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePushNotificationService embeds the service.PushNotificationService
+// interface as a nil value so tests only implement what they exercise.
+type fakePushNotificationService struct {
+	service.PushNotificationService
+	recordEvent func(ctx context.Context, event service.OneSignalWebhookEvent) error
+}
+
+func (f *fakePushNotificationService) RecordEvent(
+	ctx context.Context,
+	event service.OneSignalWebhookEvent,
+) error {
+	return f.recordEvent(ctx, event)
+}
+
+const testOneSignalSecret = "onesignal-test-secret"
+
+func newOneSignalWebhookHandler(svc service.PushNotificationService) *OneSignalWebhookHandler {
+	return &OneSignalWebhookHandler{
+		PushNotificationService: svc,
+		Secret:                  testOneSignalSecret,
+		Logger:                  testLogger(),
+	}
+}
+
+func oneSignalWebhookRequest(target, body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+}
+
+func TestOneSignalWebhook_RecordsEvent(t *testing.T) {
+	for name, req := range map[string]*http.Request{
+		"bearer token": func() *http.Request {
+			req := oneSignalWebhookRequest("/webhooks/onesignal",
+				`{"event":"notification.clicked","notificationId":"os-1"}`)
+			req.Header.Set("Authorization", "Bearer "+testOneSignalSecret)
+			return req
+		}(),
+		"query token": oneSignalWebhookRequest("/webhooks/onesignal?token="+testOneSignalSecret,
+			`{"event":"notification.clicked","notificationId":"os-1"}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var got service.OneSignalWebhookEvent
+			svc := &fakePushNotificationService{
+				recordEvent: func(_ context.Context, event service.OneSignalWebhookEvent) error {
+					got = event
+					return nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			newOneSignalWebhookHandler(svc).Handle(rec, req)
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			assert.Equal(t, service.OneSignalEventClicked, got.Event)
+			assert.Equal(t, "os-1", got.NotificationID)
+		})
+	}
+}
+
+func TestOneSignalWebhook_BadToken_RejectedWithoutRecording(t *testing.T) {
+	for name, tt := range map[string]struct {
+		secret string
+		target string
+		header string
+	}{
+		"no token":         {testOneSignalSecret, "/webhooks/onesignal", ""},
+		"wrong bearer":     {testOneSignalSecret, "/webhooks/onesignal", "Bearer not-the-secret"},
+		"wrong query":      {testOneSignalSecret, "/webhooks/onesignal?token=not-the-secret", ""},
+		"bearer overrides": {testOneSignalSecret, "/webhooks/onesignal?token=" + testOneSignalSecret, "Bearer not-the-secret"},
+		"no secret set":    {"", "/webhooks/onesignal?token=", ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			called := false
+			svc := &fakePushNotificationService{
+				recordEvent: func(context.Context, service.OneSignalWebhookEvent) error {
+					called = true
+					return nil
+				},
+			}
+			handler := newOneSignalWebhookHandler(svc)
+			handler.Secret = tt.secret
+
+			req := oneSignalWebhookRequest(tt.target, `{"event":"notification.delivered","notificationId":"os-1"}`)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.Handle(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.False(t, called, "an unauthorized webhook must never be recorded")
+		})
+	}
+}
+
+func TestOneSignalWebhook_StatusCodes(t *testing.T) {
+	for name, tt := range map[string]struct {
+		body      string
+		recordErr error
+		want      int
+		recorded  bool
+	}{
+		"delivered":            {`{"event":"notification.delivered","notificationId":"os-1"}`, nil, http.StatusNoContent, true},
+		"displayed":            {`{"event":"notification.displayed","notificationId":"os-1"}`, nil, http.StatusNoContent, true},
+		"dismissed":            {`{"event":"notification.dismissed","notificationId":"os-1"}`, nil, http.StatusNoContent, true},
+		"untracked event":      {`{"event":"notification.willDisplay","notificationId":"os-1"}`, nil, http.StatusNoContent, false},
+		"unknown notification": {`{"event":"notification.delivered","notificationId":"os-1"}`, service.ErrNotificationNotFound, http.StatusNotFound, true},
+		"record fails":         {`{"event":"notification.delivered","notificationId":"os-1"}`, errors.New("db down"), http.StatusInternalServerError, true},
+		"malformed body":       {`{"event":`, nil, http.StatusBadRequest, false},
+		"no notification id":   {`{"event":"notification.delivered"}`, nil, http.StatusBadRequest, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			called := false
+			svc := &fakePushNotificationService{
+				recordEvent: func(context.Context, service.OneSignalWebhookEvent) error {
+					called = true
+					return tt.recordErr
+				},
+			}
+
+			req := oneSignalWebhookRequest("/webhooks/onesignal", tt.body)
+			req.Header.Set("Authorization", "Bearer "+testOneSignalSecret)
+			rec := httptest.NewRecorder()
+			newOneSignalWebhookHandler(svc).Handle(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Equal(t, tt.recorded, called)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+// defaultAdminActor is recorded as the actor of an audited admin action
+// whose caller didn't name themselves with an X-Actor header.
+const defaultAdminActor = "admin-api"
+
+// ParkedMessageHandler serves the admin endpoints for triaging
+// gossip.parked.queue. Authentication is applied by the router, not here.
+type ParkedMessageHandler struct {
+	ParkedMessageService service.ParkedMessageService
+	Logger               *slog.Logger
+}
+
+// List serves GET /v1/parked, the oldest parked messages first. limit
+// defaults to 50.
+func (ph *ParkedMessageHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, broker.MaxParkedScan)
+	}
+
+	messages, err := ph.ParkedMessageService.List(r.Context(), actorOf(r), limit)
+	if err != nil {
+		ph.writeServiceError(w, "failed to list parked messages", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": messages})
+}
+
+// Inspect serves GET /v1/parked/{id}.
+func (ph *ParkedMessageHandler) Inspect(w http.ResponseWriter, r *http.Request) {
+	message, err := ph.ParkedMessageService.Inspect(r.Context(), actorOf(r), r.PathValue("id"))
+	if err != nil {
+		ph.writeServiceError(w, "failed to inspect parked message", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, message)
+}
+
+// Replay serves POST /v1/parked/{id}/replay, sending the message back to
+// its original routing key.
+func (ph *ParkedMessageHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	message, err := ph.ParkedMessageService.Replay(r.Context(), actorOf(r), r.PathValue("id"))
+	if err != nil {
+		ph.writeServiceError(w, "failed to replay parked message", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, message)
+}
+
+// Discard serves POST /v1/parked/{id}/discard, dropping the message from
+// the parked queue. Its body is kept in the audit trail.
+func (ph *ParkedMessageHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	message, err := ph.ParkedMessageService.Discard(r.Context(), actorOf(r), r.PathValue("id"))
+	if err != nil {
+		ph.writeServiceError(w, "failed to discard parked message", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, message)
+}
+
+func (ph *ParkedMessageHandler) writeServiceError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, broker.ErrParkedMessageNotFound):
+		writeError(w, http.StatusNotFound, "parked message not found")
+	case errors.Is(err, broker.ErrParkedMessageNotReplayable):
+		writeError(w, http.StatusConflict, broker.ErrParkedMessageNotReplayable.Error())
+	default:
+		ph.Logger.Error(message, slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// actorOf names who is taking an audited admin action.
+func actorOf(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get("X-Actor")); actor != "" {
+		return actor
+	}
+	return defaultAdminActor
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeParkedMessageService embeds the service.ParkedMessageService
+// interface as a nil value so tests only implement what they exercise.
+type fakeParkedMessageService struct {
+	service.ParkedMessageService
+	replay func(ctx context.Context, actor, id string) (broker.ParkedMessage, error)
+}
+
+func (f *fakeParkedMessageService) Replay(
+	ctx context.Context,
+	actor, id string,
+) (broker.ParkedMessage, error) {
+	return f.replay(ctx, actor, id)
+}
+
+func serveReplay(svc service.ParkedMessageService, req *http.Request) *httptest.ResponseRecorder {
+	ph := &ParkedMessageHandler{ParkedMessageService: svc, Logger: testLogger()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/parked/{id}/replay", ph.Replay)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestParkedReplay_PassesActorAndID(t *testing.T) {
+	var gotActor, gotID string
+	svc := &fakeParkedMessageService{
+		replay: func(_ context.Context, actor, id string) (broker.ParkedMessage, error) {
+			gotActor, gotID = actor, id
+			return broker.ParkedMessage{ID: id}, nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/parked/parked-1/replay", nil)
+	req.Header.Set("X-Actor", "amina@opencrafts.io")
+
+	rec := serveReplay(svc, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "amina@opencrafts.io", gotActor)
+	assert.Equal(t, "parked-1", gotID)
+}
+
+func TestParkedReplay_MapsErrors(t *testing.T) {
+	for name, tt := range map[string]struct {
+		err  error
+		want int
+	}{
+		"not found":      {fmt.Errorf("wrapped: %w", broker.ErrParkedMessageNotFound), http.StatusNotFound},
+		"not replayable": {broker.ErrParkedMessageNotReplayable, http.StatusConflict},
+		"broker down":    {fmt.Errorf("failed to open channel"), http.StatusInternalServerError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := &fakeParkedMessageService{
+				replay: func(context.Context, string, string) (broker.ParkedMessage, error) {
+					return broker.ParkedMessage{}, tt.err
+				},
+			}
+
+			rec := serveReplay(svc, httptest.NewRequest(http.MethodPost, "/v1/parked/parked-1/replay", nil))
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
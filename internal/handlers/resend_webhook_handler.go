@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/resend/resend-go/v3"
+)
+
+// maxWebhookBodyBytes bounds how much of a webhook body is read; Resend's
+// event bodies are a few KB at most.
+const maxWebhookBodyBytes = 1 << 20
+
+// ResendWebhookHandler receives Resend's delivery webhooks (delivered,
+// bounced, complained, opened, clicked) and records them against the
+// dispatch they report on.
+type ResendWebhookHandler struct {
+	EmailService service.EmailService
+	// Verifier checks the Svix signature Resend attaches to every webhook.
+	Verifier resend.WebhooksSvc
+	// Secret is the webhook's signing secret ("whsec_...").
+	Secret string
+	Logger *slog.Logger
+}
+
+func (rh *ResendWebhookHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	if err := rh.Verifier.Verify(&resend.VerifyWebhookOptions{
+		Payload: string(body),
+		Headers: resend.WebhookHeaders{
+			Id:        r.Header.Get("svix-id"),
+			Timestamp: r.Header.Get("svix-timestamp"),
+			Signature: r.Header.Get("svix-signature"),
+		},
+		WebhookSecret: rh.Secret,
+	}); err != nil {
+		rh.Logger.Warn("rejected resend webhook", slog.Any("error", err))
+		writeError(w, http.StatusUnauthorized, "webhook verification failed")
+		return
+	}
+
+	var event service.ResendWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook payload")
+		return
+	}
+
+	// The same endpoint may be subscribed to contact/domain events too;
+	// only email events have a dispatch to attach to.
+	if !strings.HasPrefix(event.Type, "email.") || event.Data.EmailID == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = rh.EmailService.RecordDeliveryEvent(
+		r.Context(),
+		event,
+		body,
+		r.Header.Get("svix-id"),
+	)
+	switch {
+	case errors.Is(err, service.ErrDispatchNotFound):
+		// email.sent can race the commit of the dispatch row that recorded
+		// Resend's id. A non-2xx makes Svix retry later instead of the
+		// event being lost.
+		rh.Logger.Warn("resend webhook for unknown email",
+			slog.String("resend_email_id", event.Data.EmailID),
+			slog.String("event_type", event.Type),
+		)
+		writeError(w, http.StatusNotFound, "unknown email id")
+	case err != nil:
+		rh.Logger.Error("failed to record resend webhook",
+			slog.String("resend_email_id", event.Data.EmailID),
+			slog.Any("error", err),
+		)
+		writeError(w, http.StatusInternalServerError, "failed to record event")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
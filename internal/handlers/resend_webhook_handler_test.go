@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/resend/resend-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmailService embeds the service.EmailService interface as a nil
+// value so tests only implement what they exercise.
+type fakeEmailService struct {
+	service.EmailService
+	recordDeliveryEvent func(ctx context.Context, event service.ResendWebhookEvent, raw json.RawMessage, webhookID string) error
+	unsubscribe         func(ctx context.Context, token string) error
+}
+
+func (f *fakeEmailService) Unsubscribe(ctx context.Context, token string) error {
+	return f.unsubscribe(ctx, token)
+}
+
+func (f *fakeEmailService) RecordDeliveryEvent(
+	ctx context.Context,
+	event service.ResendWebhookEvent,
+	raw json.RawMessage,
+	webhookID string,
+) error {
+	return f.recordDeliveryEvent(ctx, event, raw, webhookID)
+}
+
+var testWebhookSecret = "whsec_" + base64.StdEncoding.EncodeToString(
+	[]byte("gossip-monger-test-secret"),
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// signedWebhookRequest builds a request carrying a valid Svix signature for
+// body, the way Resend would send it.
+func signedWebhookRequest(t *testing.T, body []byte) *http.Request {
+	t.Helper()
+	secret, err := base64.StdEncoding.DecodeString(testWebhookSecret[len("whsec_"):])
+	require.NoError(t, err)
+
+	id := "msg_test"
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id + "." + ts + "." + string(body)))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/resend", bytes.NewReader(body))
+	req.Header.Set("svix-id", id)
+	req.Header.Set("svix-timestamp", ts)
+	req.Header.Set("svix-signature", "v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func newResendWebhookHandler(svc service.EmailService) *ResendWebhookHandler {
+	return &ResendWebhookHandler{
+		EmailService: svc,
+		Verifier:     resend.NewClient("re_test").Webhooks,
+		Secret:       testWebhookSecret,
+		Logger:       testLogger(),
+	}
+}
+
+func TestResendWebhook_ValidSignature_RecordsEvent(t *testing.T) {
+	var got service.ResendWebhookEvent
+	var gotWebhookID string
+	svc := &fakeEmailService{
+		recordDeliveryEvent: func(_ context.Context, event service.ResendWebhookEvent, _ json.RawMessage, webhookID string) error {
+			got = event
+			gotWebhookID = webhookID
+			return nil
+		},
+	}
+
+	body := []byte(`{"type":"email.bounced","created_at":"2026-10-16T09:00:00Z","data":{"email_id":"re_123","to":["a@example.com"]}}`)
+	rec := httptest.NewRecorder()
+	newResendWebhookHandler(svc).Handle(rec, signedWebhookRequest(t, body))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "email.bounced", got.Type)
+	assert.Equal(t, "re_123", got.Data.EmailID)
+	assert.Equal(t, []string{"a@example.com"}, got.Data.To)
+	assert.Equal(t, "msg_test", gotWebhookID)
+}
+
+func TestResendWebhook_BadSignature_RejectedWithoutRecording(t *testing.T) {
+	called := false
+	svc := &fakeEmailService{
+		recordDeliveryEvent: func(context.Context, service.ResendWebhookEvent, json.RawMessage, string) error {
+			called = true
+			return nil
+		},
+	}
+
+	req := signedWebhookRequest(t, []byte(`{"type":"email.delivered","data":{"email_id":"re_123"}}`))
+	// Tamper with the body after signing.
+	req.Body = io.NopCloser(bytes.NewReader([]byte(`{"type":"email.delivered","data":{"email_id":"re_999"}}`)))
+
+	rec := httptest.NewRecorder()
+	newResendWebhookHandler(svc).Handle(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.False(t, called, "an unverified webhook must never be recorded")
+}
+
+func TestResendWebhook_UnknownEmail_ReturnsNotFoundSoResendRetries(t *testing.T) {
+	svc := &fakeEmailService{
+		recordDeliveryEvent: func(context.Context, service.ResendWebhookEvent, json.RawMessage, string) error {
+			return service.ErrDispatchNotFound
+		},
+	}
+
+	body := []byte(`{"type":"email.sent","data":{"email_id":"re_unknown"}}`)
+	rec := httptest.NewRecorder()
+	newResendWebhookHandler(svc).Handle(rec, signedWebhookRequest(t, body))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestResendWebhook_NonEmailEvent_AcknowledgedAndIgnored(t *testing.T) {
+	called := false
+	svc := &fakeEmailService{
+		recordDeliveryEvent: func(context.Context, service.ResendWebhookEvent, json.RawMessage, string) error {
+			called = true
+			return nil
+		},
+	}
+
+	body := []byte(`{"type":"contact.created","data":{"id":"c_1"}}`)
+	rec := httptest.NewRecorder()
+	newResendWebhookHandler(svc).Handle(rec, signedWebhookRequest(t, body))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, called)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+// SenderDomainHandler serves the admin endpoints for the domains each
+// service may send email from. Authentication is applied by the router,
+// not here.
+type SenderDomainHandler struct {
+	SenderDomainService service.SenderDomainService
+	Logger              *slog.Logger
+}
+
+type setSenderDomainsRequest struct {
+	AllowedSenderDomains []string `json:"allowed_sender_domains"`
+}
+
+// Get serves GET /v1/services/{service_id}/sender-domains.
+func (dh *SenderDomainHandler) Get(w http.ResponseWriter, r *http.Request) {
+	domains, err := dh.SenderDomainService.Get(r.Context(), r.PathValue("service_id"))
+	if err != nil {
+		dh.writeServiceError(w, "failed to get sender domains", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, domains)
+}
+
+// Set serves PUT /v1/services/{service_id}/sender-domains, replacing the
+// service's domains with the ones in the body.
+func (dh *SenderDomainHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var body setSenderDomainsRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.AllowedSenderDomains == nil {
+		writeError(w, http.StatusBadRequest, "body must be a JSON object with allowed_sender_domains")
+		return
+	}
+
+	domains, err := dh.SenderDomainService.Set(
+		r.Context(),
+		actorOf(r),
+		r.PathValue("service_id"),
+		body.AllowedSenderDomains,
+	)
+	if err != nil {
+		dh.writeServiceError(w, "failed to set sender domains", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, domains)
+}
+
+func (dh *SenderDomainHandler) writeServiceError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidServiceID), errors.Is(err, service.ErrInvalidSenderDomain):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrServiceNotFound):
+		writeError(w, http.StatusNotFound, "service not found")
+	default:
+		dh.Logger.Error(message, slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSenderDomainService embeds the service.SenderDomainService interface
+// as a nil value so tests only implement what they exercise.
+type fakeSenderDomainService struct {
+	service.SenderDomainService
+	set func(ctx context.Context, actor, serviceID string, domains []string) (service.ServiceSenderDomains, error)
+}
+
+func (f *fakeSenderDomainService) Set(
+	ctx context.Context,
+	actor, serviceID string,
+	domains []string,
+) (service.ServiceSenderDomains, error) {
+	return f.set(ctx, actor, serviceID, domains)
+}
+
+func serveSetSenderDomains(svc service.SenderDomainService, body string) *httptest.ResponseRecorder {
+	dh := &SenderDomainHandler{SenderDomainService: svc, Logger: testLogger()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /v1/services/{service_id}/sender-domains", dh.Set)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/services/io.opencrafts.keepup/sender-domains", strings.NewReader(body))
+	req.Header.Set("X-Actor", "amina@opencrafts.io")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSetSenderDomains_PassesActorServiceAndDomains(t *testing.T) {
+	var gotActor, gotService string
+	var gotDomains []string
+	svc := &fakeSenderDomainService{
+		set: func(_ context.Context, actor, serviceID string, domains []string) (service.ServiceSenderDomains, error) {
+			gotActor, gotService, gotDomains = actor, serviceID, domains
+			return service.ServiceSenderDomains{ServiceID: serviceID, AllowedSenderDomains: domains}, nil
+		},
+	}
+
+	rec := serveSetSenderDomains(svc, `{"allowed_sender_domains": ["keepup.app"]}`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "amina@opencrafts.io", gotActor)
+	assert.Equal(t, "io.opencrafts.keepup", gotService)
+	assert.Equal(t, []string{"keepup.app"}, gotDomains)
+}
+
+func TestSetSenderDomains_BadRequests(t *testing.T) {
+	svc := &fakeSenderDomainService{
+		set: func(context.Context, string, string, []string) (service.ServiceSenderDomains, error) {
+			return service.ServiceSenderDomains{}, service.ErrInvalidSenderDomain
+		},
+	}
+
+	for _, body := range []string{`not json`, `{}`, `{"allowed_sender_domains": ["localhost"]}`} {
+		rec := serveSetSenderDomains(svc, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
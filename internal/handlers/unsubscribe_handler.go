@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+)
+
+// UnsubscribeHandler serves the List-Unsubscribe links of non-transactional
+// email. A POST unsubscribes, as RFC 8058 one-click unsubscribe requires;
+// a GET, which link scanners and prefetchers make too, only asks the
+// recipient to confirm.
+type UnsubscribeHandler struct {
+	EmailService service.EmailService
+	Logger       *slog.Logger
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!doctype html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+{{if .Confirm}}<form method="post" action="/unsubscribe?token={{.Token}}">
+<input type="hidden" name="List-Unsubscribe" value="One-Click">
+<p>Stop receiving these emails?</p>
+<button type="submit">Unsubscribe</button>
+</form>{{else}}<p>{{.Message}}</p>{{end}}
+</body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Confirm bool
+	Token   string
+	Message string
+}
+
+func (uh *UnsubscribeHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writePage(w, http.StatusBadRequest, unsubscribePageData{Message: "This unsubscribe link is invalid."})
+		return
+	}
+	writePage(w, http.StatusOK, unsubscribePageData{Confirm: true, Token: token})
+}
+
+func (uh *UnsubscribeHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	err := uh.EmailService.Unsubscribe(r.Context(), r.URL.Query().Get("token"))
+	switch {
+	case errors.Is(err, service.ErrInvalidUnsubscribeToken):
+		writePage(w, http.StatusBadRequest, unsubscribePageData{Message: "This unsubscribe link is invalid."})
+	case err != nil:
+		uh.Logger.Error("failed to record unsubscribe", slog.Any("error", err))
+		writePage(w, http.StatusInternalServerError, unsubscribePageData{Message: "Something went wrong; please try again later."})
+	default:
+		writePage(w, http.StatusOK, unsubscribePageData{Message: "You have been unsubscribed."})
+	}
+}
+
+func writePage(w http.ResponseWriter, status int, data unsubscribePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	unsubscribePage.Execute(w, data)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsubscribe_GetOnlyAsksToConfirm(t *testing.T) {
+	uh := &UnsubscribeHandler{
+		// Reaching the service on a GET would panic on the nil fake.
+		EmailService: &fakeEmailService{},
+		Logger:       testLogger(),
+	}
+	rec := httptest.NewRecorder()
+
+	uh.Confirm(rec, httptest.NewRequest(http.MethodGet, "/unsubscribe?token=abc.def", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `action="/unsubscribe?token=abc.def"`)
+	assert.Contains(t, rec.Body.String(), `method="post"`)
+}
+
+func TestUnsubscribe_OneClickPostRecordsOptOut(t *testing.T) {
+	var got string
+	uh := &UnsubscribeHandler{
+		EmailService: &fakeEmailService{unsubscribe: func(_ context.Context, token string) error {
+			got = token
+			return nil
+		}},
+		Logger: testLogger(),
+	}
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/unsubscribe?token=abc.def",
+		strings.NewReader("List-Unsubscribe=One-Click"),
+	)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	uh.Unsubscribe(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "abc.def", got)
+}
+
+func TestUnsubscribe_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"forged link", service.ErrInvalidUnsubscribeToken, http.StatusBadRequest},
+		{"database down", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uh := &UnsubscribeHandler{
+				EmailService: &fakeEmailService{unsubscribe: func(context.Context, string) error {
+					return tt.err
+				}},
+				Logger: testLogger(),
+			}
+			rec := httptest.NewRecorder()
+
+			uh.Unsubscribe(rec, httptest.NewRequest(http.MethodPost, "/unsubscribe?token=abc.def", nil))
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
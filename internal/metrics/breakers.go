@@ -0,0 +1,39 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// breakerCollector reports circuit breaker states, read at every scrape.
+type breakerCollector struct {
+	states func() map[string]float64
+	state  *prometheus.Desc
+}
+
+// RegisterBreakers adds the gossip_circuit_breaker_state gauge to Registry.
+// states returns every breaker's state by name: 0 while closed, 1 while
+// half-open and 2 while open. It is called at every scrape, so the gauge
+// follows a breaker that went half-open without anything calling it.
+func RegisterBreakers(states func() map[string]float64) error {
+	return Registry.Register(newBreakerCollector(states))
+}
+
+func newBreakerCollector(states func() map[string]float64) *breakerCollector {
+	return &breakerCollector{
+		states: states,
+		state: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "circuit_breaker_state"),
+			"Circuit breaker state: 0 closed, 1 half-open, 2 open.",
+			[]string{"breaker"},
+			nil,
+		),
+	}
+}
+
+func (c *breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+}
+
+func (c *breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, state := range c.states() {
+		ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, state, name)
+	}
+}
@@ -0,0 +1,103 @@
+// Package metrics holds the Prometheus collectors gossip-monger exposes on
+// /metrics, so the packages that record them don't each need a registry
+// passed in.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gossip"
+
+// Registry holds every gossip-monger collector, plus the Go runtime and
+// process collectors. It is separate from prometheus.DefaultRegisterer so
+// libraries can't add metrics to /metrics behind our back.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Consumer metrics, labelled by queue.
+var (
+	MessagesConsumed = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_consumed_total",
+		Help:      "Messages delivered to a consumer.",
+	}, []string{"queue"})
+	MessagesAcked = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_acked_total",
+		Help:      "Messages acked, after being handled, retried or parked.",
+	}, []string{"queue"})
+	MessagesNacked = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_nacked_total",
+		Help:      "Messages nacked, into the fixed-delay retry queue or, on a queue without one, discarded.",
+	}, []string{"queue"})
+	MessagesRetried = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_retried_total",
+		Help:      "Failed messages sent to a retry tier.",
+	}, []string{"queue", "tier"})
+	MessagesParked = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_parked_total",
+		Help:      "Messages moved to the parked queue, because they exhausted their retries or failed permanently.",
+	}, []string{"queue", "reason"})
+	HandlerDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Time a consumer's handler took per message.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"queue", "outcome"})
+)
+
+// Handler outcomes for HandlerDuration.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Reasons for MessagesParked.
+const (
+	ParkedExhausted = "exhausted"
+	ParkedPermanent = "permanent"
+)
+
+// Provider metrics.
+var (
+	// ProviderCallDuration is labelled with the provider ("onesignal",
+	// "resend", "smtp", "africastalking") and the status the attempt was
+	// recorded with: "sent", "failed", "circuit_open", or "rejected" for an
+	// SMS every recipient was refused.
+	ProviderCallDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_call_duration_seconds",
+		Help:      "Time a call to a notification provider took, by outcome.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"provider", "status"})
+)
+
+// ObserveProviderCall records a provider call that started at start and
+// ended with status.
+func ObserveProviderCall(provider, status string, start time.Time) {
+	ProviderCallDuration.WithLabelValues(provider, status).
+		Observe(time.Since(start).Seconds())
+}
+
+// Handler serves Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
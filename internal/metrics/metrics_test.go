@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolCollector_ReportsEveryStat(t *testing.T) {
+	// pgxpool connects lazily, so nothing needs to be listening.
+	pool, err := pgxpool.New(context.Background(), "postgres://gossip@127.0.0.1:1/gossip")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	collector := newPoolCollector(pool)
+
+	assert.Equal(t, 12, testutil.CollectAndCount(collector))
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP gossip_db_pool_acquired_conns Connections currently acquired from the pool.
+# TYPE gossip_db_pool_acquired_conns gauge
+gossip_db_pool_acquired_conns 0
+`), "gossip_db_pool_acquired_conns"))
+}
+
+func TestBreakerCollector_ReadsStatesAtEveryScrape(t *testing.T) {
+	states := map[string]float64{"onesignal:app-1": 2}
+	collector := newBreakerCollector(func() map[string]float64 { return states })
+
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP gossip_circuit_breaker_state Circuit breaker state: 0 closed, 1 half-open, 2 open.
+# TYPE gossip_circuit_breaker_state gauge
+gossip_circuit_breaker_state{breaker="onesignal:app-1"} 2
+`)))
+
+	states["onesignal:app-1"] = 1
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP gossip_circuit_breaker_state Circuit breaker state: 0 closed, 1 half-open, 2 open.
+# TYPE gossip_circuit_breaker_state gauge
+gossip_circuit_breaker_state{breaker="onesignal:app-1"} 1
+`)))
+}
+
+func TestHandler_ServesRegisteredMetrics(t *testing.T) {
+	MessagesParked.WithLabelValues("gossip.test.queue", ParkedPermanent).Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(),
+		`gossip_messages_parked_total{queue="gossip.test.queue",reason="permanent"} 1`)
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
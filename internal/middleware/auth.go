@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// BearerAuth rejects any request that doesn't carry
+// "Authorization: Bearer <token>" with 401 Unauthorized. An empty token
+// rejects every request, so an unconfigured deployment fails closed.
+func BearerAuth(token string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || !ok ||
+				subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]any{"error": "invalid or missing bearer token"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
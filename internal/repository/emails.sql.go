@@ -11,8 +11,105 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cancelEmailRequest = `-- name: CancelEmailRequest :one
+UPDATE email_requests
+  SET status = 'cancelled',
+      cancelled_at = NOW()
+  WHERE queue_message_id = $1
+    AND service_id = $2
+    AND status IN ('failed', 'circuit_open')
+RETURNING id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at, cancelled_at, amqp_message_id, attempt_count, notification_type, suppressed_addresses, transactional, to_user_ids, resolved_to_addresses, notify_request_id, failure_reason
+`
+
+type CancelEmailRequestParams struct {
+	QueueMessageID string `json:"queue_message_id"`
+	ServiceID      string `json:"service_id"`
+}
+
+// Cancels an email whose last attempt failed and is waiting in the retry
+// queue; Send skips a cancelled request when the retry arrives. A
+// dispatched email can't be recalled. Only the service that sent the
+// email may cancel it.
+func (q *Queries) CancelEmailRequest(ctx context.Context, arg CancelEmailRequestParams) (EmailRequest, error) {
+	row := q.db.QueryRow(ctx, cancelEmailRequest, arg.QueueMessageID, arg.ServiceID)
+	var i EmailRequest
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.QueueMessageID,
+		&i.Exchange,
+		&i.RoutingKey,
+		&i.FromAddress,
+		&i.ReplyTo,
+		&i.ToAddresses,
+		&i.CcAddresses,
+		&i.BccAddresses,
+		&i.Subject,
+		&i.BodyHtml,
+		&i.BodyText,
+		&i.Attachments,
+		&i.TemplateID,
+		&i.TemplateVars,
+		&i.Status,
+		&i.ReceivedAt,
+		&i.ProcessedAt,
+		&i.CancelledAt,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.NotificationType,
+		&i.SuppressedAddresses,
+		&i.Transactional,
+		&i.ToUserIds,
+		&i.ResolvedToAddresses,
+		&i.NotifyRequestID,
+		&i.FailureReason,
+	)
+	return i, err
+}
+
+const createEmailDeliveryEvent = `-- name: CreateEmailDeliveryEvent :exec
+INSERT INTO email_delivery_events(
+  dispatch_id,
+  resend_email_id,
+  event_type,
+  recipient,
+  raw_payload,
+  occurred_at,
+  webhook_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT DO NOTHING
+`
+
+type CreateEmailDeliveryEventParams struct {
+	DispatchID    uuid.UUID          `json:"dispatch_id"`
+	ResendEmailID string             `json:"resend_email_id"`
+	EventType     string             `json:"event_type"`
+	Recipient     *string            `json:"recipient"`
+	RawPayload    json.RawMessage    `json:"raw_payload"`
+	OccurredAt    pgtype.Timestamptz `json:"occurred_at"`
+	WebhookID     *string            `json:"webhook_id"`
+}
+
+// Records a Resend delivery event (delivered, bounced, complained, ...)
+// against its dispatch. A redelivered webhook (same webhook_id and
+// recipient, or no recipient either time) is silently ignored rather than
+// recorded twice.
+func (q *Queries) CreateEmailDeliveryEvent(ctx context.Context, arg CreateEmailDeliveryEventParams) error {
+	_, err := q.db.Exec(ctx, createEmailDeliveryEvent,
+		arg.DispatchID,
+		arg.ResendEmailID,
+		arg.EventType,
+		arg.Recipient,
+		arg.RawPayload,
+		arg.OccurredAt,
+		arg.WebhookID,
+	)
+	return err
+}
+
 const createEmailDispatch = `-- name: CreateEmailDispatch :one
 INSERT INTO email_dispatches(
   email_request_id,
@@ -20,18 +117,22 @@ INSERT INTO email_dispatches(
   resend_payload,
   status,
   http_status_code,
-  resend_error
-) VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, email_request_id, resend_email_id, resend_payload, status, http_status_code, resend_error, dispatched_at
+  resend_error,
+  provider,
+  provider_message_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, email_request_id, resend_email_id, resend_payload, status, http_status_code, resend_error, dispatched_at, provider, provider_message_id
 `
 
 type CreateEmailDispatchParams struct {
-	EmailRequestID uuid.UUID       `json:"email_request_id"`
-	ResendEmailID  *string         `json:"resend_email_id"`
-	ResendPayload  json.RawMessage `json:"resend_payload"`
-	Status         string          `json:"status"`
-	HttpStatusCode *int32          `json:"http_status_code"`
-	ResendError    *string         `json:"resend_error"`
+	EmailRequestID    uuid.UUID       `json:"email_request_id"`
+	ResendEmailID     *string         `json:"resend_email_id"`
+	ResendPayload     json.RawMessage `json:"resend_payload"`
+	Status            string          `json:"status"`
+	HttpStatusCode    *int32          `json:"http_status_code"`
+	ResendError       *string         `json:"resend_error"`
+	Provider          string          `json:"provider"`
+	ProviderMessageID *string         `json:"provider_message_id"`
 }
 
 // Records an email dispatch to the email sending service for compliance
@@ -44,6 +145,8 @@ func (q *Queries) CreateEmailDispatch(ctx context.Context, arg CreateEmailDispat
 		arg.Status,
 		arg.HttpStatusCode,
 		arg.ResendError,
+		arg.Provider,
+		arg.ProviderMessageID,
 	)
 	var i EmailDispatch
 	err := row.Scan(
@@ -55,12 +158,157 @@ func (q *Queries) CreateEmailDispatch(ctx context.Context, arg CreateEmailDispat
 		&i.HttpStatusCode,
 		&i.ResendError,
 		&i.DispatchedAt,
+		&i.Provider,
+		&i.ProviderMessageID,
 	)
 	return i, err
 }
 
+const createEmailUnsubscribe = `-- name: CreateEmailUnsubscribe :exec
+INSERT INTO email_unsubscribes (service_id, address)
+VALUES ($1, lower($2::text))
+ON CONFLICT (service_id, address) DO NOTHING
+`
+
+type CreateEmailUnsubscribeParams struct {
+	ServiceID string `json:"service_id"`
+	Address   string `json:"address"`
+}
+
+// Records that address unsubscribed from a service's non-transactional
+// email. Unsubscribing twice keeps the first time.
+func (q *Queries) CreateEmailUnsubscribe(ctx context.Context, arg CreateEmailUnsubscribeParams) error {
+	_, err := q.db.Exec(ctx, createEmailUnsubscribe, arg.ServiceID, arg.Address)
+	return err
+}
+
+const emailRequestWasDelivered = `-- name: EmailRequestWasDelivered :one
+SELECT EXISTS (
+  SELECT 1
+  FROM email_delivery_events e
+  JOIN email_dispatches d ON d.id = e.dispatch_id
+  WHERE d.email_request_id = $1
+    AND e.event_type IN ('email.delivered', 'email.opened', 'email.clicked')
+)
+`
+
+// Whether Resend reported any sign that an email request reached its
+// recipient's inbox.
+func (q *Queries) EmailRequestWasDelivered(ctx context.Context, emailRequestID uuid.UUID) (bool, error) {
+	row := q.db.QueryRow(ctx, emailRequestWasDelivered, emailRequestID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const getEmailDeliveryEventsByRequestID = `-- name: GetEmailDeliveryEventsByRequestID :many
+select e.id, e.dispatch_id, e.resend_email_id, e.event_type, e.recipient, e.raw_payload, e.occurred_at, e.recorded_at, e.webhook_id
+from email_delivery_events e
+join email_dispatches d on d.id = e.dispatch_id
+where d.email_request_id = $1
+order by e.occurred_at asc, e.id asc
+`
+
+// Every delivery event Resend reported for any of an email request's
+// dispatches, in the order they happened.
+func (q *Queries) GetEmailDeliveryEventsByRequestID(ctx context.Context, emailRequestID uuid.UUID) ([]EmailDeliveryEvent, error) {
+	rows, err := q.db.Query(ctx, getEmailDeliveryEventsByRequestID, emailRequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EmailDeliveryEvent{}
+	for rows.Next() {
+		var i EmailDeliveryEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.DispatchID,
+			&i.ResendEmailID,
+			&i.EventType,
+			&i.Recipient,
+			&i.RawPayload,
+			&i.OccurredAt,
+			&i.RecordedAt,
+			&i.WebhookID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEmailDispatchByResendEmailID = `-- name: GetEmailDispatchByResendEmailID :one
+select id, email_request_id, resend_email_id, resend_payload, status, http_status_code, resend_error, dispatched_at, provider, provider_message_id
+from email_dispatches
+where resend_email_id = $1
+limit 1
+`
+
+// Resolves the dispatch a Resend webhook is reporting on; resend_email_id is
+// only ever set on a dispatch Resend actually accepted.
+func (q *Queries) GetEmailDispatchByResendEmailID(ctx context.Context, resendEmailID *string) (EmailDispatch, error) {
+	row := q.db.QueryRow(ctx, getEmailDispatchByResendEmailID, resendEmailID)
+	var i EmailDispatch
+	err := row.Scan(
+		&i.ID,
+		&i.EmailRequestID,
+		&i.ResendEmailID,
+		&i.ResendPayload,
+		&i.Status,
+		&i.HttpStatusCode,
+		&i.ResendError,
+		&i.DispatchedAt,
+		&i.Provider,
+		&i.ProviderMessageID,
+	)
+	return i, err
+}
+
+const getEmailDispatchesByRequestID = `-- name: GetEmailDispatchesByRequestID :many
+select id, email_request_id, resend_email_id, resend_payload, status, http_status_code, resend_error, dispatched_at, provider, provider_message_id
+from email_dispatches
+where email_request_id = $1
+order by dispatched_at asc, id asc
+`
+
+// Every attempt to hand an email request to a provider, oldest first.
+func (q *Queries) GetEmailDispatchesByRequestID(ctx context.Context, emailRequestID uuid.UUID) ([]EmailDispatch, error) {
+	rows, err := q.db.Query(ctx, getEmailDispatchesByRequestID, emailRequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EmailDispatch{}
+	for rows.Next() {
+		var i EmailDispatch
+		if err := rows.Scan(
+			&i.ID,
+			&i.EmailRequestID,
+			&i.ResendEmailID,
+			&i.ResendPayload,
+			&i.Status,
+			&i.HttpStatusCode,
+			&i.ResendError,
+			&i.DispatchedAt,
+			&i.Provider,
+			&i.ProviderMessageID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getEmailRequestByID = `-- name: GetEmailRequestByID :one
-select id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at
+select id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at, cancelled_at, amqp_message_id, attempt_count, notification_type, suppressed_addresses, transactional, to_user_ids, resolved_to_addresses, notify_request_id, failure_reason
 from email_requests
 where id = $1
 limit 1
@@ -89,12 +337,22 @@ func (q *Queries) GetEmailRequestByID(ctx context.Context, id uuid.UUID) (EmailR
 		&i.Status,
 		&i.ReceivedAt,
 		&i.ProcessedAt,
+		&i.CancelledAt,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.NotificationType,
+		&i.SuppressedAddresses,
+		&i.Transactional,
+		&i.ToUserIds,
+		&i.ResolvedToAddresses,
+		&i.NotifyRequestID,
+		&i.FailureReason,
 	)
 	return i, err
 }
 
 const getEmailRequestByQueueMessageID = `-- name: GetEmailRequestByQueueMessageID :one
-select id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at
+select id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at, cancelled_at, amqp_message_id, attempt_count, notification_type, suppressed_addresses, transactional, to_user_ids, resolved_to_addresses, notify_request_id, failure_reason
 from email_requests
 where queue_message_id = $1
 limit 1
@@ -128,27 +386,105 @@ func (q *Queries) GetEmailRequestByQueueMessageID(ctx context.Context, queueMess
 		&i.Status,
 		&i.ReceivedAt,
 		&i.ProcessedAt,
+		&i.CancelledAt,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.NotificationType,
+		&i.SuppressedAddresses,
+		&i.Transactional,
+		&i.ToUserIds,
+		&i.ResolvedToAddresses,
+		&i.NotifyRequestID,
+		&i.FailureReason,
 	)
 	return i, err
 }
 
 const getEmailRequestByService = `-- name: GetEmailRequestByService :many
-select id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at
+select id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at, cancelled_at, amqp_message_id, attempt_count, notification_type, suppressed_addresses, transactional, to_user_ids, resolved_to_addresses, notify_request_id, failure_reason
 from email_requests
 where service_id = $1
-order by received_at desc
-limit $2 offset $3
+  and (
+    $2::timestamptz is null
+    or (received_at, id) < ($2::timestamptz, $3::uuid)
+  )
+order by received_at desc, id desc
+limit $4
 `
 
 type GetEmailRequestByServiceParams struct {
-	ServiceID string `json:"service_id"`
-	Limit     int32  `json:"limit"`
-	Offset    int32  `json:"offset"`
+	ServiceID        string      `json:"service_id"`
+	CursorReceivedAt *time.Time  `json:"cursor_received_at"`
+	CursorID         pgtype.UUID `json:"cursor_id"`
+	PageSize         int32       `json:"page_size"`
 }
 
-// Orders the time it was recieved ie the most previous
+// Keyset-paginated, most recently received first. Pass the received_at and
+// id of the last row of the previous page as the cursor, or NULLs for the
+// first page.
 func (q *Queries) GetEmailRequestByService(ctx context.Context, arg GetEmailRequestByServiceParams) ([]EmailRequest, error) {
-	rows, err := q.db.Query(ctx, getEmailRequestByService, arg.ServiceID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getEmailRequestByService,
+		arg.ServiceID,
+		arg.CursorReceivedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EmailRequest{}
+	for rows.Next() {
+		var i EmailRequest
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.QueueMessageID,
+			&i.Exchange,
+			&i.RoutingKey,
+			&i.FromAddress,
+			&i.ReplyTo,
+			&i.ToAddresses,
+			&i.CcAddresses,
+			&i.BccAddresses,
+			&i.Subject,
+			&i.BodyHtml,
+			&i.BodyText,
+			&i.Attachments,
+			&i.TemplateID,
+			&i.TemplateVars,
+			&i.Status,
+			&i.ReceivedAt,
+			&i.ProcessedAt,
+			&i.CancelledAt,
+			&i.AmqpMessageID,
+			&i.AttemptCount,
+			&i.NotificationType,
+			&i.SuppressedAddresses,
+			&i.Transactional,
+			&i.ToUserIds,
+			&i.ResolvedToAddresses,
+			&i.NotifyRequestID,
+			&i.FailureReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEmailRequestsByNotifyRequestID = `-- name: GetEmailRequestsByNotifyRequestID :many
+SELECT id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at, cancelled_at, amqp_message_id, attempt_count, notification_type, suppressed_addresses, transactional, to_user_ids, resolved_to_addresses, notify_request_id, failure_reason FROM email_requests
+WHERE notify_request_id = $1
+ORDER BY received_at ASC
+`
+
+func (q *Queries) GetEmailRequestsByNotifyRequestID(ctx context.Context, notifyRequestID pgtype.UUID) ([]EmailRequest, error) {
+	rows, err := q.db.Query(ctx, getEmailRequestsByNotifyRequestID, notifyRequestID)
 	if err != nil {
 		return nil, err
 	}
@@ -176,6 +512,16 @@ func (q *Queries) GetEmailRequestByService(ctx context.Context, arg GetEmailRequ
 			&i.Status,
 			&i.ReceivedAt,
 			&i.ProcessedAt,
+			&i.CancelledAt,
+			&i.AmqpMessageID,
+			&i.AttemptCount,
+			&i.NotificationType,
+			&i.SuppressedAddresses,
+			&i.Transactional,
+			&i.ToUserIds,
+			&i.ResolvedToAddresses,
+			&i.NotifyRequestID,
+			&i.FailureReason,
 		); err != nil {
 			return nil, err
 		}
@@ -187,11 +533,81 @@ func (q *Queries) GetEmailRequestByService(ctx context.Context, arg GetEmailRequ
 	return items, nil
 }
 
+const linkEmailRequestToNotifyRequest = `-- name: LinkEmailRequestToNotifyRequest :exec
+UPDATE email_requests
+SET notify_request_id = $1
+WHERE queue_message_id = $2
+`
+
+type LinkEmailRequestToNotifyRequestParams struct {
+	NotifyRequestID pgtype.UUID `json:"notify_request_id"`
+	QueueMessageID  string      `json:"queue_message_id"`
+}
+
+// Marks the email sent under queue_message_id as one channel of a notify
+// request.
+func (q *Queries) LinkEmailRequestToNotifyRequest(ctx context.Context, arg LinkEmailRequestToNotifyRequestParams) error {
+	_, err := q.db.Exec(ctx, linkEmailRequestToNotifyRequest, arg.NotifyRequestID, arg.QueueMessageID)
+	return err
+}
+
+const listUnsubscribedAddresses = `-- name: ListUnsubscribedAddresses :many
+SELECT address
+FROM email_unsubscribes
+WHERE service_id = $1
+  AND address = ANY($2::text[])
+`
+
+type ListUnsubscribedAddressesParams struct {
+	ServiceID string   `json:"service_id"`
+	Addresses []string `json:"addresses"`
+}
+
+// The addresses, of those given (lowercased), that unsubscribed from the
+// service's non-transactional email.
+func (q *Queries) ListUnsubscribedAddresses(ctx context.Context, arg ListUnsubscribedAddressesParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listUnsubscribedAddresses, arg.ServiceID, arg.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		items = append(items, address)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setEmailRequestSuppressedAddresses = `-- name: SetEmailRequestSuppressedAddresses :exec
+UPDATE email_requests
+  SET suppressed_addresses = $2
+  WHERE id = $1
+`
+
+type SetEmailRequestSuppressedAddressesParams struct {
+	ID                  uuid.UUID `json:"id"`
+	SuppressedAddresses []string  `json:"suppressed_addresses"`
+}
+
+// Records the recipients an email request's latest attempt left out, NULL
+// if it left out none.
+func (q *Queries) SetEmailRequestSuppressedAddresses(ctx context.Context, arg SetEmailRequestSuppressedAddressesParams) error {
+	_, err := q.db.Exec(ctx, setEmailRequestSuppressedAddresses, arg.ID, arg.SuppressedAddresses)
+	return err
+}
+
 const updateEmailRequestStatusByID = `-- name: UpdateEmailRequestStatusByID :one
 UPDATE email_requests
   SET status = $2
   WHERE id = $1
-RETURNING id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at
+RETURNING id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at, cancelled_at, amqp_message_id, attempt_count, notification_type, suppressed_addresses, transactional, to_user_ids, resolved_to_addresses, notify_request_id, failure_reason
 `
 
 type UpdateEmailRequestStatusByIDParams struct {
@@ -224,6 +640,16 @@ func (q *Queries) UpdateEmailRequestStatusByID(ctx context.Context, arg UpdateEm
 		&i.Status,
 		&i.ReceivedAt,
 		&i.ProcessedAt,
+		&i.CancelledAt,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.NotificationType,
+		&i.SuppressedAddresses,
+		&i.Transactional,
+		&i.ToUserIds,
+		&i.ResolvedToAddresses,
+		&i.NotifyRequestID,
+		&i.FailureReason,
 	)
 	return i, err
 }
@@ -234,10 +660,14 @@ INSERT INTO email_requests (
   queue_message_id,
   exchange,
   routing_key,
+  amqp_message_id,
+  attempt_count,
 
   from_address,
   reply_to,
   to_addresses,
+  to_user_ids,
+  resolved_to_addresses,
   cc_addresses,
   bcc_addresses,
   subject,
@@ -247,51 +677,73 @@ INSERT INTO email_requests (
 
   template_id,
   template_vars,
+  notification_type,
+  transactional,
 
   status,
+  failure_reason,
   processed_at
 
-) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+) VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
 ON CONFLICT (queue_message_id) DO UPDATE SET
+  exchange = EXCLUDED.exchange,
+  routing_key = EXCLUDED.routing_key,
+  amqp_message_id = EXCLUDED.amqp_message_id,
+  attempt_count = EXCLUDED.attempt_count,
+  resolved_to_addresses = EXCLUDED.resolved_to_addresses,
   status = EXCLUDED.status,
+  failure_reason = EXCLUDED.failure_reason,
   processed_at = EXCLUDED.processed_at
-RETURNING id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at
+RETURNING id, service_id, queue_message_id, exchange, routing_key, from_address, reply_to, to_addresses, cc_addresses, bcc_addresses, subject, body_html, body_text, attachments, template_id, template_vars, status, received_at, processed_at, cancelled_at, amqp_message_id, attempt_count, notification_type, suppressed_addresses, transactional, to_user_ids, resolved_to_addresses, notify_request_id, failure_reason
 `
 
 type UpsertEmailRequestParams struct {
-	ServiceID      string          `json:"service_id"`
-	QueueMessageID string          `json:"queue_message_id"`
-	Exchange       string          `json:"exchange"`
-	RoutingKey     string          `json:"routing_key"`
-	FromAddress    string          `json:"from_address"`
-	ReplyTo        *string         `json:"reply_to"`
-	ToAddresses    []string        `json:"to_addresses"`
-	CcAddresses    []string        `json:"cc_addresses"`
-	BccAddresses   []string        `json:"bcc_addresses"`
-	Subject        string          `json:"subject"`
-	BodyHtml       *string         `json:"body_html"`
-	BodyText       *string         `json:"body_text"`
-	Attachments    json.RawMessage `json:"attachments"`
-	TemplateID     *string         `json:"template_id"`
-	TemplateVars   json.RawMessage `json:"template_vars"`
-	Status         string          `json:"status"`
-	ProcessedAt    *time.Time      `json:"processed_at"`
+	ServiceID           string          `json:"service_id"`
+	QueueMessageID      string          `json:"queue_message_id"`
+	Exchange            string          `json:"exchange"`
+	RoutingKey          string          `json:"routing_key"`
+	AmqpMessageID       *string         `json:"amqp_message_id"`
+	AttemptCount        int32           `json:"attempt_count"`
+	FromAddress         string          `json:"from_address"`
+	ReplyTo             *string         `json:"reply_to"`
+	ToAddresses         []string        `json:"to_addresses"`
+	ToUserIds           []uuid.UUID     `json:"to_user_ids"`
+	ResolvedToAddresses []string        `json:"resolved_to_addresses"`
+	CcAddresses         []string        `json:"cc_addresses"`
+	BccAddresses        []string        `json:"bcc_addresses"`
+	Subject             string          `json:"subject"`
+	BodyHtml            *string         `json:"body_html"`
+	BodyText            *string         `json:"body_text"`
+	Attachments         json.RawMessage `json:"attachments"`
+	TemplateID          *string         `json:"template_id"`
+	TemplateVars        json.RawMessage `json:"template_vars"`
+	NotificationType    *string         `json:"notification_type"`
+	Transactional       *bool           `json:"transactional"`
+	Status              string          `json:"status"`
+	FailureReason       *string         `json:"failure_reason"`
+	ProcessedAt         *time.Time      `json:"processed_at"`
 }
 
 // Persists an email request to the database for replayability, or updates
 // it in place if this is a retry of the same queue_message_id (dead-lettered
 // redelivery) — retrying must not hit the queue_message_id UNIQUE
 // constraint as a fresh insert, or the retry mechanism would just fail
-// forever on the second attempt without ever reaching Resend again.
+// forever on the second attempt without ever reaching Resend again. The
+// delivery columns are overwritten too, so they describe the delivery that
+// last handled the request.
 func (q *Queries) UpsertEmailRequest(ctx context.Context, arg UpsertEmailRequestParams) (EmailRequest, error) {
 	row := q.db.QueryRow(ctx, upsertEmailRequest,
 		arg.ServiceID,
 		arg.QueueMessageID,
 		arg.Exchange,
 		arg.RoutingKey,
+		arg.AmqpMessageID,
+		arg.AttemptCount,
 		arg.FromAddress,
 		arg.ReplyTo,
 		arg.ToAddresses,
+		arg.ToUserIds,
+		arg.ResolvedToAddresses,
 		arg.CcAddresses,
 		arg.BccAddresses,
 		arg.Subject,
@@ -300,7 +752,10 @@ func (q *Queries) UpsertEmailRequest(ctx context.Context, arg UpsertEmailRequest
 		arg.Attachments,
 		arg.TemplateID,
 		arg.TemplateVars,
+		arg.NotificationType,
+		arg.Transactional,
 		arg.Status,
+		arg.FailureReason,
 		arg.ProcessedAt,
 	)
 	var i EmailRequest
@@ -324,6 +779,16 @@ func (q *Queries) UpsertEmailRequest(ctx context.Context, arg UpsertEmailRequest
 		&i.Status,
 		&i.ReceivedAt,
 		&i.ProcessedAt,
+		&i.CancelledAt,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.NotificationType,
+		&i.SuppressedAddresses,
+		&i.Transactional,
+		&i.ToUserIds,
+		&i.ResolvedToAddresses,
+		&i.NotifyRequestID,
+		&i.FailureReason,
 	)
 	return i, err
 }
@@ -0,0 +1,228 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: in_app.sql
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createInAppNotification = `-- name: CreateInAppNotification :exec
+INSERT INTO in_app_notifications (
+  user_id,
+  service_id,
+  notify_request_id,
+  notification_type,
+  title,
+  body,
+  url,
+  status
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (notify_request_id, user_id) DO NOTHING
+`
+
+type CreateInAppNotificationParams struct {
+	UserID           uuid.UUID `json:"user_id"`
+	ServiceID        string    `json:"service_id"`
+	NotifyRequestID  uuid.UUID `json:"notify_request_id"`
+	NotificationType *string   `json:"notification_type"`
+	Title            string    `json:"title"`
+	Body             string    `json:"body"`
+	Url              *string   `json:"url"`
+	Status           string    `json:"status"`
+}
+
+// Puts a notify request's notification in a user's inbox. A retried notify
+// request leaves the one already there as it is.
+func (q *Queries) CreateInAppNotification(ctx context.Context, arg CreateInAppNotificationParams) error {
+	_, err := q.db.Exec(ctx, createInAppNotification,
+		arg.UserID,
+		arg.ServiceID,
+		arg.NotifyRequestID,
+		arg.NotificationType,
+		arg.Title,
+		arg.Body,
+		arg.Url,
+		arg.Status,
+	)
+	return err
+}
+
+const dismissInAppNotification = `-- name: DismissInAppNotification :one
+UPDATE in_app_notifications
+SET dismissed_at = COALESCE(dismissed_at, NOW()),
+    read_at = COALESCE(read_at, NOW())
+WHERE id = $1
+  AND user_id = $2
+  AND status = 'delivered'
+RETURNING id, user_id, service_id, notify_request_id, notification_type, title, body, url, status, created_at, read_at, dismissed_at
+`
+
+type DismissInAppNotificationParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Takes one of a user's in-app notifications out of their inbox. A
+// dismissed notification counts as read.
+func (q *Queries) DismissInAppNotification(ctx context.Context, arg DismissInAppNotificationParams) (InAppNotification, error) {
+	row := q.db.QueryRow(ctx, dismissInAppNotification, arg.ID, arg.UserID)
+	var i InAppNotification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ServiceID,
+		&i.NotifyRequestID,
+		&i.NotificationType,
+		&i.Title,
+		&i.Body,
+		&i.Url,
+		&i.Status,
+		&i.CreatedAt,
+		&i.ReadAt,
+		&i.DismissedAt,
+	)
+	return i, err
+}
+
+const getInAppNotificationsByNotifyRequestID = `-- name: GetInAppNotificationsByNotifyRequestID :many
+SELECT id, user_id, service_id, notify_request_id, notification_type, title, body, url, status, created_at, read_at, dismissed_at FROM in_app_notifications
+WHERE notify_request_id = $1
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetInAppNotificationsByNotifyRequestID(ctx context.Context, notifyRequestID uuid.UUID) ([]InAppNotification, error) {
+	rows, err := q.db.Query(ctx, getInAppNotificationsByNotifyRequestID, notifyRequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []InAppNotification{}
+	for rows.Next() {
+		var i InAppNotification
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ServiceID,
+			&i.NotifyRequestID,
+			&i.NotificationType,
+			&i.Title,
+			&i.Body,
+			&i.Url,
+			&i.Status,
+			&i.CreatedAt,
+			&i.ReadAt,
+			&i.DismissedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInboxNotifications = `-- name: ListInboxNotifications :many
+SELECT id, user_id, service_id, notify_request_id, notification_type, title, body, url, status, created_at, read_at, dismissed_at FROM in_app_notifications
+WHERE user_id = $1
+  AND status = 'delivered'
+  AND dismissed_at IS NULL
+  AND (
+    $2::timestamptz IS NULL
+    OR (created_at, id) < ($2::timestamptz, $3::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListInboxNotificationsParams struct {
+	UserID          uuid.UUID   `json:"user_id"`
+	CursorCreatedAt *time.Time  `json:"cursor_created_at"`
+	CursorID        pgtype.UUID `json:"cursor_id"`
+	PageSize        int32       `json:"page_size"`
+}
+
+// A user's inbox: their delivered in-app notifications not yet dismissed,
+// keyset-paginated, newest first. Pass the created_at and id of the last
+// row of the previous page as the cursor, or NULLs for the first page.
+func (q *Queries) ListInboxNotifications(ctx context.Context, arg ListInboxNotificationsParams) ([]InAppNotification, error) {
+	rows, err := q.db.Query(ctx, listInboxNotifications,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []InAppNotification{}
+	for rows.Next() {
+		var i InAppNotification
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ServiceID,
+			&i.NotifyRequestID,
+			&i.NotificationType,
+			&i.Title,
+			&i.Body,
+			&i.Url,
+			&i.Status,
+			&i.CreatedAt,
+			&i.ReadAt,
+			&i.DismissedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markInAppNotificationRead = `-- name: MarkInAppNotificationRead :one
+UPDATE in_app_notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1
+  AND user_id = $2
+  AND status = 'delivered'
+RETURNING id, user_id, service_id, notify_request_id, notification_type, title, body, url, status, created_at, read_at, dismissed_at
+`
+
+type MarkInAppNotificationReadParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Marks one of a user's in-app notifications read. Reading it again keeps
+// the first read_at.
+func (q *Queries) MarkInAppNotificationRead(ctx context.Context, arg MarkInAppNotificationReadParams) (InAppNotification, error) {
+	row := q.db.QueryRow(ctx, markInAppNotificationRead, arg.ID, arg.UserID)
+	var i InAppNotification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ServiceID,
+		&i.NotifyRequestID,
+		&i.NotificationType,
+		&i.Title,
+		&i.Body,
+		&i.Url,
+		&i.Status,
+		&i.CreatedAt,
+		&i.ReadAt,
+		&i.DismissedAt,
+	)
+	return i, err
+}
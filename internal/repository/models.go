@@ -21,39 +21,82 @@ type EmailDeliveryEvent struct {
 	RawPayload    json.RawMessage    `json:"raw_payload"`
 	OccurredAt    pgtype.Timestamptz `json:"occurred_at"`
 	RecordedAt    pgtype.Timestamptz `json:"recorded_at"`
+	WebhookID     *string            `json:"webhook_id"`
 }
 
 type EmailDispatch struct {
-	ID             uuid.UUID          `json:"id"`
-	EmailRequestID uuid.UUID          `json:"email_request_id"`
-	ResendEmailID  *string            `json:"resend_email_id"`
-	ResendPayload  json.RawMessage    `json:"resend_payload"`
-	Status         string             `json:"status"`
-	HttpStatusCode *int32             `json:"http_status_code"`
-	ResendError    *string            `json:"resend_error"`
-	DispatchedAt   pgtype.Timestamptz `json:"dispatched_at"`
+	ID                uuid.UUID          `json:"id"`
+	EmailRequestID    uuid.UUID          `json:"email_request_id"`
+	ResendEmailID     *string            `json:"resend_email_id"`
+	ResendPayload     json.RawMessage    `json:"resend_payload"`
+	Status            string             `json:"status"`
+	HttpStatusCode    *int32             `json:"http_status_code"`
+	ResendError       *string            `json:"resend_error"`
+	DispatchedAt      pgtype.Timestamptz `json:"dispatched_at"`
+	Provider          string             `json:"provider"`
+	ProviderMessageID *string            `json:"provider_message_id"`
 }
 
 type EmailRequest struct {
-	ID             uuid.UUID          `json:"id"`
+	ID                  uuid.UUID          `json:"id"`
+	ServiceID           string             `json:"service_id"`
+	QueueMessageID      string             `json:"queue_message_id"`
+	Exchange            string             `json:"exchange"`
+	RoutingKey          string             `json:"routing_key"`
+	FromAddress         string             `json:"from_address"`
+	ReplyTo             *string            `json:"reply_to"`
+	ToAddresses         []string           `json:"to_addresses"`
+	CcAddresses         []string           `json:"cc_addresses"`
+	BccAddresses        []string           `json:"bcc_addresses"`
+	Subject             string             `json:"subject"`
+	BodyHtml            *string            `json:"body_html"`
+	BodyText            *string            `json:"body_text"`
+	Attachments         json.RawMessage    `json:"attachments"`
+	TemplateID          *string            `json:"template_id"`
+	TemplateVars        json.RawMessage    `json:"template_vars"`
+	Status              string             `json:"status"`
+	ReceivedAt          pgtype.Timestamptz `json:"received_at"`
+	ProcessedAt         *time.Time         `json:"processed_at"`
+	CancelledAt         *time.Time         `json:"cancelled_at"`
+	AmqpMessageID       *string            `json:"amqp_message_id"`
+	AttemptCount        int32              `json:"attempt_count"`
+	NotificationType    *string            `json:"notification_type"`
+	SuppressedAddresses []string           `json:"suppressed_addresses"`
+	Transactional       *bool              `json:"transactional"`
+	ToUserIds           []uuid.UUID        `json:"to_user_ids"`
+	ResolvedToAddresses []string           `json:"resolved_to_addresses"`
+	NotifyRequestID     pgtype.UUID        `json:"notify_request_id"`
+	FailureReason       *string            `json:"failure_reason"`
+}
+
+type EmailSuppression struct {
+	Address    string             `json:"address"`
+	Reason     string             `json:"reason"`
+	DispatchID pgtype.UUID        `json:"dispatch_id"`
+	Note       *string            `json:"note"`
+	CreatedBy  string             `json:"created_by"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type EmailUnsubscribe struct {
 	ServiceID      string             `json:"service_id"`
-	QueueMessageID string             `json:"queue_message_id"`
-	Exchange       string             `json:"exchange"`
-	RoutingKey     string             `json:"routing_key"`
-	FromAddress    string             `json:"from_address"`
-	ReplyTo        *string            `json:"reply_to"`
-	ToAddresses    []string           `json:"to_addresses"`
-	CcAddresses    []string           `json:"cc_addresses"`
-	BccAddresses   []string           `json:"bcc_addresses"`
-	Subject        string             `json:"subject"`
-	BodyHtml       *string            `json:"body_html"`
-	BodyText       *string            `json:"body_text"`
-	Attachments    json.RawMessage    `json:"attachments"`
-	TemplateID     *string            `json:"template_id"`
-	TemplateVars   json.RawMessage    `json:"template_vars"`
-	Status         string             `json:"status"`
-	ReceivedAt     pgtype.Timestamptz `json:"received_at"`
-	ProcessedAt    *time.Time         `json:"processed_at"`
+	Address        string             `json:"address"`
+	UnsubscribedAt pgtype.Timestamptz `json:"unsubscribed_at"`
+}
+
+type InAppNotification struct {
+	ID               uuid.UUID          `json:"id"`
+	UserID           uuid.UUID          `json:"user_id"`
+	ServiceID        string             `json:"service_id"`
+	NotifyRequestID  uuid.UUID          `json:"notify_request_id"`
+	NotificationType *string            `json:"notification_type"`
+	Title            string             `json:"title"`
+	Body             string             `json:"body"`
+	Url              *string            `json:"url"`
+	Status           string             `json:"status"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	ReadAt           *time.Time         `json:"read_at"`
+	DismissedAt      *time.Time         `json:"dismissed_at"`
 }
 
 type Notification struct {
@@ -119,14 +162,99 @@ type Notification struct {
 	SentAt                  pgtype.Timestamp `json:"sent_at"`
 	DeliveredAt             pgtype.Timestamp `json:"delivered_at"`
 	QueueMessageID          *string          `json:"queue_message_id"`
+	ReadAt                  pgtype.Timestamp `json:"read_at"`
+	FailedAt                pgtype.Timestamp `json:"failed_at"`
+	DismissedAt             pgtype.Timestamp `json:"dismissed_at"`
+	CancelledAt             pgtype.Timestamp `json:"cancelled_at"`
+	SuppressedUserIds       []string         `json:"suppressed_user_ids"`
+	NotifyRequestID         pgtype.UUID      `json:"notify_request_id"`
+	DispatchRetries         int32            `json:"dispatch_retries"`
+}
+
+type NotificationPreference struct {
+	UserID           uuid.UUID          `json:"user_id"`
+	Channel          string             `json:"channel"`
+	NotificationType string             `json:"notification_type"`
+	Enabled          bool               `json:"enabled"`
+	SourceServiceID  string             `json:"source_service_id"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
+type NotifyRequest struct {
+	ID                   uuid.UUID          `json:"id"`
+	ServiceID            string             `json:"service_id"`
+	QueueMessageID       string             `json:"queue_message_id"`
+	Exchange             string             `json:"exchange"`
+	RoutingKey           string             `json:"routing_key"`
+	AmqpMessageID        *string            `json:"amqp_message_id"`
+	AttemptCount         int32              `json:"attempt_count"`
+	ToUserIds            []uuid.UUID        `json:"to_user_ids"`
+	Channels             []string           `json:"channels"`
+	Policy               string             `json:"policy"`
+	FallbackAfterMinutes *int32             `json:"fallback_after_minutes"`
+	Payload              json.RawMessage    `json:"payload"`
+	NextChannel          int32              `json:"next_channel"`
+	NextAttemptAt        *time.Time         `json:"next_attempt_at"`
+	Status               string             `json:"status"`
+	ReceivedAt           pgtype.Timestamptz `json:"received_at"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+	ClaimCount           int32              `json:"claim_count"`
+}
+
+type ParkedMessageAction struct {
+	ID                 uuid.UUID          `json:"id"`
+	Action             string             `json:"action"`
+	Actor              string             `json:"actor"`
+	ParkedMessageID    *string            `json:"parked_message_id"`
+	OriginalExchange   *string            `json:"original_exchange"`
+	OriginalRoutingKey *string            `json:"original_routing_key"`
+	Body               []byte             `json:"body"`
+	Succeeded          bool               `json:"succeeded"`
+	Error              *string            `json:"error"`
+	PerformedAt        pgtype.Timestamptz `json:"performed_at"`
 }
 
 type Service struct {
-	ID          string             `json:"id"`
-	Name        string             `json:"name"`
-	Description *string            `json:"description"`
-	IsActive    bool               `json:"is_active"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	Description          *string            `json:"description"`
+	IsActive             bool               `json:"is_active"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	OnesignalAppID       *string            `json:"onesignal_app_id"`
+	OnesignalRestApiKey  *string            `json:"onesignal_rest_api_key"`
+	AllowedSenderDomains []string           `json:"allowed_sender_domains"`
+	ResendApiKey         *string            `json:"resend_api_key"`
+}
+
+type SmsDispatch struct {
+	ID             uuid.UUID          `json:"id"`
+	SmsRequestID   uuid.UUID          `json:"sms_request_id"`
+	Provider       string             `json:"provider"`
+	Status         string             `json:"status"`
+	HttpStatusCode *int32             `json:"http_status_code"`
+	ProviderError  *string            `json:"provider_error"`
+	Recipients     json.RawMessage    `json:"recipients"`
+	DispatchedAt   pgtype.Timestamptz `json:"dispatched_at"`
+}
+
+type SmsRequest struct {
+	ID                uuid.UUID          `json:"id"`
+	ServiceID         string             `json:"service_id"`
+	QueueMessageID    string             `json:"queue_message_id"`
+	Exchange          string             `json:"exchange"`
+	RoutingKey        string             `json:"routing_key"`
+	AmqpMessageID     *string            `json:"amqp_message_id"`
+	AttemptCount      int32              `json:"attempt_count"`
+	ToNumbers         []string           `json:"to_numbers"`
+	Body              string             `json:"body"`
+	SenderID          *string            `json:"sender_id"`
+	Status            string             `json:"status"`
+	ReceivedAt        pgtype.Timestamptz `json:"received_at"`
+	ProcessedAt       *time.Time         `json:"processed_at"`
+	ToUserIds         []uuid.UUID        `json:"to_user_ids"`
+	ResolvedToNumbers []string           `json:"resolved_to_numbers"`
+	NotificationType  *string            `json:"notification_type"`
+	SuppressedNumbers []string           `json:"suppressed_numbers"`
 }
 
 type User struct {
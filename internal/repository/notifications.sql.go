@@ -13,6 +13,222 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cancelPendingNotification = `-- name: CancelPendingNotification :one
+UPDATE notifications
+SET
+    status = 'cancelled',
+    cancelled_at = NOW(),
+    updated_at = NOW()
+WHERE queue_message_id = $1
+  AND source_service_id = $2
+  AND status IN ('pending', 'failed', 'circuit_open')
+RETURNING id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries
+`
+
+type CancelPendingNotificationParams struct {
+	QueueMessageID  *string `json:"queue_message_id"`
+	SourceServiceID *string `json:"source_service_id"`
+}
+
+// Cancels a push that hasn't gone out yet: one waiting for its send_after,
+// or one whose failed attempt is waiting in the retry queue. A push the
+// scheduler has already claimed ('dispatching') is past cancelling. Only
+// the service that sent the push may cancel it.
+func (q *Queries) CancelPendingNotification(ctx context.Context, arg CancelPendingNotificationParams) (Notification, error) {
+	row := q.db.QueryRow(ctx, cancelPendingNotification, arg.QueueMessageID, arg.SourceServiceID)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.AppID,
+		&i.IncludedSegments,
+		&i.ExcludedSegments,
+		&i.IncludePlayerIds,
+		&i.IncludeExternalUserIds,
+		&i.IncludeEmailTokens,
+		&i.IncludePhoneNumbers,
+		&i.IncludeIosTokens,
+		&i.IncludeWpWnsUris,
+		&i.IncludeAmazonRegIds,
+		&i.IncludeChromeRegIds,
+		&i.IncludeChromeWebRegIds,
+		&i.IncludeAndroidRegIds,
+		&i.Contents,
+		&i.Headings,
+		&i.Subtitle,
+		&i.Buttons,
+		&i.WebButtons,
+		&i.BigPicture,
+		&i.LargeIcon,
+		&i.SmallIcon,
+		&i.IosAttachments,
+		&i.AndroidChannelID,
+		&i.AndroidAccentColor,
+		&i.AndroidLedColor,
+		&i.AndroidGroup,
+		&i.AndroidGroupMessage,
+		&i.AndroidSound,
+		&i.IosSound,
+		&i.WpWnsSound,
+		&i.AdmSound,
+		&i.ChromeWebImage,
+		&i.ChromeWebIcon,
+		&i.ChromeWebBadge,
+		&i.ChromeWebColor,
+		&i.ChromeWebSound,
+		&i.Url,
+		&i.WebUrl,
+		&i.AppUrl,
+		&i.Data,
+		&i.Filters,
+		&i.Tags,
+		&i.SendAfter,
+		&i.DelayedOption,
+		&i.DeliveryTimeOfDay,
+		&i.Ttl,
+		&i.Priority,
+		&i.OnesignalNotificationID,
+		&i.OnesignalStatus,
+		&i.OnesignalResponse,
+		&i.OnesignalError,
+		&i.TargetUserID,
+		&i.SourceServiceID,
+		&i.SourceUserID,
+		&i.NotificationType,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SentAt,
+		&i.DeliveredAt,
+		&i.QueueMessageID,
+		&i.ReadAt,
+		&i.FailedAt,
+		&i.DismissedAt,
+		&i.CancelledAt,
+		&i.SuppressedUserIds,
+		&i.NotifyRequestID,
+		&i.DispatchRetries,
+	)
+	return i, err
+}
+
+const claimDueNotifications = `-- name: ClaimDueNotifications :many
+UPDATE notifications
+SET
+    status = 'dispatching',
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM notifications
+    WHERE (status = 'pending' AND send_after <= (NOW() AT TIME ZONE 'UTC'))
+       OR (status = 'dispatching'
+           AND updated_at < NOW() - make_interval(secs => $1::int))
+    ORDER BY send_after ASC
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries
+`
+
+type ClaimDueNotificationsParams struct {
+	StaleAfterSeconds int32 `json:"stale_after_seconds"`
+	BatchSize         int32 `json:"batch_size"`
+}
+
+// Claims scheduled notifications whose send_after has passed by flipping
+// them to 'dispatching' in the same statement that selects them. SKIP
+// LOCKED makes concurrent replicas claim disjoint rows instead of queueing
+// behind each other, so no row is handed to two dispatchers. A row stuck in
+// 'dispatching' past stale_after_seconds (its replica died mid-dispatch)
+// is claimed again. send_after is stored as UTC wall-clock time.
+func (q *Queries) ClaimDueNotifications(ctx context.Context, arg ClaimDueNotificationsParams) ([]Notification, error) {
+	rows, err := q.db.Query(ctx, claimDueNotifications, arg.StaleAfterSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Notification{}
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.AppID,
+			&i.IncludedSegments,
+			&i.ExcludedSegments,
+			&i.IncludePlayerIds,
+			&i.IncludeExternalUserIds,
+			&i.IncludeEmailTokens,
+			&i.IncludePhoneNumbers,
+			&i.IncludeIosTokens,
+			&i.IncludeWpWnsUris,
+			&i.IncludeAmazonRegIds,
+			&i.IncludeChromeRegIds,
+			&i.IncludeChromeWebRegIds,
+			&i.IncludeAndroidRegIds,
+			&i.Contents,
+			&i.Headings,
+			&i.Subtitle,
+			&i.Buttons,
+			&i.WebButtons,
+			&i.BigPicture,
+			&i.LargeIcon,
+			&i.SmallIcon,
+			&i.IosAttachments,
+			&i.AndroidChannelID,
+			&i.AndroidAccentColor,
+			&i.AndroidLedColor,
+			&i.AndroidGroup,
+			&i.AndroidGroupMessage,
+			&i.AndroidSound,
+			&i.IosSound,
+			&i.WpWnsSound,
+			&i.AdmSound,
+			&i.ChromeWebImage,
+			&i.ChromeWebIcon,
+			&i.ChromeWebBadge,
+			&i.ChromeWebColor,
+			&i.ChromeWebSound,
+			&i.Url,
+			&i.WebUrl,
+			&i.AppUrl,
+			&i.Data,
+			&i.Filters,
+			&i.Tags,
+			&i.SendAfter,
+			&i.DelayedOption,
+			&i.DeliveryTimeOfDay,
+			&i.Ttl,
+			&i.Priority,
+			&i.OnesignalNotificationID,
+			&i.OnesignalStatus,
+			&i.OnesignalResponse,
+			&i.OnesignalError,
+			&i.TargetUserID,
+			&i.SourceServiceID,
+			&i.SourceUserID,
+			&i.NotificationType,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SentAt,
+			&i.DeliveredAt,
+			&i.QueueMessageID,
+			&i.ReadAt,
+			&i.FailedAt,
+			&i.DismissedAt,
+			&i.CancelledAt,
+			&i.SuppressedUserIds,
+			&i.NotifyRequestID,
+			&i.DispatchRetries,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const cleanupOldNotifications = `-- name: CleanupOldNotifications :exec
 DELETE FROM notifications
 WHERE created_at < NOW() - INTERVAL '90 days'
@@ -35,7 +251,7 @@ func (q *Queries) DeleteNotification(ctx context.Context, id uuid.UUID) error {
 }
 
 const getNotificationByID = `-- name: GetNotificationByID :one
-SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id FROM notifications 
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications 
 WHERE id = $1
 `
 
@@ -105,12 +321,19 @@ func (q *Queries) GetNotificationByID(ctx context.Context, id uuid.UUID) (Notifi
 		&i.SentAt,
 		&i.DeliveredAt,
 		&i.QueueMessageID,
+		&i.ReadAt,
+		&i.FailedAt,
+		&i.DismissedAt,
+		&i.CancelledAt,
+		&i.SuppressedUserIds,
+		&i.NotifyRequestID,
+		&i.DispatchRetries,
 	)
 	return i, err
 }
 
 const getNotificationByOneSignalID = `-- name: GetNotificationByOneSignalID :one
-SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id FROM notifications 
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications 
 WHERE onesignal_notification_id = $1
 `
 
@@ -180,12 +403,19 @@ func (q *Queries) GetNotificationByOneSignalID(ctx context.Context, onesignalNot
 		&i.SentAt,
 		&i.DeliveredAt,
 		&i.QueueMessageID,
+		&i.ReadAt,
+		&i.FailedAt,
+		&i.DismissedAt,
+		&i.CancelledAt,
+		&i.SuppressedUserIds,
+		&i.NotifyRequestID,
+		&i.DispatchRetries,
 	)
 	return i, err
 }
 
 const getNotificationByQueueMessageID = `-- name: GetNotificationByQueueMessageID :one
-SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id FROM notifications
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications
 WHERE queue_message_id = $1
 `
 
@@ -260,6 +490,13 @@ func (q *Queries) GetNotificationByQueueMessageID(ctx context.Context, queueMess
 		&i.SentAt,
 		&i.DeliveredAt,
 		&i.QueueMessageID,
+		&i.ReadAt,
+		&i.FailedAt,
+		&i.DismissedAt,
+		&i.CancelledAt,
+		&i.SuppressedUserIds,
+		&i.NotifyRequestID,
+		&i.DispatchRetries,
 	)
 	return i, err
 }
@@ -335,21 +572,32 @@ func (q *Queries) GetNotificationStatsByType(ctx context.Context, notificationTy
 }
 
 const getNotificationsByExternalUserID = `-- name: GetNotificationsByExternalUserID :many
-SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id FROM notifications 
-WHERE $1 = ANY(include_external_user_ids)
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications
+WHERE include_external_user_ids @> ARRAY[$1::text]
+  AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $4
 `
 
 type GetNotificationsByExternalUserIDParams struct {
-	IncludeExternalUserIds []string `json:"include_external_user_ids"`
-	Limit                  int32    `json:"limit"`
-	Offset                 int32    `json:"offset"`
+	ExternalUserID  string           `json:"external_user_id"`
+	CursorCreatedAt pgtype.Timestamp `json:"cursor_created_at"`
+	CursorID        pgtype.UUID      `json:"cursor_id"`
+	PageSize        int32            `json:"page_size"`
 }
 
+// Keyset-paginated, newest first. Pass the created_at and id of the last
+// row of the previous page as the cursor, or NULLs for the first page.
 func (q *Queries) GetNotificationsByExternalUserID(ctx context.Context, arg GetNotificationsByExternalUserIDParams) ([]Notification, error) {
-	rows, err := q.db.Query(ctx, getNotificationsByExternalUserID, arg.IncludeExternalUserIds, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getNotificationsByExternalUserID,
+		arg.ExternalUserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -420,6 +668,109 @@ func (q *Queries) GetNotificationsByExternalUserID(ctx context.Context, arg GetN
 			&i.SentAt,
 			&i.DeliveredAt,
 			&i.QueueMessageID,
+			&i.ReadAt,
+			&i.FailedAt,
+			&i.DismissedAt,
+			&i.CancelledAt,
+			&i.SuppressedUserIds,
+			&i.NotifyRequestID,
+			&i.DispatchRetries,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNotificationsByNotifyRequestID = `-- name: GetNotificationsByNotifyRequestID :many
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications
+WHERE notify_request_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetNotificationsByNotifyRequestID(ctx context.Context, notifyRequestID pgtype.UUID) ([]Notification, error) {
+	rows, err := q.db.Query(ctx, getNotificationsByNotifyRequestID, notifyRequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Notification{}
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.AppID,
+			&i.IncludedSegments,
+			&i.ExcludedSegments,
+			&i.IncludePlayerIds,
+			&i.IncludeExternalUserIds,
+			&i.IncludeEmailTokens,
+			&i.IncludePhoneNumbers,
+			&i.IncludeIosTokens,
+			&i.IncludeWpWnsUris,
+			&i.IncludeAmazonRegIds,
+			&i.IncludeChromeRegIds,
+			&i.IncludeChromeWebRegIds,
+			&i.IncludeAndroidRegIds,
+			&i.Contents,
+			&i.Headings,
+			&i.Subtitle,
+			&i.Buttons,
+			&i.WebButtons,
+			&i.BigPicture,
+			&i.LargeIcon,
+			&i.SmallIcon,
+			&i.IosAttachments,
+			&i.AndroidChannelID,
+			&i.AndroidAccentColor,
+			&i.AndroidLedColor,
+			&i.AndroidGroup,
+			&i.AndroidGroupMessage,
+			&i.AndroidSound,
+			&i.IosSound,
+			&i.WpWnsSound,
+			&i.AdmSound,
+			&i.ChromeWebImage,
+			&i.ChromeWebIcon,
+			&i.ChromeWebBadge,
+			&i.ChromeWebColor,
+			&i.ChromeWebSound,
+			&i.Url,
+			&i.WebUrl,
+			&i.AppUrl,
+			&i.Data,
+			&i.Filters,
+			&i.Tags,
+			&i.SendAfter,
+			&i.DelayedOption,
+			&i.DeliveryTimeOfDay,
+			&i.Ttl,
+			&i.Priority,
+			&i.OnesignalNotificationID,
+			&i.OnesignalStatus,
+			&i.OnesignalResponse,
+			&i.OnesignalError,
+			&i.TargetUserID,
+			&i.SourceServiceID,
+			&i.SourceUserID,
+			&i.NotificationType,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SentAt,
+			&i.DeliveredAt,
+			&i.QueueMessageID,
+			&i.ReadAt,
+			&i.FailedAt,
+			&i.DismissedAt,
+			&i.CancelledAt,
+			&i.SuppressedUserIds,
+			&i.NotifyRequestID,
+			&i.DispatchRetries,
 		); err != nil {
 			return nil, err
 		}
@@ -432,21 +783,32 @@ func (q *Queries) GetNotificationsByExternalUserID(ctx context.Context, arg GetN
 }
 
 const getNotificationsByStatus = `-- name: GetNotificationsByStatus :many
-SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id FROM notifications 
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications
 WHERE status = $1
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3
+  AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $4
 `
 
 type GetNotificationsByStatusParams struct {
-	Status *string `json:"status"`
-	Limit  int32   `json:"limit"`
-	Offset int32   `json:"offset"`
+	Status          *string          `json:"status"`
+	CursorCreatedAt pgtype.Timestamp `json:"cursor_created_at"`
+	CursorID        pgtype.UUID      `json:"cursor_id"`
+	PageSize        int32            `json:"page_size"`
 }
 
+// Keyset-paginated, newest first. Pass the created_at and id of the last
+// row of the previous page as the cursor, or NULLs for the first page.
 func (q *Queries) GetNotificationsByStatus(ctx context.Context, arg GetNotificationsByStatusParams) ([]Notification, error) {
-	rows, err := q.db.Query(ctx, getNotificationsByStatus, arg.Status, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getNotificationsByStatus,
+		arg.Status,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -517,6 +879,13 @@ func (q *Queries) GetNotificationsByStatus(ctx context.Context, arg GetNotificat
 			&i.SentAt,
 			&i.DeliveredAt,
 			&i.QueueMessageID,
+			&i.ReadAt,
+			&i.FailedAt,
+			&i.DismissedAt,
+			&i.CancelledAt,
+			&i.SuppressedUserIds,
+			&i.NotifyRequestID,
+			&i.DispatchRetries,
 		); err != nil {
 			return nil, err
 		}
@@ -529,21 +898,32 @@ func (q *Queries) GetNotificationsByStatus(ctx context.Context, arg GetNotificat
 }
 
 const getNotificationsByTargetUser = `-- name: GetNotificationsByTargetUser :many
-SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id FROM notifications 
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications
 WHERE target_user_id = $1
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3
+  AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $4
 `
 
 type GetNotificationsByTargetUserParams struct {
-	TargetUserID pgtype.UUID `json:"target_user_id"`
-	Limit        int32       `json:"limit"`
-	Offset       int32       `json:"offset"`
+	TargetUserID    pgtype.UUID      `json:"target_user_id"`
+	CursorCreatedAt pgtype.Timestamp `json:"cursor_created_at"`
+	CursorID        pgtype.UUID      `json:"cursor_id"`
+	PageSize        int32            `json:"page_size"`
 }
 
+// Keyset-paginated, newest first. Pass the created_at and id of the last
+// row of the previous page as the cursor, or NULLs for the first page.
 func (q *Queries) GetNotificationsByTargetUser(ctx context.Context, arg GetNotificationsByTargetUserParams) ([]Notification, error) {
-	rows, err := q.db.Query(ctx, getNotificationsByTargetUser, arg.TargetUserID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getNotificationsByTargetUser,
+		arg.TargetUserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -614,6 +994,13 @@ func (q *Queries) GetNotificationsByTargetUser(ctx context.Context, arg GetNotif
 			&i.SentAt,
 			&i.DeliveredAt,
 			&i.QueueMessageID,
+			&i.ReadAt,
+			&i.FailedAt,
+			&i.DismissedAt,
+			&i.CancelledAt,
+			&i.SuppressedUserIds,
+			&i.NotifyRequestID,
+			&i.DispatchRetries,
 		); err != nil {
 			return nil, err
 		}
@@ -626,21 +1013,32 @@ func (q *Queries) GetNotificationsByTargetUser(ctx context.Context, arg GetNotif
 }
 
 const getNotificationsByType = `-- name: GetNotificationsByType :many
-SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id FROM notifications 
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications
 WHERE notification_type = $1
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3
+  AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $4
 `
 
 type GetNotificationsByTypeParams struct {
-	NotificationType *string `json:"notification_type"`
-	Limit            int32   `json:"limit"`
-	Offset           int32   `json:"offset"`
+	NotificationType *string          `json:"notification_type"`
+	CursorCreatedAt  pgtype.Timestamp `json:"cursor_created_at"`
+	CursorID         pgtype.UUID      `json:"cursor_id"`
+	PageSize         int32            `json:"page_size"`
 }
 
+// Keyset-paginated, newest first. Pass the created_at and id of the last
+// row of the previous page as the cursor, or NULLs for the first page.
 func (q *Queries) GetNotificationsByType(ctx context.Context, arg GetNotificationsByTypeParams) ([]Notification, error) {
-	rows, err := q.db.Query(ctx, getNotificationsByType, arg.NotificationType, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getNotificationsByType,
+		arg.NotificationType,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -711,6 +1109,13 @@ func (q *Queries) GetNotificationsByType(ctx context.Context, arg GetNotificatio
 			&i.SentAt,
 			&i.DeliveredAt,
 			&i.QueueMessageID,
+			&i.ReadAt,
+			&i.FailedAt,
+			&i.DismissedAt,
+			&i.CancelledAt,
+			&i.SuppressedUserIds,
+			&i.NotifyRequestID,
+			&i.DispatchRetries,
 		); err != nil {
 			return nil, err
 		}
@@ -723,7 +1128,7 @@ func (q *Queries) GetNotificationsByType(ctx context.Context, arg GetNotificatio
 }
 
 const getPendingNotifications = `-- name: GetPendingNotifications :many
-SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id FROM notifications 
+SELECT id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries FROM notifications 
 WHERE status = 'pending'
   AND (send_after IS NULL OR send_after <= NOW())
 ORDER BY created_at ASC
@@ -802,6 +1207,13 @@ func (q *Queries) GetPendingNotifications(ctx context.Context, limit int32) ([]N
 			&i.SentAt,
 			&i.DeliveredAt,
 			&i.QueueMessageID,
+			&i.ReadAt,
+			&i.FailedAt,
+			&i.DismissedAt,
+			&i.CancelledAt,
+			&i.SuppressedUserIds,
+			&i.NotifyRequestID,
+			&i.DispatchRetries,
 		); err != nil {
 			return nil, err
 		}
@@ -813,19 +1225,188 @@ func (q *Queries) GetPendingNotifications(ctx context.Context, limit int32) ([]N
 	return items, nil
 }
 
+const linkNotificationToNotifyRequest = `-- name: LinkNotificationToNotifyRequest :exec
+UPDATE notifications
+SET notify_request_id = $1
+WHERE queue_message_id = $2
+`
+
+type LinkNotificationToNotifyRequestParams struct {
+	NotifyRequestID pgtype.UUID `json:"notify_request_id"`
+	QueueMessageID  *string     `json:"queue_message_id"`
+}
+
+// Marks the push sent under queue_message_id as one channel of a notify
+// request.
+func (q *Queries) LinkNotificationToNotifyRequest(ctx context.Context, arg LinkNotificationToNotifyRequestParams) error {
+	_, err := q.db.Exec(ctx, linkNotificationToNotifyRequest, arg.NotifyRequestID, arg.QueueMessageID)
+	return err
+}
+
+const markNotificationAsDismissed = `-- name: MarkNotificationAsDismissed :exec
+UPDATE notifications
+SET
+    dismissed_at = COALESCE(dismissed_at, NOW()),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkNotificationAsDismissed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, markNotificationAsDismissed, id)
+	return err
+}
+
 const markNotificationAsRead = `-- name: MarkNotificationAsRead :exec
 UPDATE notifications
 SET
-    read_at = NOW(),
+    read_at = COALESCE(read_at, NOW()),
     updated_at = NOW()
 WHERE id = $1
 `
 
+// Keeps the first read time: OneSignal reports a click per device, and a
+// later click must not move read_at forward.
 func (q *Queries) MarkNotificationAsRead(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.Exec(ctx, markNotificationAsRead, id)
 	return err
 }
 
+const markNotificationCancelled = `-- name: MarkNotificationCancelled :exec
+UPDATE notifications
+SET
+    status = 'cancelled',
+    cancelled_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+// Records a push OneSignal itself was holding as cancelled, once OneSignal
+// has accepted the cancellation.
+func (q *Queries) MarkNotificationCancelled(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, markNotificationCancelled, id)
+	return err
+}
+
+const rescheduleNotification = `-- name: RescheduleNotification :one
+UPDATE notifications
+SET
+    send_after = $2,
+    updated_at = NOW()
+WHERE queue_message_id = $1
+  AND source_service_id = $3
+  AND status = 'pending'
+RETURNING id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries
+`
+
+type RescheduleNotificationParams struct {
+	QueueMessageID  *string          `json:"queue_message_id"`
+	SendAfter       pgtype.Timestamp `json:"send_after"`
+	SourceServiceID *string          `json:"source_service_id"`
+}
+
+// Moves the send_after of a push still waiting for the scheduler. Only a
+// 'pending' row qualifies; send_after is stored as UTC wall-clock time.
+// Only the service that sent the push may reschedule it.
+func (q *Queries) RescheduleNotification(ctx context.Context, arg RescheduleNotificationParams) (Notification, error) {
+	row := q.db.QueryRow(ctx, rescheduleNotification, arg.QueueMessageID, arg.SendAfter, arg.SourceServiceID)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.AppID,
+		&i.IncludedSegments,
+		&i.ExcludedSegments,
+		&i.IncludePlayerIds,
+		&i.IncludeExternalUserIds,
+		&i.IncludeEmailTokens,
+		&i.IncludePhoneNumbers,
+		&i.IncludeIosTokens,
+		&i.IncludeWpWnsUris,
+		&i.IncludeAmazonRegIds,
+		&i.IncludeChromeRegIds,
+		&i.IncludeChromeWebRegIds,
+		&i.IncludeAndroidRegIds,
+		&i.Contents,
+		&i.Headings,
+		&i.Subtitle,
+		&i.Buttons,
+		&i.WebButtons,
+		&i.BigPicture,
+		&i.LargeIcon,
+		&i.SmallIcon,
+		&i.IosAttachments,
+		&i.AndroidChannelID,
+		&i.AndroidAccentColor,
+		&i.AndroidLedColor,
+		&i.AndroidGroup,
+		&i.AndroidGroupMessage,
+		&i.AndroidSound,
+		&i.IosSound,
+		&i.WpWnsSound,
+		&i.AdmSound,
+		&i.ChromeWebImage,
+		&i.ChromeWebIcon,
+		&i.ChromeWebBadge,
+		&i.ChromeWebColor,
+		&i.ChromeWebSound,
+		&i.Url,
+		&i.WebUrl,
+		&i.AppUrl,
+		&i.Data,
+		&i.Filters,
+		&i.Tags,
+		&i.SendAfter,
+		&i.DelayedOption,
+		&i.DeliveryTimeOfDay,
+		&i.Ttl,
+		&i.Priority,
+		&i.OnesignalNotificationID,
+		&i.OnesignalStatus,
+		&i.OnesignalResponse,
+		&i.OnesignalError,
+		&i.TargetUserID,
+		&i.SourceServiceID,
+		&i.SourceUserID,
+		&i.NotificationType,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SentAt,
+		&i.DeliveredAt,
+		&i.QueueMessageID,
+		&i.ReadAt,
+		&i.FailedAt,
+		&i.DismissedAt,
+		&i.CancelledAt,
+		&i.SuppressedUserIds,
+		&i.NotifyRequestID,
+		&i.DispatchRetries,
+	)
+	return i, err
+}
+
+const retryScheduledNotification = `-- name: RetryScheduledNotification :exec
+UPDATE notifications
+SET
+    status = 'pending',
+    send_after = $2,
+    dispatch_retries = dispatch_retries + 1,
+    updated_at = NOW()
+WHERE id = $1
+  AND status IN ('dispatching', 'failed')
+`
+
+type RetryScheduledNotificationParams struct {
+	ID        uuid.UUID        `json:"id"`
+	SendAfter pgtype.Timestamp `json:"send_after"`
+}
+
+// Puts a claimed push whose dispatch failed in a way that may pass later
+// back to 'pending', due again at send_after, and counts the retry.
+func (q *Queries) RetryScheduledNotification(ctx context.Context, arg RetryScheduledNotificationParams) error {
+	_, err := q.db.Exec(ctx, retryScheduledNotification, arg.ID, arg.SendAfter)
+	return err
+}
+
 const updateNotificationOneSignalData = `-- name: UpdateNotificationOneSignalData :exec
 UPDATE notifications
 SET
@@ -945,12 +1526,15 @@ INSERT INTO notifications (
     onesignal_response,
     onesignal_error,
     queue_message_id,
-    status
+    status,
+    buttons,
+    web_buttons,
+    suppressed_user_ids
 
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
     $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39,
-    $40, $41, $42, $43, $44, $45, $46, $47, $48, $49, $50, $51, $52, $53, $54, $55
+    $40, $41, $42, $43, $44, $45, $46, $47, $48, $49, $50, $51, $52, $53, $54, $55, $56, $57, $58
 )
 ON CONFLICT (queue_message_id) DO UPDATE SET
     app_id = EXCLUDED.app_id,
@@ -1007,8 +1591,11 @@ ON CONFLICT (queue_message_id) DO UPDATE SET
     onesignal_response = EXCLUDED.onesignal_response,
     onesignal_error = EXCLUDED.onesignal_error,
     status = EXCLUDED.status,
+    buttons = EXCLUDED.buttons,
+    web_buttons = EXCLUDED.web_buttons,
+    suppressed_user_ids = EXCLUDED.suppressed_user_ids,
     updated_at = NOW()
-RETURNING id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id
+RETURNING id, app_id, included_segments, excluded_segments, include_player_ids, include_external_user_ids, include_email_tokens, include_phone_numbers, include_ios_tokens, include_wp_wns_uris, include_amazon_reg_ids, include_chrome_reg_ids, include_chrome_web_reg_ids, include_android_reg_ids, contents, headings, subtitle, buttons, web_buttons, big_picture, large_icon, small_icon, ios_attachments, android_channel_id, android_accent_color, android_led_color, android_group, android_group_message, android_sound, ios_sound, wp_wns_sound, adm_sound, chrome_web_image, chrome_web_icon, chrome_web_badge, chrome_web_color, chrome_web_sound, url, web_url, app_url, data, filters, tags, send_after, delayed_option, delivery_time_of_day, ttl, priority, onesignal_notification_id, onesignal_status, onesignal_response, onesignal_error, target_user_id, source_service_id, source_user_id, notification_type, status, created_at, updated_at, sent_at, delivered_at, queue_message_id, read_at, failed_at, dismissed_at, cancelled_at, suppressed_user_ids, notify_request_id, dispatch_retries
 `
 
 type UpsertNotificationParams struct {
@@ -1067,6 +1654,9 @@ type UpsertNotificationParams struct {
 	OnesignalError          *string          `json:"onesignal_error"`
 	QueueMessageID          *string          `json:"queue_message_id"`
 	Status                  *string          `json:"status"`
+	Buttons                 json.RawMessage  `json:"buttons"`
+	WebButtons              json.RawMessage  `json:"web_buttons"`
+	SuppressedUserIds       []string         `json:"suppressed_user_ids"`
 }
 
 // Inserts a notification send attempt, or updates it in place if this is a
@@ -1131,6 +1721,9 @@ func (q *Queries) UpsertNotification(ctx context.Context, arg UpsertNotification
 		arg.OnesignalError,
 		arg.QueueMessageID,
 		arg.Status,
+		arg.Buttons,
+		arg.WebButtons,
+		arg.SuppressedUserIds,
 	)
 	var i Notification
 	err := row.Scan(
@@ -1196,6 +1789,13 @@ func (q *Queries) UpsertNotification(ctx context.Context, arg UpsertNotification
 		&i.SentAt,
 		&i.DeliveredAt,
 		&i.QueueMessageID,
+		&i.ReadAt,
+		&i.FailedAt,
+		&i.DismissedAt,
+		&i.CancelledAt,
+		&i.SuppressedUserIds,
+		&i.NotifyRequestID,
+		&i.DispatchRetries,
 	)
 	return i, err
 }
@@ -0,0 +1,255 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: notify.sql
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueNotifyRequests = `-- name: ClaimDueNotifyRequests :many
+UPDATE notify_requests
+SET
+    status = 'dispatching',
+    claim_count = claim_count + 1,
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM notify_requests
+    WHERE (status = 'pending' AND next_attempt_at <= NOW())
+       OR (status = 'dispatching'
+           AND updated_at < NOW() - make_interval(secs => $1::int))
+    ORDER BY next_attempt_at ASC
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, service_id, queue_message_id, exchange, routing_key, amqp_message_id, attempt_count, to_user_ids, channels, policy, fallback_after_minutes, payload, next_channel, next_attempt_at, status, received_at, updated_at, claim_count
+`
+
+type ClaimDueNotifyRequestsParams struct {
+	StaleAfterSeconds int32 `json:"stale_after_seconds"`
+	BatchSize         int32 `json:"batch_size"`
+}
+
+// Claims notify requests whose fallback is due by flipping them to
+// 'dispatching', the way ClaimDueNotifications claims scheduled pushes. A
+// row stuck in 'dispatching' past stale_after_seconds is claimed again;
+// claim_count says how many times in a row it has been.
+func (q *Queries) ClaimDueNotifyRequests(ctx context.Context, arg ClaimDueNotifyRequestsParams) ([]NotifyRequest, error) {
+	rows, err := q.db.Query(ctx, claimDueNotifyRequests, arg.StaleAfterSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []NotifyRequest{}
+	for rows.Next() {
+		var i NotifyRequest
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceID,
+			&i.QueueMessageID,
+			&i.Exchange,
+			&i.RoutingKey,
+			&i.AmqpMessageID,
+			&i.AttemptCount,
+			&i.ToUserIds,
+			&i.Channels,
+			&i.Policy,
+			&i.FallbackAfterMinutes,
+			&i.Payload,
+			&i.NextChannel,
+			&i.NextAttemptAt,
+			&i.Status,
+			&i.ReceivedAt,
+			&i.UpdatedAt,
+			&i.ClaimCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNotifyRequestByID = `-- name: GetNotifyRequestByID :one
+SELECT id, service_id, queue_message_id, exchange, routing_key, amqp_message_id, attempt_count, to_user_ids, channels, policy, fallback_after_minutes, payload, next_channel, next_attempt_at, status, received_at, updated_at, claim_count FROM notify_requests
+WHERE id = $1
+`
+
+func (q *Queries) GetNotifyRequestByID(ctx context.Context, id uuid.UUID) (NotifyRequest, error) {
+	row := q.db.QueryRow(ctx, getNotifyRequestByID, id)
+	var i NotifyRequest
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.QueueMessageID,
+		&i.Exchange,
+		&i.RoutingKey,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.ToUserIds,
+		&i.Channels,
+		&i.Policy,
+		&i.FallbackAfterMinutes,
+		&i.Payload,
+		&i.NextChannel,
+		&i.NextAttemptAt,
+		&i.Status,
+		&i.ReceivedAt,
+		&i.UpdatedAt,
+		&i.ClaimCount,
+	)
+	return i, err
+}
+
+const getNotifyRequestByQueueMessageID = `-- name: GetNotifyRequestByQueueMessageID :one
+SELECT id, service_id, queue_message_id, exchange, routing_key, amqp_message_id, attempt_count, to_user_ids, channels, policy, fallback_after_minutes, payload, next_channel, next_attempt_at, status, received_at, updated_at, claim_count FROM notify_requests
+WHERE queue_message_id = $1
+LIMIT 1
+`
+
+// Used to detect a duplicate notify.send, like
+// GetEmailRequestByQueueMessageID.
+func (q *Queries) GetNotifyRequestByQueueMessageID(ctx context.Context, queueMessageID string) (NotifyRequest, error) {
+	row := q.db.QueryRow(ctx, getNotifyRequestByQueueMessageID, queueMessageID)
+	var i NotifyRequest
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.QueueMessageID,
+		&i.Exchange,
+		&i.RoutingKey,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.ToUserIds,
+		&i.Channels,
+		&i.Policy,
+		&i.FallbackAfterMinutes,
+		&i.Payload,
+		&i.NextChannel,
+		&i.NextAttemptAt,
+		&i.Status,
+		&i.ReceivedAt,
+		&i.UpdatedAt,
+		&i.ClaimCount,
+	)
+	return i, err
+}
+
+const updateNotifyRequestProgress = `-- name: UpdateNotifyRequestProgress :exec
+UPDATE notify_requests
+SET
+    status = $2,
+    next_channel = $3,
+    next_attempt_at = $4,
+    claim_count = 0,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateNotifyRequestProgressParams struct {
+	ID            uuid.UUID  `json:"id"`
+	Status        string     `json:"status"`
+	NextChannel   int32      `json:"next_channel"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+}
+
+// Records where a notify request stands: its status, and for one waiting
+// on a fallback, which channel is next and when. Progress resets
+// claim_count.
+func (q *Queries) UpdateNotifyRequestProgress(ctx context.Context, arg UpdateNotifyRequestProgressParams) error {
+	_, err := q.db.Exec(ctx, updateNotifyRequestProgress,
+		arg.ID,
+		arg.Status,
+		arg.NextChannel,
+		arg.NextAttemptAt,
+	)
+	return err
+}
+
+const upsertNotifyRequest = `-- name: UpsertNotifyRequest :one
+INSERT INTO notify_requests (
+  service_id,
+  queue_message_id,
+  exchange,
+  routing_key,
+  amqp_message_id,
+  attempt_count,
+
+  to_user_ids,
+  channels,
+  policy,
+  fallback_after_minutes,
+  payload
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (queue_message_id) DO UPDATE SET
+  exchange = EXCLUDED.exchange,
+  routing_key = EXCLUDED.routing_key,
+  amqp_message_id = EXCLUDED.amqp_message_id,
+  attempt_count = EXCLUDED.attempt_count,
+  updated_at = NOW()
+RETURNING id, service_id, queue_message_id, exchange, routing_key, amqp_message_id, attempt_count, to_user_ids, channels, policy, fallback_after_minutes, payload, next_channel, next_attempt_at, status, received_at, updated_at, claim_count
+`
+
+type UpsertNotifyRequestParams struct {
+	ServiceID            string          `json:"service_id"`
+	QueueMessageID       string          `json:"queue_message_id"`
+	Exchange             string          `json:"exchange"`
+	RoutingKey           string          `json:"routing_key"`
+	AmqpMessageID        *string         `json:"amqp_message_id"`
+	AttemptCount         int32           `json:"attempt_count"`
+	ToUserIds            []uuid.UUID     `json:"to_user_ids"`
+	Channels             []string        `json:"channels"`
+	Policy               string          `json:"policy"`
+	FallbackAfterMinutes *int32          `json:"fallback_after_minutes"`
+	Payload              json.RawMessage `json:"payload"`
+}
+
+// Persists a notify request, or updates its delivery columns in place when
+// the same queue_message_id is retried, the same way UpsertEmailRequest
+// does.
+func (q *Queries) UpsertNotifyRequest(ctx context.Context, arg UpsertNotifyRequestParams) (NotifyRequest, error) {
+	row := q.db.QueryRow(ctx, upsertNotifyRequest,
+		arg.ServiceID,
+		arg.QueueMessageID,
+		arg.Exchange,
+		arg.RoutingKey,
+		arg.AmqpMessageID,
+		arg.AttemptCount,
+		arg.ToUserIds,
+		arg.Channels,
+		arg.Policy,
+		arg.FallbackAfterMinutes,
+		arg.Payload,
+	)
+	var i NotifyRequest
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.QueueMessageID,
+		&i.Exchange,
+		&i.RoutingKey,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.ToUserIds,
+		&i.Channels,
+		&i.Policy,
+		&i.FallbackAfterMinutes,
+		&i.Payload,
+		&i.NextChannel,
+		&i.NextAttemptAt,
+		&i.Status,
+		&i.ReceivedAt,
+		&i.UpdatedAt,
+		&i.ClaimCount,
+	)
+	return i, err
+}
@@ -0,0 +1,63 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: parked_messages.sql
+
+package repository
+
+import (
+	"context"
+)
+
+const createParkedMessageAction = `-- name: CreateParkedMessageAction :one
+INSERT INTO parked_message_actions (
+  action,
+  actor,
+  parked_message_id,
+  original_exchange,
+  original_routing_key,
+  body,
+  succeeded,
+  error
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, action, actor, parked_message_id, original_exchange, original_routing_key, body, succeeded, error, performed_at
+`
+
+type CreateParkedMessageActionParams struct {
+	Action             string  `json:"action"`
+	Actor              string  `json:"actor"`
+	ParkedMessageID    *string `json:"parked_message_id"`
+	OriginalExchange   *string `json:"original_exchange"`
+	OriginalRoutingKey *string `json:"original_routing_key"`
+	Body               []byte  `json:"body"`
+	Succeeded          bool    `json:"succeeded"`
+	Error              *string `json:"error"`
+}
+
+// Records a triage action on the parked queue, whether or not it succeeded.
+func (q *Queries) CreateParkedMessageAction(ctx context.Context, arg CreateParkedMessageActionParams) (ParkedMessageAction, error) {
+	row := q.db.QueryRow(ctx, createParkedMessageAction,
+		arg.Action,
+		arg.Actor,
+		arg.ParkedMessageID,
+		arg.OriginalExchange,
+		arg.OriginalRoutingKey,
+		arg.Body,
+		arg.Succeeded,
+		arg.Error,
+	)
+	var i ParkedMessageAction
+	err := row.Scan(
+		&i.ID,
+		&i.Action,
+		&i.Actor,
+		&i.ParkedMessageID,
+		&i.OriginalExchange,
+		&i.OriginalRoutingKey,
+		&i.Body,
+		&i.Succeeded,
+		&i.Error,
+		&i.PerformedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,215 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: preferences.sql
+
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const deleteNotificationPreference = `-- name: DeleteNotificationPreference :exec
+DELETE FROM notification_preferences
+WHERE user_id = $1
+  AND channel = $2
+  AND notification_type = $3
+  AND updated_at <= $4
+`
+
+type DeleteNotificationPreferenceParams struct {
+	UserID           uuid.UUID          `json:"user_id"`
+	Channel          string             `json:"channel"`
+	NotificationType string             `json:"notification_type"`
+	ResetAt          pgtype.Timestamptz `json:"reset_at"`
+}
+
+// Resets a preference to the default of sending, unless it was set after
+// the reset was asked for.
+func (q *Queries) DeleteNotificationPreference(ctx context.Context, arg DeleteNotificationPreferenceParams) error {
+	_, err := q.db.Exec(ctx, deleteNotificationPreference,
+		arg.UserID,
+		arg.Channel,
+		arg.NotificationType,
+		arg.ResetAt,
+	)
+	return err
+}
+
+const deleteNotificationPreferencesByUserID = `-- name: DeleteNotificationPreferencesByUserID :exec
+DELETE FROM notification_preferences
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteNotificationPreferencesByUserID(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteNotificationPreferencesByUserID, userID)
+	return err
+}
+
+const listOptedOutEmailAddresses = `-- name: ListOptedOutEmailAddresses :many
+SELECT email
+FROM (
+  SELECT DISTINCT ON (u.id) lower(u.email)::text AS email, p.enabled
+  FROM users u
+  JOIN notification_preferences p ON p.user_id = u.id
+  WHERE lower(u.email) = ANY($1::text[])
+    AND p.channel = 'email'
+    AND p.notification_type IN ($2::varchar, '*')
+  ORDER BY u.id, p.notification_type = '*'
+) preference
+WHERE NOT enabled
+`
+
+type ListOptedOutEmailAddressesParams struct {
+	Addresses        []string `json:"addresses"`
+	NotificationType string   `json:"notification_type"`
+}
+
+// The addresses, of those given, whose user doesn't want
+// notification_type by email. Addresses are matched and returned
+// lowercased.
+func (q *Queries) ListOptedOutEmailAddresses(ctx context.Context, arg ListOptedOutEmailAddressesParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listOptedOutEmailAddresses, arg.Addresses, arg.NotificationType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		items = append(items, email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOptedOutPhoneNumbers = `-- name: ListOptedOutPhoneNumbers :many
+SELECT phone
+FROM (
+  SELECT DISTINCT ON (u.id) u.phone::text AS phone, p.enabled
+  FROM users u
+  JOIN notification_preferences p ON p.user_id = u.id
+  WHERE u.phone = ANY($1::text[])
+    AND p.channel = 'sms'
+    AND p.notification_type IN ($2::varchar, '*')
+  ORDER BY u.id, p.notification_type = '*'
+) preference
+WHERE NOT enabled
+`
+
+type ListOptedOutPhoneNumbersParams struct {
+	Numbers          []string `json:"numbers"`
+	NotificationType string   `json:"notification_type"`
+}
+
+// The numbers, of those given, whose user doesn't want notification_type
+// by SMS. Numbers are matched as the user directory stores them.
+func (q *Queries) ListOptedOutPhoneNumbers(ctx context.Context, arg ListOptedOutPhoneNumbersParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listOptedOutPhoneNumbers, arg.Numbers, arg.NotificationType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var phone string
+		if err := rows.Scan(&phone); err != nil {
+			return nil, err
+		}
+		items = append(items, phone)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOptedOutUserIDs = `-- name: ListOptedOutUserIDs :many
+SELECT user_id::text AS user_id
+FROM (
+  SELECT DISTINCT ON (user_id) user_id, enabled
+  FROM notification_preferences
+  WHERE channel = $1
+    AND user_id::text = ANY($2::text[])
+    AND notification_type IN ($3::varchar, '*')
+  ORDER BY user_id, notification_type = '*'
+) preference
+WHERE NOT enabled
+`
+
+type ListOptedOutUserIDsParams struct {
+	Channel          string   `json:"channel"`
+	UserIds          []string `json:"user_ids"`
+	NotificationType string   `json:"notification_type"`
+}
+
+// The user_ids, of those given, that don't want notification_type on
+// channel. A user's preference for the type itself wins over their '*'
+// preference.
+func (q *Queries) ListOptedOutUserIDs(ctx context.Context, arg ListOptedOutUserIDsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listOptedOutUserIDs, arg.Channel, arg.UserIds, arg.NotificationType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var user_id string
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertNotificationPreference = `-- name: UpsertNotificationPreference :exec
+INSERT INTO notification_preferences (
+  user_id,
+  channel,
+  notification_type,
+  enabled,
+  source_service_id,
+  updated_at
+) VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, channel, notification_type) DO UPDATE SET
+  enabled = EXCLUDED.enabled,
+  source_service_id = EXCLUDED.source_service_id,
+  updated_at = EXCLUDED.updated_at
+WHERE notification_preferences.updated_at <= EXCLUDED.updated_at
+`
+
+type UpsertNotificationPreferenceParams struct {
+	UserID           uuid.UUID          `json:"user_id"`
+	Channel          string             `json:"channel"`
+	NotificationType string             `json:"notification_type"`
+	Enabled          bool               `json:"enabled"`
+	SourceServiceID  string             `json:"source_service_id"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Records a user's preference for a notification type on a channel. An
+// update older than the one already stored is ignored, so a retried event
+// can't undo a newer one.
+func (q *Queries) UpsertNotificationPreference(ctx context.Context, arg UpsertNotificationPreferenceParams) error {
+	_, err := q.db.Exec(ctx, upsertNotificationPreference,
+		arg.UserID,
+		arg.Channel,
+		arg.NotificationType,
+		arg.Enabled,
+		arg.SourceServiceID,
+		arg.UpdatedAt,
+	)
+	return err
+}
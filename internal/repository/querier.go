@@ -12,13 +12,72 @@ import (
 )
 
 type Querier interface {
+	// Cancels an email whose last attempt failed and is waiting in the retry
+	// queue; Send skips a cancelled request when the retry arrives. A
+	// dispatched email can't be recalled. Only the service that sent the
+	// email may cancel it.
+	CancelEmailRequest(ctx context.Context, arg CancelEmailRequestParams) (EmailRequest, error)
+	// Cancels a push that hasn't gone out yet: one waiting for its send_after,
+	// or one whose failed attempt is waiting in the retry queue. A push the
+	// scheduler has already claimed ('dispatching') is past cancelling. Only
+	// the service that sent the push may cancel it.
+	CancelPendingNotification(ctx context.Context, arg CancelPendingNotificationParams) (Notification, error)
+	// Claims scheduled notifications whose send_after has passed by flipping
+	// them to 'dispatching' in the same statement that selects them. SKIP
+	// LOCKED makes concurrent replicas claim disjoint rows instead of queueing
+	// behind each other, so no row is handed to two dispatchers. A row stuck in
+	// 'dispatching' past stale_after_seconds (its replica died mid-dispatch)
+	// is claimed again. send_after is stored as UTC wall-clock time.
+	ClaimDueNotifications(ctx context.Context, arg ClaimDueNotificationsParams) ([]Notification, error)
+	// Claims notify requests whose fallback is due by flipping them to
+	// 'dispatching', the way ClaimDueNotifications claims scheduled pushes. A
+	// row stuck in 'dispatching' past stale_after_seconds is claimed again;
+	// claim_count says how many times in a row it has been.
+	ClaimDueNotifyRequests(ctx context.Context, arg ClaimDueNotifyRequestsParams) ([]NotifyRequest, error)
 	CleanupOldNotifications(ctx context.Context) error
+	// Records a Resend delivery event (delivered, bounced, complained, ...)
+	// against its dispatch. A redelivered webhook (same webhook_id and
+	// recipient, or no recipient either time) is silently ignored rather than
+	// recorded twice.
+	CreateEmailDeliveryEvent(ctx context.Context, arg CreateEmailDeliveryEventParams) error
 	// Records an email dispatch to the email sending service for compliance
 	// purposes
 	CreateEmailDispatch(ctx context.Context, arg CreateEmailDispatchParams) (EmailDispatch, error)
+	// Adds an address to the suppression list. An address already on it keeps
+	// its original entry, and no row is returned.
+	CreateEmailSuppression(ctx context.Context, arg CreateEmailSuppressionParams) (EmailSuppression, error)
+	// Records that address unsubscribed from a service's non-transactional
+	// email. Unsubscribing twice keeps the first time.
+	CreateEmailUnsubscribe(ctx context.Context, arg CreateEmailUnsubscribeParams) error
+	// Puts a notify request's notification in a user's inbox. A retried notify
+	// request leaves the one already there as it is.
+	CreateInAppNotification(ctx context.Context, arg CreateInAppNotificationParams) error
+	// Records a triage action on the parked queue, whether or not it succeeded.
+	CreateParkedMessageAction(ctx context.Context, arg CreateParkedMessageActionParams) (ParkedMessageAction, error)
+	// Records an attempt to hand an SMS request to the provider.
+	CreateSmsDispatch(ctx context.Context, arg CreateSmsDispatchParams) (SmsDispatch, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteEmailSuppression(ctx context.Context, address string) (EmailSuppression, error)
 	DeleteNotification(ctx context.Context, id uuid.UUID) error
+	// Resets a preference to the default of sending, unless it was set after
+	// the reset was asked for.
+	DeleteNotificationPreference(ctx context.Context, arg DeleteNotificationPreferenceParams) error
+	DeleteNotificationPreferencesByUserID(ctx context.Context, userID uuid.UUID) error
 	DeleteUserByID(ctx context.Context, id uuid.UUID) error
+	// Takes one of a user's in-app notifications out of their inbox. A
+	// dismissed notification counts as read.
+	DismissInAppNotification(ctx context.Context, arg DismissInAppNotificationParams) (InAppNotification, error)
+	// Whether Resend reported any sign that an email request reached its
+	// recipient's inbox.
+	EmailRequestWasDelivered(ctx context.Context, emailRequestID uuid.UUID) (bool, error)
+	// Every delivery event Resend reported for any of an email request's
+	// dispatches, in the order they happened.
+	GetEmailDeliveryEventsByRequestID(ctx context.Context, emailRequestID uuid.UUID) ([]EmailDeliveryEvent, error)
+	// Resolves the dispatch a Resend webhook is reporting on; resend_email_id is
+	// only ever set on a dispatch Resend actually accepted.
+	GetEmailDispatchByResendEmailID(ctx context.Context, resendEmailID *string) (EmailDispatch, error)
+	// Every attempt to hand an email request to a provider, oldest first.
+	GetEmailDispatchesByRequestID(ctx context.Context, emailRequestID uuid.UUID) ([]EmailDispatch, error)
 	GetEmailRequestByID(ctx context.Context, id uuid.UUID) (EmailRequest, error)
 	// Used to detect a duplicate send before calling Resend: if a request with
 	// this queue_message_id was already dispatched, the caller must skip
@@ -26,8 +85,13 @@ type Querier interface {
 	// and an external duplicate republish become indistinguishable and both
 	// would trigger a second real send.
 	GetEmailRequestByQueueMessageID(ctx context.Context, queueMessageID string) (EmailRequest, error)
-	// Orders the time it was recieved ie the most previous
+	// Keyset-paginated, most recently received first. Pass the received_at and
+	// id of the last row of the previous page as the cursor, or NULLs for the
+	// first page.
 	GetEmailRequestByService(ctx context.Context, arg GetEmailRequestByServiceParams) ([]EmailRequest, error)
+	GetEmailRequestsByNotifyRequestID(ctx context.Context, notifyRequestID pgtype.UUID) ([]EmailRequest, error)
+	GetEmailSuppression(ctx context.Context, address string) (EmailSuppression, error)
+	GetInAppNotificationsByNotifyRequestID(ctx context.Context, notifyRequestID uuid.UUID) ([]InAppNotification, error)
 	GetNotificationByID(ctx context.Context, id uuid.UUID) (Notification, error)
 	GetNotificationByOneSignalID(ctx context.Context, onesignalNotificationID *string) (Notification, error)
 	// Used to detect a duplicate send before calling OneSignal: if a
@@ -38,26 +102,113 @@ type Querier interface {
 	GetNotificationByQueueMessageID(ctx context.Context, queueMessageID *string) (Notification, error)
 	GetNotificationStats(ctx context.Context, targetUserID pgtype.UUID) (GetNotificationStatsRow, error)
 	GetNotificationStatsByType(ctx context.Context, notificationType *string) (GetNotificationStatsByTypeRow, error)
+	// Keyset-paginated, newest first. Pass the created_at and id of the last
+	// row of the previous page as the cursor, or NULLs for the first page.
 	GetNotificationsByExternalUserID(ctx context.Context, arg GetNotificationsByExternalUserIDParams) ([]Notification, error)
+	GetNotificationsByNotifyRequestID(ctx context.Context, notifyRequestID pgtype.UUID) ([]Notification, error)
+	// Keyset-paginated, newest first. Pass the created_at and id of the last
+	// row of the previous page as the cursor, or NULLs for the first page.
 	GetNotificationsByStatus(ctx context.Context, arg GetNotificationsByStatusParams) ([]Notification, error)
+	// Keyset-paginated, newest first. Pass the created_at and id of the last
+	// row of the previous page as the cursor, or NULLs for the first page.
 	GetNotificationsByTargetUser(ctx context.Context, arg GetNotificationsByTargetUserParams) ([]Notification, error)
+	// Keyset-paginated, newest first. Pass the created_at and id of the last
+	// row of the previous page as the cursor, or NULLs for the first page.
 	GetNotificationsByType(ctx context.Context, arg GetNotificationsByTypeParams) ([]Notification, error)
+	GetNotifyRequestByID(ctx context.Context, id uuid.UUID) (NotifyRequest, error)
+	// Used to detect a duplicate notify.send, like
+	// GetEmailRequestByQueueMessageID.
+	GetNotifyRequestByQueueMessageID(ctx context.Context, queueMessageID string) (NotifyRequest, error)
+	// The REST API key some service holds for a OneSignal app, for calls about
+	// a push that went out through that app after the service that sent it
+	// moved to another.
+	GetOneSignalAppKey(ctx context.Context, onesignalAppID *string) (string, error)
 	GetPendingNotifications(ctx context.Context, limit int32) ([]Notification, error)
+	// Looks up the OneSignal app a service has its own credentials for. No row,
+	// or NULL columns, mean the service uses the globally configured app.
+	GetServiceOneSignalApp(ctx context.Context, id string) (GetServiceOneSignalAppRow, error)
+	// The domains a service may send email from. NULL or empty means none.
+	GetServiceSenderDomains(ctx context.Context, id string) (GetServiceSenderDomainsRow, error)
+	// Used to detect a duplicate send before calling the provider, like
+	// GetEmailRequestByQueueMessageID.
+	GetSmsRequestByQueueMessageID(ctx context.Context, queueMessageID string) (SmsRequest, error)
 	GetUserByEmail(ctx context.Context, lower string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
 	GetUserByUsername(ctx context.Context, username *string) (User, error)
+	// Marks the email sent under queue_message_id as one channel of a notify
+	// request.
+	LinkEmailRequestToNotifyRequest(ctx context.Context, arg LinkEmailRequestToNotifyRequestParams) error
+	// Marks the push sent under queue_message_id as one channel of a notify
+	// request.
+	LinkNotificationToNotifyRequest(ctx context.Context, arg LinkNotificationToNotifyRequestParams) error
+	// The most recently suppressed addresses first.
+	ListEmailSuppressions(ctx context.Context, pageSize int32) ([]EmailSuppression, error)
+	// A user's inbox: their delivered in-app notifications not yet dismissed,
+	// keyset-paginated, newest first. Pass the created_at and id of the last
+	// row of the previous page as the cursor, or NULLs for the first page.
+	ListInboxNotifications(ctx context.Context, arg ListInboxNotificationsParams) ([]InAppNotification, error)
+	// The addresses, of those given, whose user doesn't want
+	// notification_type by email. Addresses are matched and returned
+	// lowercased.
+	ListOptedOutEmailAddresses(ctx context.Context, arg ListOptedOutEmailAddressesParams) ([]string, error)
+	// The numbers, of those given, whose user doesn't want notification_type
+	// by SMS. Numbers are matched as the user directory stores them.
+	ListOptedOutPhoneNumbers(ctx context.Context, arg ListOptedOutPhoneNumbersParams) ([]string, error)
+	// The user_ids, of those given, that don't want notification_type on
+	// channel. A user's preference for the type itself wins over their '*'
+	// preference.
+	ListOptedOutUserIDs(ctx context.Context, arg ListOptedOutUserIDsParams) ([]string, error)
+	// Which of addresses, bare and lowercased, are on the suppression list.
+	ListSuppressedAddresses(ctx context.Context, addresses []string) ([]string, error)
+	// The addresses, of those given (lowercased), that unsubscribed from the
+	// service's non-transactional email.
+	ListUnsubscribedAddresses(ctx context.Context, arg ListUnsubscribedAddressesParams) ([]string, error)
+	// Marks one of a user's in-app notifications read. Reading it again keeps
+	// the first read_at.
+	MarkInAppNotificationRead(ctx context.Context, arg MarkInAppNotificationReadParams) (InAppNotification, error)
+	MarkNotificationAsDismissed(ctx context.Context, id uuid.UUID) error
+	// Keeps the first read time: OneSignal reports a click per device, and a
+	// later click must not move read_at forward.
 	MarkNotificationAsRead(ctx context.Context, id uuid.UUID) error
+	// Records a push OneSignal itself was holding as cancelled, once OneSignal
+	// has accepted the cancellation.
+	MarkNotificationCancelled(ctx context.Context, id uuid.UUID) error
+	// Moves the send_after of a push still waiting for the scheduler. Only a
+	// 'pending' row qualifies; send_after is stored as UTC wall-clock time.
+	// Only the service that sent the push may reschedule it.
+	RescheduleNotification(ctx context.Context, arg RescheduleNotificationParams) (Notification, error)
+	// Puts a claimed push whose dispatch failed in a way that may pass later
+	// back to 'pending', due again at send_after, and counts the retry.
+	RetryScheduledNotification(ctx context.Context, arg RetryScheduledNotificationParams) error
+	// Records the recipients an email request's latest attempt left out, NULL
+	// if it left out none.
+	SetEmailRequestSuppressedAddresses(ctx context.Context, arg SetEmailRequestSuppressedAddressesParams) error
+	// Replaces the domains a service may send email from, registering the
+	// service if it hasn't sent anything yet so it can be set up beforehand.
+	SetServiceSenderDomains(ctx context.Context, arg SetServiceSenderDomainsParams) (SetServiceSenderDomainsRow, error)
+	// Records the numbers an SMS request's to_user_ids resolved to.
+	SetSmsRequestResolvedNumbers(ctx context.Context, arg SetSmsRequestResolvedNumbersParams) error
+	// Records the numbers an SMS request was not sent to because their user
+	// opted out.
+	SetSmsRequestSuppressedNumbers(ctx context.Context, arg SetSmsRequestSuppressedNumbersParams) error
 	// Updates an email_request record effectively setting its status to one of
 	// the predefined statuses
 	UpdateEmailRequestStatusByID(ctx context.Context, arg UpdateEmailRequestStatusByIDParams) (EmailRequest, error)
 	UpdateNotificationOneSignalData(ctx context.Context, arg UpdateNotificationOneSignalDataParams) error
 	UpdateNotificationStatus(ctx context.Context, arg UpdateNotificationStatusParams) error
+	// Records where a notify request stands: its status, and for one waiting
+	// on a fallback, which channel is next and when. Progress resets
+	// claim_count.
+	UpdateNotifyRequestProgress(ctx context.Context, arg UpdateNotifyRequestProgressParams) error
+	UpdateSmsRequestStatusByID(ctx context.Context, arg UpdateSmsRequestStatusByIDParams) error
 	UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams) (User, error)
 	// Persists an email request to the database for replayability, or updates
 	// it in place if this is a retry of the same queue_message_id (dead-lettered
 	// redelivery) — retrying must not hit the queue_message_id UNIQUE
 	// constraint as a fresh insert, or the retry mechanism would just fail
-	// forever on the second attempt without ever reaching Resend again.
+	// forever on the second attempt without ever reaching Resend again. The
+	// delivery columns are overwritten too, so they describe the delivery that
+	// last handled the request.
 	UpsertEmailRequest(ctx context.Context, arg UpsertEmailRequestParams) (EmailRequest, error)
 	// Inserts a notification send attempt, or updates it in place if this is a
 	// retry of the same queue_message_id (dead-lettered redelivery). Keeping one
@@ -65,10 +216,21 @@ type Querier interface {
 	// already behaves (a single mutable outcome row, not an attempt-history
 	// table like email_requests/email_dispatches).
 	UpsertNotification(ctx context.Context, arg UpsertNotificationParams) (Notification, error)
+	// Records a user's preference for a notification type on a channel. An
+	// update older than the one already stored is ignored, so a retried event
+	// can't undo a newer one.
+	UpsertNotificationPreference(ctx context.Context, arg UpsertNotificationPreferenceParams) error
+	// Persists a notify request, or updates its delivery columns in place when
+	// the same queue_message_id is retried, the same way UpsertEmailRequest
+	// does.
+	UpsertNotifyRequest(ctx context.Context, arg UpsertNotifyRequestParams) (NotifyRequest, error)
 	// Registers a service on first use so email onboarding is self-service;
 	// ON CONFLICT DO UPDATE (a no-op) instead of DO NOTHING so RETURNING always
 	// yields exactly one row, whether the service already existed or not.
 	UpsertService(ctx context.Context, arg UpsertServiceParams) (Service, error)
+	// Persists an SMS request, or updates it in place when the same
+	// queue_message_id is retried, the same way UpsertEmailRequest does.
+	UpsertSmsRequest(ctx context.Context, arg UpsertSmsRequestParams) (SmsRequest, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -9,11 +9,95 @@ import (
 	"context"
 )
 
+const getOneSignalAppKey = `-- name: GetOneSignalAppKey :one
+SELECT onesignal_rest_api_key::text
+FROM services
+WHERE onesignal_app_id = $1
+  AND onesignal_rest_api_key IS NOT NULL
+LIMIT 1
+`
+
+// The REST API key some service holds for a OneSignal app, for calls about
+// a push that went out through that app after the service that sent it
+// moved to another.
+func (q *Queries) GetOneSignalAppKey(ctx context.Context, onesignalAppID *string) (string, error) {
+	row := q.db.QueryRow(ctx, getOneSignalAppKey, onesignalAppID)
+	var onesignal_rest_api_key string
+	err := row.Scan(&onesignal_rest_api_key)
+	return onesignal_rest_api_key, err
+}
+
+const getServiceOneSignalApp = `-- name: GetServiceOneSignalApp :one
+SELECT onesignal_app_id, onesignal_rest_api_key
+FROM services
+WHERE id = $1
+`
+
+type GetServiceOneSignalAppRow struct {
+	OnesignalAppID      *string `json:"onesignal_app_id"`
+	OnesignalRestApiKey *string `json:"onesignal_rest_api_key"`
+}
+
+// Looks up the OneSignal app a service has its own credentials for. No row,
+// or NULL columns, mean the service uses the globally configured app.
+func (q *Queries) GetServiceOneSignalApp(ctx context.Context, id string) (GetServiceOneSignalAppRow, error) {
+	row := q.db.QueryRow(ctx, getServiceOneSignalApp, id)
+	var i GetServiceOneSignalAppRow
+	err := row.Scan(&i.OnesignalAppID, &i.OnesignalRestApiKey)
+	return i, err
+}
+
+const getServiceSenderDomains = `-- name: GetServiceSenderDomains :one
+SELECT id, allowed_sender_domains
+FROM services
+WHERE id = $1
+`
+
+type GetServiceSenderDomainsRow struct {
+	ID                   string   `json:"id"`
+	AllowedSenderDomains []string `json:"allowed_sender_domains"`
+}
+
+// The domains a service may send email from. NULL or empty means none.
+func (q *Queries) GetServiceSenderDomains(ctx context.Context, id string) (GetServiceSenderDomainsRow, error) {
+	row := q.db.QueryRow(ctx, getServiceSenderDomains, id)
+	var i GetServiceSenderDomainsRow
+	err := row.Scan(&i.ID, &i.AllowedSenderDomains)
+	return i, err
+}
+
+const setServiceSenderDomains = `-- name: SetServiceSenderDomains :one
+INSERT INTO services (id, name, allowed_sender_domains)
+VALUES ($1, $1, $2::text[])
+ON CONFLICT (id) DO UPDATE
+  SET allowed_sender_domains = EXCLUDED.allowed_sender_domains
+RETURNING id, allowed_sender_domains
+`
+
+type SetServiceSenderDomainsParams struct {
+	ID                   string   `json:"id"`
+	AllowedSenderDomains []string `json:"allowed_sender_domains"`
+}
+
+type SetServiceSenderDomainsRow struct {
+	ID                   string   `json:"id"`
+	AllowedSenderDomains []string `json:"allowed_sender_domains"`
+}
+
+// Replaces the domains a service may send email from, registering the
+// service if it hasn't sent anything yet so it can be set up beforehand.
+func (q *Queries) SetServiceSenderDomains(ctx context.Context, arg SetServiceSenderDomainsParams) (SetServiceSenderDomainsRow, error) {
+	row := q.db.QueryRow(ctx, setServiceSenderDomains, arg.ID, arg.AllowedSenderDomains)
+	var i SetServiceSenderDomainsRow
+	err := row.Scan(&i.ID, &i.AllowedSenderDomains)
+	return i, err
+}
+
 const upsertService = `-- name: UpsertService :one
 INSERT INTO services (id, name)
 VALUES ($1, $2)
 ON CONFLICT (id) DO UPDATE SET is_active = services.is_active
-RETURNING id, name, description, is_active, created_at
+RETURNING id, name, description, is_active, created_at, onesignal_app_id, onesignal_rest_api_key, allowed_sender_domains, resend_api_key
 `
 
 type UpsertServiceParams struct {
@@ -33,6 +117,10 @@ func (q *Queries) UpsertService(ctx context.Context, arg UpsertServiceParams) (S
 		&i.Description,
 		&i.IsActive,
 		&i.CreatedAt,
+		&i.OnesignalAppID,
+		&i.OnesignalRestApiKey,
+		&i.AllowedSenderDomains,
+		&i.ResendApiKey,
 	)
 	return i, err
 }
@@ -0,0 +1,229 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: sms.sql
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createSmsDispatch = `-- name: CreateSmsDispatch :one
+INSERT INTO sms_dispatches(
+  sms_request_id,
+  provider,
+  status,
+  http_status_code,
+  provider_error,
+  recipients
+) VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, sms_request_id, provider, status, http_status_code, provider_error, recipients, dispatched_at
+`
+
+type CreateSmsDispatchParams struct {
+	SmsRequestID   uuid.UUID       `json:"sms_request_id"`
+	Provider       string          `json:"provider"`
+	Status         string          `json:"status"`
+	HttpStatusCode *int32          `json:"http_status_code"`
+	ProviderError  *string         `json:"provider_error"`
+	Recipients     json.RawMessage `json:"recipients"`
+}
+
+// Records an attempt to hand an SMS request to the provider.
+func (q *Queries) CreateSmsDispatch(ctx context.Context, arg CreateSmsDispatchParams) (SmsDispatch, error) {
+	row := q.db.QueryRow(ctx, createSmsDispatch,
+		arg.SmsRequestID,
+		arg.Provider,
+		arg.Status,
+		arg.HttpStatusCode,
+		arg.ProviderError,
+		arg.Recipients,
+	)
+	var i SmsDispatch
+	err := row.Scan(
+		&i.ID,
+		&i.SmsRequestID,
+		&i.Provider,
+		&i.Status,
+		&i.HttpStatusCode,
+		&i.ProviderError,
+		&i.Recipients,
+		&i.DispatchedAt,
+	)
+	return i, err
+}
+
+const getSmsRequestByQueueMessageID = `-- name: GetSmsRequestByQueueMessageID :one
+select id, service_id, queue_message_id, exchange, routing_key, amqp_message_id, attempt_count, to_numbers, body, sender_id, status, received_at, processed_at, to_user_ids, resolved_to_numbers, notification_type, suppressed_numbers
+from sms_requests
+where queue_message_id = $1
+limit 1
+`
+
+// Used to detect a duplicate send before calling the provider, like
+// GetEmailRequestByQueueMessageID.
+func (q *Queries) GetSmsRequestByQueueMessageID(ctx context.Context, queueMessageID string) (SmsRequest, error) {
+	row := q.db.QueryRow(ctx, getSmsRequestByQueueMessageID, queueMessageID)
+	var i SmsRequest
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.QueueMessageID,
+		&i.Exchange,
+		&i.RoutingKey,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.ToNumbers,
+		&i.Body,
+		&i.SenderID,
+		&i.Status,
+		&i.ReceivedAt,
+		&i.ProcessedAt,
+		&i.ToUserIds,
+		&i.ResolvedToNumbers,
+		&i.NotificationType,
+		&i.SuppressedNumbers,
+	)
+	return i, err
+}
+
+const setSmsRequestResolvedNumbers = `-- name: SetSmsRequestResolvedNumbers :exec
+UPDATE sms_requests
+  SET resolved_to_numbers = $2
+  WHERE id = $1
+`
+
+type SetSmsRequestResolvedNumbersParams struct {
+	ID                uuid.UUID `json:"id"`
+	ResolvedToNumbers []string  `json:"resolved_to_numbers"`
+}
+
+// Records the numbers an SMS request's to_user_ids resolved to.
+func (q *Queries) SetSmsRequestResolvedNumbers(ctx context.Context, arg SetSmsRequestResolvedNumbersParams) error {
+	_, err := q.db.Exec(ctx, setSmsRequestResolvedNumbers, arg.ID, arg.ResolvedToNumbers)
+	return err
+}
+
+const setSmsRequestSuppressedNumbers = `-- name: SetSmsRequestSuppressedNumbers :exec
+UPDATE sms_requests
+  SET suppressed_numbers = $2
+  WHERE id = $1
+`
+
+type SetSmsRequestSuppressedNumbersParams struct {
+	ID                uuid.UUID `json:"id"`
+	SuppressedNumbers []string  `json:"suppressed_numbers"`
+}
+
+// Records the numbers an SMS request was not sent to because their user
+// opted out.
+func (q *Queries) SetSmsRequestSuppressedNumbers(ctx context.Context, arg SetSmsRequestSuppressedNumbersParams) error {
+	_, err := q.db.Exec(ctx, setSmsRequestSuppressedNumbers, arg.ID, arg.SuppressedNumbers)
+	return err
+}
+
+const updateSmsRequestStatusByID = `-- name: UpdateSmsRequestStatusByID :exec
+UPDATE sms_requests
+  SET status = $2
+  WHERE id = $1
+`
+
+type UpdateSmsRequestStatusByIDParams struct {
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+}
+
+func (q *Queries) UpdateSmsRequestStatusByID(ctx context.Context, arg UpdateSmsRequestStatusByIDParams) error {
+	_, err := q.db.Exec(ctx, updateSmsRequestStatusByID, arg.ID, arg.Status)
+	return err
+}
+
+const upsertSmsRequest = `-- name: UpsertSmsRequest :one
+INSERT INTO sms_requests (
+  service_id,
+  queue_message_id,
+  exchange,
+  routing_key,
+  amqp_message_id,
+  attempt_count,
+
+  to_numbers,
+  to_user_ids,
+  body,
+  sender_id,
+  notification_type,
+
+  status,
+  processed_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT (queue_message_id) DO UPDATE SET
+  exchange = EXCLUDED.exchange,
+  routing_key = EXCLUDED.routing_key,
+  amqp_message_id = EXCLUDED.amqp_message_id,
+  attempt_count = EXCLUDED.attempt_count,
+  status = EXCLUDED.status,
+  processed_at = EXCLUDED.processed_at
+RETURNING id, service_id, queue_message_id, exchange, routing_key, amqp_message_id, attempt_count, to_numbers, body, sender_id, status, received_at, processed_at, to_user_ids, resolved_to_numbers, notification_type, suppressed_numbers
+`
+
+type UpsertSmsRequestParams struct {
+	ServiceID        string      `json:"service_id"`
+	QueueMessageID   string      `json:"queue_message_id"`
+	Exchange         string      `json:"exchange"`
+	RoutingKey       string      `json:"routing_key"`
+	AmqpMessageID    *string     `json:"amqp_message_id"`
+	AttemptCount     int32       `json:"attempt_count"`
+	ToNumbers        []string    `json:"to_numbers"`
+	ToUserIds        []uuid.UUID `json:"to_user_ids"`
+	Body             string      `json:"body"`
+	SenderID         *string     `json:"sender_id"`
+	NotificationType *string     `json:"notification_type"`
+	Status           string      `json:"status"`
+	ProcessedAt      *time.Time  `json:"processed_at"`
+}
+
+// Persists an SMS request, or updates it in place when the same
+// queue_message_id is retried, the same way UpsertEmailRequest does.
+func (q *Queries) UpsertSmsRequest(ctx context.Context, arg UpsertSmsRequestParams) (SmsRequest, error) {
+	row := q.db.QueryRow(ctx, upsertSmsRequest,
+		arg.ServiceID,
+		arg.QueueMessageID,
+		arg.Exchange,
+		arg.RoutingKey,
+		arg.AmqpMessageID,
+		arg.AttemptCount,
+		arg.ToNumbers,
+		arg.ToUserIds,
+		arg.Body,
+		arg.SenderID,
+		arg.NotificationType,
+		arg.Status,
+		arg.ProcessedAt,
+	)
+	var i SmsRequest
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceID,
+		&i.QueueMessageID,
+		&i.Exchange,
+		&i.RoutingKey,
+		&i.AmqpMessageID,
+		&i.AttemptCount,
+		&i.ToNumbers,
+		&i.Body,
+		&i.SenderID,
+		&i.Status,
+		&i.ReceivedAt,
+		&i.ProcessedAt,
+		&i.ToUserIds,
+		&i.ResolvedToNumbers,
+		&i.NotificationType,
+		&i.SuppressedNumbers,
+	)
+	return i, err
+}
@@ -0,0 +1,153 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.31.1
+// source: suppressions.sql
+
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createEmailSuppression = `-- name: CreateEmailSuppression :one
+INSERT INTO email_suppressions (
+  address,
+  reason,
+  dispatch_id,
+  note,
+  created_by
+) VALUES (lower($1::text), $2, $3, $4, $5)
+ON CONFLICT (address) DO NOTHING
+RETURNING address, reason, dispatch_id, note, created_by, created_at
+`
+
+type CreateEmailSuppressionParams struct {
+	Address    string      `json:"address"`
+	Reason     string      `json:"reason"`
+	DispatchID pgtype.UUID `json:"dispatch_id"`
+	Note       *string     `json:"note"`
+	CreatedBy  string      `json:"created_by"`
+}
+
+// Adds an address to the suppression list. An address already on it keeps
+// its original entry, and no row is returned.
+func (q *Queries) CreateEmailSuppression(ctx context.Context, arg CreateEmailSuppressionParams) (EmailSuppression, error) {
+	row := q.db.QueryRow(ctx, createEmailSuppression,
+		arg.Address,
+		arg.Reason,
+		arg.DispatchID,
+		arg.Note,
+		arg.CreatedBy,
+	)
+	var i EmailSuppression
+	err := row.Scan(
+		&i.Address,
+		&i.Reason,
+		&i.DispatchID,
+		&i.Note,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteEmailSuppression = `-- name: DeleteEmailSuppression :one
+DELETE FROM email_suppressions
+WHERE address = lower($1::text)
+RETURNING address, reason, dispatch_id, note, created_by, created_at
+`
+
+func (q *Queries) DeleteEmailSuppression(ctx context.Context, address string) (EmailSuppression, error) {
+	row := q.db.QueryRow(ctx, deleteEmailSuppression, address)
+	var i EmailSuppression
+	err := row.Scan(
+		&i.Address,
+		&i.Reason,
+		&i.DispatchID,
+		&i.Note,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getEmailSuppression = `-- name: GetEmailSuppression :one
+SELECT address, reason, dispatch_id, note, created_by, created_at FROM email_suppressions
+WHERE address = lower($1::text)
+`
+
+func (q *Queries) GetEmailSuppression(ctx context.Context, address string) (EmailSuppression, error) {
+	row := q.db.QueryRow(ctx, getEmailSuppression, address)
+	var i EmailSuppression
+	err := row.Scan(
+		&i.Address,
+		&i.Reason,
+		&i.DispatchID,
+		&i.Note,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listEmailSuppressions = `-- name: ListEmailSuppressions :many
+SELECT address, reason, dispatch_id, note, created_by, created_at FROM email_suppressions
+ORDER BY created_at DESC, address
+LIMIT $1
+`
+
+// The most recently suppressed addresses first.
+func (q *Queries) ListEmailSuppressions(ctx context.Context, pageSize int32) ([]EmailSuppression, error) {
+	rows, err := q.db.Query(ctx, listEmailSuppressions, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EmailSuppression{}
+	for rows.Next() {
+		var i EmailSuppression
+		if err := rows.Scan(
+			&i.Address,
+			&i.Reason,
+			&i.DispatchID,
+			&i.Note,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSuppressedAddresses = `-- name: ListSuppressedAddresses :many
+SELECT address FROM email_suppressions
+WHERE address = ANY($1::text[])
+`
+
+// Which of addresses, bare and lowercased, are on the suppression list.
+func (q *Queries) ListSuppressedAddresses(ctx context.Context, addresses []string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listSuppressedAddresses, addresses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		items = append(items, address)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/sony/gobreaker/v2"
@@ -29,11 +31,54 @@ type Settings struct {
 	HalfOpenMaxRequests uint32
 }
 
+// breakers holds every breaker New has built, by name. Their state is read
+// from them when it is asked for rather than recorded as it changes:
+// gobreaker only moves an open breaker to half-open once something calls
+// or asks it, so a recorded state would stay "open" while it sits idle.
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]stateful{}
+)
+
+// stateful is the part of *gobreaker.CircuitBreaker[T] that doesn't depend
+// on T.
+type stateful interface {
+	State() gobreaker.State
+}
+
+// OpenBreakers returns the names of the breakers that are currently open,
+// sorted.
+func OpenBreakers() []string {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	var open []string
+	for name, breaker := range breakers {
+		if breaker.State() == gobreaker.StateOpen {
+			open = append(open, name)
+		}
+	}
+	slices.Sort(open)
+	return open
+}
+
+// BreakerStates returns the state of every breaker by name, as the values
+// metrics.RegisterBreakers documents.
+func BreakerStates() map[string]float64 {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	states := make(map[string]float64, len(breakers))
+	for name, breaker := range breakers {
+		states[name] = stateValue(breaker.State())
+	}
+	return states
+}
+
 // New builds a named circuit breaker. State transitions are logged through
 // logger: opening at Warn (something is actually down), closing/half-open
-// trials at Info.
+// trials at Info. The breaker's state is reported by OpenBreakers and
+// BreakerStates, and so by the gossip_circuit_breaker_state gauge.
 func New[T any](name string, settings Settings, logger *slog.Logger) Breaker[T] {
-	return gobreaker.NewCircuitBreaker[T](gobreaker.Settings{
+	breaker := gobreaker.NewCircuitBreaker[T](gobreaker.Settings{
 		Name:        name,
 		MaxRequests: settings.HalfOpenMaxRequests,
 		Timeout:     settings.OpenTimeout,
@@ -53,6 +98,23 @@ func New[T any](name string, settings Settings, logger *slog.Logger) Breaker[T]
 			)
 		},
 	})
+
+	breakersMu.Lock()
+	breakers[name] = breaker
+	breakersMu.Unlock()
+	return breaker
+}
+
+// stateValue maps state onto the values metrics.RegisterBreakers documents.
+func stateValue(state gobreaker.State) float64 {
+	switch state {
+	case gobreaker.StateHalfOpen:
+		return 1
+	case gobreaker.StateOpen:
+		return 2
+	default:
+		return 0
+	}
 }
 
 // Open reports whether err indicates the breaker rejected the call outright
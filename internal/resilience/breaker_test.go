@@ -61,3 +61,37 @@ func TestNew_SuccessfulCallsPassThrough(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 42, v)
 }
+
+func TestNew_ReportsState(t *testing.T) {
+	b := New[string]("state-test", Settings{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Minute,
+		HalfOpenMaxRequests: 1,
+	}, discardLogger())
+
+	assert.Equal(t, 0.0, BreakerStates()["state-test"], "a new breaker is closed")
+
+	_, err := b.Execute(func() (string, error) { return "", errors.New("boom") })
+	require.Error(t, err)
+
+	assert.Equal(t, 2.0, BreakerStates()["state-test"], "the failure should have opened the breaker")
+	assert.Contains(t, OpenBreakers(), "state-test")
+}
+
+func TestNew_IdleBreakerReportsHalfOpenOnceItsTimeoutPasses(t *testing.T) {
+	b := New[string]("idle-test", Settings{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         10 * time.Millisecond,
+		HalfOpenMaxRequests: 1,
+	}, discardLogger())
+
+	_, err := b.Execute(func() (string, error) { return "", errors.New("boom") })
+	require.Error(t, err)
+	require.Contains(t, OpenBreakers(), "idle-test")
+
+	// Nothing calls the breaker while it waits out its timeout.
+	time.Sleep(20 * time.Millisecond)
+
+	assert.NotContains(t, OpenBreakers(), "idle-test")
+	assert.Equal(t, 1.0, BreakerStates()["idle-test"])
+}
@@ -0,0 +1,34 @@
+package resilience
+
+import "errors"
+
+// PermanentError marks an error that retrying cannot fix. A message that
+// fails the same way on every delivery, such as bytes that don't parse or
+// a payload that fails validation, can't be helped by redelivering it, so
+// a consumer parks it straight away rather than retrying it.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err as a PermanentError. It returns nil for a nil err.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err, or any error it wraps, is a
+// PermanentError.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,20 @@
+package resilience
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermanent_SurvivesWrapping(t *testing.T) {
+	cause := errors.New("no heading")
+	err := fmt.Errorf("failed to send: %w", Permanent(cause))
+
+	assert.True(t, IsPermanent(err))
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "failed to send: no heading", err.Error())
+	assert.False(t, IsPermanent(cause))
+	assert.NoError(t, Permanent(nil))
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/resend/resend-go/v3"
+)
+
+// ErrDispatchNotFound is returned when a Resend webhook refers to an email
+// id gossip-monger has no dispatch record for.
+var ErrDispatchNotFound = errors.New("no email dispatch found for resend email id")
+
+// ResendWebhookEvent is the subset of a Resend webhook body gossip-monger
+// needs to attribute an event to its dispatch. The full body is kept
+// verbatim alongside it as the compliance record.
+type ResendWebhookEvent struct {
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      ResendWebhookEventData `json:"data"`
+}
+
+type ResendWebhookEventData struct {
+	EmailID   string    `json:"email_id"`
+	To        []string  `json:"to"`
+	CreatedAt time.Time `json:"created_at"`
+	// Bounce is set on email.bounced events.
+	Bounce *ResendBounce `json:"bounce"`
+}
+
+// ResendBounce says why an email bounced. A Type of "Permanent" is a hard
+// bounce: the address doesn't exist or will never accept mail.
+type ResendBounce struct {
+	Type    string `json:"type"`
+	SubType string `json:"subType"`
+	Message string `json:"message"`
+}
+
+// suppressionReason returns why event puts its recipients on the
+// suppression list, or "" if it doesn't.
+func (event ResendWebhookEvent) suppressionReason() string {
+	switch {
+	case event.Type == resend.EventEmailComplained:
+		return SuppressionReasonComplaint
+	case event.Type == resend.EventEmailBounced &&
+		event.Data.Bounce != nil && event.Data.Bounce.Type == "Permanent":
+		return SuppressionReasonHardBounce
+	}
+	return ""
+}
@@ -2,13 +2,19 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Email struct {
-	FromAddress  string          `json:"from_address"`
-	ReplyTo      *string         `json:"reply_to"`
-	ToAddresses  []string        `json:"to_addresses"`
+	FromAddress string   `json:"from_address"`
+	ReplyTo     *string  `json:"reply_to"`
+	ToAddresses []string `json:"to_addresses"`
+	// ToUserIDs are users to send to at the email address the user
+	// directory has for them, in addition to ToAddresses.
+	ToUserIDs    []uuid.UUID     `json:"to_user_ids"`
 	CcAddresses  []string        `json:"cc_addresses"`
 	BccAddresses []string        `json:"bcc_addresses"`
 	Subject      string          `json:"subject"`
@@ -17,16 +23,31 @@ type Email struct {
 	Attachments  json.RawMessage `json:"attachments"`
 	TemplateID   *string         `json:"template_id"`
 	TemplateVars json.RawMessage `json:"template_vars"`
-	Status       string          `json:"status"`
-	ReceivedAt   time.Time       `json:"received_at"`
-	ProcessedAt  *time.Time      `json:"processed_at"`
+	// NotificationType is what kind of email this is, checked against the
+	// recipients' notification preferences.
+	NotificationType *string `json:"notification_type"`
+	// Transactional says whether the email is one the recipient needs
+	// regardless of their subscriptions (a receipt, a password reset) or
+	// not (a newsletter, a promotion). It is required; non-transactional
+	// email carries an unsubscribe link.
+	Transactional *bool      `json:"transactional"`
+	Status        string     `json:"status"`
+	ReceivedAt    time.Time  `json:"received_at"`
+	ProcessedAt   *time.Time `json:"processed_at"`
 }
 
+// ErrEmailRequestNotFound is returned when an email.cancel event, or an
+// admin lookup, refers to an email request gossip-monger has no record of.
+var ErrEmailRequestNotFound = errors.New("no email request found for request id")
+
 type EmailEventMetadata struct {
 	EventType       string    `json:"event_type"`
 	Timestamp       time.Time `json:"timestamp"`
 	SourceServiceID string    `json:"source_service_id"`
 	RequestID       string    `json:"request_id"`
+	// TargetRequestID is the request_id of the earlier email.send an
+	// email.cancel event refers to.
+	TargetRequestID string `json:"target_request_id,omitempty"`
 }
 
 type EmailEvent struct {
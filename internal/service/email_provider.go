@@ -0,0 +1,292 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resend/resend-go/v3"
+)
+
+// EmailProvider sends an email through one provider. Email is built and
+// validated as a Resend request, which is also what email_dispatches
+// records; other providers translate it.
+type EmailProvider interface {
+	// Name identifies the provider in dispatch records, metrics and
+	// breaker names.
+	Name() string
+	// Send hands req to the provider and returns the id it knows the email
+	// by.
+	Send(ctx context.Context, req *resend.SendEmailRequest) (messageID string, err error)
+}
+
+// resendProvider sends email through the Resend API.
+type resendProvider struct {
+	client *resend.Client
+}
+
+func (p *resendProvider) Name() string {
+	return "resend"
+}
+
+func (p *resendProvider) Send(ctx context.Context, req *resend.SendEmailRequest) (string, error) {
+	sent, err := p.client.Emails.SendWithContext(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return sent.Id, nil
+}
+
+// smtpTimeout bounds an SMTP conversation whose context has no deadline.
+const smtpTimeout = 30 * time.Second
+
+// smtpProvider sends email through an SMTP relay, using STARTTLS whenever
+// the server offers it.
+type smtpProvider struct {
+	host string
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPProvider returns an EmailProvider relaying through the SMTP server
+// at host:port, authenticating as username unless it is empty.
+func NewSMTPProvider(host string, port int, username, password string) EmailProvider {
+	p := &smtpProvider{
+		host: host,
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+	}
+	if username != "" {
+		p.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return p
+}
+
+func (p *smtpProvider) Name() string {
+	return "smtp"
+}
+
+// smtpCanSend reports whether req can go out over SMTP: Resend templates
+// are rendered by Resend, and attachments given by URL are fetched by it.
+func smtpCanSend(req *resend.SendEmailRequest) bool {
+	if req.Template != nil {
+		return false
+	}
+	for _, a := range req.Attachments {
+		if a.Path != "" && len(a.Content) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *smtpProvider) Send(ctx context.Context, req *resend.SendEmailRequest) (string, error) {
+	if !smtpCanSend(req) {
+		return "", fmt.Errorf("templates and attachments by path can only be sent through resend")
+	}
+	from, err := mail.ParseAddress(req.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid from address: %w", err)
+	}
+	messageID, message, err := buildMIMEMessage(req, from, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+
+	client, err := smtp.NewClient(conn, p.host)
+	if err != nil {
+		return "", fmt.Errorf("failed to greet smtp server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: p.host}); err != nil {
+			return "", fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	if p.auth != nil {
+		if err := client.Auth(p.auth); err != nil {
+			return "", fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return "", fmt.Errorf("smtp server refused sender: %w", err)
+	}
+	for _, rcpt := range recipients(req) {
+		if err := client.Rcpt(rcpt); err != nil {
+			return "", fmt.Errorf("smtp server refused recipient %s: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(message); err != nil {
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("smtp server refused message: %w", err)
+	}
+	// The message is accepted once DATA is; a failed QUIT doesn't undo it.
+	client.Quit()
+
+	return messageID, nil
+}
+
+// recipients returns the envelope recipients of req: to, cc and bcc, as
+// bare addresses.
+func recipients(req *resend.SendEmailRequest) []string {
+	var all []string
+	for _, list := range [][]string{req.To, req.Cc, req.Bcc} {
+		for _, rcpt := range list {
+			if addr, err := mail.ParseAddress(rcpt); err == nil {
+				all = append(all, addr.Address)
+			} else {
+				all = append(all, rcpt)
+			}
+		}
+	}
+	return all
+}
+
+// buildMIMEMessage renders req as a MIME message, returning its
+// Message-ID. Bcc recipients are left out of the headers.
+func buildMIMEMessage(
+	req *resend.SendEmailRequest,
+	from *mail.Address,
+	now time.Time,
+) (string, []byte, error) {
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+	messageID := fmt.Sprintf("<%s@%s>", uuid.NewString(), domain)
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", req.From)
+	if len(req.To) > 0 {
+		header("To", strings.Join(req.To, ", "))
+	}
+	if len(req.Cc) > 0 {
+		header("Cc", strings.Join(req.Cc, ", "))
+	}
+	if req.ReplyTo != "" {
+		header("Reply-To", req.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", req.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	for key, value := range req.Headers {
+		header(key, value)
+	}
+	header("MIME-Version", "1.0")
+
+	mixed := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	buf.WriteString("\r\n")
+
+	var body bytes.Buffer
+	alternative := multipart.NewWriter(&body)
+	for _, content := range []struct{ contentType, text string }{
+		{"text/plain; charset=utf-8", req.Text},
+		{"text/html; charset=utf-8", req.Html},
+	} {
+		if content.text == "" {
+			continue
+		}
+		part, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {content.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err := qp.Write([]byte(content.text)); err != nil {
+			return "", nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return "", nil, err
+	}
+	part, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := part.Write(body.Bytes()); err != nil {
+		return "", nil, err
+	}
+
+	for _, a := range req.Attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(a.Filename))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		if err := writeBase64(part, a.Content); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return "", nil, err
+	}
+	return messageID, buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64-encoded in 76-character lines, as RFC
+// 2045 requires.
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
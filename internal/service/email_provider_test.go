@@ -0,0 +1,250 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/resend/resend-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer is a MailHog-style SMTP server: it accepts whatever it is
+// sent, over plain text, and keeps it for the test to inspect.
+type fakeSMTPServer struct {
+	listener net.Listener
+	// auth, if set, is advertised and the credentials sent are recorded.
+	auth bool
+	// rejectRcpt is refused with a 550.
+	rejectRcpt string
+
+	mu         sync.Mutex
+	credential string
+	from       string
+	rcpts      []string
+	data       string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTPServer{listener: listener}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) provider(username, password string) EmailProvider {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return NewSMTPProvider(host, portNumber, username, password)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		s.mu.Lock()
+		switch {
+		case verb == "EHLO":
+			if s.auth {
+				reply("250-fake")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250 fake")
+			}
+		case verb == "AUTH":
+			s.credential = strings.TrimPrefix(line, "AUTH PLAIN ")
+			reply("235 authenticated")
+		case strings.HasPrefix(line, "MAIL FROM:"):
+			s.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			reply("250 ok")
+		case strings.HasPrefix(line, "RCPT TO:"):
+			rcpt := strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>")
+			if rcpt == s.rejectRcpt {
+				reply("550 no such user")
+			} else {
+				s.rcpts = append(s.rcpts, rcpt)
+				reply("250 ok")
+			}
+		case verb == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					s.mu.Unlock()
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case verb == "QUIT":
+			reply("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			reply("250 ok")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestSMTPSend_DeliversMIMEMessage(t *testing.T) {
+	server := newFakeSMTPServer(t)
+
+	messageID, err := server.provider("", "").Send(context.Background(), &resend.SendEmailRequest{
+		From:    "Posta <noreply@posta.opencrafts.io>",
+		To:      []string{"Amina <amina@example.com>"},
+		Cc:      []string{"cc@example.com"},
+		Bcc:     []string{"bcc@example.com"},
+		ReplyTo: "support@posta.opencrafts.io",
+		Subject: "Your code: 1234 ✓",
+		Text:    "Your code is 1234",
+		Html:    "<p>Your code is <b>1234</b></p>",
+		Attachments: []*resend.Attachment{
+			{Filename: "receipt.pdf", Content: []byte("%PDF-1.4 receipt")},
+		},
+	})
+	require.NoError(t, err)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, "noreply@posta.opencrafts.io", server.from)
+	assert.Equal(t, []string{"amina@example.com", "cc@example.com", "bcc@example.com"}, server.rcpts)
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.data))
+	require.NoError(t, err)
+	assert.Equal(t, messageID, msg.Header.Get("Message-ID"))
+	assert.True(t, strings.HasSuffix(messageID, "@posta.opencrafts.io>"))
+	assert.Equal(t, "Amina <amina@example.com>", msg.Header.Get("To"))
+	assert.Equal(t, "cc@example.com", msg.Header.Get("Cc"))
+	assert.Empty(t, msg.Header.Get("Bcc"), "bcc recipients must not be visible")
+	assert.Equal(t, "support@posta.opencrafts.io", msg.Header.Get("Reply-To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Your code: 1234 ✓", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := parts.NextPart()
+	require.NoError(t, err)
+	mediaType, params, err = mime.ParseMediaType(body.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	alternatives := multipart.NewReader(body, params["boundary"])
+	text, err := alternatives.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", text.Header.Get("Content-Type"))
+	plain, _ := io.ReadAll(text)
+	assert.Equal(t, "Your code is 1234", string(plain))
+	html, err := alternatives.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", html.Header.Get("Content-Type"))
+
+	attachment, err := parts.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "receipt.pdf", attachment.FileName())
+	assert.Equal(t, "application/pdf", attachment.Header.Get("Content-Type"))
+	encoded, _ := io.ReadAll(attachment)
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4 receipt", string(content))
+}
+
+func TestSMTPSend_AuthenticatesWhenConfigured(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	server.auth = true
+
+	_, err := server.provider("gossip", "secret").Send(context.Background(), &resend.SendEmailRequest{
+		From:    "noreply@posta.opencrafts.io",
+		To:      []string{"amina@example.com"},
+		Subject: "hi",
+		Text:    "hi",
+	})
+	require.NoError(t, err)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	credential, err := base64.StdEncoding.DecodeString(server.credential)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00gossip\x00secret", string(credential))
+}
+
+func TestSMTPSend_RefusedRecipientFails(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	server.rejectRcpt = "gone@example.com"
+
+	_, err := server.provider("", "").Send(context.Background(), &resend.SendEmailRequest{
+		From:    "noreply@posta.opencrafts.io",
+		To:      []string{"amina@example.com", "gone@example.com"},
+		Subject: "hi",
+		Text:    "hi",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gone@example.com")
+}
+
+func TestSMTPSend_RefusesWhatOnlyResendCanSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+
+	for name, req := range map[string]*resend.SendEmailRequest{
+		"template": {
+			From:     "noreply@posta.opencrafts.io",
+			To:       []string{"amina@example.com"},
+			Template: &resend.EmailTemplate{Id: "welcome"},
+		},
+		"attachment by path": {
+			From:        "noreply@posta.opencrafts.io",
+			To:          []string{"amina@example.com"},
+			Text:        "hi",
+			Attachments: []*resend.Attachment{{Filename: "a.pdf", Path: "https://example.com/a.pdf"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := server.provider("", "").Send(context.Background(), req)
+			assert.Error(t, err)
+		})
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Empty(t, server.from, "nothing should reach the server")
+}
@@ -6,45 +6,93 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/mail"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/metrics"
 	"github.com/opencrafts-io/gossip-monger/internal/repository"
 	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/tracing"
 	"github.com/resend/resend-go/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type EmailService interface {
-	Send(ctx context.Context, emailEvent EmailEvent) error
+	// Send records emailEvent and sends it through Resend, or SMTP while
+	// Resend's breaker is open. delivery is the
+	// message it arrived in, recorded on the email request for auditing.
+	Send(ctx context.Context, emailEvent EmailEvent, delivery broker.Delivery) error
+	// RecordDeliveryEvent stores a verified Resend webhook event against the
+	// dispatch it reports on. webhookID is the svix-id of the delivery, used
+	// to ignore redelivered webhooks.
+	RecordDeliveryEvent(
+		ctx context.Context,
+		event ResendWebhookEvent,
+		rawPayload json.RawMessage,
+		webhookID string,
+	) error
+	// Cancel retracts the email serviceID sent under requestID if its last
+	// attempt failed and it is still waiting to be retried.
+	Cancel(ctx context.Context, serviceID, requestID string) error
+	// Unsubscribe records the opt-out a signed unsubscribe link stands
+	// for, returning ErrInvalidUnsubscribeToken for a link that isn't one.
+	Unsubscribe(ctx context.Context, token string) error
 }
 
 type emailService struct {
-	pool                 *pgxpool.Pool
-	logger               *slog.Logger
-	emailClient          *resend.Client
-	allowedSenderDomains []string
-	breaker              resilience.Breaker[*resend.SendEmailResponse]
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	// accounts picks the Resend key, and its breaker, a service sends with.
+	accounts *resendAccounts
+	// unsubscribeLinks signs the List-Unsubscribe links of
+	// non-transactional email.
+	unsubscribeLinks UnsubscribeLinks
+	// fallback, if set, sends email while Resend's breaker is open.
+	fallback        EmailProvider
+	fallbackBreaker resilience.Breaker[string]
 }
 
+// NewEmailService creates the email service. emailClient sends for services
+// whose services row carries no resend_api_key of its own. fallback, which
+// may be nil, takes over while a Resend breaker is open.
 func NewEmailService(
 	pool *pgxpool.Pool,
 	emailClient *resend.Client,
-	allowedSenderDomains []string,
+	fallback EmailProvider,
+	unsubscribeLinks UnsubscribeLinks,
 	breakerSettings resilience.Settings,
 	logger *slog.Logger,
 ) EmailService {
-	return &emailService{
-		pool:                 pool,
-		emailClient:          emailClient,
-		allowedSenderDomains: allowedSenderDomains,
-		breaker:              resilience.New[*resend.SendEmailResponse]("resend", breakerSettings, logger),
-		logger:               logger,
+	es := &emailService{
+		pool:             pool,
+		accounts:         newResendAccounts(emailClient, breakerSettings, logger),
+		unsubscribeLinks: unsubscribeLinks,
+		logger:           logger,
+	}
+	if fallback != nil {
+		es.fallback = fallback
+		es.fallbackBreaker = resilience.New[string](fallback.Name(), breakerSettings, logger)
 	}
+	return es
 }
 
-func (es *emailService) Send(ctx context.Context, emailEvent EmailEvent) error {
+func (es *emailService) Send(
+	ctx context.Context,
+	emailEvent EmailEvent,
+	delivery broker.Delivery,
+) (err error) {
+	ctx, span := tracer.Start(ctx, "EmailService.Send", trace.WithAttributes(
+		attribute.String("gossip.request_id", emailEvent.Meta.RequestID),
+		attribute.String("gossip.service_id", emailEvent.Meta.SourceServiceID),
+	))
+	defer func() { tracing.End(span, err) }()
+
 	commited := false
 	conn, err := es.pool.Acquire(ctx)
 	if err != nil {
@@ -79,9 +127,11 @@ func (es *emailService) Send(ctx context.Context, emailEvent EmailEvent) error {
 		ctx,
 		emailEvent.Meta.RequestID,
 	); err == nil {
-		if existing.Status == "dispatched" {
-			es.logger.Info("duplicate request_id already dispatched, skipping resend",
+		if existing.Status == "dispatched" || existing.Status == "cancelled" ||
+			existing.Status == "suppressed" {
+			es.logger.Info("duplicate request_id already dispatched, cancelled or suppressed, skipping resend",
 				"request_id", emailEvent.Meta.RequestID,
+				"status", existing.Status,
 			)
 			return nil
 		}
@@ -89,99 +139,227 @@ func (es *emailService) Send(ctx context.Context, emailEvent EmailEvent) error {
 		return fmt.Errorf("failed to check for duplicate email request: %w", err)
 	}
 
-	if _, err := repo.UpsertService(ctx, repository.UpsertServiceParams{
+	svc, err := repo.UpsertService(ctx, repository.UpsertServiceParams{
 		ID:   emailEvent.Meta.SourceServiceID,
 		Name: emailEvent.Meta.SourceServiceID,
-	}); err != nil {
+	})
+	if err != nil {
 		return fmt.Errorf("failed to upsert service: %w", err)
 	}
 
-	now := time.Now()
-	emailReq, err := repo.UpsertEmailRequest(
+	// Users addressed by id are sent to at the address the user directory
+	// has for them, along with the addresses spelled out.
+	resolvedTo, resolveErr := resolveUserEmails(
 		ctx,
-		repository.UpsertEmailRequestParams{
-			ServiceID:      emailEvent.Meta.SourceServiceID,
-			QueueMessageID: emailEvent.Meta.RequestID,
-			Exchange:       "gossip.topic.exchange",
-			RoutingKey:     "gossip.emails.send",
-			FromAddress:    emailEvent.Email.FromAddress,
-			ReplyTo:        emailEvent.Email.ReplyTo,
-			ToAddresses:    emailEvent.Email.ToAddresses,
-			CcAddresses:    emailEvent.Email.CcAddresses,
-			BccAddresses:   emailEvent.Email.BccAddresses,
-			Subject:        emailEvent.Email.Subject,
-			BodyHtml:       emailEvent.Email.BodyHtml,
-			BodyText:       emailEvent.Email.BodyText,
-			Attachments:    emailEvent.Email.Attachments,
-			TemplateID:     emailEvent.Email.TemplateID,
-			TemplateVars:   emailEvent.Email.TemplateVars,
-			ProcessedAt:    &now,
-			Status:         "received",
-		},
+		repo,
+		emailEvent.Email.ToUserIDs,
+		delivery.Attempt,
 	)
+	if resolveErr != nil && !errors.Is(resolveErr, ErrUnknownRecipientUser) &&
+		!errors.Is(resolveErr, ErrRecipientUserUnreachable) {
+		return resolveErr
+	}
+	email := emailEvent.Email
+	email.ToAddresses = appendNewAddresses(email.ToAddresses, resolvedTo)
+
+	now := time.Now()
+	params := repository.UpsertEmailRequestParams{
+		ServiceID:           emailEvent.Meta.SourceServiceID,
+		QueueMessageID:      emailEvent.Meta.RequestID,
+		Exchange:            delivery.Exchange,
+		RoutingKey:          delivery.RoutingKey,
+		AmqpMessageID:       optionalString(delivery.MessageID),
+		AttemptCount:        int32(delivery.Attempt),
+		FromAddress:         emailEvent.Email.FromAddress,
+		ReplyTo:             emailEvent.Email.ReplyTo,
+		ToAddresses:         emailEvent.Email.ToAddresses,
+		ToUserIds:           emailEvent.Email.ToUserIDs,
+		ResolvedToAddresses: resolvedTo,
+		CcAddresses:         emailEvent.Email.CcAddresses,
+		BccAddresses:        emailEvent.Email.BccAddresses,
+		Subject:             emailEvent.Email.Subject,
+		BodyHtml:            emailEvent.Email.BodyHtml,
+		BodyText:            emailEvent.Email.BodyText,
+		Attachments:         emailEvent.Email.Attachments,
+		TemplateID:          emailEvent.Email.TemplateID,
+		TemplateVars:        emailEvent.Email.TemplateVars,
+		NotificationType:    emailEvent.Email.NotificationType,
+		Transactional:       emailEvent.Email.Transactional,
+		ProcessedAt:         &now,
+		Status:              "received",
+	}
+
+	// fail records the request as failed with cause, so the reason is on
+	// the row and not only in the logs, and returns cause.
+	fail := func(cause error) error {
+		reason := cause.Error()
+		params.Status = "failed"
+		params.FailureReason = &reason
+		if _, err := repo.UpsertEmailRequest(ctx, params); err != nil {
+			return fmt.Errorf("failed to record failed email request: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		commited = true
+		return cause
+	}
+
+	if resolveErr != nil {
+		es.logger.Warn("could not resolve to_user_ids, email not sent",
+			"request_id", emailEvent.Meta.RequestID,
+			"error", resolveErr,
+		)
+		return fail(resolveErr)
+	}
+
+	emailReq, err := repo.UpsertEmailRequest(ctx, params)
 	if err != nil {
 		return fmt.Errorf("failed to create email request: %w", err)
 	}
 
-	resendRequest, err := es.emailToResendEmailRequest(emailEvent.Email)
+	resendRequest, err := es.emailToResendEmailRequest(
+		email,
+		svc.AllowedSenderDomains,
+		svc.ID,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to convert email to resend request: %w", err)
+		return fail(resilience.Permanent(fmt.Errorf("failed to convert email to resend request: %w", err)))
 	}
 
-	sent, resendErr := es.breaker.Execute(func() (*resend.SendEmailResponse, error) {
-		return es.emailClient.Emails.Send(resendRequest)
-	})
+	// Addresses that hard-bounced or complained go first: mailing them
+	// again costs sender reputation whatever the email is.
+	addressed := len(resendRequest.To) > 0
+	suppressed, err := withoutSuppressedRecipients(ctx, repo, resendRequest)
+	if err != nil {
+		return err
+	}
+	undeliverable := noDeliverableRecipients(resendRequest, addressed)
 
-	resendPayload, err := json.Marshal(resendRequest)
+	optedOut, err := withoutOptedOutRecipients(
+		ctx,
+		repo,
+		resendRequest,
+		emailEvent.Email.NotificationType,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to marshal resend payload: %w", err)
+		return err
+	}
+	if !*emailEvent.Email.Transactional {
+		unsubscribed, err := withoutUnsubscribedRecipients(ctx, repo, svc.ID, resendRequest)
+		if err != nil {
+			return err
+		}
+		optedOut = append(optedOut, unsubscribed...)
+	}
+	suppressed = append(suppressed, optedOut...)
+	if err := repo.SetEmailRequestSuppressedAddresses(
+		ctx,
+		repository.SetEmailRequestSuppressedAddressesParams{
+			ID:                  emailReq.ID,
+			SuppressedAddresses: suppressed,
+		},
+	); err != nil {
+		return fmt.Errorf("failed to record suppressed addresses: %w", err)
 	}
 
-	// Determine dispatch status based on Resend API response. circuit_open
-	// is distinct from failed so operators can tell "the provider was
-	// already known-down, we didn't even try" apart from "we tried and
-	// Resend rejected this payload."
-	dispatchStatus := "failed"
-	if resendErr == nil {
-		dispatchStatus = "sent"
-	} else if resilience.Open(resendErr) {
-		dispatchStatus = "circuit_open"
-	}
-
-	dispatchParams := repository.CreateEmailDispatchParams{
-		EmailRequestID: emailReq.ID,
-		Status:         dispatchStatus,
-		ResendPayload:  resendPayload,
-	}
-
-	if resendErr != nil {
-		errString := resendErr.Error()
-		dispatchParams.ResendError = &errString
-		var statusCode int32 = 400
-		dispatchParams.HttpStatusCode = &statusCode
-		es.logger.Error("resend delivery failed",
+	// settle records status as the outcome of an email that isn't sent.
+	settle := func(status string) error {
+		if _, err := repo.UpdateEmailRequestStatusByID(
+			ctx,
+			repository.UpdateEmailRequestStatusByIDParams{
+				ID:     emailReq.ID,
+				Status: status,
+			},
+		); err != nil {
+			return fmt.Errorf("failed to update email request status: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		commited = true
+		return nil
+	}
+
+	switch {
+	case undeliverable:
+		if err := settle("failed"); err != nil {
+			return err
+		}
+		es.logger.Warn("every to recipient is on the suppression list, email not sent",
 			"email_request_id", emailReq.ID,
-			"status", dispatchStatus,
-			"error", resendErr,
 		)
-	} else {
-		var statusCode int32 = 200
-		dispatchParams.ResendEmailID = &sent.Id
-		dispatchParams.HttpStatusCode = &statusCode
-		es.logger.Info("resend delivery ok!",
+		// Retrying won't take them off it; an operator can, then replay
+		// the parked message.
+		return resilience.Permanent(ErrNoDeliverableRecipients)
+	// Copies of an email nobody it is addressed to wants aren't sent
+	// either.
+	case len(optedOut) > 0 && len(resendRequest.To) == 0 &&
+		(addressed || len(resendRequest.Cc)+len(resendRequest.Bcc) == 0):
+		if err := settle("suppressed"); err != nil {
+			return err
+		}
+		es.logger.Info("every recipient opted out, email suppressed",
 			"email_request_id", emailReq.ID,
+			"notification_type", derefString(emailEvent.Email.NotificationType),
 		)
-
+		return nil
 	}
 
-	_, err = repo.CreateEmailDispatch(ctx, dispatchParams)
+	resendPayload, err := json.Marshal(resendRequest)
 	if err != nil {
-		return fmt.Errorf("failed to persist email dispatch record: %w", err)
+		return fmt.Errorf("failed to marshal resend payload: %w", err)
 	}
 
+	attempts := es.deliver(ctx, es.accounts.forService(svc), resendRequest)
+	for _, attempt := range attempts {
+		dispatchParams := repository.CreateEmailDispatchParams{
+			EmailRequestID: emailReq.ID,
+			Status:         attempt.status,
+			Provider:       attempt.provider,
+			ResendPayload:  resendPayload,
+		}
+
+		if attempt.err != nil {
+			errString := attempt.err.Error()
+			dispatchParams.ResendError = &errString
+			if attempt.provider == "resend" {
+				var statusCode int32 = 400
+				dispatchParams.HttpStatusCode = &statusCode
+			}
+			es.logger.Error("email delivery failed",
+				"email_request_id", emailReq.ID,
+				"provider", attempt.provider,
+				"status", attempt.status,
+				"error", attempt.err,
+			)
+		} else {
+			dispatchParams.ProviderMessageID = &attempt.messageID
+			if attempt.provider == "resend" {
+				var statusCode int32 = 200
+				dispatchParams.ResendEmailID = &attempt.messageID
+				dispatchParams.HttpStatusCode = &statusCode
+			}
+			es.logger.Info("email delivery ok!",
+				"email_request_id", emailReq.ID,
+				"provider", attempt.provider,
+			)
+		}
+
+		_, err = repo.CreateEmailDispatch(ctx, dispatchParams)
+		if err != nil {
+			return fmt.Errorf("failed to persist email dispatch record: %w", err)
+		}
+	}
+
+	// The last attempt decides the outcome: a failover that went through
+	// means the email was sent even though Resend's breaker was open.
+	last := attempts[len(attempts)-1]
+	sendErr := last.err
+
 	finalStatus := "dispatched"
-	if resendErr != nil {
-		finalStatus = dispatchStatus // "failed" or "circuit_open"
+	if sendErr != nil {
+		finalStatus = last.status // "failed" or "circuit_open"
 	}
 	_, err = repo.UpdateEmailRequestStatusByID(
 		ctx,
@@ -195,44 +373,372 @@ func (es *emailService) Send(ctx context.Context, emailEvent EmailEvent) error {
 	}
 
 	// Commit the transaction so the attempt is recorded regardless of
-	// outcome, then propagate resendErr so the consumer nacks the message
+	// outcome, then propagate sendErr so the consumer nacks the message
 	// for a retry instead of acking a failed send as if it succeeded.
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	commited = true
 
-	if resendErr != nil {
-		return fmt.Errorf("failed to send email via resend: %w", resendErr)
+	if sendErr != nil {
+		return fmt.Errorf("failed to send email via %s: %w", last.provider, sendErr)
+	}
+
+	return nil
+}
+
+func (es *emailService) Unsubscribe(ctx context.Context, token string) error {
+	serviceID, recipient, err := es.unsubscribeLinks.Verify(token)
+	if err != nil {
+		return err
+	}
+
+	if err := repository.New(es.pool).CreateEmailUnsubscribe(ctx, repository.CreateEmailUnsubscribeParams{
+		ServiceID: serviceID,
+		Address:   recipient,
+	}); err != nil {
+		return fmt.Errorf("failed to record unsubscribe: %w", err)
+	}
+
+	es.logger.Info("recipient unsubscribed",
+		"service_id", serviceID,
+	)
+	return nil
+}
+
+// withoutOptedOutRecipients removes from req's to, cc and bcc every address
+// whose user opted out of notificationType by email, returning the
+// addresses it removed.
+func withoutOptedOutRecipients(
+	ctx context.Context,
+	repo repository.Querier,
+	req *resend.SendEmailRequest,
+	notificationType *string,
+) ([]string, error) {
+	optedOut, err := repo.ListOptedOutEmailAddresses(ctx, repository.ListOptedOutEmailAddressesParams{
+		Addresses:        bareAddresses(req),
+		NotificationType: derefString(notificationType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check notification preferences: %w", err)
+	}
+
+	return removeRecipients(req, optedOut), nil
+}
+
+// withoutUnsubscribedRecipients removes from req's to, cc and bcc every
+// address that unsubscribed from serviceID's non-transactional email,
+// returning the addresses it removed.
+func withoutUnsubscribedRecipients(
+	ctx context.Context,
+	repo repository.Querier,
+	serviceID string,
+	req *resend.SendEmailRequest,
+) ([]string, error) {
+	unsubscribed, err := repo.ListUnsubscribedAddresses(ctx, repository.ListUnsubscribedAddressesParams{
+		ServiceID: serviceID,
+		Addresses: bareAddresses(req),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check unsubscribes: %w", err)
+	}
+	return removeRecipients(req, unsubscribed), nil
+}
+
+// bareAddresses returns req's to, cc and bcc as bare, lowercased
+// addresses.
+func bareAddresses(req *resend.SendEmailRequest) []string {
+	var addresses []string
+	for _, list := range [][]string{req.To, req.Cc, req.Bcc} {
+		for _, rcpt := range list {
+			addresses = append(addresses, bareAddress(rcpt))
+		}
+	}
+	return addresses
+}
+
+// appendNewAddresses appends to list the addresses in more it doesn't
+// already hold.
+func appendNewAddresses(list, more []string) []string {
+	have := make(map[string]bool, len(list)+len(more))
+	for _, rcpt := range list {
+		have[bareAddress(rcpt)] = true
+	}
+	for _, rcpt := range more {
+		if address := bareAddress(rcpt); !have[address] {
+			have[address] = true
+			list = append(list, rcpt)
+		}
+	}
+	return list
+}
+
+// removeRecipients removes the bare, lowercased addresses from req's to,
+// cc and bcc, returning the recipients it removed as they were given.
+func removeRecipients(req *resend.SendEmailRequest, addresses []string) []string {
+	if len(addresses) == 0 {
+		return nil
+	}
+	skip := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		skip[address] = true
+	}
+	var removed []string
+	keep := func(list []string) []string {
+		var kept []string
+		for _, rcpt := range list {
+			if skip[bareAddress(rcpt)] {
+				removed = append(removed, rcpt)
+			} else {
+				kept = append(kept, rcpt)
+			}
+		}
+		return kept
+	}
+	req.To = keep(req.To)
+	req.Cc = keep(req.Cc)
+	req.Bcc = keep(req.Bcc)
+	return removed
+}
+
+// bareAddress returns rcpt without any display name, lowercased.
+func bareAddress(rcpt string) string {
+	if address, err := mail.ParseAddress(rcpt); err == nil {
+		rcpt = address.Address
 	}
+	return strings.ToLower(rcpt)
+}
+
+// emailAttempt is one provider's go at sending an email.
+type emailAttempt struct {
+	provider  string
+	messageID string
+	// status is the dispatch status: sent, failed or circuit_open. The
+	// last two are distinct so operators can tell "the provider was
+	// already known-down, we didn't even try" apart from "we tried and
+	// the provider rejected this payload."
+	status string
+	err    error
+}
+
+// deliver sends req through account, failing over to the SMTP provider if
+// account's breaker is open and the email can go out over SMTP. It returns
+// every attempt made, in order.
+func (es *emailService) deliver(
+	ctx context.Context,
+	account *resendAccount,
+	req *resend.SendEmailRequest,
+) []emailAttempt {
+	attempts := []emailAttempt{sendVia(ctx, account.provider, account.breaker, req)}
+	if !resilience.Open(attempts[0].err) || es.fallback == nil || !smtpCanSend(req) {
+		return attempts
+	}
+
+	es.logger.Warn("resend circuit open, failing over",
+		"provider", es.fallback.Name(),
+	)
+	return append(attempts, sendVia(ctx, es.fallback, es.fallbackBreaker, req))
+}
 
+func sendVia(
+	ctx context.Context,
+	provider EmailProvider,
+	breaker resilience.Breaker[string],
+	req *resend.SendEmailRequest,
+) emailAttempt {
+	start := time.Now()
+	messageID, err := breaker.Execute(func() (string, error) {
+		return provider.Send(ctx, req)
+	})
+
+	status := "failed"
+	if err == nil {
+		status = "sent"
+	} else if resilience.Open(err) {
+		status = "circuit_open"
+	}
+	metrics.ObserveProviderCall(provider.Name(), status, start)
+
+	return emailAttempt{
+		provider:  provider.Name(),
+		messageID: messageID,
+		status:    status,
+		err:       err,
+	}
+}
+
+func (es *emailService) Cancel(ctx context.Context, serviceID, requestID string) error {
+	if requestID == "" {
+		return resilience.Permanent(errors.New("target_request_id is required to cancel an email"))
+	}
+
+	repo := repository.New(es.pool)
+
+	cancelled, err := repo.CancelEmailRequest(ctx, repository.CancelEmailRequestParams{
+		QueueMessageID: requestID,
+		ServiceID:      serviceID,
+	})
+	if err == nil {
+		es.logger.Info("email request cancelled",
+			"email_request_id", cancelled.ID,
+			"request_id", requestID,
+		)
+		return nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to cancel email request: %w", err)
+	}
+
+	existing, err := repo.GetEmailRequestByQueueMessageID(ctx, requestID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The cancel may have overtaken the email.send it refers to;
+		// failing lets it be retried after the send has landed.
+		return fmt.Errorf("%w: request_id %s", ErrEmailRequestNotFound, requestID)
+	} else if err != nil {
+		return fmt.Errorf("failed to look up email request: %w", err)
+	}
+
+	if existing.ServiceID != serviceID {
+		// Retrying won't make the email serviceID's; parking the message
+		// keeps the refusal on record.
+		es.logger.Warn("refusing to cancel another service's email request",
+			"email_request_id", existing.ID,
+			"request_id", requestID,
+			"service_id", serviceID,
+			"owner_service_id", existing.ServiceID,
+		)
+		return resilience.Permanent(fmt.Errorf("%w: cannot cancel request_id %s", ErrTargetRequestNotOwned, requestID))
+	}
+
+	if existing.Status != "cancelled" {
+		// Retrying won't recall an email that already went out.
+		es.logger.Warn("email request already dispatched, cannot cancel",
+			"email_request_id", existing.ID,
+			"request_id", requestID,
+			"status", existing.Status,
+		)
+	}
+	return nil
+}
+
+func (es *emailService) RecordDeliveryEvent(
+	ctx context.Context,
+	event ResendWebhookEvent,
+	rawPayload json.RawMessage,
+	webhookID string,
+) error {
+	repo := repository.New(es.pool)
+
+	dispatch, err := repo.GetEmailDispatchByResendEmailID(ctx, &event.Data.EmailID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrDispatchNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to look up email dispatch: %w", err)
+	}
+
+	occurredAt := event.CreatedAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+
+	// Resend reports one event per email, listing every recipient of it;
+	// one row per recipient keeps "did this address bounce?" a simple
+	// lookup. An event without recipients is still recorded once.
+	recipients := make([]*string, 0, len(event.Data.To))
+	for i := range event.Data.To {
+		recipients = append(recipients, &event.Data.To[i])
+	}
+	if len(recipients) == 0 {
+		recipients = append(recipients, nil)
+	}
+
+	for _, recipient := range recipients {
+		if err := repo.CreateEmailDeliveryEvent(
+			ctx,
+			repository.CreateEmailDeliveryEventParams{
+				DispatchID:    dispatch.ID,
+				ResendEmailID: event.Data.EmailID,
+				EventType:     event.Type,
+				Recipient:     recipient,
+				RawPayload:    rawPayload,
+				OccurredAt:    pgtype.Timestamptz{Time: occurredAt, Valid: true},
+				WebhookID:     &webhookID,
+			},
+		); err != nil {
+			return fmt.Errorf("failed to record email delivery event: %w", err)
+		}
+	}
+
+	if reason := event.suppressionReason(); reason != "" {
+		if err := es.suppress(ctx, repo, dispatch, event, reason); err != nil {
+			return err
+		}
+	}
+
+	es.logger.Info("recorded email delivery event",
+		"email_request_id", dispatch.EmailRequestID,
+		"event_type", event.Type,
+	)
+	return nil
+}
+
+// suppress puts the recipient of a hard-bounced or complained-about email
+// on the suppression list. Resend lists every recipient of the email, not
+// just the one that bounced or complained, so an email sent to several is
+// left for an operator to look into rather than suppressing them all.
+func (es *emailService) suppress(
+	ctx context.Context,
+	repo repository.Querier,
+	dispatch repository.EmailDispatch,
+	event ResendWebhookEvent,
+	reason string,
+) error {
+	if len(event.Data.To) != 1 {
+		es.logger.Warn("delivery event names several recipients, none suppressed",
+			"email_request_id", dispatch.EmailRequestID,
+			"event_type", event.Type,
+			"recipients", len(event.Data.To),
+		)
+		return nil
+	}
+
+	_, err := repo.CreateEmailSuppression(ctx, repository.CreateEmailSuppressionParams{
+		Address:    bareAddress(event.Data.To[0]),
+		Reason:     reason,
+		DispatchID: pgtype.UUID{Bytes: dispatch.ID, Valid: true},
+		CreatedBy:  suppressionWebhookActor,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Already suppressed; the first reason stands.
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to suppress email address: %w", err)
+	}
+
+	es.logger.Info("email address suppressed",
+		"email_request_id", dispatch.EmailRequestID,
+		"reason", reason,
+	)
 	return nil
 }
 
+// emailToResendEmailRequest validates email and builds the request that
+// sends it for serviceID.
 func (es *emailService) emailToResendEmailRequest(
 	email Email,
+	allowedSenderDomains []string,
+	serviceID string,
 ) (*resend.SendEmailRequest, error) {
 	if strings.TrimSpace(email.FromAddress) == "" {
 		return nil, fmt.Errorf("from address is required")
 	}
 
-	allowed := false
-	for _, domain := range es.allowedSenderDomains {
-		if strings.HasSuffix(email.FromAddress, domain) {
-			allowed = true
-			break
-		}
-	}
-	if !allowed {
-		return nil, fmt.Errorf(
-			"from address must end with one of: %s",
-			strings.Join(es.allowedSenderDomains, ", "),
-		)
+	if err := checkSenderDomain(email.FromAddress, allowedSenderDomains); err != nil {
+		return nil, err
 	}
 
 	if len(email.ToAddresses) == 0 && len(email.CcAddresses) == 0 &&
 		len(email.BccAddresses) == 0 {
-		return nil, fmt.Errorf("at least one recipient is required")
+		return nil, fmt.Errorf("at least one recipient is required in to_addresses, to_user_ids, cc_addresses or bcc_addresses")
 	}
 
 	if strings.TrimSpace(email.Subject) == "" {
@@ -300,5 +806,33 @@ func (es *emailService) emailToResendEmailRequest(
 		request.Text = derefString(email.BodyText)
 	}
 
+	if email.Transactional == nil {
+		return nil, errors.New("transactional is required: say whether this email is transactional")
+	}
+	if !*email.Transactional {
+		// The unsubscribe link is the recipient's own, so it can't be
+		// shared with, or reveal, anyone else.
+		if len(email.ToAddresses) != 1 || len(email.CcAddresses) > 0 || len(email.BccAddresses) > 0 {
+			return nil, errors.New("a non-transactional email must have exactly one recipient, in to_addresses")
+		}
+		link, err := es.unsubscribeLinks.URL(serviceID, bareAddress(email.ToAddresses[0]))
+		if err != nil {
+			return nil, err
+		}
+		// RFC 8058 one-click unsubscribe.
+		request.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + link + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+
 	return request, nil
 }
+
+// optionalString returns nil for an empty s, for nullable text columns.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -1,10 +1,19 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
 	"github.com/resend/resend-go/v3"
+	"github.com/sony/gobreaker/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,10 +29,11 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "valid email with html body",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
 			},
 			expectError: false,
 			validate: func(t *testing.T, req *resend.SendEmailRequest) {
@@ -38,10 +48,11 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "valid email with text body",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyText:    stringPtr("Hello World"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyText:      stringPtr("Hello World"),
 			},
 			expectError: false,
 			validate: func(t *testing.T, req *resend.SendEmailRequest) {
@@ -52,11 +63,12 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "valid email with both html and text",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
-				BodyText:    stringPtr("Hello"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+				BodyText:      stringPtr("Hello"),
 			},
 			expectError: false,
 			validate: func(t *testing.T, req *resend.SendEmailRequest) {
@@ -67,11 +79,12 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "valid email with template",
 			email: Email{
-				FromAddress:  "sender@posta.opencrafts.io",
-				ToAddresses:  []string{"recipient@example.com"},
-				Subject:      "Test Subject",
-				TemplateID:   stringPtr("template_123"),
-				TemplateVars: json.RawMessage(`{"name":"John","age":30}`),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				TemplateID:    stringPtr("template_123"),
+				TemplateVars:  json.RawMessage(`{"name":"John","age":30}`),
 			},
 			expectError: false,
 			validate: func(t *testing.T, req *resend.SendEmailRequest) {
@@ -84,12 +97,13 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "email with cc and bcc",
 			email: Email{
-				FromAddress:  "sender@posta.opencrafts.io",
-				ToAddresses:  []string{"recipient@example.com"},
-				CcAddresses:  []string{"cc@example.com"},
-				BccAddresses: []string{"bcc@example.com"},
-				Subject:      "Test Subject",
-				BodyHtml:     stringPtr("<h1>Hello</h1>"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				CcAddresses:   []string{"cc@example.com"},
+				BccAddresses:  []string{"bcc@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
 			},
 			expectError: false,
 			validate: func(t *testing.T, req *resend.SendEmailRequest) {
@@ -100,11 +114,12 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "email with reply-to",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
-				ReplyTo:     stringPtr("reply@example.com"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+				ReplyTo:       stringPtr("reply@example.com"),
 			},
 			expectError: false,
 			validate: func(t *testing.T, req *resend.SendEmailRequest) {
@@ -114,10 +129,11 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "email with attachments",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
 				Attachments: json.RawMessage(
 					`[{"filename":"test.txt","content":"dGVzdA=="}]`,
 				),
@@ -132,10 +148,11 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "missing from address",
 			email: Email{
-				FromAddress: "",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
+				Transactional: boolPtr(true),
+				FromAddress:   "",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
 			},
 			expectError: true,
 			errorMsg:    "from address is required",
@@ -143,10 +160,11 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "missing to addresses",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
 			},
 			expectError: true,
 			errorMsg:    "at least one recipient is required",
@@ -154,10 +172,11 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "missing subject",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
 			},
 			expectError: true,
 			errorMsg:    "subject is required",
@@ -165,9 +184,10 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "missing body and template",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
 			},
 			expectError: true,
 			errorMsg:    "either template_id or body content (body_html/body_text) is required",
@@ -175,11 +195,12 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "both body and template provided",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
-				TemplateID:  stringPtr("template_123"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+				TemplateID:    stringPtr("template_123"),
 			},
 			expectError: true,
 			errorMsg:    "cannot use both template and body content; provide only one",
@@ -187,11 +208,12 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "invalid attachments json",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
-				Attachments: json.RawMessage(`invalid json`),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+				Attachments:   json.RawMessage(`invalid json`),
 			},
 			expectError: true,
 			errorMsg:    "invalid attachments format",
@@ -199,11 +221,12 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "invalid template vars json",
 			email: Email{
-				FromAddress:  "sender@posta.opencrafts.io",
-				ToAddresses:  []string{"recipient@example.com"},
-				Subject:      "Test Subject",
-				TemplateID:   stringPtr("template_123"),
-				TemplateVars: json.RawMessage(`not valid json`),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				TemplateID:    stringPtr("template_123"),
+				TemplateVars:  json.RawMessage(`not valid json`),
 			},
 			expectError: true,
 			errorMsg:    "invalid template_vars format",
@@ -211,11 +234,12 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "empty template id with whitespace",
 			email: Email{
-				FromAddress: "sender@posta.opencrafts.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
-				TemplateID:  stringPtr("   "),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+				TemplateID:    stringPtr("   "),
 			},
 			expectError: false,
 			validate: func(t *testing.T, req *resend.SendEmailRequest) {
@@ -225,10 +249,11 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "from address matching a second configured domain",
 			email: Email{
-				FromAddress: "sender@posta.example-brand.io",
-				ToAddresses: []string{"recipient@example.com"},
-				Subject:     "Test Subject",
-				BodyHtml:    stringPtr("<h1>Hello</h1>"),
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.example-brand.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
 			},
 			expectError: false,
 			validate: func(t *testing.T, req *resend.SendEmailRequest) {
@@ -238,26 +263,136 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 		{
 			name: "from address outside all configured domains",
 			email: Email{
-				FromAddress: "sender@unrelated-domain.io",
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@unrelated-domain.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+			},
+			expectError: true,
+			errorMsg:    "from address domain must be one of",
+		},
+		{
+			name: "domain that merely ends with an allowed domain",
+			email: Email{
+				Transactional: boolPtr(true),
+				FromAddress:   "evil@attacker-posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+			},
+			expectError: true,
+			errorMsg:    "from address domain must be one of: posta.opencrafts.io, posta.example-brand.io",
+		},
+		{
+			name: "subdomain of an allowed domain",
+			email: Email{
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@mail.posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+			},
+			expectError: true,
+			errorMsg:    "from address domain must be one of",
+		},
+		{
+			name: "from address with a display name and upper-case domain",
+			email: Email{
+				Transactional: boolPtr(true),
+				FromAddress:   "Posta <noreply@Posta.OpenCrafts.io>",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+			},
+			expectError: false,
+			validate: func(t *testing.T, req *resend.SendEmailRequest) {
+				assert.Equal(t, "Posta <noreply@Posta.OpenCrafts.io>", req.From)
+			},
+		},
+		{
+			name: "malformed from address",
+			email: Email{
+				Transactional: boolPtr(true),
+				FromAddress:   "posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Test Subject",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+			},
+			expectError: true,
+			errorMsg:    "is not a valid email address",
+		},
+		{
+			name: "transactional not stated",
+			email: Email{
+				FromAddress: "sender@posta.opencrafts.io",
 				ToAddresses: []string{"recipient@example.com"},
 				Subject:     "Test Subject",
 				BodyHtml:    stringPtr("<h1>Hello</h1>"),
 			},
 			expectError: true,
-			errorMsg:    "from address must end with one of",
+			errorMsg:    "transactional is required",
+		},
+		{
+			name: "non-transactional email gets one-click unsubscribe headers",
+			email: Email{
+				Transactional: boolPtr(false),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"Amina <Amina@Example.com>"},
+				Subject:       "This week at Sherehe",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+			},
+			expectError: false,
+			validate: func(t *testing.T, req *resend.SendEmailRequest) {
+				assert.Equal(t, "List-Unsubscribe=One-Click", req.Headers["List-Unsubscribe-Post"])
+				link := req.Headers["List-Unsubscribe"]
+				require.True(t, strings.HasPrefix(link, "<https://gossip.opencrafts.io/unsubscribe?token="), link)
+				parsed, err := url.Parse(strings.Trim(link, "<>"))
+				require.NoError(t, err)
+				serviceID, recipient, err := testUnsubscribeLinks.Verify(parsed.Query().Get("token"))
+				require.NoError(t, err)
+				assert.Equal(t, "io.opencrafts.sherehe", serviceID)
+				assert.Equal(t, "amina@example.com", recipient)
+			},
+		},
+		{
+			name: "transactional email has no unsubscribe headers",
+			email: Email{
+				Transactional: boolPtr(true),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				Subject:       "Your receipt",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+			},
+			expectError: false,
+			validate: func(t *testing.T, req *resend.SendEmailRequest) {
+				assert.Empty(t, req.Headers)
+			},
+		},
+		{
+			name: "non-transactional email to several recipients",
+			email: Email{
+				Transactional: boolPtr(false),
+				FromAddress:   "sender@posta.opencrafts.io",
+				ToAddresses:   []string{"recipient@example.com"},
+				BccAddresses:  []string{"other@example.com"},
+				Subject:       "This week at Sherehe",
+				BodyHtml:      stringPtr("<h1>Hello</h1>"),
+			},
+			expectError: true,
+			errorMsg:    "exactly one recipient",
 		},
 	}
 
-	es := &emailService{
-		allowedSenderDomains: []string{
-			"@posta.opencrafts.io",
-			"@posta.example-brand.io",
-		},
+	allowedSenderDomains := []string{
+		"@posta.opencrafts.io",
+		"@posta.example-brand.io",
 	}
+	es := &emailService{unsubscribeLinks: testUnsubscribeLinks}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req, err := es.emailToResendEmailRequest(tt.email)
+			req, err := es.emailToResendEmailRequest(tt.email, allowedSenderDomains, "io.opencrafts.sherehe")
 
 			if tt.expectError {
 				require.Error(t, err)
@@ -272,6 +407,249 @@ func TestEmailToResendEmailRequest(t *testing.T) {
 	}
 }
 
+func TestEmailToResendEmailRequest_ServiceWithNoDomainsIsRejected(t *testing.T) {
+	es := &emailService{unsubscribeLinks: testUnsubscribeLinks}
+	email := Email{
+		Transactional: boolPtr(true),
+		FromAddress:   "sender@posta.opencrafts.io",
+		ToAddresses:   []string{"recipient@example.com"},
+		Subject:       "Your receipt",
+		BodyHtml:      stringPtr("<h1>Hello</h1>"),
+	}
+
+	for name, domains := range map[string][]string{
+		"no domains":    nil,
+		"empty list":    {},
+		"blank entries": {" ", "@"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := es.emailToResendEmailRequest(email, domains, "io.opencrafts.sherehe")
+
+			assert.ErrorIs(t, err, ErrNoSenderDomains)
+			assert.Nil(t, req)
+		})
+	}
+}
+
+// fakeEmailProvider answers every send with messageID, or err if set.
+type fakeEmailProvider struct {
+	name      string
+	messageID string
+	err       error
+	calls     int
+}
+
+func (f *fakeEmailProvider) Name() string {
+	return f.name
+}
+
+func (f *fakeEmailProvider) Send(context.Context, *resend.SendEmailRequest) (string, error) {
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.messageID, nil
+}
+
+func TestDeliver_FailsOverToSMTPWhileResendIsOpen(t *testing.T) {
+	resendCalls := 0
+	account := &resendAccount{
+		provider: &fakeEmailProvider{name: "resend"},
+		breaker:  fakeBreaker[string]{forcedErr: gobreaker.ErrOpenState, calls: &resendCalls},
+	}
+	smtp := &fakeEmailProvider{name: "smtp", messageID: "<id@posta.opencrafts.io>"}
+	es := &emailService{logger: testLogger(), fallback: smtp, fallbackBreaker: fakeBreaker[string]{}}
+
+	attempts := es.deliver(context.Background(), account, &resend.SendEmailRequest{
+		From: "noreply@posta.opencrafts.io",
+		To:   []string{"amina@example.com"},
+		Text: "hi",
+	})
+
+	require.Len(t, attempts, 2)
+	assert.Equal(t, "resend", attempts[0].provider)
+	assert.Equal(t, "circuit_open", attempts[0].status)
+	assert.Equal(t, "smtp", attempts[1].provider)
+	assert.Equal(t, "sent", attempts[1].status)
+	assert.Equal(t, "<id@posta.opencrafts.io>", attempts[1].messageID)
+	assert.NoError(t, attempts[1].err)
+	assert.Equal(t, 1, resendCalls)
+	assert.Equal(t, 1, smtp.calls)
+}
+
+func TestDeliver_NoFailover(t *testing.T) {
+	tests := []struct {
+		name        string
+		resendErr   error
+		breakerErr  error
+		noFallback  bool
+		req         *resend.SendEmailRequest
+		wantStatus  string
+		wantMessage string
+	}{
+		{
+			name:        "resend sent",
+			req:         &resend.SendEmailRequest{Text: "hi"},
+			wantStatus:  "sent",
+			wantMessage: "re_123",
+		},
+		{
+			// Resend may have got the email; sending it again elsewhere
+			// could deliver it twice.
+			name:       "resend failed",
+			resendErr:  errors.New("rate limited"),
+			req:        &resend.SendEmailRequest{Text: "hi"},
+			wantStatus: "failed",
+		},
+		{
+			name:       "template only resend can render",
+			breakerErr: gobreaker.ErrOpenState,
+			req:        &resend.SendEmailRequest{Template: &resend.EmailTemplate{Id: "welcome"}},
+			wantStatus: "circuit_open",
+		},
+		{
+			name:       "no smtp configured",
+			breakerErr: gobreaker.ErrOpenState,
+			noFallback: true,
+			req:        &resend.SendEmailRequest{Text: "hi"},
+			wantStatus: "circuit_open",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &resendAccount{
+				provider: &fakeEmailProvider{name: "resend", messageID: "re_123", err: tt.resendErr},
+				breaker:  fakeBreaker[string]{forcedErr: tt.breakerErr},
+			}
+			smtp := &fakeEmailProvider{name: "smtp"}
+			es := &emailService{logger: testLogger(), fallbackBreaker: fakeBreaker[string]{}}
+			if !tt.noFallback {
+				es.fallback = smtp
+			}
+
+			attempts := es.deliver(context.Background(), account, tt.req)
+
+			require.Len(t, attempts, 1)
+			assert.Equal(t, "resend", attempts[0].provider)
+			assert.Equal(t, tt.wantStatus, attempts[0].status)
+			assert.Equal(t, tt.wantMessage, attempts[0].messageID)
+			assert.Zero(t, smtp.calls)
+		})
+	}
+}
+
+func TestWithoutOptedOutRecipients_DropsOptedOutAddresses(t *testing.T) {
+	var checked repository.ListOptedOutEmailAddressesParams
+	repo := &fakeQuerier{
+		listOptedOutEmailAddresses: func(_ context.Context, arg repository.ListOptedOutEmailAddressesParams) ([]string, error) {
+			checked = arg
+			return []string{"amina@example.com", "bcc@example.com"}, nil
+		},
+	}
+	req := &resend.SendEmailRequest{
+		To:  []string{"Amina <Amina@Example.com>", "otieno@example.com"},
+		Cc:  []string{"cc@example.com"},
+		Bcc: []string{"bcc@example.com"},
+	}
+
+	removed, err := withoutOptedOutRecipients(context.Background(), repo, req, stringPtr("marketing"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "marketing", checked.NotificationType)
+	assert.Equal(t,
+		[]string{"amina@example.com", "otieno@example.com", "cc@example.com", "bcc@example.com"},
+		checked.Addresses,
+	)
+	assert.Equal(t, []string{"Amina <Amina@Example.com>", "bcc@example.com"}, removed)
+	assert.Equal(t, []string{"otieno@example.com"}, req.To)
+	assert.Equal(t, []string{"cc@example.com"}, req.Cc)
+	assert.Empty(t, req.Bcc)
+}
+
+func TestEmailToResendEmailRequest_NonTransactionalNeedsUnsubscribeLinks(t *testing.T) {
+	es := &emailService{}
+
+	_, err := es.emailToResendEmailRequest(Email{
+		Transactional: boolPtr(false),
+		FromAddress:   "sender@posta.opencrafts.io",
+		ToAddresses:   []string{"recipient@example.com"},
+		Subject:       "This week at Sherehe",
+		BodyText:      stringPtr("Hello"),
+	}, []string{"posta.opencrafts.io"}, "io.opencrafts.sherehe")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsubscribe links are not configured")
+}
+
+func TestWithoutUnsubscribedRecipients_DropsUnsubscribedAddresses(t *testing.T) {
+	repo := &fakeQuerier{
+		listUnsubscribedAddresses: func(_ context.Context, arg repository.ListUnsubscribedAddressesParams) ([]string, error) {
+			assert.Equal(t, "io.opencrafts.sherehe", arg.ServiceID)
+			assert.Equal(t, []string{"amina@example.com"}, arg.Addresses)
+			return []string{"amina@example.com"}, nil
+		},
+	}
+	req := &resend.SendEmailRequest{To: []string{"Amina <amina@example.com>"}}
+
+	removed, err := withoutUnsubscribedRecipients(context.Background(), repo, "io.opencrafts.sherehe", req)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Amina <amina@example.com>"}, removed)
+	assert.Empty(t, req.To)
+}
+
+func TestResolveUserEmails(t *testing.T) {
+	aminaID, barakaID, unknownID := uuid.New(), uuid.New(), uuid.New()
+	repo := &fakeQuerier{
+		getUserByID: func(_ context.Context, id uuid.UUID) (repository.User, error) {
+			switch id {
+			case aminaID:
+				return repository.User{ID: id, Email: "amina@example.com"}, nil
+			case barakaID:
+				return repository.User{ID: id}, nil
+			}
+			return repository.User{}, pgx.ErrNoRows
+		},
+	}
+
+	resolved, err := resolveUserEmails(context.Background(), repo, []uuid.UUID{aminaID, aminaID}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amina@example.com"}, resolved)
+
+	// The directory may not have caught up with a new user yet.
+	_, err = resolveUserEmails(context.Background(), repo, []uuid.UUID{aminaID, unknownID}, unknownUserAttempts-1)
+	assert.ErrorIs(t, err, ErrUnknownRecipientUser)
+	assert.False(t, resilience.IsPermanent(err))
+
+	_, err = resolveUserEmails(context.Background(), repo, []uuid.UUID{aminaID, unknownID}, unknownUserAttempts)
+	assert.ErrorIs(t, err, ErrUnknownRecipientUser)
+	assert.ErrorContains(t, err, unknownID.String())
+	assert.True(t, resilience.IsPermanent(err))
+
+	_, err = resolveUserEmails(context.Background(), repo, []uuid.UUID{barakaID}, 1)
+	assert.ErrorIs(t, err, ErrRecipientUserUnreachable)
+	assert.True(t, resilience.IsPermanent(err))
+}
+
+func TestAppendNewAddresses_SkipsAddressesAlreadyPresent(t *testing.T) {
+	to := appendNewAddresses(
+		[]string{"Amina <Amina@example.com>"},
+		[]string{"amina@example.com", "baraka@example.com"},
+	)
+
+	assert.Equal(t, []string{"Amina <Amina@example.com>", "baraka@example.com"}, to)
+}
+
+var testUnsubscribeLinks = UnsubscribeLinks{
+	BaseURL: "https://gossip.opencrafts.io/",
+	Secret:  "unsubscribe-secret",
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 // Helper function to create string pointers
 func stringPtr(s string) *string {
 	return &s
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/resend/resend-go/v3"
+)
+
+// Why an address is on the suppression list, as recorded in
+// email_suppressions.reason.
+const (
+	SuppressionReasonHardBounce = "hard_bounce"
+	SuppressionReasonComplaint  = "complaint"
+	SuppressionReasonManual     = "manual"
+)
+
+// suppressionWebhookActor is recorded as the creator of suppressions added
+// from Resend delivery events.
+const suppressionWebhookActor = "resend-webhook"
+
+var (
+	// ErrEmailSuppressionNotFound is returned when an address isn't on the
+	// suppression list.
+	ErrEmailSuppressionNotFound = errors.New("address is not suppressed")
+	// ErrEmailSuppressionExists is returned when adding an address that is
+	// already on the suppression list.
+	ErrEmailSuppressionExists = errors.New("address is already suppressed")
+	// ErrInvalidSuppressionAddress is returned when adding an address that
+	// isn't an email address.
+	ErrInvalidSuppressionAddress = errors.New("address must be an email address")
+	// ErrNoDeliverableRecipients is returned when every recipient of an
+	// email, or every to recipient, is on the suppression list.
+	ErrNoDeliverableRecipients = errors.New("every to recipient is on the suppression list")
+)
+
+// EmailSuppressionService manages the addresses no email is sent to. Hard
+// bounces and complaints are added by EmailService as Resend reports them;
+// this is how operators see the list, add to it and take addresses off.
+type EmailSuppressionService interface {
+	// List returns up to limit suppressions, the most recent first. A limit
+	// of zero means DefaultPageSize.
+	List(ctx context.Context, limit int32) ([]repository.EmailSuppression, error)
+	Get(ctx context.Context, address string) (repository.EmailSuppression, error)
+	// Add suppresses address on actor's say-so.
+	Add(ctx context.Context, actor, address string, note *string) (repository.EmailSuppression, error)
+	// Remove lets email go to address again.
+	Remove(ctx context.Context, actor, address string) (repository.EmailSuppression, error)
+}
+
+type emailSuppressionService struct {
+	repo   repository.Querier
+	logger *slog.Logger
+}
+
+func NewEmailSuppressionService(
+	repo repository.Querier,
+	logger *slog.Logger,
+) EmailSuppressionService {
+	return &emailSuppressionService{repo: repo, logger: logger}
+}
+
+func (ss *emailSuppressionService) List(
+	ctx context.Context,
+	limit int32,
+) ([]repository.EmailSuppression, error) {
+	suppressions, err := ss.repo.ListEmailSuppressions(ctx, clampPageSize(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list email suppressions: %w", err)
+	}
+	return suppressions, nil
+}
+
+func (ss *emailSuppressionService) Get(
+	ctx context.Context,
+	address string,
+) (repository.EmailSuppression, error) {
+	suppression, err := ss.repo.GetEmailSuppression(ctx, bareAddress(address))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return suppression, ErrEmailSuppressionNotFound
+	} else if err != nil {
+		return suppression, fmt.Errorf("failed to get email suppression: %w", err)
+	}
+	return suppression, nil
+}
+
+func (ss *emailSuppressionService) Add(
+	ctx context.Context,
+	actor, address string,
+	note *string,
+) (repository.EmailSuppression, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil {
+		return repository.EmailSuppression{}, ErrInvalidSuppressionAddress
+	}
+
+	suppression, err := ss.repo.CreateEmailSuppression(ctx, repository.CreateEmailSuppressionParams{
+		Address:   parsed.Address,
+		Reason:    SuppressionReasonManual,
+		Note:      note,
+		CreatedBy: actor,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return suppression, ErrEmailSuppressionExists
+	} else if err != nil {
+		return suppression, fmt.Errorf("failed to add email suppression: %w", err)
+	}
+
+	ss.logger.Info("email address suppressed",
+		"reason", SuppressionReasonManual,
+		"actor", actor,
+	)
+	return suppression, nil
+}
+
+func (ss *emailSuppressionService) Remove(
+	ctx context.Context,
+	actor, address string,
+) (repository.EmailSuppression, error) {
+	suppression, err := ss.repo.DeleteEmailSuppression(ctx, bareAddress(address))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return suppression, ErrEmailSuppressionNotFound
+	} else if err != nil {
+		return suppression, fmt.Errorf("failed to remove email suppression: %w", err)
+	}
+
+	ss.logger.Info("email address unsuppressed",
+		"reason", suppression.Reason,
+		"actor", actor,
+	)
+	return suppression, nil
+}
+
+// withoutSuppressedRecipients removes from req's to, cc and bcc every
+// address on the suppression list, returning the addresses it removed.
+func withoutSuppressedRecipients(
+	ctx context.Context,
+	repo repository.Querier,
+	req *resend.SendEmailRequest,
+) ([]string, error) {
+	suppressed, err := repo.ListSuppressedAddresses(ctx, bareAddresses(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suppression list: %w", err)
+	}
+	return removeRecipients(req, suppressed), nil
+}
+
+// noDeliverableRecipients reports whether an email with req's recipients, what is
+// left once the suppression list has been applied, can't be sent: it has
+// no recipients left or, addressed to someone before, no to address left.
+// Its cc and bcc copies alone would go to nobody in particular, and Resend
+// refuses an email without a to address anyway.
+func noDeliverableRecipients(req *resend.SendEmailRequest, addressed bool) bool {
+	if addressed && len(req.To) == 0 {
+		return true
+	}
+	return len(req.To)+len(req.Cc)+len(req.Bcc) == 0
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/resend/resend-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailSuppressionAdd_RecordsManualEntry(t *testing.T) {
+	var got repository.CreateEmailSuppressionParams
+	repo := &fakeQuerier{
+		createEmailSuppression: func(_ context.Context, arg repository.CreateEmailSuppressionParams) (repository.EmailSuppression, error) {
+			got = arg
+			return repository.EmailSuppression{Address: arg.Address, Reason: arg.Reason}, nil
+		},
+	}
+	ss := NewEmailSuppressionService(repo, testLogger())
+
+	_, err := ss.Add(context.Background(), "amina@opencrafts.io", " Amina <amina@example.com> ", strPtr("asked by phone"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "amina@example.com", got.Address)
+	assert.Equal(t, SuppressionReasonManual, got.Reason)
+	assert.Equal(t, "amina@opencrafts.io", got.CreatedBy)
+	assert.Equal(t, "asked by phone", *got.Note)
+}
+
+func TestEmailSuppressionAdd_RejectsInvalidAddress(t *testing.T) {
+	ss := NewEmailSuppressionService(&fakeQuerier{}, testLogger())
+
+	_, err := ss.Add(context.Background(), "admin-api", "not an address", nil)
+
+	assert.ErrorIs(t, err, ErrInvalidSuppressionAddress)
+}
+
+func TestEmailSuppressionAdd_AlreadySuppressed(t *testing.T) {
+	repo := &fakeQuerier{
+		createEmailSuppression: func(context.Context, repository.CreateEmailSuppressionParams) (repository.EmailSuppression, error) {
+			return repository.EmailSuppression{}, pgx.ErrNoRows
+		},
+	}
+	ss := NewEmailSuppressionService(repo, testLogger())
+
+	_, err := ss.Add(context.Background(), "admin-api", "amina@example.com", nil)
+
+	assert.ErrorIs(t, err, ErrEmailSuppressionExists)
+}
+
+func TestEmailSuppressionRemove_NotSuppressed(t *testing.T) {
+	repo := &fakeQuerier{
+		deleteEmailSuppression: func(_ context.Context, address string) (repository.EmailSuppression, error) {
+			assert.Equal(t, "amina@example.com", address)
+			return repository.EmailSuppression{}, pgx.ErrNoRows
+		},
+	}
+	ss := NewEmailSuppressionService(repo, testLogger())
+
+	_, err := ss.Remove(context.Background(), "admin-api", "Amina@Example.com")
+
+	assert.ErrorIs(t, err, ErrEmailSuppressionNotFound)
+}
+
+func TestWithoutSuppressedRecipients_DropsSuppressedAddresses(t *testing.T) {
+	repo := &fakeQuerier{
+		listSuppressedAddresses: func(_ context.Context, addresses []string) ([]string, error) {
+			assert.Equal(t, []string{"amina@example.com", "baraka@example.com", "chege@example.com"}, addresses)
+			return []string{"amina@example.com", "chege@example.com"}, nil
+		},
+	}
+	req := &resend.SendEmailRequest{
+		To:  []string{"Amina <amina@example.com>"},
+		Cc:  []string{"baraka@example.com"},
+		Bcc: []string{"chege@example.com"},
+	}
+
+	removed, err := withoutSuppressedRecipients(context.Background(), repo, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Amina <amina@example.com>", "chege@example.com"}, removed)
+	assert.Empty(t, req.To)
+	assert.Equal(t, []string{"baraka@example.com"}, req.Cc)
+	assert.Empty(t, req.Bcc)
+	assert.True(t, noDeliverableRecipients(req, true), "the cc copy alone is not sent")
+}
+
+func TestResendWebhookEvent_SuppressionReason(t *testing.T) {
+	for name, tt := range map[string]struct {
+		event ResendWebhookEvent
+		want  string
+	}{
+		"complaint": {
+			event: ResendWebhookEvent{Type: resend.EventEmailComplained},
+			want:  SuppressionReasonComplaint,
+		},
+		"hard bounce": {
+			event: ResendWebhookEvent{
+				Type: resend.EventEmailBounced,
+				Data: ResendWebhookEventData{Bounce: &ResendBounce{Type: "Permanent"}},
+			},
+			want: SuppressionReasonHardBounce,
+		},
+		"soft bounce": {
+			event: ResendWebhookEvent{
+				Type: resend.EventEmailBounced,
+				Data: ResendWebhookEventData{Bounce: &ResendBounce{Type: "Transient"}},
+			},
+		},
+		"bounce without details": {
+			event: ResendWebhookEvent{Type: resend.EventEmailBounced},
+		},
+		"delivered": {
+			event: ResendWebhookEvent{Type: resend.EventEmailDelivered},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.event.suppressionReason())
+		})
+	}
+}
+
+func TestNoDeliverableRecipients(t *testing.T) {
+	for name, tt := range map[string]struct {
+		req       resend.SendEmailRequest
+		addressed bool
+		want      bool
+	}{
+		"to left":                 {resend.SendEmailRequest{To: []string{"amina@example.com"}}, true, false},
+		"nobody left":             {resend.SendEmailRequest{}, true, true},
+		"only copies left":        {resend.SendEmailRequest{Cc: []string{"baraka@example.com"}}, true, true},
+		"copies only to begin":    {resend.SendEmailRequest{Bcc: []string{"chege@example.com"}}, false, false},
+		"nobody to begin or left": {resend.SendEmailRequest{}, false, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, noDeliverableRecipients(&tt.req, tt.addressed))
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+)
+
+// healthCheckTimeout bounds each readiness check, so one hung dependency
+// can't hold up the probe past the orchestrator's own timeout.
+const healthCheckTimeout = 2 * time.Second
+
+// Readiness and component statuses reported by HealthService.
+const (
+	HealthStatusReady    = "ready"
+	HealthStatusNotReady = "not_ready"
+	// HealthStatusDegraded is a ready service with a non-critical
+	// component failing.
+	HealthStatusDegraded = "degraded"
+
+	ComponentStatusOK      = "ok"
+	ComponentStatusFailing = "failing"
+)
+
+// HealthCheck is one component of the readiness report.
+type HealthCheck struct {
+	Name string
+	// Critical checks decide readiness. A failing non-critical check only
+	// marks the service degraded.
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+// ComponentHealth is the outcome of one HealthCheck.
+type ComponentHealth struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+}
+
+// HealthReport is the readiness of the service and of each component.
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// Ready reports whether the service should be sent traffic.
+func (r HealthReport) Ready() bool {
+	return r.Status != HealthStatusNotReady
+}
+
+type HealthService interface {
+	// Ready runs every check concurrently and reports the outcome.
+	Ready(ctx context.Context) HealthReport
+}
+
+type healthService struct {
+	checks []HealthCheck
+	logger *slog.Logger
+}
+
+func NewHealthService(checks []HealthCheck, logger *slog.Logger) HealthService {
+	return &healthService{checks: checks, logger: logger}
+}
+
+func (hs *healthService) Ready(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Status:     HealthStatusReady,
+		Components: make(map[string]ComponentHealth, len(hs.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range hs.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			component := ComponentHealth{Status: ComponentStatusOK, Critical: check.Critical}
+			if err := check.Check(checkCtx); err != nil {
+				component.Status = ComponentStatusFailing
+				component.Error = err.Error()
+				hs.logger.Warn("health check failing",
+					"component", check.Name,
+					"critical", check.Critical,
+					"error", err,
+				)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[check.Name] = component
+			switch {
+			case component.Status == ComponentStatusOK:
+			case check.Critical:
+				report.Status = HealthStatusNotReady
+			case report.Status == HealthStatusReady:
+				report.Status = HealthStatusDegraded
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// RabbitMQHealthCheck fails while the broker connection is down, including
+// while it is being re-established.
+func RabbitMQHealthCheck(conn broker.Connection) HealthCheck {
+	return HealthCheck{
+		Name:     "rabbitmq",
+		Critical: true,
+		Check: func(context.Context) error {
+			if conn.IsClosed() {
+				return errors.New("connection is closed")
+			}
+			return nil
+		},
+	}
+}
+
+// pinger is the part of *pgxpool.Pool DatabaseHealthCheck needs.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// DatabaseHealthCheck fails unless Postgres answers a ping through the
+// pool. The ping error is logged rather than reported: it names the
+// database host and user, and /readyz is unauthenticated.
+func DatabaseHealthCheck(pool pinger, logger *slog.Logger) HealthCheck {
+	return HealthCheck{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			if err := pool.Ping(ctx); err != nil {
+				logger.Warn("database ping failed", "error", err)
+				return errors.New("ping failed")
+			}
+			return nil
+		},
+	}
+}
+
+// MigrationsHealthCheck fails until the database schema is at the latest
+// migration this build embeds. versions returns the database's version and
+// that latest one.
+func MigrationsHealthCheck(
+	versions func(ctx context.Context) (current, latest int64, err error),
+	logger *slog.Logger,
+) HealthCheck {
+	return HealthCheck{
+		Name:     "migrations",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			current, latest, err := versions(ctx)
+			if err != nil {
+				logger.Warn("failed to read migration status", "error", err)
+				return errors.New("failed to read migration status")
+			}
+			if current < latest {
+				return fmt.Errorf("database is at version %d, expected %d", current, latest)
+			}
+			return nil
+		},
+	}
+}
+
+// ProviderHealthCheck reports the provider circuit breakers that are open.
+// It is not critical: taking the service out of rotation wouldn't bring a
+// provider back, and failed sends are retried anyway.
+func ProviderHealthCheck() HealthCheck {
+	return HealthCheck{
+		Name: "providers",
+		Check: func(context.Context) error {
+			if open := resilience.OpenBreakers(); len(open) > 0 {
+				return fmt.Errorf("circuit open: %s", strings.Join(open, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// ConsumersHealthCheck fails once any background consumer has stopped.
+// stopped returns the names of those that have.
+func ConsumersHealthCheck(stopped func() []string) HealthCheck {
+	return HealthCheck{
+		Name:     "consumers",
+		Critical: true,
+		Check: func(context.Context) error {
+			if names := stopped(); len(names) > 0 {
+				return fmt.Errorf("stopped: %s", strings.Join(names, ", "))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixedCheck(name string, critical bool, err error) HealthCheck {
+	return HealthCheck{
+		Name:     name,
+		Critical: critical,
+		Check:    func(context.Context) error { return err },
+	}
+}
+
+func TestHealthReady_AllPassing(t *testing.T) {
+	hs := NewHealthService([]HealthCheck{
+		fixedCheck("rabbitmq", true, nil),
+		fixedCheck("providers", false, nil),
+	}, testLogger())
+
+	report := hs.Ready(context.Background())
+
+	assert.True(t, report.Ready())
+	assert.Equal(t, HealthStatusReady, report.Status)
+	assert.Equal(t, ComponentHealth{Status: ComponentStatusOK, Critical: true}, report.Components["rabbitmq"])
+}
+
+func TestHealthReady_CriticalFailureIsNotReady(t *testing.T) {
+	hs := NewHealthService([]HealthCheck{
+		fixedCheck("rabbitmq", true, errors.New("connection is closed")),
+		fixedCheck("providers", false, errors.New("circuit open: resend")),
+	}, testLogger())
+
+	report := hs.Ready(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, HealthStatusNotReady, report.Status)
+	assert.Equal(t, ComponentHealth{
+		Status:   ComponentStatusFailing,
+		Critical: true,
+		Error:    "connection is closed",
+	}, report.Components["rabbitmq"])
+	assert.Equal(t, ComponentStatusFailing, report.Components["providers"].Status)
+}
+
+func TestHealthReady_NonCriticalFailureIsDegraded(t *testing.T) {
+	hs := NewHealthService([]HealthCheck{
+		fixedCheck("rabbitmq", true, nil),
+		fixedCheck("providers", false, errors.New("circuit open: resend")),
+	}, testLogger())
+
+	report := hs.Ready(context.Background())
+
+	assert.True(t, report.Ready())
+	assert.Equal(t, HealthStatusDegraded, report.Status)
+}
+
+func TestHealthReady_HungCheckTimesOut(t *testing.T) {
+	hs := NewHealthService([]HealthCheck{{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}}, testLogger())
+
+	report := hs.Ready(context.Background())
+
+	require.False(t, report.Ready())
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["database"].Error)
+}
+
+func TestMigrationsHealthCheck_FailsBehindLatest(t *testing.T) {
+	versions := func(current, latest int64) func(context.Context) (int64, int64, error) {
+		return func(context.Context) (int64, int64, error) { return current, latest, nil }
+	}
+
+	assert.NoError(t, MigrationsHealthCheck(versions(7, 7), testLogger()).Check(context.Background()))
+	assert.EqualError(t,
+		MigrationsHealthCheck(versions(5, 7), testLogger()).Check(context.Background()),
+		"database is at version 5, expected 7")
+}
+
+func TestConsumersHealthCheck_NamesStoppedConsumers(t *testing.T) {
+	stopped := []string{}
+	hc := ConsumersHealthCheck(func() []string { return stopped })
+
+	assert.NoError(t, hc.Check(context.Background()))
+
+	stopped = []string{"email_consumer", "user_consumer"}
+	assert.EqualError(t, hc.Check(context.Background()), "stopped: email_consumer, user_consumer")
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+)
+
+const (
+	// DefaultPageSize is the page size used when a listing doesn't ask for
+	// one.
+	DefaultPageSize int32 = 50
+	// MaxPageSize caps how many rows a single page may return.
+	MaxPageSize int32 = 200
+)
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor wasn't one this
+	// service handed out.
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+	// ErrInvalidNotificationFilter is returned unless a notification
+	// listing is filtered by exactly one field.
+	ErrInvalidNotificationFilter = errors.New(
+		"filter by exactly one of target_user_id, external_user_id, notification_type or status",
+	)
+)
+
+// Page is one page of a keyset-paginated listing. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NotificationFilter selects the notifications to list. Exactly one field
+// must be set.
+type NotificationFilter struct {
+	TargetUserID     *uuid.UUID
+	ExternalUserID   string
+	NotificationType string
+	Status           string
+}
+
+// NotificationRecord is a stored notification as the admin API returns it.
+type NotificationRecord struct {
+	repository.Notification
+	// DeliveryTimeOfDay replaces the embedded pgtype.Time, which has no
+	// JSON form of its own.
+	DeliveryTimeOfDay *string `json:"delivery_time_of_day"`
+}
+
+// EmailRequestDetail is an email request together with every attempt to
+// send it and every delivery event Resend reported for those attempts.
+type EmailRequestDetail struct {
+	repository.EmailRequest
+	Dispatches     []repository.EmailDispatch      `json:"dispatches"`
+	DeliveryEvents []repository.EmailDeliveryEvent `json:"delivery_events"`
+}
+
+// NotifyRequestDetail is a notify request together with the push, emails
+// and in-app notifications it sent.
+type NotifyRequestDetail struct {
+	repository.NotifyRequest
+	Notifications      []NotificationRecord           `json:"notifications"`
+	EmailRequests      []repository.EmailRequest      `json:"email_requests"`
+	InAppNotifications []repository.InAppNotification `json:"in_app_notifications"`
+}
+
+// HistoryService answers read-only questions about what gossip-monger has
+// sent, for support staff and operators.
+type HistoryService interface {
+	ListNotifications(
+		ctx context.Context,
+		filter NotificationFilter,
+		cursor string,
+		pageSize int32,
+	) (Page[NotificationRecord], error)
+	ListEmailRequests(
+		ctx context.Context,
+		serviceID string,
+		cursor string,
+		pageSize int32,
+	) (Page[repository.EmailRequest], error)
+	GetEmailRequest(ctx context.Context, id uuid.UUID) (EmailRequestDetail, error)
+	GetNotifyRequest(ctx context.Context, id uuid.UUID) (NotifyRequestDetail, error)
+}
+
+type historyService struct {
+	repo   repository.Querier
+	logger *slog.Logger
+}
+
+func NewHistoryService(repo repository.Querier, logger *slog.Logger) HistoryService {
+	return &historyService{repo: repo, logger: logger}
+}
+
+func (hs *historyService) ListNotifications(
+	ctx context.Context,
+	filter NotificationFilter,
+	cursor string,
+	pageSize int32,
+) (Page[NotificationRecord], error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return Page[NotificationRecord]{}, err
+	}
+	// One extra row tells us whether there is a next page.
+	limit := clampPageSize(pageSize) + 1
+	cursorCreatedAt := pgtype.Timestamp{Time: after.at, Valid: after.valid}
+	cursorID := pgtype.UUID{Bytes: after.id, Valid: after.valid}
+
+	var rows []repository.Notification
+	switch {
+	case filter.count() != 1:
+		return Page[NotificationRecord]{}, ErrInvalidNotificationFilter
+	case filter.TargetUserID != nil:
+		rows, err = hs.repo.GetNotificationsByTargetUser(ctx, repository.GetNotificationsByTargetUserParams{
+			TargetUserID:    pgtype.UUID{Bytes: *filter.TargetUserID, Valid: true},
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        limit,
+		})
+	case filter.ExternalUserID != "":
+		rows, err = hs.repo.GetNotificationsByExternalUserID(ctx, repository.GetNotificationsByExternalUserIDParams{
+			ExternalUserID:  filter.ExternalUserID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        limit,
+		})
+	case filter.NotificationType != "":
+		rows, err = hs.repo.GetNotificationsByType(ctx, repository.GetNotificationsByTypeParams{
+			NotificationType: &filter.NotificationType,
+			CursorCreatedAt:  cursorCreatedAt,
+			CursorID:         cursorID,
+			PageSize:         limit,
+		})
+	default:
+		rows, err = hs.repo.GetNotificationsByStatus(ctx, repository.GetNotificationsByStatusParams{
+			Status:          &filter.Status,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        limit,
+		})
+	}
+	if err != nil {
+		return Page[NotificationRecord]{}, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	rows, next := paginate(rows, limit-1, func(n repository.Notification) string {
+		return encodeCursor(n.CreatedAt.Time, n.ID)
+	})
+
+	return Page[NotificationRecord]{Items: notificationRecords(rows), NextCursor: next}, nil
+}
+
+func (hs *historyService) ListEmailRequests(
+	ctx context.Context,
+	serviceID string,
+	cursor string,
+	pageSize int32,
+) (Page[repository.EmailRequest], error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return Page[repository.EmailRequest]{}, err
+	}
+	limit := clampPageSize(pageSize) + 1
+
+	params := repository.GetEmailRequestByServiceParams{
+		ServiceID: serviceID,
+		CursorID:  pgtype.UUID{Bytes: after.id, Valid: after.valid},
+		PageSize:  limit,
+	}
+	if after.valid {
+		params.CursorReceivedAt = &after.at
+	}
+
+	rows, err := hs.repo.GetEmailRequestByService(ctx, params)
+	if err != nil {
+		return Page[repository.EmailRequest]{}, fmt.Errorf("failed to list email requests: %w", err)
+	}
+
+	rows, next := paginate(rows, limit-1, func(r repository.EmailRequest) string {
+		return encodeCursor(r.ReceivedAt.Time, r.ID)
+	})
+	return Page[repository.EmailRequest]{Items: rows, NextCursor: next}, nil
+}
+
+func (hs *historyService) GetEmailRequest(
+	ctx context.Context,
+	id uuid.UUID,
+) (EmailRequestDetail, error) {
+	request, err := hs.repo.GetEmailRequestByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return EmailRequestDetail{}, fmt.Errorf("%w: %s", ErrEmailRequestNotFound, id)
+	}
+	if err != nil {
+		return EmailRequestDetail{}, fmt.Errorf("failed to get email request: %w", err)
+	}
+
+	dispatches, err := hs.repo.GetEmailDispatchesByRequestID(ctx, id)
+	if err != nil {
+		return EmailRequestDetail{}, fmt.Errorf("failed to get email dispatches: %w", err)
+	}
+
+	events, err := hs.repo.GetEmailDeliveryEventsByRequestID(ctx, id)
+	if err != nil {
+		return EmailRequestDetail{}, fmt.Errorf("failed to get email delivery events: %w", err)
+	}
+
+	return EmailRequestDetail{
+		EmailRequest:   request,
+		Dispatches:     dispatches,
+		DeliveryEvents: events,
+	}, nil
+}
+
+func (hs *historyService) GetNotifyRequest(
+	ctx context.Context,
+	id uuid.UUID,
+) (NotifyRequestDetail, error) {
+	request, err := hs.repo.GetNotifyRequestByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotifyRequestDetail{}, fmt.Errorf("%w: %s", ErrNotifyRequestNotFound, id)
+	}
+	if err != nil {
+		return NotifyRequestDetail{}, fmt.Errorf("failed to get notify request: %w", err)
+	}
+
+	parentID := pgtype.UUID{Bytes: id, Valid: true}
+	pushes, err := hs.repo.GetNotificationsByNotifyRequestID(ctx, parentID)
+	if err != nil {
+		return NotifyRequestDetail{}, fmt.Errorf("failed to get notify pushes: %w", err)
+	}
+
+	emails, err := hs.repo.GetEmailRequestsByNotifyRequestID(ctx, parentID)
+	if err != nil {
+		return NotifyRequestDetail{}, fmt.Errorf("failed to get notify emails: %w", err)
+	}
+
+	inApp, err := hs.repo.GetInAppNotificationsByNotifyRequestID(ctx, id)
+	if err != nil {
+		return NotifyRequestDetail{}, fmt.Errorf("failed to get notify in-app notifications: %w", err)
+	}
+
+	return NotifyRequestDetail{
+		NotifyRequest:      request,
+		Notifications:      notificationRecords(pushes),
+		EmailRequests:      emails,
+		InAppNotifications: inApp,
+	}, nil
+}
+
+// notificationRecords converts stored notifications to the form the admin
+// API returns.
+func notificationRecords(rows []repository.Notification) []NotificationRecord {
+	records := make([]NotificationRecord, 0, len(rows))
+	for _, n := range rows {
+		record := NotificationRecord{Notification: n}
+		if n.DeliveryTimeOfDay.Valid {
+			timeOfDay := formatTimeOfDay(n.DeliveryTimeOfDay)
+			record.DeliveryTimeOfDay = &timeOfDay
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// count returns how many of the filter's fields are set.
+func (f NotificationFilter) count() int {
+	n := 0
+	for _, set := range []bool{
+		f.TargetUserID != nil,
+		f.ExternalUserID != "",
+		f.NotificationType != "",
+		f.Status != "",
+	} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+func clampPageSize(pageSize int32) int32 {
+	switch {
+	case pageSize <= 0:
+		return DefaultPageSize
+	case pageSize > MaxPageSize:
+		return MaxPageSize
+	default:
+		return pageSize
+	}
+}
+
+// paginate trims rows, fetched with one more than pageSize, to pageSize and
+// returns the cursor for the next page if there is one.
+func paginate[T any](rows []T, pageSize int32, cursorOf func(T) string) ([]T, string) {
+	if int32(len(rows)) <= pageSize {
+		return rows, ""
+	}
+	rows = rows[:pageSize]
+	return rows, cursorOf(rows[len(rows)-1])
+}
+
+// keysetCursor is the position after which the next page starts: the
+// timestamp and id of the last row already returned.
+type keysetCursor struct {
+	at    time.Time
+	id    uuid.UUID
+	valid bool
+}
+
+// encodeCursor renders a keyset position as an opaque, URL-safe token.
+func encodeCursor(at time.Time, id uuid.UUID) string {
+	raw := at.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a token from encodeCursor. An empty token means the
+// first page.
+func decodeCursor(cursor string) (keysetCursor, error) {
+	if cursor == "" {
+		return keysetCursor{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return keysetCursor{}, ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return keysetCursor{}, ErrInvalidCursor
+	}
+
+	parsedAt, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return keysetCursor{}, ErrInvalidCursor
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return keysetCursor{}, ErrInvalidCursor
+	}
+	return keysetCursor{at: parsedAt, id: parsedID, valid: true}, nil
+}
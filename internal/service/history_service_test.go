@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func notificationAt(at time.Time) repository.Notification {
+	return repository.Notification{
+		ID:        uuid.New(),
+		CreatedAt: pgtype.Timestamp{Time: at, Valid: true},
+	}
+}
+
+func TestListNotifications_PagesThroughWithCursor(t *testing.T) {
+	base := time.Date(2026, 10, 16, 9, 0, 0, 123456000, time.UTC)
+	newest, middle, oldest := notificationAt(base), notificationAt(base.Add(-time.Minute)), notificationAt(base.Add(-2*time.Minute))
+
+	var calls []repository.GetNotificationsByExternalUserIDParams
+	repo := &fakeQuerier{
+		getNotificationsByExternalUserID: func(_ context.Context, arg repository.GetNotificationsByExternalUserIDParams) ([]repository.Notification, error) {
+			calls = append(calls, arg)
+			if !arg.CursorCreatedAt.Valid {
+				return []repository.Notification{newest, middle, oldest}, nil
+			}
+			return []repository.Notification{oldest}, nil
+		},
+	}
+	hs := NewHistoryService(repo, testLogger())
+	filter := NotificationFilter{ExternalUserID: "user-1"}
+
+	first, err := hs.ListNotifications(context.Background(), filter, "", 2)
+	require.NoError(t, err)
+	require.Len(t, first.Items, 2)
+	assert.Equal(t, newest.ID, first.Items[0].ID)
+	assert.Equal(t, middle.ID, first.Items[1].ID)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := hs.ListNotifications(context.Background(), filter, first.NextCursor, 2)
+	require.NoError(t, err)
+	require.Len(t, second.Items, 1)
+	assert.Empty(t, second.NextCursor, "the last page must not offer a next cursor")
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, int32(3), calls[0].PageSize, "one extra row is fetched to detect a next page")
+	assert.Equal(t, middle.CreatedAt.Time, calls[1].CursorCreatedAt.Time)
+	assert.Equal(t, pgtype.UUID{Bytes: middle.ID, Valid: true}, calls[1].CursorID)
+}
+
+func TestListNotifications_RequiresExactlyOneFilter(t *testing.T) {
+	hs := NewHistoryService(&fakeQuerier{}, testLogger())
+	userID := uuid.New()
+
+	for name, filter := range map[string]NotificationFilter{
+		"no filter":   {},
+		"two filters": {TargetUserID: &userID, Status: "sent"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := hs.ListNotifications(context.Background(), filter, "", 0)
+			assert.ErrorIs(t, err, ErrInvalidNotificationFilter)
+		})
+	}
+}
+
+func TestListNotifications_RejectsForgedCursor(t *testing.T) {
+	hs := NewHistoryService(&fakeQuerier{}, testLogger())
+
+	for _, cursor := range []string{"not base64!", "bm8tc2VwYXJhdG9y", encodeCursor(time.Now(), uuid.New())[:10]} {
+		_, err := hs.ListNotifications(context.Background(), NotificationFilter{Status: "sent"}, cursor, 0)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func TestGetEmailRequest_IncludesDispatchesAndEvents(t *testing.T) {
+	id := uuid.New()
+	dispatchID := uuid.New()
+	repo := &fakeQuerier{
+		getEmailRequestByID: func(_ context.Context, got uuid.UUID) (repository.EmailRequest, error) {
+			return repository.EmailRequest{ID: got, Status: "dispatched"}, nil
+		},
+		getEmailDispatchesByRequestID: func(_ context.Context, got uuid.UUID) ([]repository.EmailDispatch, error) {
+			assert.Equal(t, id, got)
+			return []repository.EmailDispatch{{ID: dispatchID, EmailRequestID: id}}, nil
+		},
+		getEmailDeliveryEventsByRequest: func(_ context.Context, got uuid.UUID) ([]repository.EmailDeliveryEvent, error) {
+			assert.Equal(t, id, got)
+			return []repository.EmailDeliveryEvent{{DispatchID: dispatchID, EventType: "email.delivered"}}, nil
+		},
+	}
+
+	detail, err := NewHistoryService(repo, testLogger()).GetEmailRequest(context.Background(), id)
+
+	require.NoError(t, err)
+	assert.Equal(t, id, detail.ID)
+	require.Len(t, detail.Dispatches, 1)
+	require.Len(t, detail.DeliveryEvents, 1)
+	assert.Equal(t, "email.delivered", detail.DeliveryEvents[0].EventType)
+}
+
+func TestGetEmailRequest_NotFound(t *testing.T) {
+	repo := &fakeQuerier{
+		getEmailRequestByID: func(context.Context, uuid.UUID) (repository.EmailRequest, error) {
+			return repository.EmailRequest{}, pgx.ErrNoRows
+		},
+	}
+
+	_, err := NewHistoryService(repo, testLogger()).GetEmailRequest(context.Background(), uuid.New())
+
+	assert.ErrorIs(t, err, ErrEmailRequestNotFound)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+)
+
+// ErrInAppNotificationNotFound is returned when a user refers to an in-app
+// notification that isn't in their inbox.
+var ErrInAppNotificationNotFound = errors.New("no in-app notification found")
+
+// InboxService serves each user's in-app notifications: those notify
+// requests sent on the in_app channel, less any the user opted out of.
+type InboxService interface {
+	// List returns userID's inbox, newest first, leaving out what they
+	// dismissed.
+	List(ctx context.Context, userID uuid.UUID, cursor string, pageSize int32) (Page[repository.InAppNotification], error)
+	// MarkRead marks one of userID's in-app notifications read. Once one
+	// is, the notify request it came from counts it as delivered.
+	MarkRead(ctx context.Context, userID, id uuid.UUID) (repository.InAppNotification, error)
+	// Dismiss takes one of userID's in-app notifications out of their
+	// inbox, marking it read if it wasn't.
+	Dismiss(ctx context.Context, userID, id uuid.UUID) (repository.InAppNotification, error)
+}
+
+type inboxService struct {
+	repo   repository.Querier
+	logger *slog.Logger
+}
+
+func NewInboxService(repo repository.Querier, logger *slog.Logger) InboxService {
+	return &inboxService{repo: repo, logger: logger}
+}
+
+func (is *inboxService) List(
+	ctx context.Context,
+	userID uuid.UUID,
+	cursor string,
+	pageSize int32,
+) (Page[repository.InAppNotification], error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return Page[repository.InAppNotification]{}, err
+	}
+	limit := clampPageSize(pageSize) + 1
+
+	params := repository.ListInboxNotificationsParams{
+		UserID:   userID,
+		CursorID: pgtype.UUID{Bytes: after.id, Valid: after.valid},
+		PageSize: limit,
+	}
+	if after.valid {
+		params.CursorCreatedAt = &after.at
+	}
+
+	rows, err := is.repo.ListInboxNotifications(ctx, params)
+	if err != nil {
+		return Page[repository.InAppNotification]{}, fmt.Errorf("failed to list inbox: %w", err)
+	}
+
+	rows, next := paginate(rows, limit-1, func(n repository.InAppNotification) string {
+		return encodeCursor(n.CreatedAt.Time, n.ID)
+	})
+	return Page[repository.InAppNotification]{Items: rows, NextCursor: next}, nil
+}
+
+func (is *inboxService) MarkRead(
+	ctx context.Context,
+	userID, id uuid.UUID,
+) (repository.InAppNotification, error) {
+	n, err := is.repo.MarkInAppNotificationRead(ctx, repository.MarkInAppNotificationReadParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.InAppNotification{}, fmt.Errorf("%w: %s", ErrInAppNotificationNotFound, id)
+	} else if err != nil {
+		return repository.InAppNotification{}, fmt.Errorf("failed to mark in-app notification read: %w", err)
+	}
+	return n, nil
+}
+
+func (is *inboxService) Dismiss(
+	ctx context.Context,
+	userID, id uuid.UUID,
+) (repository.InAppNotification, error) {
+	n, err := is.repo.DismissInAppNotification(ctx, repository.DismissInAppNotificationParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.InAppNotification{}, fmt.Errorf("%w: %s", ErrInAppNotificationNotFound, id)
+	} else if err != nil {
+		return repository.InAppNotification{}, fmt.Errorf("failed to dismiss in-app notification: %w", err)
+	}
+
+	is.logger.Info("in-app notification dismissed",
+		"in_app_notification_id", id,
+		"user_id", userID,
+	)
+	return n, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeInboxRepo answers the inbox queries from rows.
+type fakeInboxRepo struct {
+	repository.Querier
+	rows  []repository.InAppNotification
+	lists []repository.ListInboxNotificationsParams
+}
+
+func (f *fakeInboxRepo) ListInboxNotifications(
+	_ context.Context,
+	arg repository.ListInboxNotificationsParams,
+) ([]repository.InAppNotification, error) {
+	f.lists = append(f.lists, arg)
+	var page []repository.InAppNotification
+	for _, n := range f.rows {
+		if arg.CursorCreatedAt == nil || n.CreatedAt.Time.Before(*arg.CursorCreatedAt) {
+			page = append(page, n)
+		}
+	}
+	return page[:min(len(page), int(arg.PageSize))], nil
+}
+
+func (f *fakeInboxRepo) MarkInAppNotificationRead(
+	_ context.Context,
+	arg repository.MarkInAppNotificationReadParams,
+) (repository.InAppNotification, error) {
+	for i, n := range f.rows {
+		if n.ID == arg.ID && n.UserID == arg.UserID {
+			now := time.Now()
+			f.rows[i].ReadAt = &now
+			return f.rows[i], nil
+		}
+	}
+	return repository.InAppNotification{}, pgx.ErrNoRows
+}
+
+func inAppAt(userID uuid.UUID, at time.Time) repository.InAppNotification {
+	return repository.InAppNotification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Status:    "delivered",
+		CreatedAt: pgtype.Timestamptz{Time: at, Valid: true},
+	}
+}
+
+func TestInboxList_PagesThroughWithCursor(t *testing.T) {
+	userID := uuid.New()
+	base := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	repo := &fakeInboxRepo{rows: []repository.InAppNotification{
+		inAppAt(userID, base),
+		inAppAt(userID, base.Add(-time.Minute)),
+		inAppAt(userID, base.Add(-2*time.Minute)),
+	}}
+	is := NewInboxService(repo, testLogger())
+
+	first, err := is.List(context.Background(), userID, "", 2)
+	require.NoError(t, err)
+	require.Len(t, first.Items, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := is.List(context.Background(), userID, first.NextCursor, 2)
+	require.NoError(t, err)
+	require.Len(t, second.Items, 1)
+	assert.Equal(t, repo.rows[2].ID, second.Items[0].ID)
+	assert.Empty(t, second.NextCursor)
+
+	require.Len(t, repo.lists, 2)
+	assert.Equal(t, userID, repo.lists[0].UserID)
+	assert.Equal(t, int32(3), repo.lists[0].PageSize, "one extra row is fetched to detect a next page")
+}
+
+func TestInboxList_RejectsForeignCursor(t *testing.T) {
+	is := NewInboxService(&fakeInboxRepo{}, testLogger())
+
+	_, err := is.List(context.Background(), uuid.New(), "not-a-cursor", 0)
+
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestInboxMarkRead_OnlyTheUsersOwn(t *testing.T) {
+	userID := uuid.New()
+	repo := &fakeInboxRepo{rows: []repository.InAppNotification{inAppAt(userID, time.Now())}}
+	is := NewInboxService(repo, testLogger())
+
+	read, err := is.MarkRead(context.Background(), userID, repo.rows[0].ID)
+	require.NoError(t, err)
+	assert.NotNil(t, read.ReadAt)
+
+	_, err = is.MarkRead(context.Background(), uuid.New(), repo.rows[0].ID)
+	assert.ErrorIs(t, err, ErrInAppNotificationNotFound)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Channel policies of a notify.send event.
+const (
+	// NotifyPolicyAll sends on every channel.
+	NotifyPolicyAll = "all"
+	// NotifyPolicyFirstSuccess tries the channels in order and stops at
+	// the first one that accepts the notification.
+	NotifyPolicyFirstSuccess = "first_success"
+	// NotifyPolicyFallback sends on the first channel that accepts the
+	// notification, then moves on to the next one if it hasn't been
+	// delivered within FallbackAfterMinutes.
+	NotifyPolicyFallback = "fallback"
+)
+
+// Notify is one notification for a set of users, to be sent on one or
+// more channels. It says what to tell them; the push, email and in-app
+// notifications it becomes are built from it.
+type Notify struct {
+	// ToUserIDs are the Verisafe users to notify: push goes to them as
+	// external user ids, email to the address the user directory has
+	// for them, and in-app to their inbox.
+	ToUserIDs []uuid.UUID `json:"to_user_ids"`
+	// Channels are "push", "email" and "in_app", in the order they are
+	// tried.
+	Channels             []string `json:"channels"`
+	Policy               string   `json:"policy"`
+	FallbackAfterMinutes int      `json:"fallback_after_minutes"`
+	// NotificationType is checked against the users' preferences on
+	// each channel.
+	NotificationType *string `json:"notification_type"`
+	// Title is the push heading, the email subject and the in-app
+	// title; Body is the push content, the email's plain text and the
+	// in-app body.
+	Title string  `json:"title"`
+	Body  string  `json:"body"`
+	URL   *string `json:"url"`
+	// Email holds what only the email needs. It is required when
+	// Channels includes "email".
+	Email *NotifyEmail `json:"email"`
+}
+
+type NotifyEmail struct {
+	FromAddress   string  `json:"from_address"`
+	ReplyTo       *string `json:"reply_to"`
+	Transactional *bool   `json:"transactional"`
+	// BodyHtml, if set, is sent alongside the plain-text Body.
+	BodyHtml *string `json:"body_html"`
+}
+
+type NotifyEventMetadata struct {
+	EventType       string    `json:"event_type"`
+	Timestamp       time.Time `json:"timestamp"`
+	SourceServiceID string    `json:"source_service_id"`
+	RequestID       string    `json:"request_id"`
+}
+
+type NotifyEvent struct {
+	Notify Notify              `json:"notify"`
+	Meta   NotifyEventMetadata `json:"metadata"`
+}
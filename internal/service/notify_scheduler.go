@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+)
+
+// NotifyScheduler takes up notify requests whose fallback has fallen due,
+// the way PushScheduler dispatches scheduled pushes. Any number of
+// replicas may run one: ClaimDueNotifyRequests hands each due request to
+// exactly one of them.
+type NotifyScheduler struct {
+	repo          repository.Querier
+	notifyService NotifyService
+	pollInterval  time.Duration
+	batchSize     int32
+	claimTimeout  time.Duration
+	logger        *slog.Logger
+}
+
+// NewNotifyScheduler creates a scheduler that polls every pollInterval for
+// up to batchSize due notify requests. A claimed request not settled
+// within claimTimeout is claimed again; this is also how one whose
+// channels all failed in a way that may pass later is retried, until
+// NotifyService settles it once it has been claimed more than
+// MAX_RETRY_ATTEMPTS times in a row.
+func NewNotifyScheduler(
+	repo repository.Querier,
+	notifyService NotifyService,
+	pollInterval time.Duration,
+	batchSize int32,
+	claimTimeout time.Duration,
+	logger *slog.Logger,
+) *NotifyScheduler {
+	return &NotifyScheduler{
+		repo:          repo,
+		notifyService: notifyService,
+		pollInterval:  pollInterval,
+		batchSize:     batchSize,
+		claimTimeout:  claimTimeout,
+		logger:        logger,
+	}
+}
+
+// Start polls for due notify requests until ctx is cancelled.
+func (ns *NotifyScheduler) Start(ctx context.Context) error {
+	ns.logger.Info("notify scheduler started",
+		"poll_interval", ns.pollInterval.String(),
+		"batch_size", ns.batchSize,
+	)
+
+	ticker := time.NewTicker(ns.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ns.logger.Info("notify scheduler stopped")
+			return ctx.Err()
+		case <-ticker.C:
+			for {
+				more, err := ns.continueDue(ctx)
+				if err != nil {
+					ns.logger.Error("failed to claim due notify requests",
+						"error", err,
+					)
+					break
+				}
+				if !more || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// continueDue claims one batch of due notify requests and continues each.
+// more reports whether the batch was full.
+func (ns *NotifyScheduler) continueDue(ctx context.Context) (more bool, err error) {
+	due, err := ns.repo.ClaimDueNotifyRequests(ctx, repository.ClaimDueNotifyRequestsParams{
+		StaleAfterSeconds: int32(ns.claimTimeout / time.Second),
+		BatchSize:         ns.batchSize,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, notifyReq := range due {
+		if err := ns.notifyService.Continue(ctx, notifyReq); err != nil {
+			ns.logger.Error("notify fallback failed",
+				"notify_request_id", notifyReq.ID,
+				"error", err,
+			)
+		}
+	}
+	return len(due) == int(ns.batchSize), nil
+}
@@ -0,0 +1,685 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// How the push, email or in-app notification a notify request sent on one
+// channel turned out.
+const (
+	notifyOutcomeAccepted   = "accepted"
+	notifyOutcomeSuppressed = "suppressed"
+	notifyOutcomeFailed     = "failed"
+)
+
+// notifyChildPrefix starts the request id of every push and email a notify
+// request sends. The push and email consumers refuse it from publishers,
+// so a publisher's own request id can never collide with one.
+const notifyChildPrefix = "notify/"
+
+// ErrNotifyRequestNotFound is returned when an admin lookup refers to a
+// notify request gossip-monger has no record of.
+var ErrNotifyRequestNotFound = errors.New("no notify request found")
+
+// NotifyService fans a notify.send event out to push, email and in-app.
+// Push and email go through PushNotificationService and EmailService as
+// ordinary push and email requests, linked to the notify request and sent
+// under a request id derived from the notify request's id, so a retried
+// notify.send doesn't send a channel twice. In-app puts a notification in
+// each user's inbox, once per user however often the request is retried.
+type NotifyService interface {
+	// Send records notifyEvent and sends it on its channels as its policy
+	// says. delivery is the message it arrived in.
+	Send(ctx context.Context, notifyEvent NotifyEvent, delivery broker.Delivery) error
+	// Continue takes up a notify request whose fallback fell due: unless
+	// the channel it last sent on has been delivered, it moves on to the
+	// next one.
+	Continue(ctx context.Context, notifyReq repository.NotifyRequest) error
+}
+
+type notifyService struct {
+	repo  repository.Querier
+	push  PushNotificationService
+	email EmailService
+	// maxAttempts is MAX_RETRY_ATTEMPTS: how many times a pass that failed
+	// in a way that may pass later is retried before the request is
+	// settled on what got through.
+	maxAttempts int
+	logger      *slog.Logger
+}
+
+func NewNotifyService(
+	repo repository.Querier,
+	push PushNotificationService,
+	email EmailService,
+	maxAttempts int,
+	logger *slog.Logger,
+) NotifyService {
+	return &notifyService{
+		repo:        repo,
+		push:        push,
+		email:       email,
+		maxAttempts: maxAttempts,
+		logger:      logger,
+	}
+}
+
+func (ns *notifyService) Send(
+	ctx context.Context,
+	notifyEvent NotifyEvent,
+	delivery broker.Delivery,
+) (err error) {
+	ctx, span := tracer.Start(ctx, "NotifyService.Send", trace.WithAttributes(
+		attribute.String("gossip.request_id", notifyEvent.Meta.RequestID),
+		attribute.String("gossip.service_id", notifyEvent.Meta.SourceServiceID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	notify := notifyEvent.Notify
+	if err := validateNotify(notify); err != nil {
+		return resilience.Permanent(err)
+	}
+
+	// Only a request whose last pass left a channel to retry is worth
+	// running again; any other has sent what it is going to, or is
+	// waiting on its fallback.
+	existing, err := ns.repo.GetNotifyRequestByQueueMessageID(ctx, notifyEvent.Meta.RequestID)
+	if err == nil {
+		if existing.Status != "received" {
+			ns.logger.Info("duplicate request_id already handled, skipping",
+				"request_id", notifyEvent.Meta.RequestID,
+				"status", existing.Status,
+			)
+			return nil
+		}
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check for duplicate notify request: %w", err)
+	}
+
+	svc, err := ns.repo.UpsertService(ctx, repository.UpsertServiceParams{
+		ID:   notifyEvent.Meta.SourceServiceID,
+		Name: notifyEvent.Meta.SourceServiceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert service: %w", err)
+	}
+	// Checked now rather than when the email channel's turn comes, which
+	// under fallback may be well after the push went out.
+	if slices.Contains(notify.Channels, channelEmail) {
+		if err := checkSenderDomain(notify.Email.FromAddress, svc.AllowedSenderDomains); err != nil {
+			return resilience.Permanent(err)
+		}
+	}
+
+	payload, err := json.Marshal(notify)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notify payload: %w", err)
+	}
+	var fallbackAfter *int32
+	if notify.Policy == NotifyPolicyFallback {
+		minutes := int32(notify.FallbackAfterMinutes)
+		fallbackAfter = &minutes
+	}
+
+	notifyReq, err := ns.repo.UpsertNotifyRequest(ctx, repository.UpsertNotifyRequestParams{
+		ServiceID:            notifyEvent.Meta.SourceServiceID,
+		QueueMessageID:       notifyEvent.Meta.RequestID,
+		Exchange:             delivery.Exchange,
+		RoutingKey:           delivery.RoutingKey,
+		AmqpMessageID:        optionalString(delivery.MessageID),
+		AttemptCount:         int32(delivery.Attempt),
+		ToUserIds:            notify.ToUserIDs,
+		Channels:             notify.Channels,
+		Policy:               notify.Policy,
+		FallbackAfterMinutes: fallbackAfter,
+		Payload:              payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create notify request: %w", err)
+	}
+
+	return ns.run(ctx, notifyReq, notify, delivery, delivery.Attempt > ns.maxAttempts)
+}
+
+func (ns *notifyService) Continue(
+	ctx context.Context,
+	notifyReq repository.NotifyRequest,
+) (err error) {
+	ctx, span := tracer.Start(ctx, "NotifyService.Continue", trace.WithAttributes(
+		attribute.String("gossip.request_id", notifyReq.QueueMessageID),
+		attribute.String("gossip.service_id", notifyReq.ServiceID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	var notify Notify
+	if err := json.Unmarshal(notifyReq.Payload, &notify); err != nil {
+		return fmt.Errorf("failed to unmarshal notify payload: %w", err)
+	}
+
+	next := int(notifyReq.NextChannel)
+	if next <= 0 || next >= len(notify.Channels) {
+		return ns.settle(ctx, notifyReq, notify, min(max(next, 0), len(notify.Channels)))
+	}
+	// Each claim is an attempt. One past the last means the last failed
+	// before it got as far as settling, so give up on the fallback.
+	if int(notifyReq.ClaimCount) > ns.maxAttempts+1 {
+		ns.logger.Warn("notify fallback out of retries",
+			"notify_request_id", notifyReq.ID,
+			"claim_count", notifyReq.ClaimCount,
+		)
+		return ns.settle(ctx, notifyReq, notify, next)
+	}
+
+	previous := notify.Channels[next-1]
+	delivered, err := ns.delivered(ctx, notifyReq.ID, notify, previous)
+	if err != nil {
+		return err
+	}
+	if delivered {
+		ns.logger.Info("notification delivered, no fallback needed",
+			"notify_request_id", notifyReq.ID,
+			"channel", previous,
+		)
+		return ns.settle(ctx, notifyReq, notify, next)
+	}
+
+	return ns.run(ctx, notifyReq, notify, broker.Delivery{
+		Exchange:   notifyReq.Exchange,
+		RoutingKey: notifyReq.RoutingKey,
+		MessageID:  derefString(notifyReq.AmqpMessageID),
+		Attempt:    int(notifyReq.AttemptCount),
+	}, int(notifyReq.ClaimCount) > ns.maxAttempts)
+}
+
+// run sends notify on notifyReq's channels from its next_channel on, as
+// its policy says, and records where that leaves the request.
+//
+// A channel that fails, or whose users all opted out, is passed over for
+// the next. If one failed in a way that may pass later and the policy
+// still wants it, that error is returned and the request left as it was,
+// so the whole pass is retried; channels that did get through are skipped
+// then, what they sent already being sent. On lastAttempt there is no retry
+// to come, so the request is settled on what got through instead.
+func (ns *notifyService) run(
+	ctx context.Context,
+	notifyReq repository.NotifyRequest,
+	notify Notify,
+	delivery broker.Delivery,
+	lastAttempt bool,
+) error {
+	var retryErr error
+	for i := int(notifyReq.NextChannel); i < len(notify.Channels); i++ {
+		channel := notify.Channels[i]
+		outcome, err := ns.sendOn(ctx, notifyReq, notify, channel, delivery)
+		if err != nil {
+			ns.logger.Error("notify channel failed",
+				"notify_request_id", notifyReq.ID,
+				"channel", channel,
+				"error", err,
+			)
+			if !resilience.IsPermanent(err) {
+				retryErr = err
+			}
+		}
+		if outcome != notifyOutcomeAccepted || notify.Policy == NotifyPolicyAll {
+			continue
+		}
+
+		if notify.Policy == NotifyPolicyFallback && i+1 < len(notify.Channels) {
+			due := time.Now().Add(time.Duration(notify.FallbackAfterMinutes) * time.Minute)
+			ns.logger.Info("notify request waiting on fallback",
+				"notify_request_id", notifyReq.ID,
+				"channel", channel,
+				"fallback_at", due,
+			)
+			return ns.progress(ctx, notifyReq.ID, "pending", int32(i+1), &due)
+		}
+		// One channel got through. Retrying one that didn't would
+		// notify the users twice.
+		return ns.settle(ctx, notifyReq, notify, i+1)
+	}
+
+	if retryErr != nil {
+		if !lastAttempt {
+			return retryErr
+		}
+		ns.logger.Warn("notify request out of retries, settling on what got through",
+			"notify_request_id", notifyReq.ID,
+			"error", retryErr,
+		)
+	}
+	return ns.settle(ctx, notifyReq, notify, len(notify.Channels))
+}
+
+// sendOn sends notify on channel and reports how it turned out. The error
+// is the one the channel's service returned, if any; of several, one that
+// may pass on a retry wins over one that won't.
+func (ns *notifyService) sendOn(
+	ctx context.Context,
+	notifyReq repository.NotifyRequest,
+	notify Notify,
+	channel string,
+	delivery broker.Delivery,
+) (string, error) {
+	parentID := pgtype.UUID{Bytes: notifyReq.ID, Valid: true}
+
+	var sendErr error
+	switch channel {
+	case channelPush:
+		queueMessageID := childQueueMessageID(notifyReq.ID, channelPush)
+		sendErr = ns.push.Send(ctx, notifyPush(notifyReq.ServiceID, notify), queueMessageID)
+		if err := ns.repo.LinkNotificationToNotifyRequest(ctx, repository.LinkNotificationToNotifyRequestParams{
+			NotifyRequestID: parentID,
+			QueueMessageID:  &queueMessageID,
+		}); err != nil {
+			return "", fmt.Errorf("failed to link push to notify request: %w", err)
+		}
+	case channelEmail:
+		for _, userID := range uniqueUserIDs(notify.ToUserIDs) {
+			queueMessageID := childEmailQueueMessageID(notifyReq.ID, userID)
+			err := ns.email.Send(ctx, notifyEmail(notifyReq.ServiceID, queueMessageID, userID, notify), delivery)
+			if err != nil && (sendErr == nil || resilience.IsPermanent(sendErr)) {
+				sendErr = err
+			}
+			if err := ns.repo.LinkEmailRequestToNotifyRequest(ctx, repository.LinkEmailRequestToNotifyRequestParams{
+				NotifyRequestID: parentID,
+				QueueMessageID:  queueMessageID,
+			}); err != nil {
+				return "", fmt.Errorf("failed to link email to notify request: %w", err)
+			}
+		}
+	case channelInApp:
+		sendErr = ns.sendInApp(ctx, notifyReq, notify)
+	}
+
+	outcomes, err := ns.childOutcomes(ctx, notifyReq.ID, notify, channel)
+	if err != nil {
+		return "", err
+	}
+	return channelOutcome(outcomes), sendErr
+}
+
+// childOutcomes classifies each push, email or in-app notification notify
+// request id sent on channel. One that was never stored failed.
+func (ns *notifyService) childOutcomes(
+	ctx context.Context,
+	id uuid.UUID,
+	notify Notify,
+	channel string,
+) ([]string, error) {
+	var statuses []string
+	switch channel {
+	case channelPush:
+		queueMessageID := childQueueMessageID(id, channelPush)
+		push, err := ns.repo.GetNotificationByQueueMessageID(ctx, &queueMessageID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []string{notifyOutcomeFailed}, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to look up notify push: %w", err)
+		}
+		statuses = append(statuses, derefString(push.Status))
+	case channelEmail:
+		for _, userID := range uniqueUserIDs(notify.ToUserIDs) {
+			email, err := ns.repo.GetEmailRequestByQueueMessageID(ctx, childEmailQueueMessageID(id, userID))
+			if errors.Is(err, pgx.ErrNoRows) {
+				statuses = append(statuses, "")
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to look up notify email: %w", err)
+			}
+			statuses = append(statuses, email.Status)
+		}
+	case channelInApp:
+		inApp, err := ns.repo.GetInAppNotificationsByNotifyRequestID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up notify in-app notifications: %w", err)
+		}
+		byUser := make(map[uuid.UUID]string, len(inApp))
+		for _, n := range inApp {
+			byUser[n.UserID] = n.Status
+		}
+		for _, userID := range uniqueUserIDs(notify.ToUserIDs) {
+			statuses = append(statuses, byUser[userID])
+		}
+	}
+
+	outcomes := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		switch status {
+		case "sent", "delivered", "dispatched":
+			outcomes = append(outcomes, notifyOutcomeAccepted)
+		case "suppressed":
+			outcomes = append(outcomes, notifyOutcomeSuppressed)
+		default:
+			outcomes = append(outcomes, notifyOutcomeFailed)
+		}
+	}
+	return outcomes, nil
+}
+
+// channelOutcome sums up the outcomes of a channel's pushes or emails: it
+// accepted the notification if any of them did, and its users opted out
+// only if all of theirs did.
+func channelOutcome(outcomes []string) string {
+	switch {
+	case slices.Contains(outcomes, notifyOutcomeAccepted):
+		return notifyOutcomeAccepted
+	case len(outcomes) > 0 && !slices.ContainsFunc(outcomes, func(outcome string) bool {
+		return outcome != notifyOutcomeSuppressed
+	}):
+		return notifyOutcomeSuppressed
+	}
+	return notifyOutcomeFailed
+}
+
+// delivered reports whether the push, or any of the emails, notify request
+// id sent on channel is known to have reached its recipient. An in-app
+// notification has once one of its users has read it.
+func (ns *notifyService) delivered(
+	ctx context.Context,
+	id uuid.UUID,
+	notify Notify,
+	channel string,
+) (bool, error) {
+	switch channel {
+	case channelPush:
+		queueMessageID := childQueueMessageID(id, channelPush)
+		push, err := ns.repo.GetNotificationByQueueMessageID(ctx, &queueMessageID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("failed to look up notify push: %w", err)
+		}
+		return derefString(push.Status) == "delivered", nil
+	case channelEmail:
+		for _, userID := range uniqueUserIDs(notify.ToUserIDs) {
+			email, err := ns.repo.GetEmailRequestByQueueMessageID(ctx, childEmailQueueMessageID(id, userID))
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			} else if err != nil {
+				return false, fmt.Errorf("failed to look up notify email: %w", err)
+			}
+			delivered, err := ns.repo.EmailRequestWasDelivered(ctx, email.ID)
+			if err != nil {
+				return false, fmt.Errorf("failed to check notify email delivery: %w", err)
+			}
+			if delivered {
+				return true, nil
+			}
+		}
+	case channelInApp:
+		inApp, err := ns.repo.GetInAppNotificationsByNotifyRequestID(ctx, id)
+		if err != nil {
+			return false, fmt.Errorf("failed to look up notify in-app notifications: %w", err)
+		}
+		return slices.ContainsFunc(inApp, func(n repository.InAppNotification) bool {
+			return n.ReadAt != nil
+		}), nil
+	}
+	return false, nil
+}
+
+// sendInApp puts notify in the inbox of each of its users, as suppressed
+// for those who opted out of its type in-app so they never see it.
+func (ns *notifyService) sendInApp(
+	ctx context.Context,
+	notifyReq repository.NotifyRequest,
+	notify Notify,
+) error {
+	userIDs := uniqueUserIDs(notify.ToUserIDs)
+	ids := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		ids = append(ids, userID.String())
+	}
+	optedOut, err := ns.repo.ListOptedOutUserIDs(ctx, repository.ListOptedOutUserIDsParams{
+		Channel:          channelInApp,
+		UserIds:          ids,
+		NotificationType: derefString(notify.NotificationType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check notification preferences: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		status := "delivered"
+		if slices.Contains(optedOut, userID.String()) {
+			status = "suppressed"
+		}
+		if err := ns.repo.CreateInAppNotification(ctx, repository.CreateInAppNotificationParams{
+			UserID:           userID,
+			ServiceID:        notifyReq.ServiceID,
+			NotifyRequestID:  notifyReq.ID,
+			NotificationType: notify.NotificationType,
+			Title:            notify.Title,
+			Body:             notify.Body,
+			Url:              notify.URL,
+			Status:           status,
+		}); err != nil {
+			return fmt.Errorf("failed to create in-app notification: %w", err)
+		}
+	}
+	return nil
+}
+
+// settle records the aggregate status of a notify request that has no
+// channel left to try. tried is how many of its channels were; every push
+// and email they sent counts towards it.
+func (ns *notifyService) settle(
+	ctx context.Context,
+	notifyReq repository.NotifyRequest,
+	notify Notify,
+	tried int,
+) error {
+	var outcomes []string
+	for _, channel := range notify.Channels[:tried] {
+		channelOutcomes, err := ns.childOutcomes(ctx, notifyReq.ID, notify, channel)
+		if err != nil {
+			return err
+		}
+		outcomes = append(outcomes, channelOutcomes...)
+	}
+
+	status := aggregateNotifyStatus(notify.Policy, outcomes)
+	if err := ns.progress(ctx, notifyReq.ID, status, int32(tried), nil); err != nil {
+		return err
+	}
+
+	ns.logger.Info("notify request settled",
+		"notify_request_id", notifyReq.ID,
+		"policy", notify.Policy,
+		"status", status,
+	)
+	return nil
+}
+
+func (ns *notifyService) progress(
+	ctx context.Context,
+	id uuid.UUID,
+	status string,
+	nextChannel int32,
+	nextAttemptAt *time.Time,
+) error {
+	if err := ns.repo.UpdateNotifyRequestProgress(ctx, repository.UpdateNotifyRequestProgressParams{
+		ID:            id,
+		Status:        status,
+		NextChannel:   nextChannel,
+		NextAttemptAt: nextAttemptAt,
+	}); err != nil {
+		return fmt.Errorf("failed to update notify request: %w", err)
+	}
+	return nil
+}
+
+// aggregateNotifyStatus sums up the outcomes of the pushes and emails a
+// notify request sent. One whose users all opted out counts neither way.
+// Under "all" every other one is meant to get through, so only some doing
+// so is "partial"; under the other policies one is enough.
+func aggregateNotifyStatus(policy string, outcomes []string) string {
+	accepted, suppressed := 0, 0
+	for _, outcome := range outcomes {
+		switch outcome {
+		case notifyOutcomeAccepted:
+			accepted++
+		case notifyOutcomeSuppressed:
+			suppressed++
+		}
+	}
+
+	switch {
+	case accepted > 0 && (policy != NotifyPolicyAll || accepted+suppressed == len(outcomes)):
+		return "sent"
+	case accepted > 0:
+		return "partial"
+	case suppressed > 0 && suppressed == len(outcomes):
+		return "suppressed"
+	}
+	return "failed"
+}
+
+// childQueueMessageID is the request id the push or email notify request id
+// sends on channel goes out under.
+func childQueueMessageID(id uuid.UUID, channel string) string {
+	return notifyChildPrefix + id.String() + "/" + channel
+}
+
+// childEmailQueueMessageID is the request id of the email notify request
+// id sends userID. Each user gets an email of their own, so no one sees
+// the others' addresses and one user who can't be emailed doesn't stop the
+// rest.
+func childEmailQueueMessageID(id, userID uuid.UUID) string {
+	return childQueueMessageID(id, channelEmail) + "/" + userID.String()
+}
+
+// uniqueUserIDs returns ids without repeats, in order.
+func uniqueUserIDs(ids []uuid.UUID) []uuid.UUID {
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// IsReservedRequestID reports whether requestID is one only a notify
+// request's push or email may use.
+func IsReservedRequestID(requestID string) bool {
+	return strings.HasPrefix(requestID, notifyChildPrefix)
+}
+
+// notifyPush builds the push notify sends, targeting its users by external
+// user id.
+func notifyPush(serviceID string, notify Notify) repository.Notification {
+	headings, _ := json.Marshal(map[string]string{"en": notify.Title})
+	contents, _ := json.Marshal(map[string]string{"en": notify.Body})
+
+	userIDs := make([]string, 0, len(notify.ToUserIDs))
+	for _, id := range notify.ToUserIDs {
+		userIDs = append(userIDs, id.String())
+	}
+
+	return repository.Notification{
+		IncludeExternalUserIds: userIDs,
+		Headings:               headings,
+		Contents:               contents,
+		Url:                    notify.URL,
+		SourceServiceID:        &serviceID,
+		NotificationType:       notify.NotificationType,
+	}
+}
+
+// notifyEmail builds the email notify sends userID, addressed to them by
+// id.
+func notifyEmail(serviceID, queueMessageID string, userID uuid.UUID, notify Notify) EmailEvent {
+	email := Email{
+		ToUserIDs:        []uuid.UUID{userID},
+		Subject:          notify.Title,
+		BodyText:         &notify.Body,
+		NotificationType: notify.NotificationType,
+	}
+	if notify.Email != nil {
+		email.FromAddress = notify.Email.FromAddress
+		email.ReplyTo = notify.Email.ReplyTo
+		email.Transactional = notify.Email.Transactional
+		email.BodyHtml = notify.Email.BodyHtml
+	}
+
+	return EmailEvent{
+		Email: email,
+		Meta: EmailEventMetadata{
+			EventType:       "email.send",
+			Timestamp:       time.Now(),
+			SourceServiceID: serviceID,
+			RequestID:       queueMessageID,
+		},
+	}
+}
+
+// validateNotify checks what would otherwise fail only once a channel is
+// tried.
+func validateNotify(notify Notify) error {
+	if len(notify.ToUserIDs) == 0 {
+		return errors.New("at least one user in to_user_ids is required")
+	}
+	if strings.TrimSpace(notify.Title) == "" {
+		return errors.New("title is required")
+	}
+	if strings.TrimSpace(notify.Body) == "" {
+		return errors.New("body is required")
+	}
+
+	if len(notify.Channels) == 0 {
+		return errors.New("at least one channel is required")
+	}
+	for i, channel := range notify.Channels {
+		if channel != channelPush && channel != channelEmail && channel != channelInApp {
+			return fmt.Errorf("channel %q is not one of push, email or in_app", channel)
+		}
+		if slices.Contains(notify.Channels[:i], channel) {
+			return fmt.Errorf("channel %q is listed twice", channel)
+		}
+	}
+	if slices.Contains(notify.Channels, channelEmail) {
+		if notify.Email == nil {
+			return errors.New("email is required when channels include email")
+		}
+		if _, err := mail.ParseAddress(notify.Email.FromAddress); err != nil {
+			return fmt.Errorf("email.from_address %q is not a valid email address: %w", notify.Email.FromAddress, err)
+		}
+		if notify.Email.Transactional == nil {
+			return errors.New("email.transactional is required: say whether this email is transactional")
+		}
+	}
+
+	switch notify.Policy {
+	case NotifyPolicyAll, NotifyPolicyFirstSuccess:
+	case NotifyPolicyFallback:
+		if notify.FallbackAfterMinutes <= 0 {
+			return errors.New("fallback_after_minutes must be positive for the fallback policy")
+		}
+	default:
+		return fmt.Errorf(
+			"policy %q is not one of %s, %s or %s",
+			notify.Policy, NotifyPolicyAll, NotifyPolicyFirstSuccess, NotifyPolicyFallback,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,608 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotifyRepo keeps the push and email a notify request sends, by
+// queue message id, its in-app notifications, and the progress Send and
+// Continue record.
+type fakeNotifyRepo struct {
+	repository.Querier
+	existing *repository.NotifyRequest
+	upserted repository.UpsertNotifyRequestParams
+	pushes   map[string]string
+	emails   map[string]string
+	inApp    []repository.InAppNotification
+	// optedOut are the user ids ListOptedOutUserIDs reports.
+	optedOut []string
+	// delivered is what EmailRequestWasDelivered reports.
+	delivered bool
+	links     []string
+	progress  []repository.UpdateNotifyRequestProgressParams
+}
+
+func newFakeNotifyRepo() *fakeNotifyRepo {
+	return &fakeNotifyRepo{pushes: map[string]string{}, emails: map[string]string{}}
+}
+
+func (f *fakeNotifyRepo) GetNotifyRequestByQueueMessageID(context.Context, string) (repository.NotifyRequest, error) {
+	if f.existing == nil {
+		return repository.NotifyRequest{}, pgx.ErrNoRows
+	}
+	return *f.existing, nil
+}
+
+func (f *fakeNotifyRepo) UpsertService(_ context.Context, arg repository.UpsertServiceParams) (repository.Service, error) {
+	return repository.Service{
+		ID:                   arg.ID,
+		Name:                 arg.Name,
+		AllowedSenderDomains: []string{"posta.opencrafts.io"},
+	}, nil
+}
+
+func (f *fakeNotifyRepo) UpsertNotifyRequest(
+	_ context.Context,
+	arg repository.UpsertNotifyRequestParams,
+) (repository.NotifyRequest, error) {
+	f.upserted = arg
+	return repository.NotifyRequest{
+		ID:             testNotifyRequestID,
+		ServiceID:      arg.ServiceID,
+		QueueMessageID: arg.QueueMessageID,
+		Channels:       arg.Channels,
+		Policy:         arg.Policy,
+		Status:         "received",
+	}, nil
+}
+
+func (f *fakeNotifyRepo) LinkNotificationToNotifyRequest(
+	_ context.Context,
+	arg repository.LinkNotificationToNotifyRequestParams,
+) error {
+	f.links = append(f.links, *arg.QueueMessageID)
+	return nil
+}
+
+func (f *fakeNotifyRepo) LinkEmailRequestToNotifyRequest(
+	_ context.Context,
+	arg repository.LinkEmailRequestToNotifyRequestParams,
+) error {
+	f.links = append(f.links, arg.QueueMessageID)
+	return nil
+}
+
+func (f *fakeNotifyRepo) GetNotificationByQueueMessageID(
+	_ context.Context,
+	queueMessageID *string,
+) (repository.Notification, error) {
+	status, ok := f.pushes[*queueMessageID]
+	if !ok {
+		return repository.Notification{}, pgx.ErrNoRows
+	}
+	return repository.Notification{Status: &status}, nil
+}
+
+func (f *fakeNotifyRepo) GetEmailRequestByQueueMessageID(
+	_ context.Context,
+	queueMessageID string,
+) (repository.EmailRequest, error) {
+	status, ok := f.emails[queueMessageID]
+	if !ok {
+		return repository.EmailRequest{}, pgx.ErrNoRows
+	}
+	return repository.EmailRequest{ID: uuid.New(), Status: status}, nil
+}
+
+func (f *fakeNotifyRepo) EmailRequestWasDelivered(context.Context, uuid.UUID) (bool, error) {
+	return f.delivered, nil
+}
+
+func (f *fakeNotifyRepo) ListOptedOutUserIDs(context.Context, repository.ListOptedOutUserIDsParams) ([]string, error) {
+	return f.optedOut, nil
+}
+
+func (f *fakeNotifyRepo) CreateInAppNotification(
+	_ context.Context,
+	arg repository.CreateInAppNotificationParams,
+) error {
+	if slices.ContainsFunc(f.inApp, func(n repository.InAppNotification) bool {
+		return n.NotifyRequestID == arg.NotifyRequestID && n.UserID == arg.UserID
+	}) {
+		return nil
+	}
+	f.inApp = append(f.inApp, repository.InAppNotification{
+		ID:              uuid.New(),
+		UserID:          arg.UserID,
+		NotifyRequestID: arg.NotifyRequestID,
+		Title:           arg.Title,
+		Body:            arg.Body,
+		Status:          arg.Status,
+	})
+	return nil
+}
+
+func (f *fakeNotifyRepo) GetInAppNotificationsByNotifyRequestID(
+	_ context.Context,
+	notifyRequestID uuid.UUID,
+) ([]repository.InAppNotification, error) {
+	var rows []repository.InAppNotification
+	for _, n := range f.inApp {
+		if n.NotifyRequestID == notifyRequestID {
+			rows = append(rows, n)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeNotifyRepo) UpdateNotifyRequestProgress(
+	_ context.Context,
+	arg repository.UpdateNotifyRequestProgressParams,
+) error {
+	f.progress = append(f.progress, arg)
+	return nil
+}
+
+// lastProgress is the progress the notify request was last left in.
+func (f *fakeNotifyRepo) lastProgress(t *testing.T) repository.UpdateNotifyRequestProgressParams {
+	t.Helper()
+	require.NotEmpty(t, f.progress)
+	return f.progress[len(f.progress)-1]
+}
+
+// fakeNotifyPush stores each push it is sent under status and returns err,
+// as PushNotificationService.Send would.
+type fakeNotifyPush struct {
+	PushNotificationService
+	repo   *fakeNotifyRepo
+	status string
+	err    error
+	sent   []repository.Notification
+}
+
+func (f *fakeNotifyPush) Send(_ context.Context, push repository.Notification, queueMessageID string) error {
+	f.sent = append(f.sent, push)
+	if f.status != "" {
+		f.repo.pushes[queueMessageID] = f.status
+	}
+	return f.err
+}
+
+// fakeNotifyEmail does the same for EmailService.Send. The email to
+// unknownUser fails as the email service fails one to a user it doesn't
+// know.
+type fakeNotifyEmail struct {
+	EmailService
+	repo        *fakeNotifyRepo
+	status      string
+	err         error
+	unknownUser uuid.UUID
+	sent        []EmailEvent
+}
+
+func (f *fakeNotifyEmail) Send(_ context.Context, emailEvent EmailEvent, _ broker.Delivery) error {
+	f.sent = append(f.sent, emailEvent)
+	if slices.Contains(emailEvent.Email.ToUserIDs, f.unknownUser) {
+		f.repo.emails[emailEvent.Meta.RequestID] = "failed"
+		return resilience.Permanent(ErrUnknownRecipientUser)
+	}
+	if f.status != "" {
+		f.repo.emails[emailEvent.Meta.RequestID] = f.status
+	}
+	return f.err
+}
+
+func validNotifyEvent(policy string, channels ...string) NotifyEvent {
+	return NotifyEvent{
+		Notify: Notify{
+			ToUserIDs:            []uuid.UUID{testNotifyUserID},
+			Channels:             channels,
+			Policy:               policy,
+			FallbackAfterMinutes: 15,
+			Title:                "Assignment due",
+			Body:                 "Your assignment is due tomorrow",
+			Email: &NotifyEmail{
+				FromAddress:   "Academia <noreply@posta.opencrafts.io>",
+				Transactional: boolPtr(true),
+			},
+		},
+		Meta: NotifyEventMetadata{
+			EventType:       "notify.send",
+			SourceServiceID: "io.opencrafts.academia",
+			RequestID:       "req-1",
+		},
+	}
+}
+
+// testNotifyRequestID is the id of the notify request every test sends.
+var testNotifyRequestID = uuid.MustParse("6f1d2c3b-4a5e-4f60-8172-93a4b5c6d7e8")
+
+// testNotifyUserID is the user it notifies.
+var testNotifyUserID = uuid.MustParse("4f0c6a2e-8b1d-4c3e-9a7f-2d5e6b8c9a01")
+
+// testMaxAttempts is the MAX_RETRY_ATTEMPTS notify tests run with.
+const testMaxAttempts = 3
+
+var notifyDelivery = broker.Delivery{
+	Exchange:   "gossip.topic.exchange",
+	RoutingKey: "gossip.notify.send",
+	MessageID:  "msg-1",
+	Attempt:    1,
+}
+
+func TestNotifySend_FirstSuccessStopsAtFirstAcceptedChannel(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	push := &fakeNotifyPush{repo: repo, status: "sent"}
+	email := &fakeNotifyEmail{repo: repo, status: "sent"}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+
+	err := ns.Send(context.Background(), validNotifyEvent(NotifyPolicyFirstSuccess, "push", "email"), notifyDelivery)
+
+	require.NoError(t, err)
+	assert.Len(t, push.sent, 1)
+	assert.Empty(t, email.sent)
+	assert.Equal(t, []string{childQueueMessageID(testNotifyRequestID, "push")}, repo.links)
+	assert.Equal(t, "sent", repo.lastProgress(t).Status)
+	assert.Equal(t, int32(1), repo.lastProgress(t).NextChannel)
+}
+
+func TestNotifySend_FirstSuccessFallsThroughFailedChannel(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	push := &fakeNotifyPush{repo: repo, status: "failed", err: resilience.Permanent(errors.New("no subscriptions"))}
+	email := &fakeNotifyEmail{repo: repo, status: "sent"}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+
+	err := ns.Send(context.Background(), validNotifyEvent(NotifyPolicyFirstSuccess, "push", "email"), notifyDelivery)
+
+	require.NoError(t, err)
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, childEmailQueueMessageID(testNotifyRequestID, testNotifyUserID), email.sent[0].Meta.RequestID)
+	assert.Equal(t, "sent", repo.lastProgress(t).Status)
+}
+
+func TestNotifySend_AllReportsPartialWhenAChannelFails(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	push := &fakeNotifyPush{repo: repo, status: "sent"}
+	email := &fakeNotifyEmail{repo: repo, status: "failed", err: resilience.Permanent(errors.New("rejected"))}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+
+	err := ns.Send(context.Background(), validNotifyEvent(NotifyPolicyAll, "push", "email"), notifyDelivery)
+
+	require.NoError(t, err)
+	assert.Len(t, push.sent, 1)
+	assert.Len(t, email.sent, 1)
+	assert.Equal(t, "partial", repo.lastProgress(t).Status)
+}
+
+func TestNotifySend_EmailsEachUserSeparately(t *testing.T) {
+	otherUserID, unknownUserID := uuid.New(), uuid.New()
+	repo := newFakeNotifyRepo()
+	push := &fakeNotifyPush{repo: repo, status: "sent"}
+	email := &fakeNotifyEmail{repo: repo, status: "dispatched", unknownUser: unknownUserID}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+	event := validNotifyEvent(NotifyPolicyAll, "push", "email")
+	event.Notify.ToUserIDs = []uuid.UUID{testNotifyUserID, otherUserID, unknownUserID, otherUserID}
+
+	err := ns.Send(context.Background(), event, notifyDelivery)
+
+	require.NoError(t, err)
+	require.Len(t, email.sent, 3)
+	for i, userID := range []uuid.UUID{testNotifyUserID, otherUserID, unknownUserID} {
+		assert.Equal(t, []uuid.UUID{userID}, email.sent[i].Email.ToUserIDs)
+		assert.Equal(t, childEmailQueueMessageID(testNotifyRequestID, userID), email.sent[i].Meta.RequestID)
+	}
+	assert.Len(t, repo.links, 4, "the push and every email are linked")
+	assert.Equal(t, "partial", repo.lastProgress(t).Status, "the unknown user's email failed")
+}
+
+func TestNotifySend_InAppFillsEachInboxOnce(t *testing.T) {
+	optedOutUserID := uuid.New()
+	repo := newFakeNotifyRepo()
+	repo.optedOut = []string{optedOutUserID.String()}
+	ns := NewNotifyService(repo, &fakeNotifyPush{repo: repo}, &fakeNotifyEmail{repo: repo}, testMaxAttempts, testLogger())
+	event := validNotifyEvent(NotifyPolicyAll, "in_app")
+	event.Notify.ToUserIDs = []uuid.UUID{testNotifyUserID, optedOutUserID, testNotifyUserID}
+
+	require.NoError(t, ns.Send(context.Background(), event, notifyDelivery))
+	require.NoError(t, ns.Send(context.Background(), event, notifyDelivery), "a redelivered request")
+
+	require.Len(t, repo.inApp, 2)
+	assert.Equal(t, testNotifyUserID, repo.inApp[0].UserID)
+	assert.Equal(t, "delivered", repo.inApp[0].Status)
+	assert.Equal(t, "Assignment due", repo.inApp[0].Title)
+	assert.Equal(t, optedOutUserID, repo.inApp[1].UserID)
+	assert.Equal(t, "suppressed", repo.inApp[1].Status)
+	assert.Equal(t, "sent", repo.lastProgress(t).Status)
+}
+
+func TestNotifySend_InAppSuppressedWhenEveryoneOptedOut(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	repo.optedOut = []string{testNotifyUserID.String()}
+	push := &fakeNotifyPush{repo: repo, status: "sent"}
+	ns := NewNotifyService(repo, push, &fakeNotifyEmail{repo: repo}, testMaxAttempts, testLogger())
+
+	err := ns.Send(context.Background(), validNotifyEvent(NotifyPolicyFirstSuccess, "in_app", "push"), notifyDelivery)
+
+	require.NoError(t, err)
+	assert.Len(t, push.sent, 1, "an opted-out inbox passes over to push")
+	assert.Equal(t, "sent", repo.lastProgress(t).Status)
+}
+
+func TestNotifyContinue_InAppDeliveredOnceRead(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	notify := validNotifyEvent(NotifyPolicyFallback, "in_app", "push").Notify
+	ns := NewNotifyService(repo, &fakeNotifyPush{repo: repo}, &fakeNotifyEmail{repo: repo}, testMaxAttempts, testLogger())
+	repo.inApp = []repository.InAppNotification{{
+		UserID:          testNotifyUserID,
+		NotifyRequestID: testNotifyRequestID,
+		Status:          "delivered",
+	}}
+
+	delivered, err := ns.(*notifyService).delivered(context.Background(), testNotifyRequestID, notify, channelInApp)
+	require.NoError(t, err)
+	assert.False(t, delivered, "unread")
+
+	readAt := time.Now()
+	repo.inApp[0].ReadAt = &readAt
+	delivered, err = ns.(*notifyService).delivered(context.Background(), testNotifyRequestID, notify, channelInApp)
+	require.NoError(t, err)
+	assert.True(t, delivered)
+}
+
+func TestNotifyContinue_FallbackDeliveredByAnyEmail(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	repo.emails[childEmailQueueMessageID(testNotifyRequestID, testNotifyUserID)] = "dispatched"
+	repo.delivered = true
+	ns := NewNotifyService(repo, &fakeNotifyPush{repo: repo}, &fakeNotifyEmail{repo: repo}, testMaxAttempts, testLogger())
+
+	delivered, err := ns.(*notifyService).delivered(
+		context.Background(),
+		testNotifyRequestID,
+		validNotifyEvent(NotifyPolicyFallback, "email", "push").Notify,
+		channelEmail,
+	)
+
+	require.NoError(t, err)
+	assert.True(t, delivered)
+}
+
+func TestNotifySend_RetryableFailureIsReturned(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	providerDown := errors.New("provider unavailable")
+	push := &fakeNotifyPush{repo: repo, err: providerDown}
+	email := &fakeNotifyEmail{repo: repo, err: providerDown}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+
+	err := ns.Send(context.Background(), validNotifyEvent(NotifyPolicyFirstSuccess, "push", "email"), notifyDelivery)
+
+	assert.ErrorIs(t, err, providerDown)
+	assert.False(t, resilience.IsPermanent(err))
+	assert.Empty(t, repo.progress)
+}
+
+func TestNotifySend_AllSettlesPartialOnLastAttempt(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	push := &fakeNotifyPush{repo: repo, status: "sent"}
+	email := &fakeNotifyEmail{repo: repo, status: "circuit_open", err: errors.New("resend unavailable")}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+	event := validNotifyEvent(NotifyPolicyAll, "push", "email")
+
+	delivery := notifyDelivery
+	err := ns.Send(context.Background(), event, delivery)
+	require.Error(t, err, "a retry may still get the email through")
+	assert.Empty(t, repo.progress)
+
+	delivery.Attempt = testMaxAttempts + 1
+	err = ns.Send(context.Background(), event, delivery)
+
+	require.NoError(t, err)
+	assert.Equal(t, "partial", repo.lastProgress(t).Status)
+}
+
+func TestNotifySend_FallbackWaitsAfterFirstChannel(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	push := &fakeNotifyPush{repo: repo, status: "sent"}
+	email := &fakeNotifyEmail{repo: repo, status: "sent"}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+
+	before := time.Now()
+	err := ns.Send(context.Background(), validNotifyEvent(NotifyPolicyFallback, "push", "email"), notifyDelivery)
+
+	require.NoError(t, err)
+	assert.Empty(t, email.sent)
+	progress := repo.lastProgress(t)
+	assert.Equal(t, "pending", progress.Status)
+	assert.Equal(t, int32(1), progress.NextChannel)
+	require.NotNil(t, progress.NextAttemptAt)
+	assert.WithinDuration(t, before.Add(15*time.Minute), *progress.NextAttemptAt, time.Minute)
+	require.NotNil(t, repo.upserted.FallbackAfterMinutes)
+	assert.Equal(t, int32(15), *repo.upserted.FallbackAfterMinutes)
+}
+
+func TestNotifyContinue_FallsBackWhenNotDelivered(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	repo.pushes[childQueueMessageID(testNotifyRequestID, "push")] = "sent"
+	push := &fakeNotifyPush{repo: repo}
+	email := &fakeNotifyEmail{repo: repo, status: "sent"}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+
+	err := ns.Continue(context.Background(), pendingNotifyRequest(t))
+
+	require.NoError(t, err)
+	require.Len(t, email.sent, 1)
+	assert.Equal(t, "sent", repo.lastProgress(t).Status)
+	assert.Equal(t, int32(2), repo.lastProgress(t).NextChannel)
+}
+
+func TestNotifyContinue_SettlesWhenDelivered(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	repo.pushes[childQueueMessageID(testNotifyRequestID, "push")] = "delivered"
+	push := &fakeNotifyPush{repo: repo}
+	email := &fakeNotifyEmail{repo: repo, status: "sent"}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+
+	err := ns.Continue(context.Background(), pendingNotifyRequest(t))
+
+	require.NoError(t, err)
+	assert.Empty(t, email.sent)
+	assert.Equal(t, "sent", repo.lastProgress(t).Status)
+	assert.Equal(t, int32(1), repo.lastProgress(t).NextChannel)
+}
+
+func TestNotifyContinue_SettlesOnceOutOfRetries(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	repo.pushes[childQueueMessageID(testNotifyRequestID, "push")] = "sent"
+	push := &fakeNotifyPush{repo: repo}
+	email := &fakeNotifyEmail{repo: repo, err: errors.New("resend unavailable")}
+	ns := NewNotifyService(repo, push, email, testMaxAttempts, testLogger())
+	notifyReq := pendingNotifyRequest(t)
+
+	notifyReq.ClaimCount = testMaxAttempts
+	err := ns.Continue(context.Background(), notifyReq)
+	require.Error(t, err)
+	assert.Empty(t, repo.progress, "left claimed, so it is claimed again")
+
+	notifyReq.ClaimCount = testMaxAttempts + 1
+	err = ns.Continue(context.Background(), notifyReq)
+
+	require.NoError(t, err)
+	assert.Equal(t, "sent", repo.lastProgress(t).Status, "the push did go out")
+	assert.Equal(t, int32(2), repo.lastProgress(t).NextChannel)
+}
+
+func TestNotifySend_SenderDomainCheckedUpFront(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	push := &fakeNotifyPush{repo: repo, status: "sent"}
+	ns := NewNotifyService(repo, push, &fakeNotifyEmail{repo: repo}, testMaxAttempts, testLogger())
+	event := validNotifyEvent(NotifyPolicyFallback, "push", "email")
+	event.Notify.Email.FromAddress = "noreply@elsewhere.example"
+
+	err := ns.Send(context.Background(), event, notifyDelivery)
+
+	require.Error(t, err)
+	assert.True(t, resilience.IsPermanent(err))
+	assert.Empty(t, push.sent, "nothing is sent for a request whose email can't go out")
+}
+
+func TestNotifySend_SkipsHandledRequest(t *testing.T) {
+	repo := newFakeNotifyRepo()
+	repo.existing = &repository.NotifyRequest{Status: "pending"}
+	push := &fakeNotifyPush{repo: repo}
+	ns := NewNotifyService(repo, push, &fakeNotifyEmail{repo: repo}, testMaxAttempts, testLogger())
+
+	err := ns.Send(context.Background(), validNotifyEvent(NotifyPolicyFallback, "push", "email"), notifyDelivery)
+
+	require.NoError(t, err)
+	assert.Empty(t, push.sent)
+	assert.Empty(t, repo.progress)
+}
+
+// pendingNotifyRequest is a fallback request whose push went out and
+// whose fallback to email has fallen due.
+func pendingNotifyRequest(t *testing.T) repository.NotifyRequest {
+	t.Helper()
+	payload, err := json.Marshal(validNotifyEvent(NotifyPolicyFallback, "push", "email").Notify)
+	require.NoError(t, err)
+	return repository.NotifyRequest{
+		ID:             testNotifyRequestID,
+		ServiceID:      "io.opencrafts.academia",
+		QueueMessageID: "req-1",
+		Channels:       []string{"push", "email"},
+		Policy:         NotifyPolicyFallback,
+		Payload:        payload,
+		NextChannel:    1,
+		Status:         "dispatching",
+	}
+}
+
+func TestValidateNotify(t *testing.T) {
+	valid := validNotifyEvent(NotifyPolicyFallback, "push", "email").Notify
+	assert.NoError(t, validateNotify(valid))
+
+	for name, mutate := range map[string]func(*Notify){
+		"no users":             func(n *Notify) { n.ToUserIDs = nil },
+		"no title":             func(n *Notify) { n.Title = " " },
+		"no channels":          func(n *Notify) { n.Channels = nil },
+		"unknown channel":      func(n *Notify) { n.Channels = []string{"push", "sms"} },
+		"repeated channel":     func(n *Notify) { n.Channels = []string{"push", "push"} },
+		"email without fields": func(n *Notify) { n.Email = nil },
+		"email without sender": func(n *Notify) { n.Email = &NotifyEmail{Transactional: boolPtr(true)} },
+		"email not classified": func(n *Notify) {
+			n.Email = &NotifyEmail{FromAddress: "noreply@posta.opencrafts.io"}
+		},
+		"unknown policy":         func(n *Notify) { n.Policy = "broadcast" },
+		"fallback without delay": func(n *Notify) { n.FallbackAfterMinutes = 0 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			notify := valid
+			mutate(&notify)
+			assert.Error(t, validateNotify(notify))
+		})
+	}
+}
+
+func TestAggregateNotifyStatus(t *testing.T) {
+	for name, tt := range map[string]struct {
+		policy   string
+		outcomes []string
+		want     string
+	}{
+		"all accepted": {
+			policy:   NotifyPolicyAll,
+			outcomes: []string{notifyOutcomeAccepted, notifyOutcomeAccepted},
+			want:     "sent",
+		},
+		"all with one failed": {
+			policy:   NotifyPolicyAll,
+			outcomes: []string{notifyOutcomeAccepted, notifyOutcomeFailed},
+			want:     "partial",
+		},
+		"all with one opted out": {
+			policy:   NotifyPolicyAll,
+			outcomes: []string{notifyOutcomeSuppressed, notifyOutcomeAccepted},
+			want:     "sent",
+		},
+		"first success after a failure": {
+			policy:   NotifyPolicyFirstSuccess,
+			outcomes: []string{notifyOutcomeFailed, notifyOutcomeAccepted},
+			want:     "sent",
+		},
+		"everyone opted out": {
+			policy:   NotifyPolicyFallback,
+			outcomes: []string{notifyOutcomeSuppressed, notifyOutcomeSuppressed},
+			want:     "suppressed",
+		},
+		"nothing got through": {
+			policy:   NotifyPolicyFirstSuccess,
+			outcomes: []string{notifyOutcomeSuppressed, notifyOutcomeFailed},
+			want:     "failed",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, aggregateNotifyStatus(tt.policy, tt.outcomes))
+		})
+	}
+}
+
+func TestChildQueueMessageID_IsReserved(t *testing.T) {
+	assert.Equal(t,
+		"notify/6f1d2c3b-4a5e-4f60-8172-93a4b5c6d7e8/push",
+		childQueueMessageID(testNotifyRequestID, "push"),
+	)
+	assert.True(t, IsReservedRequestID(childQueueMessageID(testNotifyRequestID, "email")))
+	assert.False(t, IsReservedRequestID("b7e2d1c4-22a3-4f6b-8d2e-000000000001"))
+	assert.False(t, IsReservedRequestID("b7e2d1c4-22a3-4f6b-8d2e-000000000001/push"))
+}
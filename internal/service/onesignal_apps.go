@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/OneSignal/onesignal-go-api/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/tracing"
+)
+
+// OneSignalApp is a OneSignal app and the REST API key that can send
+// through it.
+type OneSignalApp struct {
+	AppID      string
+	RestAPIKey string
+}
+
+// oneSignalApp is a OneSignal app ready to call: its own API client, and
+// its own breaker so one app's outage doesn't stop pushes through another.
+type oneSignalApp struct {
+	appID   string
+	client  *onesignal.APIClient
+	breaker resilience.Breaker[*onesignalCallResult]
+}
+
+// oneSignalApps resolves which OneSignal app a service's pushes go out
+// through.
+type oneSignalApps interface {
+	forService(ctx context.Context, serviceID *string) (*oneSignalApp, error)
+	// forApp returns the app appID, the one a stored push went out
+	// through, whichever app its service has moved to since.
+	forApp(ctx context.Context, appID string) (*oneSignalApp, error)
+}
+
+// oneSignalAppRegistry resolves a service to the app set on its services
+// row, falling back to the globally configured app. Clients and breakers
+// are built once per app and reused.
+type oneSignalAppRegistry struct {
+	repo            repository.Querier
+	defaultApp      OneSignalApp
+	breakerSettings resilience.Settings
+	logger          *slog.Logger
+
+	mu sync.Mutex
+	// apps and keys are keyed by app id. keys holds the REST API key each
+	// cached client was built with, so a rotated key gets a new client.
+	apps map[string]*oneSignalApp
+	keys map[string]string
+}
+
+func newOneSignalAppRegistry(
+	repo repository.Querier,
+	defaultApp OneSignalApp,
+	breakerSettings resilience.Settings,
+	logger *slog.Logger,
+) *oneSignalAppRegistry {
+	return &oneSignalAppRegistry{
+		repo:            repo,
+		defaultApp:      defaultApp,
+		breakerSettings: breakerSettings,
+		logger:          logger,
+		apps:            map[string]*oneSignalApp{},
+		keys:            map[string]string{},
+	}
+}
+
+func (r *oneSignalAppRegistry) forService(
+	ctx context.Context,
+	serviceID *string,
+) (*oneSignalApp, error) {
+	app := r.defaultApp
+	if serviceID != nil && *serviceID != "" {
+		row, err := r.repo.GetServiceOneSignalApp(ctx, *serviceID)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// Services push without registering; unknown ones use the
+			// default app.
+		case err != nil:
+			return nil, fmt.Errorf("failed to look up onesignal app for service: %w", err)
+		case row.OnesignalAppID != nil && row.OnesignalRestApiKey != nil:
+			app = OneSignalApp{
+				AppID:      *row.OnesignalAppID,
+				RestAPIKey: *row.OnesignalRestApiKey,
+			}
+		}
+	}
+
+	if app.AppID == "" {
+		return nil, errors.New("no onesignal app configured: set ONESIGNAL_APP_ID or the service's onesignal_app_id")
+	}
+	return r.get(app), nil
+}
+
+func (r *oneSignalAppRegistry) forApp(
+	ctx context.Context,
+	appID string,
+) (*oneSignalApp, error) {
+	// A push stored before services had apps of their own went out
+	// through the default app.
+	if appID == "" || appID == r.defaultApp.AppID {
+		if r.defaultApp.AppID == "" {
+			return nil, errors.New("no onesignal app configured: set ONESIGNAL_APP_ID")
+		}
+		return r.get(r.defaultApp), nil
+	}
+
+	key, err := r.repo.GetOneSignalAppKey(ctx, &appID)
+	switch {
+	case err == nil:
+		return r.get(OneSignalApp{AppID: appID, RestAPIKey: key}), nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("failed to look up onesignal app key: %w", err)
+	}
+
+	// No service holds the app's key any more, but a client built
+	// before it was taken away still works until the key is revoked.
+	r.mu.Lock()
+	cached, ok := r.apps[appID]
+	r.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+	return nil, resilience.Permanent(fmt.Errorf("no REST API key on record for onesignal app %q", appID))
+}
+
+// get returns the cached client for app, building it on first use.
+func (r *oneSignalAppRegistry) get(app OneSignalApp) *oneSignalApp {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cached, ok := r.apps[app.AppID]
+	if ok && r.keys[app.AppID] == app.RestAPIKey {
+		return cached
+	}
+
+	config := onesignal.NewConfiguration()
+	config.HTTPClient = tracing.NewHTTPClient(0)
+	config.AddDefaultHeader("Authorization", fmt.Sprintf("Basic %s", app.RestAPIKey))
+
+	built := &oneSignalApp{
+		appID:  app.AppID,
+		client: onesignal.NewAPIClient(config),
+	}
+	if ok {
+		// Key rotated: keep the breaker, its state is about the app.
+		built.breaker = cached.breaker
+	} else {
+		built.breaker = resilience.New[*onesignalCallResult](
+			"onesignal:"+app.AppID,
+			r.breakerSettings,
+			r.logger,
+		)
+	}
+
+	r.apps[app.AppID] = built
+	r.keys[app.AppID] = app.RestAPIKey
+	return built
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOneSignalAppRegistry_ServiceWithOwnApp(t *testing.T) {
+	appID, key := "keepup-app", "keepup-key"
+	repo := &fakeQuerier{
+		getServiceOneSignalApp: func(_ context.Context, id string) (repository.GetServiceOneSignalAppRow, error) {
+			if id == "io.opencrafts.keepup" {
+				return repository.GetServiceOneSignalAppRow{
+					OnesignalAppID:      &appID,
+					OnesignalRestApiKey: &key,
+				}, nil
+			}
+			return repository.GetServiceOneSignalAppRow{}, pgx.ErrNoRows
+		},
+	}
+	registry := newOneSignalAppRegistry(
+		repo,
+		OneSignalApp{AppID: "default-app", RestAPIKey: "default-key"},
+		resilience.Settings{ConsecutiveFailures: 5},
+		testLogger(),
+	)
+
+	keepUp := "io.opencrafts.keepup"
+	sherehe := "io.opencrafts.sherehe"
+
+	own, err := registry.forService(context.Background(), &keepUp)
+	require.NoError(t, err)
+	assert.Equal(t, "keepup-app", own.appID)
+
+	fallback, err := registry.forService(context.Background(), &sherehe)
+	require.NoError(t, err)
+	assert.Equal(t, "default-app", fallback.appID)
+
+	again, err := registry.forService(context.Background(), &keepUp)
+	require.NoError(t, err)
+	assert.Same(t, own, again, "the client and breaker for an app must be reused")
+	assert.NotSame(t, own.breaker, fallback.breaker)
+}
+
+func TestOneSignalAppRegistry_RotatedKeyKeepsBreaker(t *testing.T) {
+	registry := newOneSignalAppRegistry(
+		&fakeQuerier{},
+		OneSignalApp{},
+		resilience.Settings{ConsecutiveFailures: 5},
+		testLogger(),
+	)
+
+	before := registry.get(OneSignalApp{AppID: "app", RestAPIKey: "old"})
+	after := registry.get(OneSignalApp{AppID: "app", RestAPIKey: "new"})
+
+	assert.NotSame(t, before.client, after.client)
+	assert.Same(t, before.breaker, after.breaker)
+}
+
+func TestOneSignalAppRegistry_ForAppOfPushNotService(t *testing.T) {
+	oldKey := "keepup-old-key"
+	// The push went out through an app its service has since moved off;
+	// the app's key is still on record for a service that shares it.
+	repo := &fakeQuerier{
+		getOneSignalAppKey: func(_ context.Context, appID *string) (string, error) {
+			if *appID == "keepup-old-app" {
+				return oldKey, nil
+			}
+			return "", pgx.ErrNoRows
+		},
+	}
+	registry := newOneSignalAppRegistry(
+		repo,
+		OneSignalApp{AppID: "default-app", RestAPIKey: "default-key"},
+		resilience.Settings{ConsecutiveFailures: 5},
+		testLogger(),
+	)
+
+	old, err := registry.forApp(context.Background(), "keepup-old-app")
+	require.NoError(t, err)
+	assert.Equal(t, "keepup-old-app", old.appID)
+
+	legacy, err := registry.forApp(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "default-app", legacy.appID, "a push stored without an app went out through the default one")
+
+	_, err = registry.forApp(context.Background(), "forgotten-app")
+	require.Error(t, err)
+	assert.True(t, resilience.IsPermanent(err), "no key will turn up on a retry")
+}
+
+func TestOneSignalAppRegistry_NoAppConfigured(t *testing.T) {
+	registry := newOneSignalAppRegistry(
+		&fakeQuerier{},
+		OneSignalApp{},
+		resilience.Settings{},
+		testLogger(),
+	)
+
+	_, err := registry.forService(context.Background(), nil)
+
+	require.Error(t, err)
+}
+
+func TestSend_AppIDOfAnotherApp_PersistsFailed(t *testing.T) {
+	var captured repository.UpsertNotificationParams
+	calls := 0
+	repo := &fakeQuerier{
+		upsertNotification: func(_ context.Context, arg repository.UpsertNotificationParams) (repository.Notification, error) {
+			captured = arg
+			return repository.Notification{}, nil
+		},
+	}
+
+	pns := &pushNotificationService{
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
+	}
+
+	push := validPushNotification()
+	push.AppID = "someone-elses-app"
+
+	err := pns.Send(context.Background(), push, "req-app")
+
+	require.Error(t, err)
+	assert.Equal(t, 0, calls)
+	require.NotNil(t, captured.Status)
+	assert.Equal(t, "failed", *captured.Status)
+}
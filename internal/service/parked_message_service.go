@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+)
+
+// Triage actions recorded in parked_message_actions.
+const (
+	ParkedActionList    = "list"
+	ParkedActionInspect = "inspect"
+	ParkedActionReplay  = "replay"
+	ParkedActionDiscard = "discard"
+)
+
+// parkedQueue is the part of broker.ParkedQueueBrowser the service uses.
+type parkedQueue interface {
+	List(ctx context.Context, limit int) ([]broker.ParkedMessage, error)
+	Get(ctx context.Context, id string) (broker.ParkedMessage, error)
+	Replay(ctx context.Context, id string) (broker.ParkedMessage, error)
+	Discard(ctx context.Context, id string) (broker.ParkedMessage, error)
+}
+
+// ParkedMessageService triages messages that exhausted their retries:
+// browsing, replaying them to where they came from, or discarding them.
+// Every action, successful or not, is recorded in parked_message_actions
+// against the actor who took it.
+type ParkedMessageService interface {
+	List(ctx context.Context, actor string, limit int) ([]broker.ParkedMessage, error)
+	Inspect(ctx context.Context, actor, id string) (broker.ParkedMessage, error)
+	Replay(ctx context.Context, actor, id string) (broker.ParkedMessage, error)
+	Discard(ctx context.Context, actor, id string) (broker.ParkedMessage, error)
+}
+
+type parkedMessageService struct {
+	queue  parkedQueue
+	repo   repository.Querier
+	logger *slog.Logger
+}
+
+func NewParkedMessageService(
+	browser *broker.ParkedQueueBrowser,
+	repo repository.Querier,
+	logger *slog.Logger,
+) ParkedMessageService {
+	return &parkedMessageService{queue: browser, repo: repo, logger: logger}
+}
+
+func (ps *parkedMessageService) List(
+	ctx context.Context,
+	actor string,
+	limit int,
+) ([]broker.ParkedMessage, error) {
+	if limit <= 0 || limit > broker.MaxParkedScan {
+		limit = int(DefaultPageSize)
+	}
+
+	messages, err := ps.queue.List(ctx, limit)
+	if auditErr := ps.audit(ctx, ParkedActionList, actor, "", broker.ParkedMessage{}, err); auditErr != nil {
+		return nil, auditErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parked messages: %w", err)
+	}
+	return messages, nil
+}
+
+func (ps *parkedMessageService) Inspect(
+	ctx context.Context,
+	actor, id string,
+) (broker.ParkedMessage, error) {
+	return ps.act(ctx, ParkedActionInspect, actor, id, ps.queue.Get)
+}
+
+func (ps *parkedMessageService) Replay(
+	ctx context.Context,
+	actor, id string,
+) (broker.ParkedMessage, error) {
+	return ps.act(ctx, ParkedActionReplay, actor, id, ps.queue.Replay)
+}
+
+func (ps *parkedMessageService) Discard(
+	ctx context.Context,
+	actor, id string,
+) (broker.ParkedMessage, error) {
+	return ps.act(ctx, ParkedActionDiscard, actor, id, ps.queue.Discard)
+}
+
+// act runs a triage action on one parked message and audits it.
+func (ps *parkedMessageService) act(
+	ctx context.Context,
+	action, actor, id string,
+	run func(ctx context.Context, id string) (broker.ParkedMessage, error),
+) (broker.ParkedMessage, error) {
+	message, err := run(ctx, id)
+	if auditErr := ps.audit(ctx, action, actor, id, message, err); auditErr != nil {
+		return message, auditErr
+	}
+	if err != nil {
+		return message, fmt.Errorf("failed to %s parked message: %w", action, err)
+	}
+	return message, nil
+}
+
+// audit records an action. A replay or discard has already happened by the
+// time this runs, so a failure here is logged loudly and returned: the
+// queue and the audit trail now disagree.
+func (ps *parkedMessageService) audit(
+	ctx context.Context,
+	action, actor, id string,
+	message broker.ParkedMessage,
+	actionErr error,
+) error {
+	params := repository.CreateParkedMessageActionParams{
+		Action:    action,
+		Actor:     actor,
+		Succeeded: actionErr == nil,
+	}
+	if id != "" {
+		params.ParkedMessageID = &id
+	}
+	if message.ID != "" {
+		params.OriginalExchange = &message.OriginalExchange
+		params.OriginalRoutingKey = &message.OriginalRoutingKey
+		if action == ParkedActionReplay || action == ParkedActionDiscard {
+			params.Body = message.RawBody()
+		}
+	}
+	if actionErr != nil {
+		errText := actionErr.Error()
+		params.Error = &errText
+	}
+
+	if _, err := ps.repo.CreateParkedMessageAction(ctx, params); err != nil {
+		ps.logger.Error("failed to audit parked message action",
+			slog.String("action", action),
+			slog.String("actor", actor),
+			slog.String("parked_message_id", id),
+			slog.Bool("succeeded", actionErr == nil),
+			slog.Any("error", err),
+		)
+		return fmt.Errorf("failed to audit %s of parked message: %w", action, err)
+	}
+	return nil
+}
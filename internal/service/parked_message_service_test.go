@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeParkedQueue stands in for broker.ParkedQueueBrowser; every call
+// returns message and err.
+type fakeParkedQueue struct {
+	message broker.ParkedMessage
+	err     error
+}
+
+func (f fakeParkedQueue) List(context.Context, int) ([]broker.ParkedMessage, error) {
+	return []broker.ParkedMessage{f.message}, f.err
+}
+
+func (f fakeParkedQueue) Get(context.Context, string) (broker.ParkedMessage, error) {
+	return f.message, f.err
+}
+
+func (f fakeParkedQueue) Replay(context.Context, string) (broker.ParkedMessage, error) {
+	return f.message, f.err
+}
+
+func (f fakeParkedQueue) Discard(context.Context, string) (broker.ParkedMessage, error) {
+	return f.message, f.err
+}
+
+func recordingAudit(audited *[]repository.CreateParkedMessageActionParams) *fakeQuerier {
+	return &fakeQuerier{
+		createParkedMessageAction: func(_ context.Context, arg repository.CreateParkedMessageActionParams) (repository.ParkedMessageAction, error) {
+			*audited = append(*audited, arg)
+			return repository.ParkedMessageAction{}, nil
+		},
+	}
+}
+
+func TestParkedMessageService_Replay_AuditsActorAndOrigin(t *testing.T) {
+	var audited []repository.CreateParkedMessageActionParams
+	ps := &parkedMessageService{
+		queue: fakeParkedQueue{message: broker.ParkedMessage{
+			ID:                 "parked-1",
+			OriginalExchange:   "gossip.topic.exchange",
+			OriginalRoutingKey: "gossip.emails.send",
+		}},
+		repo:   recordingAudit(&audited),
+		logger: testLogger(),
+	}
+
+	message, err := ps.Replay(context.Background(), "cli:amina", "parked-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "parked-1", message.ID)
+	require.Len(t, audited, 1)
+	assert.Equal(t, ParkedActionReplay, audited[0].Action)
+	assert.Equal(t, "cli:amina", audited[0].Actor)
+	assert.True(t, audited[0].Succeeded)
+	require.NotNil(t, audited[0].ParkedMessageID)
+	assert.Equal(t, "parked-1", *audited[0].ParkedMessageID)
+	require.NotNil(t, audited[0].OriginalRoutingKey)
+	assert.Equal(t, "gossip.emails.send", *audited[0].OriginalRoutingKey)
+}
+
+func TestParkedMessageService_FailedAction_IsAuditedToo(t *testing.T) {
+	var audited []repository.CreateParkedMessageActionParams
+	ps := &parkedMessageService{
+		queue:  fakeParkedQueue{err: broker.ErrParkedMessageNotFound},
+		repo:   recordingAudit(&audited),
+		logger: testLogger(),
+	}
+
+	_, err := ps.Discard(context.Background(), "admin-api", "missing")
+
+	assert.ErrorIs(t, err, broker.ErrParkedMessageNotFound)
+	require.Len(t, audited, 1)
+	assert.False(t, audited[0].Succeeded)
+	require.NotNil(t, audited[0].Error)
+	assert.Contains(t, *audited[0].Error, "parked message not found")
+}
+
+func TestParkedMessageService_AuditFailure_IsReturned(t *testing.T) {
+	ps := &parkedMessageService{
+		queue: fakeParkedQueue{message: broker.ParkedMessage{ID: "parked-1"}},
+		repo: &fakeQuerier{
+			createParkedMessageAction: func(context.Context, repository.CreateParkedMessageActionParams) (repository.ParkedMessageAction, error) {
+				return repository.ParkedMessageAction{}, errors.New("db down")
+			},
+		},
+		logger: testLogger(),
+	}
+
+	_, err := ps.Discard(context.Background(), "admin-api", "parked-1")
+
+	assert.ErrorContains(t, err, "failed to audit discard")
+}
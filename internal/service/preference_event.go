@@ -0,0 +1,38 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Channels a notification preference can be set for.
+const (
+	channelPush  = "push"
+	channelEmail = "email"
+	channelSms   = "sms"
+	channelInApp = "in_app"
+)
+
+type NotificationPreference struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Channel string    `json:"channel"`
+	// NotificationType is the notification_type the preference applies to,
+	// or "*" for every type on the channel.
+	NotificationType string `json:"notification_type"`
+	// Enabled is required by preferences.update and ignored by
+	// preferences.reset.
+	Enabled *bool `json:"enabled"`
+}
+
+type PreferenceEventMetadata struct {
+	EventType       string    `json:"event_type"`
+	Timestamp       time.Time `json:"timestamp"`
+	SourceServiceID string    `json:"source_service_id"`
+	RequestID       string    `json:"request_id"`
+}
+
+type PreferenceEvent struct {
+	Preference NotificationPreference  `json:"preference"`
+	Meta       PreferenceEventMetadata `json:"metadata"`
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+)
+
+// maxNotificationTypeLength is the width of the notification_type columns.
+const maxNotificationTypeLength = 100
+
+type PreferenceService interface {
+	// Update records whether the user wants the preference's
+	// notification_type on its channel.
+	Update(ctx context.Context, event PreferenceEvent) error
+	// Reset forgets the preference, so the user gets the notification_type
+	// on its channel again unless a "*" preference says otherwise.
+	Reset(ctx context.Context, event PreferenceEvent) error
+}
+
+type preferenceService struct {
+	repo   repository.Querier
+	logger *slog.Logger
+}
+
+func NewPreferenceService(repo repository.Querier, logger *slog.Logger) PreferenceService {
+	return &preferenceService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (ps *preferenceService) Update(ctx context.Context, event PreferenceEvent) error {
+	pref := event.Preference
+	if err := validatePreference(pref); err != nil {
+		return resilience.Permanent(err)
+	}
+	if pref.Enabled == nil {
+		return resilience.Permanent(errors.New("enabled is required to update a preference"))
+	}
+
+	if err := ps.repo.UpsertNotificationPreference(ctx, repository.UpsertNotificationPreferenceParams{
+		UserID:           pref.UserID,
+		Channel:          pref.Channel,
+		NotificationType: pref.NotificationType,
+		Enabled:          *pref.Enabled,
+		SourceServiceID:  event.Meta.SourceServiceID,
+		UpdatedAt:        eventTime(event.Meta.Timestamp),
+	}); err != nil {
+		return fmt.Errorf("failed to record notification preference: %w", err)
+	}
+
+	ps.logger.Info("notification preference updated",
+		"user_id", pref.UserID,
+		"channel", pref.Channel,
+		"notification_type", pref.NotificationType,
+		"enabled", *pref.Enabled,
+	)
+	return nil
+}
+
+func (ps *preferenceService) Reset(ctx context.Context, event PreferenceEvent) error {
+	pref := event.Preference
+	if err := validatePreference(pref); err != nil {
+		return resilience.Permanent(err)
+	}
+
+	if err := ps.repo.DeleteNotificationPreference(ctx, repository.DeleteNotificationPreferenceParams{
+		UserID:           pref.UserID,
+		Channel:          pref.Channel,
+		NotificationType: pref.NotificationType,
+		ResetAt:          eventTime(event.Meta.Timestamp),
+	}); err != nil {
+		return fmt.Errorf("failed to reset notification preference: %w", err)
+	}
+
+	ps.logger.Info("notification preference reset",
+		"user_id", pref.UserID,
+		"channel", pref.Channel,
+		"notification_type", pref.NotificationType,
+	)
+	return nil
+}
+
+func validatePreference(pref NotificationPreference) error {
+	if pref.UserID == uuid.Nil {
+		return errors.New("user_id is required")
+	}
+	switch pref.Channel {
+	case channelPush, channelEmail, channelSms, channelInApp:
+	default:
+		return fmt.Errorf("channel must be one of push, email, sms or in_app, got %q", pref.Channel)
+	}
+	if pref.NotificationType == "" {
+		return errors.New(`notification_type is required; use "*" for every type`)
+	}
+	if len(pref.NotificationType) > maxNotificationTypeLength {
+		return fmt.Errorf("notification_type must be at most %d characters", maxNotificationTypeLength)
+	}
+	return nil
+}
+
+// eventTime is when a preference event was published, or now if the
+// publisher didn't say.
+func eventTime(timestamp time.Time) pgtype.Timestamptz {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return pgtype.Timestamptz{Time: timestamp, Valid: true}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func preferenceEvent(enabled *bool) PreferenceEvent {
+	return PreferenceEvent{
+		Preference: NotificationPreference{
+			UserID:           uuid.MustParse("a1b2c3d4-e5f6-7890-abcd-ef1234567890"),
+			Channel:          "email",
+			NotificationType: "marketing",
+			Enabled:          enabled,
+		},
+		Meta: PreferenceEventMetadata{
+			EventType:       "preferences.update",
+			Timestamp:       time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+			SourceServiceID: "io.opencrafts.settings",
+			RequestID:       "req-1",
+		},
+	}
+}
+
+func TestPreferenceUpdate_RecordsPreferenceAsOfEventTime(t *testing.T) {
+	var captured repository.UpsertNotificationPreferenceParams
+	repo := &fakeQuerier{
+		upsertNotificationPreference: func(_ context.Context, arg repository.UpsertNotificationPreferenceParams) error {
+			captured = arg
+			return nil
+		},
+	}
+	disabled := false
+
+	err := NewPreferenceService(repo, testLogger()).Update(context.Background(), preferenceEvent(&disabled))
+
+	require.NoError(t, err)
+	assert.Equal(t, "email", captured.Channel)
+	assert.Equal(t, "marketing", captured.NotificationType)
+	assert.False(t, captured.Enabled)
+	assert.Equal(t, "io.opencrafts.settings", captured.SourceServiceID)
+	assert.Equal(t, time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC), captured.UpdatedAt.Time)
+}
+
+func TestPreferenceUpdate_InvalidEventIsPermanent(t *testing.T) {
+	enabled := true
+	tests := map[string]func(e *PreferenceEvent){
+		"missing user":    func(e *PreferenceEvent) { e.Preference.UserID = uuid.Nil },
+		"unknown channel": func(e *PreferenceEvent) { e.Preference.Channel = "fax" },
+		"missing type":    func(e *PreferenceEvent) { e.Preference.NotificationType = "" },
+		"missing enabled": func(e *PreferenceEvent) { e.Preference.Enabled = nil },
+	}
+
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			event := preferenceEvent(&enabled)
+			mutate(&event)
+
+			// fakeQuerier panics if the repository is reached.
+			err := NewPreferenceService(&fakeQuerier{}, testLogger()).Update(context.Background(), event)
+
+			require.Error(t, err)
+			assert.True(t, resilience.IsPermanent(err))
+		})
+	}
+}
+
+func TestPreferenceReset_DeletesPreferenceWithoutEnabled(t *testing.T) {
+	var captured repository.DeleteNotificationPreferenceParams
+	repo := &fakeQuerier{
+		deleteNotificationPreference: func(_ context.Context, arg repository.DeleteNotificationPreferenceParams) error {
+			captured = arg
+			return nil
+		},
+	}
+	event := preferenceEvent(nil)
+	event.Meta.EventType = "preferences.reset"
+	event.Meta.Timestamp = time.Time{}
+
+	err := NewPreferenceService(repo, testLogger()).Reset(context.Background(), event)
+
+	require.NoError(t, err)
+	assert.Equal(t, "marketing", captured.NotificationType)
+	assert.WithinDuration(t, time.Now(), captured.ResetAt.Time, time.Minute,
+		"an event without a timestamp resets as of now")
+}
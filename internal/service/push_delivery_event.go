@@ -0,0 +1,28 @@
+package service
+
+import "errors"
+
+// ErrNotificationNotFound is returned when a OneSignal webhook refers to a
+// notification id gossip-monger has no record of.
+var ErrNotificationNotFound = errors.New("no notification found for onesignal notification id")
+
+// ErrTargetRequestNotOwned is returned when a cancel or reschedule names a
+// push or email that another service sent.
+var ErrTargetRequestNotOwned = errors.New("target request was sent by another service")
+
+// OneSignal webhook event names gossip-monger acts on. OneSignal's web push
+// webhooks call a delivery "displayed"; Event Streams can be configured to
+// send either name.
+const (
+	OneSignalEventDisplayed = "notification.displayed"
+	OneSignalEventDelivered = "notification.delivered"
+	OneSignalEventClicked   = "notification.clicked"
+	OneSignalEventDismissed = "notification.dismissed"
+)
+
+// OneSignalWebhookEvent is the body OneSignal posts for a notification
+// lifecycle event, in the shape of its web push webhooks.
+type OneSignalWebhookEvent struct {
+	Event          string `json:"event"`
+	NotificationID string `json:"notificationId"`
+}
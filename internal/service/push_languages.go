@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/OneSignal/onesignal-go-api/v5"
+)
+
+// languageSetters maps every language code OneSignal's LanguageStringMap
+// accepts to the setter for it. Codes are case-sensitive, as OneSignal
+// expects them ("zh-Hans", not "zh-hans").
+var languageSetters = map[string]func(*onesignal.LanguageStringMap, string){
+	"en":      (*onesignal.LanguageStringMap).SetEn,
+	"ar":      (*onesignal.LanguageStringMap).SetAr,
+	"bs":      (*onesignal.LanguageStringMap).SetBs,
+	"bg":      (*onesignal.LanguageStringMap).SetBg,
+	"ca":      (*onesignal.LanguageStringMap).SetCa,
+	"zh-Hans": (*onesignal.LanguageStringMap).SetZhHans,
+	"zh-Hant": (*onesignal.LanguageStringMap).SetZhHant,
+	"zh":      (*onesignal.LanguageStringMap).SetZh,
+	"hr":      (*onesignal.LanguageStringMap).SetHr,
+	"cs":      (*onesignal.LanguageStringMap).SetCs,
+	"da":      (*onesignal.LanguageStringMap).SetDa,
+	"nl":      (*onesignal.LanguageStringMap).SetNl,
+	"et":      (*onesignal.LanguageStringMap).SetEt,
+	"fi":      (*onesignal.LanguageStringMap).SetFi,
+	"fr":      (*onesignal.LanguageStringMap).SetFr,
+	"ka":      (*onesignal.LanguageStringMap).SetKa,
+	"de":      (*onesignal.LanguageStringMap).SetDe,
+	"el":      (*onesignal.LanguageStringMap).SetEl,
+	"hi":      (*onesignal.LanguageStringMap).SetHi,
+	"he":      (*onesignal.LanguageStringMap).SetHe,
+	"hu":      (*onesignal.LanguageStringMap).SetHu,
+	"id":      (*onesignal.LanguageStringMap).SetId,
+	"it":      (*onesignal.LanguageStringMap).SetIt,
+	"ja":      (*onesignal.LanguageStringMap).SetJa,
+	"ko":      (*onesignal.LanguageStringMap).SetKo,
+	"lv":      (*onesignal.LanguageStringMap).SetLv,
+	"lt":      (*onesignal.LanguageStringMap).SetLt,
+	"ms":      (*onesignal.LanguageStringMap).SetMs,
+	"nb":      (*onesignal.LanguageStringMap).SetNb,
+	"pl":      (*onesignal.LanguageStringMap).SetPl,
+	"fa":      (*onesignal.LanguageStringMap).SetFa,
+	"pt":      (*onesignal.LanguageStringMap).SetPt,
+	"pa":      (*onesignal.LanguageStringMap).SetPa,
+	"ro":      (*onesignal.LanguageStringMap).SetRo,
+	"ru":      (*onesignal.LanguageStringMap).SetRu,
+	"sr":      (*onesignal.LanguageStringMap).SetSr,
+	"sk":      (*onesignal.LanguageStringMap).SetSk,
+	"es":      (*onesignal.LanguageStringMap).SetEs,
+	"sv":      (*onesignal.LanguageStringMap).SetSv,
+	"th":      (*onesignal.LanguageStringMap).SetTh,
+	"tr":      (*onesignal.LanguageStringMap).SetTr,
+	"uk":      (*onesignal.LanguageStringMap).SetUk,
+	"vi":      (*onesignal.LanguageStringMap).SetVi,
+}
+
+// toLanguageStringMap converts a {"<language code>": "<text>"} object into
+// OneSignal's LanguageStringMap. An unsupported code is an error rather
+// than being dropped, so a publisher finds out its translation never went
+// out. field names the payload field in that error.
+func toLanguageStringMap(
+	field string,
+	texts map[string]string,
+) (*onesignal.LanguageStringMap, error) {
+	var unknown []string
+	langMap := onesignal.NewLanguageStringMap()
+	for lang, text := range texts {
+		set, ok := languageSetters[lang]
+		if !ok {
+			unknown = append(unknown, lang)
+			continue
+		}
+		set(langMap, text)
+	}
+
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return nil, fmt.Errorf(
+			"%s has unsupported language code(s): %s",
+			field,
+			strings.Join(unknown, ", "),
+		)
+	}
+	return langMap, nil
+}
@@ -8,13 +8,18 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/OneSignal/onesignal-go-api/v5"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/opencrafts-io/gossip-monger/internal/metrics"
 	"github.com/opencrafts-io/gossip-monger/internal/repository"
 	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // onesignalCallResult bundles the two non-error return values of the
@@ -29,6 +34,9 @@ type PushNotificationEventMetaData struct {
 	Timestamp       time.Time `json:"timestamp"`
 	SourceServiceID string    `json:"source_service_id"`
 	RequestID       string    `json:"request_id"`
+	// TargetRequestID is the request_id of the earlier push.send a
+	// push.cancel or push.reschedule event refers to.
+	TargetRequestID string `json:"target_request_id,omitempty"`
 }
 
 type PushNotificationEvent struct {
@@ -36,28 +44,73 @@ type PushNotificationEvent struct {
 	Metadata     PushNotificationEventMetaData `json:"metadata"`
 }
 
+// UnmarshalJSON decodes a push event, taking delivery_time_of_day as a
+// readable time ("09:00", "9:00AM") rather than pgtype.Time's internal
+// representation.
+func (e *PushNotificationEvent) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Notification struct {
+			repository.Notification
+			// Shadows the embedded field of the same JSON name.
+			DeliveryTimeOfDay *string `json:"delivery_time_of_day"`
+		} `json:"notification"`
+		Metadata PushNotificationEventMetaData `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	e.Notification = raw.Notification.Notification
+	e.Metadata = raw.Metadata
+	if raw.Notification.DeliveryTimeOfDay != nil {
+		timeOfDay, err := parseTimeOfDay(*raw.Notification.DeliveryTimeOfDay)
+		if err != nil {
+			return err
+		}
+		e.Notification.DeliveryTimeOfDay = timeOfDay
+	}
+	return nil
+}
+
 type PushNotificationService interface {
+	// Send sends push now, or stores it as pending if it has a send_after
+	// in the future.
 	Send(ctx context.Context, push repository.Notification, queueMessageID string) error
+	// Dispatch sends an already-persisted push to OneSignal immediately,
+	// regardless of its send_after. Used by the PushScheduler once a
+	// scheduled push falls due.
+	Dispatch(ctx context.Context, push repository.Notification) error
+	// RecordEvent moves the notification a OneSignal webhook reports on
+	// through its lifecycle: delivered, read (clicked) or dismissed.
+	RecordEvent(ctx context.Context, event OneSignalWebhookEvent) error
+	// Cancel retracts the push serviceID sent under requestID if it hasn't
+	// gone out yet, asking OneSignal to cancel it when OneSignal is the one
+	// holding it back.
+	Cancel(ctx context.Context, serviceID, requestID string) error
+	// Reschedule moves the send_after of the pending push serviceID sent
+	// under requestID.
+	Reschedule(ctx context.Context, serviceID, requestID string, sendAfter pgtype.Timestamp) error
 }
 
 type pushNotificationService struct {
-	repo            repository.Querier
-	logger          *slog.Logger
-	onesignalClient *onesignal.APIClient
-	breaker         resilience.Breaker[*onesignalCallResult]
+	repo   repository.Querier
+	logger *slog.Logger
+	apps   oneSignalApps
 }
 
+// NewPushNotificationService creates the push service. Pushes from a
+// service with its own OneSignal app on its services row go out through
+// that app; every other push goes out through defaultApp.
 func NewPushNotificationService(
 	repo repository.Querier,
 	logger *slog.Logger,
-	onesignalClient *onesignal.APIClient,
+	defaultApp OneSignalApp,
 	breakerSettings resilience.Settings,
 ) PushNotificationService {
 	return &pushNotificationService{
-		repo:            repo,
-		onesignalClient: onesignalClient,
-		breaker:         resilience.New[*onesignalCallResult]("onesignal", breakerSettings, logger),
-		logger:          logger,
+		repo:   repo,
+		apps:   newOneSignalAppRegistry(repo, defaultApp, breakerSettings, logger),
+		logger: logger,
 	}
 }
 
@@ -65,19 +118,26 @@ func (pns *pushNotificationService) Send(
 	ctx context.Context,
 	push repository.Notification,
 	queueMessageID string,
-) error {
+) (err error) {
+	ctx, span := tracer.Start(ctx, "PushNotificationService.Send", trace.WithAttributes(
+		attribute.String("gossip.queue_message_id", queueMessageID),
+	))
+	defer func() { tracing.End(span, err) }()
+
 	push.QueueMessageID = &queueMessageID
 
 	// A retry (DLX redelivery) and an external duplicate republish of the
 	// same queueMessageID are indistinguishable at this point — both must
-	// be safe. If this id already reached OneSignal successfully, skip
-	// resending: proceeding would happily resend a push that already went
-	// through.
+	// be safe. If this id was already accepted — sent, or scheduled and
+	// waiting for (or claimed by) the scheduler — skip it: proceeding would
+	// happily send a push that already went through, or send a scheduled
+	// one early. Only a failed attempt is worth repeating.
 	existing, err := pns.repo.GetNotificationByQueueMessageID(ctx, &queueMessageID)
 	if err == nil {
-		if existing.Status != nil && *existing.Status == "sent" {
-			pns.logger.Info("duplicate queue_message_id already sent, skipping resend",
+		if existing.Status != nil && !retryableStatus(*existing.Status) {
+			pns.logger.Info("duplicate queue_message_id already accepted, skipping resend",
 				"queue_message_id", queueMessageID,
+				"status", *existing.Status,
 			)
 			return nil
 		}
@@ -85,18 +145,94 @@ func (pns *pushNotificationService) Send(
 		return fmt.Errorf("failed to check for duplicate notification: %w", err)
 	}
 
-	payload, err := pns.preparePushPayload(push)
+	if push.SendAfter.Valid {
+		return pns.schedule(ctx, push)
+	}
+
+	return pns.Dispatch(ctx, push)
+}
+
+// schedule validates a push with a send_after and stores it as pending for
+// the PushScheduler to dispatch once it is due. Scheduling is kept here
+// rather than handed to OneSignal so a scheduled push stays cancellable and
+// editable until it actually goes out.
+func (pns *pushNotificationService) schedule(
+	ctx context.Context,
+	push repository.Notification,
+) error {
+	app, err := pns.apps.forService(ctx, push.SourceServiceID)
+	if err != nil {
+		return err
+	}
+
+	err = assignApp(&push, app)
+	if err == nil {
+		err = pns.validateSendAfter(push.SendAfter.Time)
+	}
+	if err == nil {
+		_, err = pns.preparePushPayload(push)
+	}
+	if err != nil {
+		pns.persistValidationFailure(ctx, &push, err)
+		return resilience.Permanent(err)
+	}
+
+	// send_after is a TIMESTAMP column; store it as UTC wall-clock time so
+	// the scheduler's comparison doesn't depend on the publisher's offset.
+	push.SendAfter.Time = push.SendAfter.Time.UTC()
+	if err := pns.persistOutcome(ctx, &push, "pending"); err != nil {
+		return err
+	}
+
+	pns.logger.Info("push notification scheduled",
+		"queue_message_id", *push.QueueMessageID,
+		"send_after", push.SendAfter.Time,
+	)
+	return nil
+}
+
+func (pns *pushNotificationService) Dispatch(
+	ctx context.Context,
+	push repository.Notification,
+) error {
+	app, err := pns.apps.forService(ctx, push.SourceServiceID)
+	if err != nil {
+		return err
+	}
+
+	var payload *onesignal.Notification
+	err = assignApp(&push, app)
+	if err == nil {
+		payload, err = pns.preparePushPayload(push)
+	}
 	if err != nil {
-		if persistErr := pns.persistOutcome(ctx, &push, "failed"); persistErr != nil {
-			pns.logger.Error("failed to persist notification after validation error",
-				"error", persistErr,
+		pns.persistValidationFailure(ctx, &push, err)
+		return resilience.Permanent(err)
+	}
+
+	// Preferences are checked now rather than when a scheduled push is
+	// accepted, so an opt-out made in the meantime is honoured.
+	sendable, suppressed, err := pns.withoutOptedOutUsers(ctx, push)
+	if err != nil {
+		return err
+	}
+	push.SuppressedUserIds = suppressed
+	if len(suppressed) > 0 {
+		if !pns.hasTargeting(sendable) {
+			pns.logger.Info("every recipient opted out, push suppressed",
+				"queue_message_id", derefString(push.QueueMessageID),
+				"notification_type", derefString(push.NotificationType),
 			)
+			return pns.persistOutcome(ctx, &push, "suppressed")
+		}
+		if payload, err = pns.preparePushPayload(sendable); err != nil {
+			return resilience.Permanent(err)
 		}
-		return err
 	}
 
-	result, callErr := pns.breaker.Execute(func() (*onesignalCallResult, error) {
-		_, httpResp, err := pns.onesignalClient.DefaultApi.
+	callStart := time.Now()
+	result, callErr := app.breaker.Execute(func() (*onesignalCallResult, error) {
+		_, httpResp, err := app.client.DefaultApi.
 			CreateNotification(ctx).Notification(*payload).Execute()
 		return &onesignalCallResult{httpResp: httpResp}, err
 	})
@@ -132,6 +268,7 @@ func (pns *pushNotificationService) Send(
 	case finalErr != nil:
 		outcomeStatus = "failed"
 	}
+	metrics.ObserveProviderCall("onesignal", outcomeStatus, callStart)
 
 	if persistErr := pns.persistOutcome(ctx, &push, outcomeStatus); persistErr != nil {
 		return persistErr
@@ -140,6 +277,233 @@ func (pns *pushNotificationService) Send(
 	return finalErr
 }
 
+func (pns *pushNotificationService) RecordEvent(
+	ctx context.Context,
+	event OneSignalWebhookEvent,
+) error {
+	push, err := pns.repo.GetNotificationByOneSignalID(ctx, &event.NotificationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotificationNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to look up notification: %w", err)
+	}
+
+	// Clicking or dismissing a notification proves it was delivered, and
+	// OneSignal does not guarantee the delivered event arrives first.
+	// Only a "sent" row moves to "delivered" — a push sent to a segment
+	// reports one delivery per device, and only the first one counts.
+	if push.Status != nil && *push.Status == "sent" {
+		delivered := "delivered"
+		if err := pns.repo.UpdateNotificationStatus(ctx, repository.UpdateNotificationStatusParams{
+			ID:     push.ID,
+			Status: &delivered,
+		}); err != nil {
+			return fmt.Errorf("failed to mark notification delivered: %w", err)
+		}
+	}
+
+	switch event.Event {
+	case OneSignalEventClicked:
+		if err := pns.repo.MarkNotificationAsRead(ctx, push.ID); err != nil {
+			return fmt.Errorf("failed to mark notification read: %w", err)
+		}
+	case OneSignalEventDismissed:
+		if err := pns.repo.MarkNotificationAsDismissed(ctx, push.ID); err != nil {
+			return fmt.Errorf("failed to mark notification dismissed: %w", err)
+		}
+	}
+
+	pns.logger.Info("recorded push notification event",
+		"notification_id", push.ID,
+		"event", event.Event,
+	)
+	return nil
+}
+
+func (pns *pushNotificationService) Cancel(
+	ctx context.Context,
+	serviceID, requestID string,
+) error {
+	if requestID == "" {
+		return resilience.Permanent(errors.New("target_request_id is required to cancel a push notification"))
+	}
+
+	cancelled, err := pns.repo.CancelPendingNotification(ctx, repository.CancelPendingNotificationParams{
+		QueueMessageID:  &requestID,
+		SourceServiceID: &serviceID,
+	})
+	if err == nil {
+		pns.logger.Info("push notification cancelled",
+			"notification_id", cancelled.ID,
+			"queue_message_id", requestID,
+		)
+		return nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to cancel notification: %w", err)
+	}
+
+	// Nothing was waiting to be cancelled; work out why.
+	push, err := pns.repo.GetNotificationByQueueMessageID(ctx, &requestID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The cancel may have overtaken the push.send it refers to;
+		// failing lets it be retried after the send has landed.
+		return fmt.Errorf("%w: queue_message_id %s", ErrNotificationNotFound, requestID)
+	} else if err != nil {
+		return fmt.Errorf("failed to look up notification: %w", err)
+	}
+	if err := pns.checkOwner(push, serviceID, "cancel"); err != nil {
+		return err
+	}
+
+	status := ""
+	if push.Status != nil {
+		status = *push.Status
+	}
+
+	switch {
+	case status == "cancelled":
+		return nil
+	case status == "sent" && push.OnesignalNotificationID != nil:
+		// OneSignal accepted it, but may still be holding it back
+		// (delayed_option, delivery_time_of_day). Only OneSignal knows.
+		return pns.cancelOnOneSignal(ctx, push)
+	default:
+		// Retrying won't make a push that already went out cancellable.
+		pns.logger.Warn("push notification already dispatched, cannot cancel",
+			"notification_id", push.ID,
+			"queue_message_id", requestID,
+			"status", status,
+		)
+		return nil
+	}
+}
+
+// cancelOnOneSignal asks OneSignal to cancel a push it accepted but may not
+// have delivered yet, and marks it cancelled if OneSignal agrees.
+func (pns *pushNotificationService) cancelOnOneSignal(
+	ctx context.Context,
+	push repository.Notification,
+) error {
+	// The app the push went out through, not the one its service uses
+	// now: OneSignal only knows the push by its id within that app.
+	app, err := pns.apps.forApp(ctx, push.AppID)
+	if err != nil {
+		return err
+	}
+
+	result, err := app.breaker.Execute(func() (*onesignalCallResult, error) {
+		_, httpResp, err := app.client.DefaultApi.
+			CancelNotification(ctx, *push.OnesignalNotificationID).
+			AppId(app.appID).
+			Execute()
+		return &onesignalCallResult{httpResp: httpResp}, err
+	})
+
+	statusCode := 0
+	if result != nil && result.httpResp != nil {
+		statusCode = result.httpResp.StatusCode
+		if result.httpResp.Body != nil {
+			result.httpResp.Body.Close()
+		}
+	}
+
+	if err != nil {
+		// A 4xx is OneSignal refusing, typically because the push has
+		// already been delivered; anything else is worth retrying.
+		if statusCode >= 400 && statusCode < 500 {
+			pns.logger.Warn("onesignal refused to cancel push notification",
+				"notification_id", push.ID,
+				"onesignal_notification_id", *push.OnesignalNotificationID,
+				"status_code", statusCode,
+				"error", err,
+			)
+			return nil
+		}
+		return fmt.Errorf("failed to cancel notification on onesignal: %w", err)
+	}
+
+	if err := pns.repo.MarkNotificationCancelled(ctx, push.ID); err != nil {
+		return fmt.Errorf("failed to mark notification cancelled: %w", err)
+	}
+
+	pns.logger.Info("push notification cancelled on onesignal",
+		"notification_id", push.ID,
+		"onesignal_notification_id", *push.OnesignalNotificationID,
+	)
+	return nil
+}
+
+func (pns *pushNotificationService) Reschedule(
+	ctx context.Context,
+	serviceID, requestID string,
+	sendAfter pgtype.Timestamp,
+) error {
+	if requestID == "" {
+		return resilience.Permanent(errors.New("target_request_id is required to reschedule a push notification"))
+	}
+	if !sendAfter.Valid {
+		return resilience.Permanent(errors.New("send_after is required to reschedule a push notification"))
+	}
+	if err := pns.validateSendAfter(sendAfter.Time); err != nil {
+		return resilience.Permanent(err)
+	}
+
+	// Same UTC normalisation as schedule.
+	sendAfter.Time = sendAfter.Time.UTC()
+	rescheduled, err := pns.repo.RescheduleNotification(ctx, repository.RescheduleNotificationParams{
+		QueueMessageID:  &requestID,
+		SendAfter:       sendAfter,
+		SourceServiceID: &serviceID,
+	})
+	if err == nil {
+		pns.logger.Info("push notification rescheduled",
+			"notification_id", rescheduled.ID,
+			"queue_message_id", requestID,
+			"send_after", sendAfter.Time,
+		)
+		return nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to reschedule notification: %w", err)
+	}
+
+	push, err := pns.repo.GetNotificationByQueueMessageID(ctx, &requestID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: queue_message_id %s", ErrNotificationNotFound, requestID)
+	} else if err != nil {
+		return fmt.Errorf("failed to look up notification: %w", err)
+	}
+	if err := pns.checkOwner(push, serviceID, "reschedule"); err != nil {
+		return err
+	}
+
+	status := ""
+	if push.Status != nil {
+		status = *push.Status
+	}
+	pns.logger.Warn("push notification is no longer pending, cannot reschedule",
+		"notification_id", push.ID,
+		"queue_message_id", requestID,
+		"status", status,
+	)
+	return nil
+}
+
+// persistValidationFailure records push as failed with the validation error
+// that stopped it, so the reason is on the row and not only in the logs.
+func (pns *pushNotificationService) persistValidationFailure(
+	ctx context.Context,
+	push *repository.Notification,
+	validationErr error,
+) {
+	errMsg := validationErr.Error()
+	push.OnesignalError = &errMsg
+	if err := pns.persistOutcome(ctx, push, "failed"); err != nil {
+		pns.logger.Error("failed to persist notification after validation error",
+			"error", err,
+		)
+	}
+}
+
 // persistOutcome upserts push (keyed by its QueueMessageID) with the given
 // status, recording every attempt — success, provider error, or
 // breaker-rejected — rather than only ever recording success.
@@ -277,23 +641,22 @@ func (pns *pushNotificationService) notificationToUpsertParams(
 		OnesignalError:          n.OnesignalError,
 		QueueMessageID:          n.QueueMessageID,
 		Status:                  n.Status,
+		Buttons:                 n.Buttons,
+		WebButtons:              n.WebButtons,
+		SuppressedUserIds:       n.SuppressedUserIds,
 	}
 }
 
 func (pns *pushNotificationService) preparePushPayload(
 	pushNotification repository.Notification,
 ) (*onesignal.Notification, error) {
-	appID := pushNotification.AppID
-	if appID == "" {
-		appID = os.Getenv("ONESIGNAL_APP_ID")
-	}
-	notification := *onesignal.NewNotification(appID)
+	notification := *onesignal.NewNotification(pushNotification.AppID)
 	// set default to push
 	notification.SetTargetChannel("push")
 
 	if !pns.hasTargeting(pushNotification) {
 		return nil, errors.New(
-			"at least one targeting mechanism must be specified (segments, player IDs, external user IDs, email tokens, or phone numbers)",
+			"at least one targeting mechanism must be specified (segments, filters, player IDs, external user IDs, email tokens, or phone numbers)",
 		)
 	}
 
@@ -313,12 +676,9 @@ func (pns *pushNotificationService) preparePushPayload(
 			"Atleast the English heading must be present for a push notification",
 		)
 	}
-	heading := onesignal.NewLanguageStringMap()
-	for lang, content := range rawHeadings {
-		switch lang {
-		case "en":
-			heading.SetEn(content)
-		}
+	heading, err := toLanguageStringMap("headings", rawHeadings)
+	if err != nil {
+		return nil, err
 	}
 	notification.SetHeadings(*heading)
 
@@ -327,12 +687,9 @@ func (pns *pushNotificationService) preparePushPayload(
 		if err := json.Unmarshal(pushNotification.Subtitle, &rawSubtitles); err != nil {
 			return nil, err
 		}
-		subtitle := onesignal.NewLanguageStringMap()
-		for lang, content := range rawSubtitles {
-			switch lang {
-			case "en":
-				subtitle.SetEn(content)
-			}
+		subtitle, err := toLanguageStringMap("subtitle", rawSubtitles)
+		if err != nil {
+			return nil, err
 		}
 		notification.SetSubtitle(*subtitle)
 	}
@@ -345,12 +702,9 @@ func (pns *pushNotificationService) preparePushPayload(
 	if err := json.Unmarshal(pushNotification.Contents, &rawContents); err != nil {
 		return nil, err
 	}
-	contents := onesignal.NewLanguageStringMap()
-	for lang, content := range rawContents {
-		switch lang {
-		case "en":
-			contents.SetEn(content)
-		}
+	contents, err := toLanguageStringMap("contents", rawContents)
+	if err != nil {
+		return nil, err
 	}
 	notification.SetContents(*contents)
 	// set the notification payload
@@ -402,6 +756,33 @@ func (pns *pushNotificationService) preparePushPayload(
 			pushNotification.IncludeAndroidRegIds,
 		)
 	}
+	// OneSignal renamed player IDs to subscription IDs; the values are
+	// the same.
+	if len(pushNotification.IncludePlayerIds) > 0 {
+		notification.SetIncludeSubscriptionIds(
+			pushNotification.IncludePlayerIds,
+		)
+	}
+
+	if jsonPresent(pushNotification.Filters) && jsonPresent(pushNotification.Tags) {
+		return nil, errors.New(
+			"filters and tags cannot be combined; express the tag conditions as tag filters",
+		)
+	}
+	if jsonPresent(pushNotification.Filters) {
+		filters, err := toOneSignalFilters(pushNotification.Filters)
+		if err != nil {
+			return nil, err
+		}
+		notification.SetFilters(filters)
+	}
+	if jsonPresent(pushNotification.Tags) {
+		filters, err := tagsToOneSignalFilters(pushNotification.Tags)
+		if err != nil {
+			return nil, err
+		}
+		notification.SetFilters(filters)
+	}
 
 	var targetUsers []string
 	if pushNotification.TargetUserID.Valid {
@@ -440,12 +821,82 @@ func (pns *pushNotificationService) preparePushPayload(
 		notification.SetBigPicture(*pushNotification.BigPicture)
 	}
 	if pushNotification.AndroidLedColor != nil {
-		notification.SetAndroidLedColor(*pushNotification.AndroidLedColor)
+		color, err := toOneSignalColor("android_led_color", *pushNotification.AndroidLedColor)
+		if err != nil {
+			return nil, err
+		}
+		notification.SetAndroidLedColor(color)
+	}
+	if pushNotification.AndroidAccentColor != nil {
+		color, err := toOneSignalColor("android_accent_color", *pushNotification.AndroidAccentColor)
+		if err != nil {
+			return nil, err
+		}
+		notification.SetAndroidAccentColor(color)
+	}
+
+	if pushNotification.AndroidGroupMessage != nil && pushNotification.AndroidGroup == nil {
+		return nil, errors.New("android_group_message requires android_group")
+	}
+	if pushNotification.AndroidGroup != nil {
+		notification.SetAndroidGroup(*pushNotification.AndroidGroup)
+	}
+	if pushNotification.AndroidGroupMessage != nil {
+		var rawGroupMessages map[string]string
+		if err := json.Unmarshal(pushNotification.AndroidGroupMessage, &rawGroupMessages); err != nil {
+			return nil, fmt.Errorf("android_group_message must be an object keyed by language: %w", err)
+		}
+		groupMessage, err := toLanguageStringMap("android_group_message", rawGroupMessages)
+		if err != nil {
+			return nil, err
+		}
+		// The SDK types android_group_message as a plain string, but
+		// OneSignal takes it per language like contents.
+		if notification.AdditionalProperties == nil {
+			notification.AdditionalProperties = map[string]any{}
+		}
+		notification.AdditionalProperties["android_group_message"] = groupMessage
 	}
 
 	if pushNotification.IosSound != nil {
 		notification.SetIosSound(*pushNotification.IosSound)
 	}
+	if pushNotification.WpWnsSound != nil {
+		notification.SetWpWnsSound(*pushNotification.WpWnsSound)
+	}
+	if pushNotification.AdmSound != nil {
+		notification.SetAdmSound(*pushNotification.AdmSound)
+	}
+
+	if pushNotification.IosAttachments != nil {
+		var attachments map[string]string
+		if err := json.Unmarshal(pushNotification.IosAttachments, &attachments); err != nil {
+			return nil, fmt.Errorf("ios_attachments must map attachment ids to URLs: %w", err)
+		}
+		iosAttachments := make(map[string]any, len(attachments))
+		for id, url := range attachments {
+			iosAttachments[id] = url
+		}
+		notification.SetIosAttachments(iosAttachments)
+	}
+
+	if pushNotification.ChromeWebImage != nil {
+		notification.SetChromeWebImage(*pushNotification.ChromeWebImage)
+	}
+	if pushNotification.ChromeWebIcon != nil {
+		notification.SetChromeWebIcon(*pushNotification.ChromeWebIcon)
+	}
+	if pushNotification.ChromeWebBadge != nil {
+		notification.SetChromeWebBadge(*pushNotification.ChromeWebBadge)
+	}
+	// Web push has no colour or sound of its own in OneSignal's API;
+	// failing says so instead of dropping them.
+	if pushNotification.ChromeWebColor != nil {
+		return nil, errors.New("chrome_web_color is not supported by OneSignal web push")
+	}
+	if pushNotification.ChromeWebSound != nil {
+		return nil, errors.New("chrome_web_sound is not supported by OneSignal web push")
+	}
 
 	// Urls
 	if pushNotification.Url != nil {
@@ -466,19 +917,28 @@ func (pns *pushNotificationService) preparePushPayload(
 		notification.SetTtl(*pushNotification.Ttl)
 	}
 
-	// Set SendAfter if provided and validate it's a future date
-	if pushNotification.SendAfter.Valid {
-		if err := pns.validateSendAfter(pushNotification.SendAfter.Time); err != nil {
-			return nil, err
-		}
-		notification.SetSendAfter(
-			pushNotification.SendAfter.Time,
-		)
-	}
-
 	if pushNotification.DelayedOption != nil {
+		switch *pushNotification.DelayedOption {
+		case "timezone", "last-active":
+		default:
+			return nil, fmt.Errorf(
+				"delayed_option must be \"timezone\" or \"last-active\", got %q",
+				*pushNotification.DelayedOption,
+			)
+		}
 		notification.SetDelayedOption(*pushNotification.DelayedOption)
 	}
+	if pushNotification.DeliveryTimeOfDay.Valid {
+		if pushNotification.DelayedOption == nil ||
+			*pushNotification.DelayedOption != "timezone" {
+			return nil, errors.New(
+				"delivery_time_of_day requires delayed_option \"timezone\"",
+			)
+		}
+		notification.SetDeliveryTimeOfDay(
+			formatTimeOfDay(pushNotification.DeliveryTimeOfDay),
+		)
+	}
 
 	if pushNotification.Priority != nil {
 		notification.SetPriority(*pushNotification.Priority)
@@ -502,9 +962,86 @@ func (pns *pushNotificationService) preparePushPayload(
 		notification.SetButtons(osButtons)
 	}
 
+	if pushNotification.WebButtons != nil {
+		webButtons, err := toOneSignalWebButtons(pushNotification.WebButtons)
+		if err != nil {
+			return nil, err
+		}
+		notification.SetWebButtons(webButtons)
+	}
+
 	return &notification, nil
 }
 
+// assignApp records on push the OneSignal app it goes out through. A
+// publisher may name the app in app_id, but only the one its service is
+// configured for: any other app would need credentials it doesn't have.
+func assignApp(push *repository.Notification, app *oneSignalApp) error {
+	if push.AppID != "" && push.AppID != app.appID {
+		return fmt.Errorf(
+			"app_id %q is not the onesignal app configured for this service",
+			push.AppID,
+		)
+	}
+	push.AppID = app.appID
+	return nil
+}
+
+// retryableStatus reports whether a notification in status may be sent
+// again for the same queue_message_id: only attempts that never reached
+// the user are.
+func retryableStatus(status string) bool {
+	return status == "failed" || status == "circuit_open"
+}
+
+// withoutOptedOutUsers returns push without the target_user_id and
+// include_external_user_ids whose user opted out of its notification_type
+// by push, and the ids it removed. Segments, filters and device tokens
+// aren't users, so they are left alone.
+func (pns *pushNotificationService) withoutOptedOutUsers(
+	ctx context.Context,
+	push repository.Notification,
+) (repository.Notification, []string, error) {
+	var users []string
+	if push.TargetUserID.Valid {
+		users = append(users, push.TargetUserID.String())
+	}
+	for _, id := range push.IncludeExternalUserIds {
+		users = append(users, strings.ToLower(id))
+	}
+	if len(users) == 0 {
+		return push, nil, nil
+	}
+
+	optedOut, err := pns.repo.ListOptedOutUserIDs(ctx, repository.ListOptedOutUserIDsParams{
+		Channel:          channelPush,
+		UserIds:          users,
+		NotificationType: derefString(push.NotificationType),
+	})
+	if err != nil {
+		return push, nil, fmt.Errorf("failed to check notification preferences: %w", err)
+	}
+	if len(optedOut) == 0 {
+		return push, nil, nil
+	}
+
+	skip := make(map[string]bool, len(optedOut))
+	for _, id := range optedOut {
+		skip[id] = true
+	}
+	if push.TargetUserID.Valid && skip[push.TargetUserID.String()] {
+		push.TargetUserID = pgtype.UUID{}
+	}
+	var remaining []string
+	for _, id := range push.IncludeExternalUserIds {
+		if !skip[strings.ToLower(id)] {
+			remaining = append(remaining, id)
+		}
+	}
+	push.IncludeExternalUserIds = remaining
+	return push, optedOut, nil
+}
+
 // Helper: Check if at least one targeting mechanism is specified
 func (pns *pushNotificationService) hasTargeting(
 	n repository.Notification,
@@ -521,7 +1058,9 @@ func (pns *pushNotificationService) hasTargeting(
 		len(n.IncludeAmazonRegIds) > 0 ||
 		len(n.IncludeChromeRegIds) > 0 ||
 		len(n.IncludeChromeWebRegIds) > 0 ||
-		len(n.IncludeAndroidRegIds) > 0
+		len(n.IncludeAndroidRegIds) > 0 ||
+		jsonPresent(n.Filters) ||
+		jsonPresent(n.Tags)
 }
 
 // Helper: Validate TTL is a positive integer
@@ -553,3 +1092,26 @@ func (pns *pushNotificationService) validateSendAfter(
 	}
 	return nil
 }
+
+// checkOwner refuses a cancel or reschedule of a push serviceID didn't
+// send. The error is permanent, so the message is parked with it as the
+// reason: retrying won't make the push serviceID's.
+func (pns *pushNotificationService) checkOwner(
+	push repository.Notification,
+	serviceID, action string,
+) error {
+	if derefString(push.SourceServiceID) == serviceID {
+		return nil
+	}
+	pns.logger.Warn("refusing to act on another service's push notification",
+		"action", action,
+		"notification_id", push.ID,
+		"queue_message_id", derefString(push.QueueMessageID),
+		"service_id", serviceID,
+		"owner_service_id", derefString(push.SourceServiceID),
+	)
+	return resilience.Permanent(fmt.Errorf(
+		"%w: cannot %s queue_message_id %s",
+		ErrTargetRequestNotOwned, action, derefString(push.QueueMessageID),
+	))
+}
@@ -6,8 +6,11 @@ import (
 	"io"
 	"log/slog"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/opencrafts-io/gossip-monger/internal/repository"
 	"github.com/opencrafts-io/gossip-monger/internal/resilience"
 	"github.com/sony/gobreaker/v2"
@@ -21,8 +24,37 @@ import (
 // the point — it surfaces an unexpected call immediately.
 type fakeQuerier struct {
 	repository.Querier
-	upsertNotification        func(ctx context.Context, arg repository.UpsertNotificationParams) (repository.Notification, error)
-	getNotificationByQueueMsg func(ctx context.Context, id *string) (repository.Notification, error)
+	upsertNotification         func(ctx context.Context, arg repository.UpsertNotificationParams) (repository.Notification, error)
+	getNotificationByQueueMsg  func(ctx context.Context, id *string) (repository.Notification, error)
+	getNotificationByOneSignal func(ctx context.Context, id *string) (repository.Notification, error)
+	updateNotificationStatus   func(ctx context.Context, arg repository.UpdateNotificationStatusParams) error
+	retryScheduledNotification func(ctx context.Context, arg repository.RetryScheduledNotificationParams) error
+	markNotificationAsRead     func(ctx context.Context, id uuid.UUID) error
+	claimDueNotifications      func(ctx context.Context, arg repository.ClaimDueNotificationsParams) ([]repository.Notification, error)
+	cancelPendingNotification  func(ctx context.Context, arg repository.CancelPendingNotificationParams) (repository.Notification, error)
+	rescheduleNotification     func(ctx context.Context, arg repository.RescheduleNotificationParams) (repository.Notification, error)
+	getServiceOneSignalApp     func(ctx context.Context, id string) (repository.GetServiceOneSignalAppRow, error)
+	getOneSignalAppKey         func(ctx context.Context, appID *string) (string, error)
+
+	getNotificationsByExternalUserID func(ctx context.Context, arg repository.GetNotificationsByExternalUserIDParams) ([]repository.Notification, error)
+	getEmailRequestByID              func(ctx context.Context, id uuid.UUID) (repository.EmailRequest, error)
+	getEmailDispatchesByRequestID    func(ctx context.Context, id uuid.UUID) ([]repository.EmailDispatch, error)
+	getEmailDeliveryEventsByRequest  func(ctx context.Context, id uuid.UUID) ([]repository.EmailDeliveryEvent, error)
+	createParkedMessageAction        func(ctx context.Context, arg repository.CreateParkedMessageActionParams) (repository.ParkedMessageAction, error)
+
+	listOptedOutUserIDs          func(ctx context.Context, arg repository.ListOptedOutUserIDsParams) ([]string, error)
+	listOptedOutEmailAddresses   func(ctx context.Context, arg repository.ListOptedOutEmailAddressesParams) ([]string, error)
+	upsertNotificationPreference func(ctx context.Context, arg repository.UpsertNotificationPreferenceParams) error
+	deleteNotificationPreference func(ctx context.Context, arg repository.DeleteNotificationPreferenceParams) error
+	listUnsubscribedAddresses    func(ctx context.Context, arg repository.ListUnsubscribedAddressesParams) ([]string, error)
+
+	createEmailSuppression  func(ctx context.Context, arg repository.CreateEmailSuppressionParams) (repository.EmailSuppression, error)
+	deleteEmailSuppression  func(ctx context.Context, address string) (repository.EmailSuppression, error)
+	listSuppressedAddresses func(ctx context.Context, addresses []string) ([]string, error)
+
+	getUserByID func(ctx context.Context, id uuid.UUID) (repository.User, error)
+
+	setServiceSenderDomains func(ctx context.Context, arg repository.SetServiceSenderDomainsParams) (repository.SetServiceSenderDomainsRow, error)
 }
 
 func (f *fakeQuerier) UpsertNotification(
@@ -43,6 +75,166 @@ func (f *fakeQuerier) GetNotificationByQueueMessageID(
 	return repository.Notification{}, pgx.ErrNoRows
 }
 
+func (f *fakeQuerier) GetNotificationByOneSignalID(
+	ctx context.Context,
+	id *string,
+) (repository.Notification, error) {
+	return f.getNotificationByOneSignal(ctx, id)
+}
+
+func (f *fakeQuerier) UpdateNotificationStatus(
+	ctx context.Context,
+	arg repository.UpdateNotificationStatusParams,
+) error {
+	return f.updateNotificationStatus(ctx, arg)
+}
+
+func (f *fakeQuerier) RetryScheduledNotification(
+	ctx context.Context,
+	arg repository.RetryScheduledNotificationParams,
+) error {
+	return f.retryScheduledNotification(ctx, arg)
+}
+
+func (f *fakeQuerier) MarkNotificationAsRead(ctx context.Context, id uuid.UUID) error {
+	return f.markNotificationAsRead(ctx, id)
+}
+
+func (f *fakeQuerier) ClaimDueNotifications(
+	ctx context.Context,
+	arg repository.ClaimDueNotificationsParams,
+) ([]repository.Notification, error) {
+	return f.claimDueNotifications(ctx, arg)
+}
+
+func (f *fakeQuerier) CancelPendingNotification(
+	ctx context.Context,
+	arg repository.CancelPendingNotificationParams,
+) (repository.Notification, error) {
+	return f.cancelPendingNotification(ctx, arg)
+}
+
+func (f *fakeQuerier) RescheduleNotification(
+	ctx context.Context,
+	arg repository.RescheduleNotificationParams,
+) (repository.Notification, error) {
+	return f.rescheduleNotification(ctx, arg)
+}
+
+func (f *fakeQuerier) GetServiceOneSignalApp(
+	ctx context.Context,
+	id string,
+) (repository.GetServiceOneSignalAppRow, error) {
+	return f.getServiceOneSignalApp(ctx, id)
+}
+
+func (f *fakeQuerier) GetOneSignalAppKey(ctx context.Context, appID *string) (string, error) {
+	return f.getOneSignalAppKey(ctx, appID)
+}
+
+func (f *fakeQuerier) GetNotificationsByExternalUserID(
+	ctx context.Context,
+	arg repository.GetNotificationsByExternalUserIDParams,
+) ([]repository.Notification, error) {
+	return f.getNotificationsByExternalUserID(ctx, arg)
+}
+
+func (f *fakeQuerier) GetEmailRequestByID(ctx context.Context, id uuid.UUID) (repository.EmailRequest, error) {
+	return f.getEmailRequestByID(ctx, id)
+}
+
+func (f *fakeQuerier) GetEmailDispatchesByRequestID(
+	ctx context.Context,
+	id uuid.UUID,
+) ([]repository.EmailDispatch, error) {
+	return f.getEmailDispatchesByRequestID(ctx, id)
+}
+
+func (f *fakeQuerier) GetEmailDeliveryEventsByRequestID(
+	ctx context.Context,
+	id uuid.UUID,
+) ([]repository.EmailDeliveryEvent, error) {
+	return f.getEmailDeliveryEventsByRequest(ctx, id)
+}
+
+func (f *fakeQuerier) CreateParkedMessageAction(
+	ctx context.Context,
+	arg repository.CreateParkedMessageActionParams,
+) (repository.ParkedMessageAction, error) {
+	return f.createParkedMessageAction(ctx, arg)
+}
+
+func (f *fakeQuerier) ListOptedOutUserIDs(
+	ctx context.Context,
+	arg repository.ListOptedOutUserIDsParams,
+) ([]string, error) {
+	if f.listOptedOutUserIDs != nil {
+		return f.listOptedOutUserIDs(ctx, arg)
+	}
+	// Default: nobody opted out.
+	return nil, nil
+}
+
+func (f *fakeQuerier) ListOptedOutEmailAddresses(
+	ctx context.Context,
+	arg repository.ListOptedOutEmailAddressesParams,
+) ([]string, error) {
+	if f.listOptedOutEmailAddresses != nil {
+		return f.listOptedOutEmailAddresses(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (f *fakeQuerier) UpsertNotificationPreference(
+	ctx context.Context,
+	arg repository.UpsertNotificationPreferenceParams,
+) error {
+	return f.upsertNotificationPreference(ctx, arg)
+}
+
+func (f *fakeQuerier) DeleteNotificationPreference(
+	ctx context.Context,
+	arg repository.DeleteNotificationPreferenceParams,
+) error {
+	return f.deleteNotificationPreference(ctx, arg)
+}
+
+func (f *fakeQuerier) ListUnsubscribedAddresses(
+	ctx context.Context,
+	arg repository.ListUnsubscribedAddressesParams,
+) ([]string, error) {
+	return f.listUnsubscribedAddresses(ctx, arg)
+}
+
+func (f *fakeQuerier) CreateEmailSuppression(
+	ctx context.Context,
+	arg repository.CreateEmailSuppressionParams,
+) (repository.EmailSuppression, error) {
+	return f.createEmailSuppression(ctx, arg)
+}
+
+func (f *fakeQuerier) DeleteEmailSuppression(
+	ctx context.Context,
+	address string,
+) (repository.EmailSuppression, error) {
+	return f.deleteEmailSuppression(ctx, address)
+}
+
+func (f *fakeQuerier) GetUserByID(ctx context.Context, id uuid.UUID) (repository.User, error) {
+	return f.getUserByID(ctx, id)
+}
+
+func (f *fakeQuerier) ListSuppressedAddresses(ctx context.Context, addresses []string) ([]string, error) {
+	return f.listSuppressedAddresses(ctx, addresses)
+}
+
+func (f *fakeQuerier) SetServiceSenderDomains(
+	ctx context.Context,
+	arg repository.SetServiceSenderDomainsParams,
+) (repository.SetServiceSenderDomainsRow, error) {
+	return f.setServiceSenderDomains(ctx, arg)
+}
+
 // fakeBreaker lets a test force the breaker-open path without waiting on
 // real consecutive failures/timeouts, and tracks whether it was invoked at
 // all so a test can assert the provider was never reached.
@@ -62,6 +254,24 @@ func (f fakeBreaker[T]) Execute(req func() (T, error)) (T, error) {
 	return req()
 }
 
+// fakeApps resolves every service to the same app, so a test can hand the
+// service a fakeBreaker without a real OneSignal client behind it.
+type fakeApps struct {
+	app *oneSignalApp
+}
+
+func (f fakeApps) forService(context.Context, *string) (*oneSignalApp, error) {
+	return f.app, nil
+}
+
+func (f fakeApps) forApp(context.Context, string) (*oneSignalApp, error) {
+	return f.app, nil
+}
+
+func singleApp(breaker resilience.Breaker[*onesignalCallResult]) fakeApps {
+	return fakeApps{app: &oneSignalApp{appID: "test-app", breaker: breaker}}
+}
+
 func testLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
@@ -84,9 +294,9 @@ func TestSend_BreakerOpen_RecordsCircuitOpenAndReturnsError(t *testing.T) {
 	}
 
 	pns := &pushNotificationService{
-		repo:    repo,
-		logger:  testLogger(),
-		breaker: fakeBreaker[*onesignalCallResult]{forcedErr: gobreaker.ErrOpenState},
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{forcedErr: gobreaker.ErrOpenState}),
 	}
 
 	err := pns.Send(context.Background(), validPushNotification(), "req-123")
@@ -110,9 +320,9 @@ func TestSend_ValidationError_PersistsFailedStatusWithoutCallingProvider(t *test
 	}
 
 	pns := &pushNotificationService{
-		repo:    repo,
-		logger:  testLogger(),
-		breaker: fakeBreaker[*onesignalCallResult]{calls: &calls},
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
 	}
 
 	// No targeting mechanism specified at all -> preparePushPayload fails
@@ -125,6 +335,7 @@ func TestSend_ValidationError_PersistsFailedStatusWithoutCallingProvider(t *test
 	err := pns.Send(context.Background(), invalid, "req-456")
 
 	require.Error(t, err)
+	assert.True(t, resilience.IsPermanent(err), "a validation failure must be parked, not retried")
 	assert.Equal(t, 0, calls, "breaker/provider must not be invoked when payload validation fails")
 	require.NotNil(t, captured.Status)
 	assert.Equal(t, "failed", *captured.Status)
@@ -144,9 +355,9 @@ func TestSend_QueueMessageIDThreadedThroughOnEveryAttempt(t *testing.T) {
 	}
 
 	pns := &pushNotificationService{
-		repo:    repo,
-		logger:  testLogger(),
-		breaker: fakeBreaker[*onesignalCallResult]{forcedErr: gobreaker.ErrOpenState},
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{forcedErr: gobreaker.ErrOpenState}),
 	}
 
 	// Same queueMessageID sent twice, simulating a dead-lettered redelivery
@@ -161,6 +372,70 @@ func TestSend_QueueMessageIDThreadedThroughOnEveryAttempt(t *testing.T) {
 	assert.Equal(t, "req-same", seen[1])
 }
 
+func TestSend_EveryTargetOptedOut_RecordsSuppressedWithoutCallingProvider(t *testing.T) {
+	userID := uuid.New()
+	var captured repository.UpsertNotificationParams
+	var checked repository.ListOptedOutUserIDsParams
+	calls := 0
+	repo := &fakeQuerier{
+		upsertNotification: func(_ context.Context, arg repository.UpsertNotificationParams) (repository.Notification, error) {
+			captured = arg
+			return repository.Notification{}, nil
+		},
+		listOptedOutUserIDs: func(_ context.Context, arg repository.ListOptedOutUserIDsParams) ([]string, error) {
+			checked = arg
+			return []string{userID.String()}, nil
+		},
+	}
+
+	pns := &pushNotificationService{
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
+	}
+
+	push := validPushNotification()
+	push.IncludedSegments = nil
+	push.TargetUserID = pgtype.UUID{Bytes: userID, Valid: true}
+	push.NotificationType = strPtr("event_reminder")
+
+	err := pns.Send(context.Background(), push, "req-muted")
+
+	require.NoError(t, err, "a suppressed push is done with, not retried")
+	assert.Equal(t, 0, calls, "breaker/provider must not be invoked for a suppressed push")
+	assert.Equal(t, "push", checked.Channel)
+	assert.Equal(t, "event_reminder", checked.NotificationType)
+	assert.Equal(t, []string{userID.String()}, checked.UserIds)
+	require.NotNil(t, captured.Status)
+	assert.Equal(t, "suppressed", *captured.Status)
+	assert.Equal(t, []string{userID.String()}, captured.SuppressedUserIds)
+	assert.True(t, captured.TargetUserID.Valid, "the stored push keeps who it was for")
+}
+
+func TestWithoutOptedOutUsers_DropsOnlyOptedOutUsers(t *testing.T) {
+	repo := &fakeQuerier{
+		listOptedOutUserIDs: func(_ context.Context, arg repository.ListOptedOutUserIDsParams) ([]string, error) {
+			assert.Equal(t, []string{"4f0c0000-0000-0000-0000-000000000001", "4f0c0000-0000-0000-0000-000000000002"}, arg.UserIds)
+			return []string{"4f0c0000-0000-0000-0000-000000000002"}, nil
+		},
+	}
+	pns := &pushNotificationService{repo: repo, logger: testLogger()}
+
+	push := validPushNotification()
+	push.IncludeExternalUserIds = []string{
+		"4f0c0000-0000-0000-0000-000000000001",
+		"4F0C0000-0000-0000-0000-000000000002",
+	}
+
+	sendable, suppressed, err := pns.withoutOptedOutUsers(context.Background(), push)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4f0c0000-0000-0000-0000-000000000002"}, suppressed)
+	assert.Equal(t, []string{"4f0c0000-0000-0000-0000-000000000001"}, sendable.IncludeExternalUserIds)
+	assert.Equal(t, []string{"Active Users"}, sendable.IncludedSegments)
+	assert.Len(t, push.IncludeExternalUserIds, 2, "the original push must be left as published")
+}
+
 func TestSend_DuplicateAlreadySent_SkipsResendWithoutCallingProvider(t *testing.T) {
 	sentStatus := "sent"
 	calls := 0
@@ -171,9 +446,9 @@ func TestSend_DuplicateAlreadySent_SkipsResendWithoutCallingProvider(t *testing.
 	}
 
 	pns := &pushNotificationService{
-		repo:    repo,
-		logger:  testLogger(),
-		breaker: fakeBreaker[*onesignalCallResult]{calls: &calls},
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
 	}
 
 	err := pns.Send(context.Background(), validPushNotification(), "req-already-sent")
@@ -181,3 +456,562 @@ func TestSend_DuplicateAlreadySent_SkipsResendWithoutCallingProvider(t *testing.
 	require.NoError(t, err)
 	assert.Equal(t, 0, calls, "an already-sent queue_message_id must not trigger a second real send")
 }
+
+func TestSend_FutureSendAfter_PersistsPendingWithoutCallingProvider(t *testing.T) {
+	var captured repository.UpsertNotificationParams
+	calls := 0
+	repo := &fakeQuerier{
+		upsertNotification: func(_ context.Context, arg repository.UpsertNotificationParams) (repository.Notification, error) {
+			captured = arg
+			return repository.Notification{}, nil
+		},
+	}
+
+	pns := &pushNotificationService{
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
+	}
+
+	push := validPushNotification()
+	push.SendAfter = pgtype.Timestamp{Time: time.Now().Add(time.Hour), Valid: true}
+
+	err := pns.Send(context.Background(), push, "req-scheduled")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, calls, "a scheduled push must not reach OneSignal before it is due")
+	require.NotNil(t, captured.Status)
+	assert.Equal(t, "pending", *captured.Status)
+}
+
+func TestSend_DuplicateStillPending_SkipsWithoutCallingProvider(t *testing.T) {
+	pendingStatus := "pending"
+	calls := 0
+	repo := &fakeQuerier{
+		getNotificationByQueueMsg: func(_ context.Context, _ *string) (repository.Notification, error) {
+			return repository.Notification{Status: &pendingStatus}, nil
+		},
+	}
+
+	pns := &pushNotificationService{
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
+	}
+
+	err := pns.Send(context.Background(), validPushNotification(), "req-pending")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, calls, "a redelivered scheduled push must not be sent early")
+}
+
+func TestRecordEvent_ClickOnSentPush_MarksDeliveredAndRead(t *testing.T) {
+	sentStatus := "sent"
+	id := uuid.New()
+	var statusUpdate *string
+	var readID uuid.UUID
+	repo := &fakeQuerier{
+		getNotificationByOneSignal: func(_ context.Context, _ *string) (repository.Notification, error) {
+			return repository.Notification{ID: id, Status: &sentStatus}, nil
+		},
+		updateNotificationStatus: func(_ context.Context, arg repository.UpdateNotificationStatusParams) error {
+			statusUpdate = arg.Status
+			return nil
+		},
+		markNotificationAsRead: func(_ context.Context, got uuid.UUID) error {
+			readID = got
+			return nil
+		},
+	}
+
+	pns := &pushNotificationService{repo: repo, logger: testLogger()}
+
+	err := pns.RecordEvent(context.Background(), OneSignalWebhookEvent{
+		Event:          OneSignalEventClicked,
+		NotificationID: "os-1",
+	})
+
+	require.NoError(t, err)
+	require.NotNil(t, statusUpdate)
+	assert.Equal(t, "delivered", *statusUpdate)
+	assert.Equal(t, id, readID)
+}
+
+func TestRecordEvent_UnknownNotification_ReturnsNotFound(t *testing.T) {
+	repo := &fakeQuerier{
+		getNotificationByOneSignal: func(_ context.Context, _ *string) (repository.Notification, error) {
+			return repository.Notification{}, pgx.ErrNoRows
+		},
+	}
+
+	pns := &pushNotificationService{repo: repo, logger: testLogger()}
+
+	err := pns.RecordEvent(context.Background(), OneSignalWebhookEvent{
+		Event:          OneSignalEventDelivered,
+		NotificationID: "os-unknown",
+	})
+
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+}
+
+func TestCancel_PendingPush_CancelledWithoutCallingProvider(t *testing.T) {
+	calls := 0
+	var cancelled repository.CancelPendingNotificationParams
+	repo := &fakeQuerier{
+		cancelPendingNotification: func(_ context.Context, arg repository.CancelPendingNotificationParams) (repository.Notification, error) {
+			cancelled = arg
+			return repository.Notification{ID: uuid.New()}, nil
+		},
+	}
+
+	pns := &pushNotificationService{
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
+	}
+
+	err := pns.Cancel(context.Background(), "io.opencrafts.academia", "req-to-cancel")
+
+	require.NoError(t, err)
+	require.NotNil(t, cancelled.QueueMessageID)
+	assert.Equal(t, "req-to-cancel", *cancelled.QueueMessageID)
+	require.NotNil(t, cancelled.SourceServiceID)
+	assert.Equal(t, "io.opencrafts.academia", *cancelled.SourceServiceID)
+	assert.Equal(t, 0, calls, "a push still held by gossip-monger is cancelled without asking OneSignal")
+}
+
+func TestCancel_UnknownRequest_ReturnsNotFoundSoItIsRetried(t *testing.T) {
+	repo := &fakeQuerier{
+		cancelPendingNotification: func(context.Context, repository.CancelPendingNotificationParams) (repository.Notification, error) {
+			return repository.Notification{}, pgx.ErrNoRows
+		},
+	}
+
+	pns := &pushNotificationService{repo: repo, logger: testLogger()}
+
+	err := pns.Cancel(context.Background(), "io.opencrafts.academia", "req-not-yet-arrived")
+
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+}
+
+func TestCancel_AlreadyDelivered_IsANoOp(t *testing.T) {
+	delivered := "delivered"
+	calls := 0
+	repo := &fakeQuerier{
+		cancelPendingNotification: func(context.Context, repository.CancelPendingNotificationParams) (repository.Notification, error) {
+			return repository.Notification{}, pgx.ErrNoRows
+		},
+		getNotificationByQueueMsg: func(_ context.Context, _ *string) (repository.Notification, error) {
+			return repository.Notification{Status: &delivered, SourceServiceID: strPtr("io.opencrafts.academia")}, nil
+		},
+	}
+
+	pns := &pushNotificationService{
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
+	}
+
+	err := pns.Cancel(context.Background(), "io.opencrafts.academia", "req-delivered")
+
+	require.NoError(t, err, "retrying cannot make a delivered push cancellable")
+	assert.Equal(t, 0, calls)
+}
+
+func TestCancel_AnotherServicesPush_IsPermanentlyRefused(t *testing.T) {
+	sent := "sent"
+	calls := 0
+	repo := &fakeQuerier{
+		cancelPendingNotification: func(context.Context, repository.CancelPendingNotificationParams) (repository.Notification, error) {
+			return repository.Notification{}, pgx.ErrNoRows
+		},
+		getNotificationByQueueMsg: func(context.Context, *string) (repository.Notification, error) {
+			return repository.Notification{
+				Status:                  &sent,
+				SourceServiceID:         strPtr("io.opencrafts.billing"),
+				OnesignalNotificationID: strPtr("os-1"),
+			}, nil
+		},
+	}
+
+	pns := &pushNotificationService{
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
+	}
+
+	err := pns.Cancel(context.Background(), "io.opencrafts.academia", "req-billing")
+
+	assert.ErrorIs(t, err, ErrTargetRequestNotOwned)
+	assert.True(t, resilience.IsPermanent(err))
+	assert.Equal(t, 0, calls, "another service's push is never cancelled on OneSignal")
+}
+
+func TestReschedule_PastSendAfter_Rejected(t *testing.T) {
+	pns := &pushNotificationService{repo: &fakeQuerier{}, logger: testLogger()}
+
+	err := pns.Reschedule(context.Background(), "io.opencrafts.academia", "req-1", pgtype.Timestamp{
+		Time:  time.Now().Add(-time.Hour),
+		Valid: true,
+	})
+
+	require.Error(t, err)
+}
+
+func TestReschedule_PendingPush_StoresNewSendAfterInUTC(t *testing.T) {
+	var captured repository.RescheduleNotificationParams
+	repo := &fakeQuerier{
+		rescheduleNotification: func(_ context.Context, arg repository.RescheduleNotificationParams) (repository.Notification, error) {
+			captured = arg
+			return repository.Notification{ID: uuid.New()}, nil
+		},
+	}
+
+	pns := &pushNotificationService{repo: repo, logger: testLogger()}
+
+	nairobi := time.FixedZone("EAT", 3*60*60)
+	sendAfter := time.Now().Add(2 * time.Hour).In(nairobi)
+
+	err := pns.Reschedule(context.Background(), "io.opencrafts.academia", "req-1", pgtype.Timestamp{Time: sendAfter, Valid: true})
+
+	require.NoError(t, err)
+	require.NotNil(t, captured.QueueMessageID)
+	assert.Equal(t, "req-1", *captured.QueueMessageID)
+	require.NotNil(t, captured.SourceServiceID)
+	assert.Equal(t, "io.opencrafts.academia", *captured.SourceServiceID)
+	assert.Equal(t, time.UTC, captured.SendAfter.Time.Location())
+	assert.True(t, captured.SendAfter.Time.Equal(sendAfter))
+}
+
+func TestPreparePushPayload_LanguageCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		headings string
+		subtitle string
+		contents string
+		wantErr  string
+		check    func(t *testing.T, headings, contents map[string]any)
+	}{
+		{
+			name:     "english only",
+			headings: `{"en":"hi"}`,
+			contents: `{"en":"body"}`,
+			check: func(t *testing.T, headings, contents map[string]any) {
+				assert.Equal(t, map[string]any{"en": "hi"}, headings)
+				assert.Equal(t, map[string]any{"en": "body"}, contents)
+			},
+		},
+		{
+			name:     "language OneSignal does not support",
+			headings: `{"en":"Hello","sw":"Habari"}`,
+			contents: `{"en":"body"}`,
+			wantErr:  "headings has unsupported language code(s): sw",
+		},
+		{
+			name:     "multiple supported languages",
+			headings: `{"en":"Hello","fr":"Bonjour","zh-Hans":"你好","pt":"Olá"}`,
+			contents: `{"en":"body","es":"cuerpo","ar":"نص"}`,
+			check: func(t *testing.T, headings, contents map[string]any) {
+				assert.Equal(t, map[string]any{
+					"en": "Hello", "fr": "Bonjour", "zh-Hans": "你好", "pt": "Olá",
+				}, headings)
+				assert.Equal(t, map[string]any{
+					"en": "body", "es": "cuerpo", "ar": "نص",
+				}, contents)
+			},
+		},
+		{
+			name:     "codes are case-sensitive",
+			headings: `{"en":"hi"}`,
+			contents: `{"en":"body","zh-hans":"你好"}`,
+			wantErr:  "contents has unsupported language code(s): zh-hans",
+		},
+		{
+			name:     "unknown subtitle language",
+			headings: `{"en":"hi"}`,
+			subtitle: `{"en":"sub","xx":"?","kl":"?"}`,
+			contents: `{"en":"body"}`,
+			wantErr:  "subtitle has unsupported language code(s): kl, xx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			push := validPushNotification()
+			push.Headings = json.RawMessage(tt.headings)
+			push.Contents = json.RawMessage(tt.contents)
+			if tt.subtitle != "" {
+				push.Subtitle = json.RawMessage(tt.subtitle)
+			}
+
+			pns := &pushNotificationService{logger: testLogger()}
+			payload, err := pns.preparePushPayload(push)
+
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			encoded, err := json.Marshal(payload)
+			require.NoError(t, err)
+			var decoded struct {
+				Headings map[string]any `json:"headings"`
+				Contents map[string]any `json:"contents"`
+			}
+			require.NoError(t, json.Unmarshal(encoded, &decoded))
+			tt.check(t, decoded.Headings, decoded.Contents)
+		})
+	}
+}
+
+func TestSend_UnknownLanguage_PersistsValidationError(t *testing.T) {
+	var captured repository.UpsertNotificationParams
+	calls := 0
+	repo := &fakeQuerier{
+		upsertNotification: func(_ context.Context, arg repository.UpsertNotificationParams) (repository.Notification, error) {
+			captured = arg
+			return repository.Notification{}, nil
+		},
+	}
+
+	pns := &pushNotificationService{
+		repo:   repo,
+		logger: testLogger(),
+		apps:   singleApp(fakeBreaker[*onesignalCallResult]{calls: &calls}),
+	}
+
+	push := validPushNotification()
+	push.Contents = json.RawMessage(`{"en":"body","klingon":"?"}`)
+
+	err := pns.Send(context.Background(), push, "req-lang")
+
+	require.Error(t, err)
+	assert.Equal(t, 0, calls)
+	require.NotNil(t, captured.Status)
+	assert.Equal(t, "failed", *captured.Status)
+	require.NotNil(t, captured.OnesignalError)
+	assert.Contains(t, *captured.OnesignalError, "klingon")
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestPreparePushPayload_PersistedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(n *repository.Notification)
+		wantErr string
+		want    map[string]any
+	}{
+		{
+			name: "player ids are sent as subscription ids",
+			modify: func(n *repository.Notification) {
+				n.IncludedSegments = nil
+				n.IncludePlayerIds = []string{"player-1"}
+			},
+			want: map[string]any{"include_subscription_ids": []any{"player-1"}},
+		},
+		{
+			name: "hex colours are sent as ARGB",
+			modify: func(n *repository.Notification) {
+				n.AndroidAccentColor = strPtr("#ff0000")
+				n.AndroidLedColor = strPtr("00FF00")
+			},
+			want: map[string]any{
+				"android_accent_color": "FFFF0000",
+				"android_led_color":    "FF00FF00",
+			},
+		},
+		{
+			name:    "invalid hex colour",
+			modify:  func(n *repository.Notification) { n.AndroidAccentColor = strPtr("red") },
+			wantErr: `android_accent_color must be a hex colour like "#FF0000", got "red"`,
+		},
+		{
+			name: "filters alone count as targeting",
+			modify: func(n *repository.Notification) {
+				n.IncludedSegments = nil
+				n.Filters = json.RawMessage(`[
+					{"field":"tag","key":"level","relation":">","value":"10"},
+					{"operator":"OR"},
+					{"field":"last_session","relation":"<","hours_ago":6}
+				]`)
+			},
+			want: map[string]any{"filters": []any{
+				map[string]any{"field": "tag", "key": "level", "relation": ">", "value": "10"},
+				map[string]any{"operator": "OR"},
+				map[string]any{"field": "last_session", "relation": "<", "hours_ago": "6"},
+			}},
+		},
+		{
+			name: "operator must sit between filters",
+			modify: func(n *repository.Notification) {
+				n.Filters = json.RawMessage(`[{"operator":"OR"},{"field":"country","relation":"=","value":"KE"}]`)
+			},
+			wantErr: `filters[0]: operator "OR" must sit between two filters`,
+		},
+		{
+			name: "unknown relation",
+			modify: func(n *repository.Notification) {
+				n.Filters = json.RawMessage(`[{"field":"country","relation":"like","value":"KE"}]`)
+			},
+			wantErr: `filters[0]: country filter has invalid relation "like"`,
+		},
+		{
+			name:    "tag filter without key",
+			modify:  func(n *repository.Notification) { n.Filters = json.RawMessage(`[{"field":"tag","relation":"exists"}]`) },
+			wantErr: "filters[0]: tag filter requires a key",
+		},
+		{
+			name: "tags become tag filters",
+			modify: func(n *repository.Notification) {
+				n.Tags = json.RawMessage(`[{"key":"vip","relation":"=","value":"true"}]`)
+			},
+			want: map[string]any{"filters": []any{
+				map[string]any{"field": "tag", "key": "vip", "relation": "=", "value": "true"},
+			}},
+		},
+		{
+			name: "null filters and tags are left out",
+			modify: func(n *repository.Notification) {
+				n.Filters = json.RawMessage(`null`)
+				n.Tags = json.RawMessage(` null `)
+			},
+			want: map[string]any{"filters": nil},
+		},
+		{
+			name: "filters and tags together",
+			modify: func(n *repository.Notification) {
+				n.Filters = json.RawMessage(`[{"field":"country","relation":"=","value":"KE"}]`)
+				n.Tags = json.RawMessage(`[{"key":"vip","relation":"=","value":"true"}]`)
+			},
+			wantErr: "filters and tags cannot be combined; express the tag conditions as tag filters",
+		},
+		{
+			name: "delivery time of day",
+			modify: func(n *repository.Notification) {
+				n.DelayedOption = strPtr("timezone")
+				n.DeliveryTimeOfDay = pgtype.Time{
+					Microseconds: (14*time.Hour + 30*time.Minute).Microseconds(),
+					Valid:        true,
+				}
+			},
+			want: map[string]any{"delayed_option": "timezone", "delivery_time_of_day": "2:30PM"},
+		},
+		{
+			name: "delivery time of day without timezone delay",
+			modify: func(n *repository.Notification) {
+				n.DeliveryTimeOfDay = pgtype.Time{Microseconds: time.Hour.Microseconds(), Valid: true}
+			},
+			wantErr: `delivery_time_of_day requires delayed_option "timezone"`,
+		},
+		{
+			name: "android group message per language",
+			modify: func(n *repository.Notification) {
+				n.AndroidGroup = strPtr("orders")
+				n.AndroidGroupMessage = json.RawMessage(`{"en":"$[notif_count] new orders"}`)
+			},
+			want: map[string]any{
+				"android_group":         "orders",
+				"android_group_message": map[string]any{"en": "$[notif_count] new orders"},
+			},
+		},
+		{
+			name: "web buttons, ios attachments, web images and sounds",
+			modify: func(n *repository.Notification) {
+				n.WebButtons = json.RawMessage(`[{"id":"open","text":"Open","url":"https://opencrafts.io"}]`)
+				n.IosAttachments = json.RawMessage(`{"poster":"https://opencrafts.io/p.png"}`)
+				n.ChromeWebIcon = strPtr("https://opencrafts.io/i.png")
+				n.ChromeWebBadge = strPtr("https://opencrafts.io/b.png")
+				n.ChromeWebImage = strPtr("https://opencrafts.io/w.png")
+				n.WpWnsSound = strPtr("ping")
+				n.AdmSound = strPtr("ping")
+			},
+			want: map[string]any{
+				"web_buttons":      []any{map[string]any{"id": "open", "text": "Open", "url": "https://opencrafts.io"}},
+				"ios_attachments":  map[string]any{"poster": "https://opencrafts.io/p.png"},
+				"chrome_web_icon":  "https://opencrafts.io/i.png",
+				"chrome_web_badge": "https://opencrafts.io/b.png",
+				"chrome_web_image": "https://opencrafts.io/w.png",
+				"wp_wns_sound":     "ping",
+				"adm_sound":        "ping",
+			},
+		},
+		{
+			name:    "chrome web colour has no OneSignal equivalent",
+			modify:  func(n *repository.Notification) { n.ChromeWebColor = strPtr("#FF0000") },
+			wantErr: "chrome_web_color is not supported by OneSignal web push",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			push := validPushNotification()
+			tt.modify(&push)
+
+			pns := &pushNotificationService{logger: testLogger()}
+			payload, err := pns.preparePushPayload(push)
+
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			encoded, err := json.Marshal(payload)
+			require.NoError(t, err)
+			var decoded map[string]any
+			require.NoError(t, json.Unmarshal(encoded, &decoded))
+			for field, want := range tt.want {
+				assert.Equal(t, want, decoded[field], field)
+			}
+		})
+	}
+}
+
+func TestHasTargeting_NullFiltersAndTagsAreNotTargeting(t *testing.T) {
+	pns := &pushNotificationService{logger: testLogger()}
+	push := repository.Notification{
+		Filters: json.RawMessage(`null`),
+		Tags:    json.RawMessage(`null`),
+	}
+	assert.False(t, pns.hasTargeting(push))
+
+	push.Filters = json.RawMessage(`[{"field":"country","relation":"=","value":"KE"}]`)
+	assert.True(t, pns.hasTargeting(push))
+}
+
+func TestPushNotificationEvent_UnmarshalDeliveryTimeOfDay(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "09:00", want: 9 * time.Hour},
+		{value: "21:15:30", want: 21*time.Hour + 15*time.Minute + 30*time.Second},
+		{value: "9:00AM", want: 9 * time.Hour},
+		{value: "9:45 pm", want: 21*time.Hour + 45*time.Minute},
+		{value: "25:00", wantErr: true},
+		{value: "noon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			body := `{"metadata":{"event_type":"push.send"},"notification":{` +
+				`"headings":{"en":"hi"},"delivery_time_of_day":"` + tt.value + `"}}`
+
+			var event PushNotificationEvent
+			err := json.Unmarshal([]byte(body), &event)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "push.send", event.Metadata.EventType)
+			assert.JSONEq(t, `{"en":"hi"}`, string(event.Notification.Headings))
+			require.True(t, event.Notification.DeliveryTimeOfDay.Valid)
+			assert.Equal(t, tt.want.Microseconds(), event.Notification.DeliveryTimeOfDay.Microseconds)
+		})
+	}
+}
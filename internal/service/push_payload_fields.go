@@ -0,0 +1,285 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/OneSignal/onesignal-go-api/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxFilterEntries is OneSignal's limit on filters (operators included) in
+// a single notification.
+const maxFilterEntries = 200
+
+var hexColorPattern = regexp.MustCompile(`^#?[0-9A-Fa-f]{6}$`)
+
+// toOneSignalColor converts a "#RRGGBB" colour, the format the notifications
+// table stores, to the opaque "FFRRGGBB" ARGB form OneSignal expects.
+func toOneSignalColor(field, color string) (string, error) {
+	if !hexColorPattern.MatchString(color) {
+		return "", fmt.Errorf(
+			"%s must be a hex colour like \"#FF0000\", got %q",
+			field,
+			color,
+		)
+	}
+	return "FF" + strings.ToUpper(strings.TrimPrefix(color, "#")), nil
+}
+
+// filterRelations are the relation operators OneSignal's filter grammar
+// accepts.
+var filterRelations = map[string]bool{
+	">":               true,
+	"<":               true,
+	"=":               true,
+	"!=":              true,
+	"exists":          true,
+	"not_exists":      true,
+	"time_elapsed_gt": true,
+	"time_elapsed_lt": true,
+}
+
+// filterFields are the fields a OneSignal filter may test.
+var filterFields = map[string]bool{
+	"tag":           true,
+	"last_session":  true,
+	"first_session": true,
+	"session_count": true,
+	"session_time":  true,
+	"amount_spent":  true,
+	"bought_sku":    true,
+	"language":      true,
+	"app_version":   true,
+	"location":      true,
+	"country":       true,
+}
+
+// filterEntry is one element of a filters array: either an operator
+// ({"operator": "OR"}) or a filter on a field.
+type filterEntry struct {
+	Operator *string      `json:"operator"`
+	Field    string       `json:"field"`
+	Key      *string      `json:"key"`
+	Value    *string      `json:"value"`
+	HoursAgo *json.Number `json:"hours_ago"`
+	Radius   *float32     `json:"radius"`
+	Lat      *float32     `json:"lat"`
+	Long     *float32     `json:"long"`
+	Relation *string      `json:"relation"`
+}
+
+// tagEntry is one element of the tags array: shorthand for a filter on a
+// tag, in the shape of OneSignal's retired "tags" parameter.
+type tagEntry struct {
+	Key      string  `json:"key"`
+	Relation string  `json:"relation"`
+	Value    *string `json:"value"`
+}
+
+// toOneSignalFilters validates a filters array against OneSignal's filter
+// grammar and converts it. Operators may only sit between two filters.
+func toOneSignalFilters(raw json.RawMessage) ([]onesignal.FilterExpression, error) {
+	var entries []filterEntry
+	if err := decodeStrict(raw, &entries); err != nil {
+		return nil, fmt.Errorf("filters must be an array of filters and operators: %w", err)
+	}
+	return buildFilters("filters", entries)
+}
+
+// tagsToOneSignalFilters converts the tags shorthand into the equivalent tag
+// filters, all of which must match.
+func tagsToOneSignalFilters(raw json.RawMessage) ([]onesignal.FilterExpression, error) {
+	var tags []tagEntry
+	if err := decodeStrict(raw, &tags); err != nil {
+		return nil, fmt.Errorf("tags must be an array of {key, relation, value}: %w", err)
+	}
+
+	entries := make([]filterEntry, 0, len(tags))
+	for _, tag := range tags {
+		entries = append(entries, filterEntry{
+			Field:    "tag",
+			Key:      &tag.Key,
+			Relation: &tag.Relation,
+			Value:    tag.Value,
+		})
+	}
+	return buildFilters("tags", entries)
+}
+
+func buildFilters(field string, entries []filterEntry) ([]onesignal.FilterExpression, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s must not be empty", field)
+	}
+	if len(entries) > maxFilterEntries {
+		return nil, fmt.Errorf(
+			"%s has %d entries, OneSignal allows at most %d",
+			field,
+			len(entries),
+			maxFilterEntries,
+		)
+	}
+
+	expressions := make([]onesignal.FilterExpression, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Operator != nil {
+			if entry.Field != "" {
+				return nil, fmt.Errorf("%s[%d] is both an operator and a filter", field, i)
+			}
+			op := *entry.Operator
+			if op != "AND" && op != "OR" {
+				return nil, fmt.Errorf("%s[%d]: operator must be \"AND\" or \"OR\", got %q", field, i, op)
+			}
+			if i == 0 || i == len(entries)-1 || entries[i-1].Operator != nil {
+				return nil, fmt.Errorf("%s[%d]: operator %q must sit between two filters", field, i, op)
+			}
+			operator := onesignal.NewOperator()
+			operator.SetOperator(op)
+			expressions = append(expressions, onesignal.OperatorAsFilterExpression(operator))
+			continue
+		}
+
+		filter, err := buildFilter(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+		}
+		expressions = append(expressions, onesignal.FilterAsFilterExpression(filter))
+	}
+	return expressions, nil
+}
+
+func buildFilter(entry filterEntry) (*onesignal.Filter, error) {
+	if !filterFields[entry.Field] {
+		return nil, fmt.Errorf("unsupported filter field %q", entry.Field)
+	}
+
+	filter := onesignal.NewFilter()
+	filter.SetField(entry.Field)
+
+	if entry.Field == "location" {
+		if entry.Radius == nil || entry.Lat == nil || entry.Long == nil {
+			return nil, errors.New("location filter requires radius, lat and long")
+		}
+		filter.SetRadius(*entry.Radius)
+		filter.SetLat(*entry.Lat)
+		filter.SetLong(*entry.Long)
+		return filter, nil
+	}
+
+	if entry.Relation == nil || !filterRelations[*entry.Relation] {
+		relation := ""
+		if entry.Relation != nil {
+			relation = *entry.Relation
+		}
+		return nil, fmt.Errorf("%s filter has invalid relation %q", entry.Field, relation)
+	}
+	relation := *entry.Relation
+	filter.SetRelation(relation)
+
+	switch entry.Field {
+	case "last_session", "first_session":
+		if entry.HoursAgo == nil {
+			return nil, fmt.Errorf("%s filter requires hours_ago", entry.Field)
+		}
+		if _, err := entry.HoursAgo.Float64(); err != nil {
+			return nil, fmt.Errorf("%s filter has invalid hours_ago %q", entry.Field, *entry.HoursAgo)
+		}
+		filter.SetHoursAgo(entry.HoursAgo.String())
+		return filter, nil
+	case "tag":
+		if entry.Key == nil || *entry.Key == "" {
+			return nil, errors.New("tag filter requires a key")
+		}
+		filter.SetKey(*entry.Key)
+	}
+
+	if relation == "exists" || relation == "not_exists" {
+		return filter, nil
+	}
+	if entry.Value == nil {
+		return nil, fmt.Errorf("%s filter with relation %q requires a value", entry.Field, relation)
+	}
+	filter.SetValue(*entry.Value)
+	return filter, nil
+}
+
+// toOneSignalWebButtons converts web_buttons, which unlike buttons may carry
+// the URL each button opens.
+func toOneSignalWebButtons(raw json.RawMessage) ([]onesignal.WebButton, error) {
+	var buttons []struct {
+		ID   string `json:"id"`
+		Text string `json:"text"`
+		Icon string `json:"icon"`
+		URL  string `json:"url"`
+	}
+	if err := json.Unmarshal(raw, &buttons); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal web_buttons: %w", err)
+	}
+
+	webButtons := make([]onesignal.WebButton, 0, len(buttons))
+	for i, b := range buttons {
+		if b.ID == "" {
+			return nil, fmt.Errorf("web_buttons[%d] requires an id", i)
+		}
+		btn := *onesignal.NewWebButton(b.ID)
+		btn.SetText(b.Text)
+		if b.Icon != "" {
+			btn.SetIcon(b.Icon)
+		}
+		if b.URL != "" {
+			btn.SetUrl(b.URL)
+		}
+		webButtons = append(webButtons, btn)
+	}
+	return webButtons, nil
+}
+
+// timeOfDayLayouts are the formats delivery_time_of_day is accepted in.
+var timeOfDayLayouts = []string{"15:04", "15:04:05", "3:04PM", "3:04 PM"}
+
+// parseTimeOfDay parses a delivery_time_of_day such as "09:00" or "9:00AM".
+func parseTimeOfDay(value string) (pgtype.Time, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(value))
+	for _, layout := range timeOfDayLayouts {
+		t, err := time.Parse(layout, normalized)
+		if err != nil {
+			continue
+		}
+		sinceMidnight := time.Duration(t.Hour())*time.Hour +
+			time.Duration(t.Minute())*time.Minute +
+			time.Duration(t.Second())*time.Second
+		return pgtype.Time{Microseconds: sinceMidnight.Microseconds(), Valid: true}, nil
+	}
+	return pgtype.Time{}, fmt.Errorf(
+		"delivery_time_of_day must be a time of day like \"09:00\" or \"9:00AM\", got %q",
+		value,
+	)
+}
+
+// formatTimeOfDay renders a stored delivery_time_of_day the way OneSignal
+// expects it, e.g. "9:00AM".
+func formatTimeOfDay(t pgtype.Time) string {
+	return time.Time{}.
+		Add(time.Duration(t.Microseconds) * time.Microsecond).
+		Format("3:04PM")
+}
+
+// jsonPresent reports whether raw holds a value. An explicit JSON null
+// counts as absent, the same as leaving the field out.
+func jsonPresent(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null"))
+}
+
+// decodeStrict unmarshals raw into v, rejecting fields v doesn't define so a
+// misspelt key is reported instead of silently ignored.
+func decodeStrict(raw json.RawMessage, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
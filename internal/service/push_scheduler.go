@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+)
+
+// PushScheduler dispatches pushes stored as pending with a send_after once
+// they fall due. Any number of replicas may run one: ClaimDueNotifications
+// hands each due row to exactly one of them.
+type PushScheduler struct {
+	repo         repository.Querier
+	pushService  PushNotificationService
+	pollInterval time.Duration
+	batchSize    int32
+	claimTimeout time.Duration
+	maxRetries   int
+	retryDelays  []time.Duration
+	logger       *slog.Logger
+}
+
+// NewPushScheduler creates a scheduler that polls every pollInterval for up
+// to batchSize due pushes. A claimed push whose dispatch hasn't finished
+// within claimTimeout is assumed abandoned and claimed again. One whose
+// dispatch failed in a way that may pass later is put back maxRetries times,
+// due again after retryDelays the way a failed message waits out its retry
+// tiers, before it is left failed.
+func NewPushScheduler(
+	repo repository.Querier,
+	pushService PushNotificationService,
+	pollInterval time.Duration,
+	batchSize int32,
+	claimTimeout time.Duration,
+	maxRetries int,
+	retryDelays []time.Duration,
+	logger *slog.Logger,
+) *PushScheduler {
+	return &PushScheduler{
+		repo:         repo,
+		pushService:  pushService,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+		claimTimeout: claimTimeout,
+		maxRetries:   maxRetries,
+		retryDelays:  retryDelays,
+		logger:       logger,
+	}
+}
+
+// Start polls for due pushes until ctx is cancelled.
+func (ps *PushScheduler) Start(ctx context.Context) error {
+	ps.logger.Info("push scheduler started",
+		"poll_interval", ps.pollInterval.String(),
+		"batch_size", ps.batchSize,
+	)
+
+	ticker := time.NewTicker(ps.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ps.logger.Info("push scheduler stopped")
+			return ctx.Err()
+		case <-ticker.C:
+			// Keep draining while full batches come back, so a backlog
+			// isn't worked off at one batch per tick.
+			for {
+				more, err := ps.dispatchDue(ctx)
+				if err != nil {
+					ps.logger.Error("failed to claim due push notifications",
+						"error", err,
+					)
+					break
+				}
+				if !more || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// dispatchDue claims one batch of due pushes and dispatches each. more
+// reports whether another batch is worth claiming straight away: the batch
+// was full and OneSignal wasn't found to be down.
+func (ps *PushScheduler) dispatchDue(ctx context.Context) (more bool, err error) {
+	due, err := ps.repo.ClaimDueNotifications(ctx, repository.ClaimDueNotificationsParams{
+		StaleAfterSeconds: int32(ps.claimTimeout / time.Second),
+		BatchSize:         ps.batchSize,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	more = len(due) == int(ps.batchSize)
+	for _, push := range due {
+		err := ps.pushService.Dispatch(ctx, push)
+		if err == nil {
+			continue
+		}
+
+		ps.logger.Error("scheduled push dispatch failed",
+			"notification_id", push.ID,
+			"error", err,
+		)
+
+		switch {
+		// The breaker rejected the call without trying: OneSignal is known
+		// to be down. Put the push back so it goes out once OneSignal
+		// recovers; no attempt was made, so none is counted.
+		case resilience.Open(err):
+			more = false
+			ps.setStatus(ctx, push, "pending")
+		// An attempt that may pass later, such as a 5xx or a timeout, is
+		// retried after a delay, as many times as a failed message is.
+		case !resilience.IsPermanent(err) && int(push.DispatchRetries) < ps.maxRetries:
+			delay := ps.retryDelay(int(push.DispatchRetries) + 1)
+			if err := ps.repo.RetryScheduledNotification(ctx, repository.RetryScheduledNotificationParams{
+				ID:        push.ID,
+				SendAfter: pgtype.Timestamp{Time: time.Now().UTC().Add(delay), Valid: true},
+			}); err != nil {
+				ps.logger.Error("failed to put scheduled push back for retry",
+					"notification_id", push.ID,
+					"error", err,
+				)
+				continue
+			}
+			ps.logger.Info("scheduled push will be retried",
+				"notification_id", push.ID,
+				"retry", push.DispatchRetries+1,
+				"delay", delay.String(),
+			)
+		// Retrying won't help, or has been tried enough. Dispatch has
+		// recorded why, unless it failed before reaching OneSignal.
+		default:
+			ps.setStatus(ctx, push, "failed")
+		}
+	}
+
+	return more, nil
+}
+
+// retryDelay is how long a scheduled push waits before its nth retry: the
+// nth retry delay, or the last once n runs past the end. With none
+// configured it is retried on the next poll.
+func (ps *PushScheduler) retryDelay(n int) time.Duration {
+	if len(ps.retryDelays) == 0 {
+		return ps.pollInterval
+	}
+	return ps.retryDelays[min(max(n, 1), len(ps.retryDelays))-1]
+}
+
+// setStatus records status for a claimed push, logging rather than
+// returning a failure so the rest of the batch is still dispatched.
+func (ps *PushScheduler) setStatus(ctx context.Context, push repository.Notification, status string) {
+	if err := ps.repo.UpdateNotificationStatus(ctx, repository.UpdateNotificationStatusParams{
+		ID:     push.ID,
+		Status: &status,
+	}); err != nil {
+		ps.logger.Error("failed to update scheduled push status",
+			"notification_id", push.ID,
+			"status", status,
+			"error", err,
+		)
+	}
+}
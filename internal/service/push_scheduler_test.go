@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePushService embeds the PushNotificationService interface as nil, the
+// same way fakeQuerier does, so only Dispatch needs implementing.
+type fakePushService struct {
+	PushNotificationService
+	dispatch func(ctx context.Context, push repository.Notification) error
+}
+
+func (f *fakePushService) Dispatch(ctx context.Context, push repository.Notification) error {
+	return f.dispatch(ctx, push)
+}
+
+func TestDispatchDue_BreakerOpen_ReturnsPushToPending(t *testing.T) {
+	rejected, sent := uuid.New(), uuid.New()
+	var dispatched []uuid.UUID
+	var requeued []uuid.UUID
+	repo := &fakeQuerier{
+		claimDueNotifications: func(_ context.Context, _ repository.ClaimDueNotificationsParams) ([]repository.Notification, error) {
+			return []repository.Notification{{ID: rejected}, {ID: sent}}, nil
+		},
+		updateNotificationStatus: func(_ context.Context, arg repository.UpdateNotificationStatusParams) error {
+			require.NotNil(t, arg.Status)
+			assert.Equal(t, "pending", *arg.Status)
+			requeued = append(requeued, arg.ID)
+			return nil
+		},
+	}
+	pushService := &fakePushService{
+		dispatch: func(_ context.Context, push repository.Notification) error {
+			dispatched = append(dispatched, push.ID)
+			if push.ID == rejected {
+				return gobreaker.ErrOpenState
+			}
+			return nil
+		},
+	}
+
+	ps := NewPushScheduler(repo, pushService, time.Second, 2, time.Minute, testMaxAttempts, testRetryDelays, testLogger())
+
+	more, err := ps.dispatchDue(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, more, "an open breaker must stop the scheduler draining further batches")
+	assert.Equal(t, []uuid.UUID{rejected, sent}, dispatched)
+	assert.Equal(t, []uuid.UUID{rejected}, requeued)
+}
+
+// testRetryDelays are the retry delays scheduler tests run with.
+var testRetryDelays = []time.Duration{10 * time.Second, time.Minute}
+
+func TestDispatchDue_TransientFailure_RetriedLater(t *testing.T) {
+	for name, tt := range map[string]struct {
+		retries   int32
+		wantDelay time.Duration
+	}{
+		"first retry":     {0, 10 * time.Second},
+		"past the tiers":  {2, time.Minute},
+		"last retry left": {testMaxAttempts - 1, time.Minute},
+	} {
+		t.Run(name, func(t *testing.T) {
+			push := repository.Notification{ID: uuid.New(), DispatchRetries: tt.retries}
+			var retried []repository.RetryScheduledNotificationParams
+			repo := &fakeQuerier{
+				claimDueNotifications: func(context.Context, repository.ClaimDueNotificationsParams) ([]repository.Notification, error) {
+					return []repository.Notification{push}, nil
+				},
+				retryScheduledNotification: func(_ context.Context, arg repository.RetryScheduledNotificationParams) error {
+					retried = append(retried, arg)
+					return nil
+				},
+			}
+			pushService := &fakePushService{
+				dispatch: func(context.Context, repository.Notification) error {
+					return errors.New("unexpected status code: 503")
+				},
+			}
+			ps := NewPushScheduler(repo, pushService, time.Second, 2, time.Minute, testMaxAttempts, testRetryDelays, testLogger())
+
+			before := time.Now().UTC()
+			_, err := ps.dispatchDue(context.Background())
+
+			require.NoError(t, err)
+			require.Len(t, retried, 1)
+			assert.Equal(t, push.ID, retried[0].ID)
+			assert.True(t, retried[0].SendAfter.Valid)
+			assert.WithinDuration(t, before.Add(tt.wantDelay), retried[0].SendAfter.Time, 5*time.Second)
+		})
+	}
+}
+
+func TestDispatchDue_PermanentOrOutOfRetries_LeftFailed(t *testing.T) {
+	for name, tt := range map[string]struct {
+		retries int32
+		err     error
+	}{
+		"permanent":      {0, resilience.Permanent(errors.New("invalid payload"))},
+		"out of retries": {testMaxAttempts, errors.New("unexpected status code: 503")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			push := repository.Notification{ID: uuid.New(), DispatchRetries: tt.retries}
+			var statuses []string
+			repo := &fakeQuerier{
+				claimDueNotifications: func(context.Context, repository.ClaimDueNotificationsParams) ([]repository.Notification, error) {
+					return []repository.Notification{push}, nil
+				},
+				updateNotificationStatus: func(_ context.Context, arg repository.UpdateNotificationStatusParams) error {
+					statuses = append(statuses, *arg.Status)
+					return nil
+				},
+			}
+			pushService := &fakePushService{
+				dispatch: func(context.Context, repository.Notification) error { return tt.err },
+			}
+			ps := NewPushScheduler(repo, pushService, time.Second, 2, time.Minute, testMaxAttempts, testRetryDelays, testLogger())
+
+			_, err := ps.dispatchDue(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"failed"}, statuses)
+		})
+	}
+}
+
+func TestDispatchDue_FullBatch_AsksForMore(t *testing.T) {
+	var claimed repository.ClaimDueNotificationsParams
+	repo := &fakeQuerier{
+		claimDueNotifications: func(_ context.Context, arg repository.ClaimDueNotificationsParams) ([]repository.Notification, error) {
+			claimed = arg
+			return []repository.Notification{{ID: uuid.New()}, {ID: uuid.New()}}, nil
+		},
+	}
+	pushService := &fakePushService{
+		dispatch: func(context.Context, repository.Notification) error { return nil },
+	}
+
+	ps := NewPushScheduler(repo, pushService, time.Second, 2, 5*time.Minute, testMaxAttempts, testRetryDelays, testLogger())
+
+	more, err := ps.dispatchDue(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, int32(2), claimed.BatchSize)
+	assert.Equal(t, int32(300), claimed.StaleAfterSeconds)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+)
+
+var (
+	// ErrUnknownRecipientUser is returned when to_user_ids names a user
+	// the user directory has no record of.
+	ErrUnknownRecipientUser = errors.New("unknown user in to_user_ids")
+	// ErrRecipientUserUnreachable is returned when a user in to_user_ids
+	// has no email address or phone number to send to.
+	ErrRecipientUserUnreachable = errors.New("user in to_user_ids has no contact details for this channel")
+)
+
+// resolveUserEmails looks up the email address of each user in ids.
+func resolveUserEmails(
+	ctx context.Context,
+	repo repository.Querier,
+	ids []uuid.UUID,
+	attempt int,
+) ([]string, error) {
+	return resolveUsers(ctx, repo, ids, attempt, "email address", func(user repository.User) string {
+		return user.Email
+	})
+}
+
+// resolveUserPhones looks up the phone number of each user in ids.
+func resolveUserPhones(
+	ctx context.Context,
+	repo repository.Querier,
+	ids []uuid.UUID,
+	attempt int,
+) ([]string, error) {
+	return resolveUsers(ctx, repo, ids, attempt, "phone number", func(user repository.User) string {
+		return derefString(user.Phone)
+	})
+}
+
+// unknownUserAttempts is how many deliveries a message naming a user the
+// directory doesn't know gets before that fails it for good. Verisafe's
+// user sync can lag a publish naming a user created moments before.
+const unknownUserAttempts = 3
+
+// resolveUsers returns what contact picks from each user in ids, in order
+// and without repeats. A user who has no contact on record, or whom the
+// directory still doesn't know on the unknownUserAttempts-th attempt,
+// fails the whole request with a permanent error: dropping them would send
+// to fewer people than the publisher asked for without anyone noticing.
+func resolveUsers(
+	ctx context.Context,
+	repo repository.Querier,
+	ids []uuid.UUID,
+	attempt int,
+	contactName string,
+	contact func(repository.User) string,
+) ([]string, error) {
+	var resolved []string
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		user, err := repo.GetUserByID(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = fmt.Errorf("%w: %s", ErrUnknownRecipientUser, id)
+			if attempt < unknownUserAttempts {
+				return nil, err
+			}
+			return nil, resilience.Permanent(err)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to look up user %s: %w", id, err)
+		}
+
+		value := strings.TrimSpace(contact(user))
+		if value == "" {
+			return nil, resilience.Permanent(fmt.Errorf("%w: user %s has no %s", ErrRecipientUserUnreachable, id, contactName))
+		}
+		resolved = append(resolved, value)
+	}
+	return resolved, nil
+}
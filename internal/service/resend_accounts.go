@@ -0,0 +1,143 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/tracing"
+	"github.com/resend/resend-go/v3"
+)
+
+// resendTimeout bounds a call to Resend. It is resend-go's own default,
+// kept when giving the client a traced transport.
+const resendTimeout = time.Minute
+
+// NewResendClient returns a Resend client for apiKey whose calls are
+// traced.
+func NewResendClient(apiKey string) *resend.Client {
+	return resend.NewCustomClient(tracing.NewHTTPClient(resendTimeout), apiKey)
+}
+
+// resendAccount is a Resend API key ready to send with: its own client, and
+// its own breaker so a revoked or rate-limited key doesn't stop email sent
+// through the others.
+type resendAccount struct {
+	client   *resend.Client
+	provider EmailProvider
+	breaker  resilience.Breaker[string]
+}
+
+func newResendAccount(
+	client *resend.Client,
+	breakerName string,
+	breakerSettings resilience.Settings,
+	logger *slog.Logger,
+) *resendAccount {
+	return &resendAccount{
+		client:   client,
+		provider: &resendProvider{client: client},
+		breaker:  resilience.New[string](breakerName, breakerSettings, logger),
+	}
+}
+
+// resendAccounts hands out the account a service's email is sent through:
+// the service's own Resend key if its services row has one, the shared
+// account otherwise. Accounts are built once per key and reused.
+type resendAccounts struct {
+	defaultAccount  *resendAccount
+	breakerSettings resilience.Settings
+	logger          *slog.Logger
+
+	mu       sync.Mutex
+	accounts map[string]*resendAccount
+}
+
+func newResendAccounts(
+	defaultClient *resend.Client,
+	breakerSettings resilience.Settings,
+	logger *slog.Logger,
+) *resendAccounts {
+	return &resendAccounts{
+		defaultAccount:  newResendAccount(defaultClient, "resend", breakerSettings, logger),
+		breakerSettings: breakerSettings,
+		logger:          logger,
+		accounts:        map[string]*resendAccount{},
+	}
+}
+
+func (ra *resendAccounts) forService(svc repository.Service) *resendAccount {
+	if svc.ResendApiKey == nil || *svc.ResendApiKey == "" {
+		return ra.defaultAccount
+	}
+	key := *svc.ResendApiKey
+
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+
+	if account, ok := ra.accounts[key]; ok {
+		return account
+	}
+
+	// The breaker name ends up in logs; a key fingerprint tells keys apart
+	// without leaking one.
+	fingerprint := sha256.Sum256([]byte(key))
+	account := newResendAccount(
+		NewResendClient(key),
+		"resend:"+hex.EncodeToString(fingerprint[:4]),
+		ra.breakerSettings,
+		ra.logger,
+	)
+	ra.accounts[key] = account
+	return account
+}
+
+// normalizeDomains lowercases domains and strips the leading "@" sender
+// domains have historically been written with.
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+// checkSenderDomain reports an error unless fromAddress, optionally with a
+// display name ("Posta <noreply@posta.opencrafts.io>"), is an address at
+// exactly one of allowedDomains. A suffix match is not enough: it would let
+// evil@attacker-posta.opencrafts.io through for posta.opencrafts.io. No
+// allowedDomains at all means no address is.
+func checkSenderDomain(fromAddress string, allowedDomains []string) error {
+	allowedDomains = normalizeDomains(allowedDomains)
+	if len(allowedDomains) == 0 {
+		return ErrNoSenderDomains
+	}
+
+	address, err := mail.ParseAddress(fromAddress)
+	if err != nil {
+		return fmt.Errorf("from address %q is not a valid email address: %w", fromAddress, err)
+	}
+
+	at := strings.LastIndex(address.Address, "@")
+	domain := strings.ToLower(address.Address[at+1:])
+	for _, allowed := range allowedDomains {
+		if domain == allowed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(
+		"from address domain must be one of: %s",
+		strings.Join(allowedDomains, ", "),
+	)
+}
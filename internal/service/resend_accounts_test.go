@@ -0,0 +1,25 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/resend/resend-go/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResendAccounts_OneAccountPerKey(t *testing.T) {
+	defaultClient := resend.NewClient("default-key")
+	accounts := newResendAccounts(defaultClient, resilience.Settings{ConsecutiveFailures: 5}, testLogger())
+
+	shared := accounts.forService(repository.Service{ID: "io.opencrafts.sherehe"})
+	keepUpKey := "keepup-key"
+	keepUp := accounts.forService(repository.Service{ID: "io.opencrafts.keepup", ResendApiKey: &keepUpKey})
+	keepUpAgain := accounts.forService(repository.Service{ID: "io.opencrafts.keepup", ResendApiKey: &keepUpKey})
+
+	assert.Same(t, defaultClient, shared.client)
+	assert.NotSame(t, shared, keepUp)
+	assert.NotSame(t, shared.breaker, keepUp.breaker)
+	assert.Same(t, keepUp, keepUpAgain, "a key's client and breaker must be reused")
+}
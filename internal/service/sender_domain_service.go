@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+)
+
+var (
+	// ErrNoSenderDomains is returned when a service with no allowed sender
+	// domains sends email.
+	ErrNoSenderDomains = errors.New("service has no allowed sender domains; ask the Gossip team to grant one")
+	// ErrServiceNotFound is returned when an admin lookup refers to a
+	// service gossip-monger has no record of.
+	ErrServiceNotFound = errors.New("no service found")
+	// ErrInvalidServiceID is returned when granting sender domains to a
+	// service id outside the io.opencrafts namespace.
+	ErrInvalidServiceID = errors.New("service id must be in the io.opencrafts namespace")
+	// ErrInvalidSenderDomain is returned when granting something that isn't
+	// a domain name.
+	ErrInvalidSenderDomain = errors.New("sender domains must be domain names such as posta.opencrafts.io")
+)
+
+// ServiceSenderDomains are the domains a service may send email from.
+type ServiceSenderDomains struct {
+	ServiceID            string   `json:"service_id"`
+	AllowedSenderDomains []string `json:"allowed_sender_domains"`
+}
+
+// SenderDomainService manages which domains each service may send email
+// from. A service with none can't send email at all.
+type SenderDomainService interface {
+	Get(ctx context.Context, serviceID string) (ServiceSenderDomains, error)
+	// Set replaces serviceID's domains on actor's say-so, registering the
+	// service if it hasn't sent anything yet. An empty list stops it
+	// sending email.
+	Set(ctx context.Context, actor, serviceID string, domains []string) (ServiceSenderDomains, error)
+}
+
+type senderDomainService struct {
+	repo   repository.Querier
+	logger *slog.Logger
+}
+
+func NewSenderDomainService(
+	repo repository.Querier,
+	logger *slog.Logger,
+) SenderDomainService {
+	return &senderDomainService{repo: repo, logger: logger}
+}
+
+func (sd *senderDomainService) Get(
+	ctx context.Context,
+	serviceID string,
+) (ServiceSenderDomains, error) {
+	row, err := sd.repo.GetServiceSenderDomains(ctx, serviceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ServiceSenderDomains{}, fmt.Errorf("%w: %s", ErrServiceNotFound, serviceID)
+	} else if err != nil {
+		return ServiceSenderDomains{}, fmt.Errorf("failed to get sender domains: %w", err)
+	}
+	return senderDomainsOf(row.ID, row.AllowedSenderDomains), nil
+}
+
+func (sd *senderDomainService) Set(
+	ctx context.Context,
+	actor, serviceID string,
+	domains []string,
+) (ServiceSenderDomains, error) {
+	if !strings.HasPrefix(serviceID, "io.opencrafts.") {
+		return ServiceSenderDomains{}, ErrInvalidServiceID
+	}
+
+	normalized := normalizeDomains(domains)
+	for _, domain := range normalized {
+		if !isDomainName(domain) {
+			return ServiceSenderDomains{}, fmt.Errorf("%w: %q", ErrInvalidSenderDomain, domain)
+		}
+	}
+	slices.Sort(normalized)
+
+	row, err := sd.repo.SetServiceSenderDomains(ctx, repository.SetServiceSenderDomainsParams{
+		ID:                   serviceID,
+		AllowedSenderDomains: slices.Compact(normalized),
+	})
+	if err != nil {
+		return ServiceSenderDomains{}, fmt.Errorf("failed to set sender domains: %w", err)
+	}
+
+	sd.logger.Info("service sender domains set",
+		"service_id", serviceID,
+		"allowed_sender_domains", row.AllowedSenderDomains,
+		"actor", actor,
+	)
+	return senderDomainsOf(row.ID, row.AllowedSenderDomains), nil
+}
+
+func senderDomainsOf(serviceID string, domains []string) ServiceSenderDomains {
+	if domains == nil {
+		domains = []string{}
+	}
+	return ServiceSenderDomains{ServiceID: serviceID, AllowedSenderDomains: domains}
+}
+
+// isDomainName reports whether domain, already lowercased, looks like a
+// host name with at least two labels.
+func isDomainName(domain string) bool {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 ||
+			strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
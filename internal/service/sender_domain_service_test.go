@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderDomainSet_NormalizesDomains(t *testing.T) {
+	var got repository.SetServiceSenderDomainsParams
+	repo := &fakeQuerier{
+		setServiceSenderDomains: func(_ context.Context, arg repository.SetServiceSenderDomainsParams) (repository.SetServiceSenderDomainsRow, error) {
+			got = arg
+			return repository.SetServiceSenderDomainsRow{ID: arg.ID, AllowedSenderDomains: arg.AllowedSenderDomains}, nil
+		},
+	}
+	sd := NewSenderDomainService(repo, testLogger())
+
+	domains, err := sd.Set(context.Background(), "amina@opencrafts.io", "io.opencrafts.keepup",
+		[]string{"@Posta.OpenCrafts.io", "keepup.app", "posta.opencrafts.io"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "io.opencrafts.keepup", got.ID)
+	assert.Equal(t, []string{"keepup.app", "posta.opencrafts.io"}, got.AllowedSenderDomains)
+	assert.Equal(t, got.AllowedSenderDomains, domains.AllowedSenderDomains)
+}
+
+func TestSenderDomainSet_RejectsInvalidInput(t *testing.T) {
+	sd := NewSenderDomainService(&fakeQuerier{}, testLogger())
+
+	_, err := sd.Set(context.Background(), "admin-api", "com.example.billing", []string{"posta.opencrafts.io"})
+	assert.ErrorIs(t, err, ErrInvalidServiceID)
+
+	for _, domain := range []string{"localhost", "posta..opencrafts.io", "noreply@posta.opencrafts.io", "-bad.io"} {
+		_, err := sd.Set(context.Background(), "admin-api", "io.opencrafts.keepup", []string{domain})
+		assert.ErrorIs(t, err, ErrInvalidSenderDomain, domain)
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Sms struct {
+	// ToNumbers are the recipients, in E.164 form (e.g. "+254711000000").
+	ToNumbers []string `json:"to_numbers"`
+	// ToUserIDs are users to text at the phone number the user directory
+	// has for them, in addition to ToNumbers.
+	ToUserIDs []uuid.UUID `json:"to_user_ids"`
+	Body      string      `json:"body"`
+	// SenderID is the alphanumeric sender id or short code to send from.
+	// Left empty, the provider's configured default is used.
+	SenderID *string `json:"sender_id"`
+	// NotificationType is checked against the recipients' preferences for
+	// sms. Without one, only their "*" preferences apply.
+	NotificationType *string `json:"notification_type"`
+}
+
+type SmsEventMetadata struct {
+	EventType       string    `json:"event_type"`
+	Timestamp       time.Time `json:"timestamp"`
+	SourceServiceID string    `json:"source_service_id"`
+	RequestID       string    `json:"request_id"`
+}
+
+type SmsEvent struct {
+	Sms  Sms              `json:"sms"`
+	Meta SmsEventMetadata `json:"metadata"`
+}
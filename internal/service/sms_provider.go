@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SmsMessage is one SMS, sent to every number in To.
+type SmsMessage struct {
+	To       []string
+	Body     string
+	SenderID string
+}
+
+// SmsRecipientResult is the provider's verdict on one recipient of an
+// SmsMessage.
+type SmsRecipientResult struct {
+	Number    string `json:"number"`
+	Accepted  bool   `json:"accepted"`
+	Status    string `json:"status"`
+	MessageID string `json:"message_id,omitempty"`
+	// Retryable marks a rejection that may succeed later, such as an
+	// account out of credit, rather than one that never will, such as an
+	// invalid number.
+	Retryable bool `json:"retryable,omitempty"`
+}
+
+// SmsResult is the outcome of a call to an SMS provider. It is returned
+// alongside an error too, with whatever the provider answered.
+type SmsResult struct {
+	HTTPStatusCode int
+	Recipients     []SmsRecipientResult
+}
+
+// SmsProvider sends SMS through one provider's API.
+type SmsProvider interface {
+	// Name identifies the provider in dispatch records, metrics and its
+	// breaker's name.
+	Name() string
+	// Send hands msg to the provider. It fails when the provider couldn't
+	// be reached or rejected the request as a whole; recipients it rejected
+	// one by one are reported in the result instead.
+	Send(ctx context.Context, msg SmsMessage) (*SmsResult, error)
+}
+
+// africasTalkingProvider sends SMS through the Africa's Talking messaging
+// API.
+type africasTalkingProvider struct {
+	baseURL  string
+	username string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewAfricasTalkingProvider returns an SmsProvider calling the Africa's
+// Talking API at baseURL ("https://api.africastalking.com", or
+// "https://api.sandbox.africastalking.com" for the sandbox) as username.
+func NewAfricasTalkingProvider(
+	baseURL, username, apiKey string,
+	client *http.Client,
+) SmsProvider {
+	return &africasTalkingProvider{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: username,
+		apiKey:   apiKey,
+		client:   client,
+	}
+}
+
+func (p *africasTalkingProvider) Name() string {
+	return "africastalking"
+}
+
+// africasTalkingResponse is the body of a successful messaging call.
+type africasTalkingResponse struct {
+	SMSMessageData struct {
+		Message    string `json:"Message"`
+		Recipients []struct {
+			StatusCode int    `json:"statusCode"`
+			Number     string `json:"number"`
+			Status     string `json:"status"`
+			MessageID  string `json:"messageId"`
+		} `json:"Recipients"`
+	} `json:"SMSMessageData"`
+}
+
+func (p *africasTalkingProvider) Send(ctx context.Context, msg SmsMessage) (*SmsResult, error) {
+	form := url.Values{
+		"username": {p.username},
+		"to":       {strings.Join(msg.To, ",")},
+		"message":  {msg.Body},
+	}
+	if msg.SenderID != "" {
+		form.Set("from", msg.SenderID)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		p.baseURL+"/version1/messaging",
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("apiKey", p.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &SmsResult{HTTPStatusCode: resp.StatusCode}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return result, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Error responses are plain text, e.g. "The supplied
+		// authentication is invalid".
+		return result, fmt.Errorf("africa's talking answered %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var parsed africasTalkingResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return result, fmt.Errorf("failed to parse response: %w", err)
+	}
+	for _, r := range parsed.SMSMessageData.Recipients {
+		result.Recipients = append(result.Recipients, SmsRecipientResult{
+			Number:    r.Number,
+			Accepted:  africasTalkingAccepted(r.StatusCode),
+			Status:    r.Status,
+			MessageID: r.MessageID,
+			Retryable: africasTalkingRetryable(r.StatusCode),
+		})
+	}
+	return result, nil
+}
+
+// africasTalkingAccepted reports whether a recipient status code means the
+// message was taken on: 100 Processed, 101 Sent or 102 Queued.
+func africasTalkingAccepted(statusCode int) bool {
+	return statusCode >= 100 && statusCode <= 102
+}
+
+// africasTalkingRetryable reports whether a rejected recipient may be
+// accepted on a later attempt: 405 InsufficientBalance, and the gateway's
+// own 500 and 501 errors. The rest (invalid number or sender id,
+// blacklisted, unroutable, ...) fail the same way every time.
+func africasTalkingRetryable(statusCode int) bool {
+	switch statusCode {
+	case 405, 500, 501:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAfricasTalking serves the Africa's Talking messaging endpoint,
+// answering with status and body, and records the last request it got.
+type fakeAfricasTalking struct {
+	*httptest.Server
+	status int
+	body   string
+	calls  int
+
+	apiKey string
+	form   map[string]string
+}
+
+func newFakeAfricasTalking(t *testing.T, status int, body string) *fakeAfricasTalking {
+	f := &fakeAfricasTalking{status: status, body: body}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.calls++
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/version1/messaging", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		f.apiKey = r.Header.Get("apiKey")
+		f.form = map[string]string{}
+		for k := range r.PostForm {
+			f.form[k] = r.PostForm.Get(k)
+		}
+		w.WriteHeader(f.status)
+		w.Write([]byte(f.body))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAfricasTalking) provider() SmsProvider {
+	return NewAfricasTalkingProvider(f.URL+"/", "sandbox", "at-key", f.Client())
+}
+
+const africasTalkingMixedResponse = `{"SMSMessageData":{"Message":"Sent to 1/2 Total Cost: KES 0.8000","Recipients":[
+	{"statusCode":101,"number":"+254711000001","status":"Success","cost":"KES 0.8000","messageId":"ATXid_1"},
+	{"statusCode":403,"number":"+254711000002","status":"InvalidPhoneNumber","cost":"0","messageId":"None"}
+]}}`
+
+func TestAfricasTalkingSend_PostsFormAndParsesRecipients(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+
+	result, err := fake.provider().Send(context.Background(), SmsMessage{
+		To:       []string{"+254711000001", "+254711000002"},
+		Body:     "Your code is 1234",
+		SenderID: "OPENCRAFTS",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "at-key", fake.apiKey)
+	assert.Equal(t, map[string]string{
+		"username": "sandbox",
+		"to":       "+254711000001,+254711000002",
+		"message":  "Your code is 1234",
+		"from":     "OPENCRAFTS",
+	}, fake.form)
+	assert.Equal(t, http.StatusCreated, result.HTTPStatusCode)
+	assert.Equal(t, []SmsRecipientResult{
+		{Number: "+254711000001", Accepted: true, Status: "Success", MessageID: "ATXid_1"},
+		{Number: "+254711000002", Status: "InvalidPhoneNumber", MessageID: "None"},
+	}, result.Recipients)
+}
+
+func TestAfricasTalkingSend_OmitsEmptySender(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, `{"SMSMessageData":{"Recipients":[]}}`)
+
+	_, err := fake.provider().Send(context.Background(), SmsMessage{
+		To:   []string{"+254711000001"},
+		Body: "hi",
+	})
+
+	require.NoError(t, err)
+	assert.NotContains(t, fake.form, "from")
+}
+
+func TestAfricasTalkingSend_ErrorStatusFails(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusUnauthorized, "The supplied authentication is invalid")
+
+	result, err := fake.provider().Send(context.Background(), SmsMessage{
+		To:   []string{"+254711000001"},
+		Body: "hi",
+	})
+
+	require.EqualError(t, err, "africa's talking answered 401: The supplied authentication is invalid")
+	assert.Equal(t, http.StatusUnauthorized, result.HTTPStatusCode)
+}
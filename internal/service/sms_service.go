@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/metrics"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/opencrafts-io/gossip-monger/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// e164 matches a phone number in E.164 form: "+", a country code and up to
+// fifteen digits in all.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+type SmsService interface {
+	// Send records smsEvent and sends it through the SMS provider. delivery
+	// is the message it arrived in, recorded on the SMS request for
+	// auditing.
+	Send(ctx context.Context, smsEvent SmsEvent, delivery broker.Delivery) error
+}
+
+type smsService struct {
+	repo     repository.Querier
+	provider SmsProvider
+	breaker  resilience.Breaker[*SmsResult]
+	// defaultSenderID is sent from when an event names no sender_id.
+	defaultSenderID string
+	logger          *slog.Logger
+}
+
+func NewSmsService(
+	repo repository.Querier,
+	provider SmsProvider,
+	defaultSenderID string,
+	breakerSettings resilience.Settings,
+	logger *slog.Logger,
+) SmsService {
+	return &smsService{
+		repo:            repo,
+		provider:        provider,
+		breaker:         resilience.New[*SmsResult](provider.Name(), breakerSettings, logger),
+		defaultSenderID: defaultSenderID,
+		logger:          logger,
+	}
+}
+
+func (ss *smsService) Send(
+	ctx context.Context,
+	smsEvent SmsEvent,
+	delivery broker.Delivery,
+) (err error) {
+	ctx, span := tracer.Start(ctx, "SmsService.Send", trace.WithAttributes(
+		attribute.String("gossip.request_id", smsEvent.Meta.RequestID),
+		attribute.String("gossip.service_id", smsEvent.Meta.SourceServiceID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	// As with email, a retry and a duplicate republish look the same here;
+	// an SMS the provider already took must not go out twice.
+	existing, err := ss.repo.GetSmsRequestByQueueMessageID(ctx, smsEvent.Meta.RequestID)
+	if err == nil {
+		if existing.Status == "dispatched" || existing.Status == "suppressed" {
+			ss.logger.Info("duplicate request_id already dispatched or suppressed, skipping resend",
+				"request_id", smsEvent.Meta.RequestID,
+				"status", existing.Status,
+			)
+			return nil
+		}
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check for duplicate sms request: %w", err)
+	}
+
+	if _, err := ss.repo.UpsertService(ctx, repository.UpsertServiceParams{
+		ID:   smsEvent.Meta.SourceServiceID,
+		Name: smsEvent.Meta.SourceServiceID,
+	}); err != nil {
+		return fmt.Errorf("failed to upsert service: %w", err)
+	}
+
+	now := time.Now()
+	smsReq, err := ss.repo.UpsertSmsRequest(ctx, repository.UpsertSmsRequestParams{
+		ServiceID:        smsEvent.Meta.SourceServiceID,
+		QueueMessageID:   smsEvent.Meta.RequestID,
+		Exchange:         delivery.Exchange,
+		RoutingKey:       delivery.RoutingKey,
+		AmqpMessageID:    optionalString(delivery.MessageID),
+		AttemptCount:     int32(delivery.Attempt),
+		ToNumbers:        smsEvent.Sms.ToNumbers,
+		ToUserIds:        smsEvent.Sms.ToUserIDs,
+		Body:             smsEvent.Sms.Body,
+		SenderID:         smsEvent.Sms.SenderID,
+		NotificationType: smsEvent.Sms.NotificationType,
+		Status:           "received",
+		ProcessedAt:      &now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create sms request: %w", err)
+	}
+
+	sms := smsEvent.Sms
+	if len(sms.ToUserIDs) > 0 {
+		resolved, err := resolveUserPhones(ctx, ss.repo, sms.ToUserIDs, delivery.Attempt)
+		if err != nil {
+			if resilience.IsPermanent(err) {
+				ss.setStatus(ctx, smsReq.ID, "invalid")
+			}
+			return err
+		}
+		if err := ss.repo.SetSmsRequestResolvedNumbers(ctx, repository.SetSmsRequestResolvedNumbersParams{
+			ID:                smsReq.ID,
+			ResolvedToNumbers: resolved,
+		}); err != nil {
+			return fmt.Errorf("failed to record resolved numbers: %w", err)
+		}
+		sms.ToNumbers = appendNewNumbers(sms.ToNumbers, resolved)
+	}
+
+	if err := validateSms(sms); err != nil {
+		ss.setStatus(ctx, smsReq.ID, "invalid")
+		return resilience.Permanent(err)
+	}
+
+	if err := ss.withoutOptedOutNumbers(ctx, smsReq.ID, &sms); err != nil {
+		return err
+	}
+	// validateSms made sure there was a number to send to, so none left
+	// means every recipient opted out.
+	if len(sms.ToNumbers) == 0 {
+		if err := ss.repo.UpdateSmsRequestStatusByID(ctx, repository.UpdateSmsRequestStatusByIDParams{
+			ID:     smsReq.ID,
+			Status: "suppressed",
+		}); err != nil {
+			return fmt.Errorf("failed to update sms request status: %w", err)
+		}
+		ss.logger.Info("every recipient opted out, sms suppressed",
+			"sms_request_id", smsReq.ID,
+			"notification_type", derefString(sms.NotificationType),
+		)
+		return nil
+	}
+
+	msg := SmsMessage{
+		To:       sms.ToNumbers,
+		Body:     smsEvent.Sms.Body,
+		SenderID: ss.defaultSenderID,
+	}
+	if sms.SenderID != nil && *sms.SenderID != "" {
+		msg.SenderID = *sms.SenderID
+	}
+
+	callStart := time.Now()
+	result, sendErr := ss.breaker.Execute(func() (*SmsResult, error) {
+		return ss.provider.Send(ctx, msg)
+	})
+
+	dispatchStatus, requestStatus, outcomeErr := smsOutcome(result, sendErr)
+	metrics.ObserveProviderCall(ss.provider.Name(), dispatchStatus, callStart)
+
+	dispatch := repository.CreateSmsDispatchParams{
+		SmsRequestID: smsReq.ID,
+		Provider:     ss.provider.Name(),
+		Status:       dispatchStatus,
+	}
+	if result != nil {
+		if result.HTTPStatusCode != 0 {
+			statusCode := int32(result.HTTPStatusCode)
+			dispatch.HttpStatusCode = &statusCode
+		}
+		if len(result.Recipients) > 0 {
+			if dispatch.Recipients, err = json.Marshal(result.Recipients); err != nil {
+				return fmt.Errorf("failed to marshal sms recipients: %w", err)
+			}
+		}
+	}
+	if outcomeErr != nil {
+		errString := outcomeErr.Error()
+		dispatch.ProviderError = &errString
+		ss.logger.Error("sms delivery failed",
+			"sms_request_id", smsReq.ID,
+			"status", dispatchStatus,
+			"error", outcomeErr,
+		)
+	} else {
+		ss.logger.Info("sms delivery ok!", "sms_request_id", smsReq.ID)
+	}
+
+	if _, err := ss.repo.CreateSmsDispatch(ctx, dispatch); err != nil {
+		return fmt.Errorf("failed to persist sms dispatch record: %w", err)
+	}
+	if err := ss.repo.UpdateSmsRequestStatusByID(ctx, repository.UpdateSmsRequestStatusByIDParams{
+		ID:     smsReq.ID,
+		Status: requestStatus,
+	}); err != nil {
+		return fmt.Errorf("failed to update sms request status: %w", err)
+	}
+
+	if outcomeErr != nil {
+		return fmt.Errorf("failed to send sms via %s: %w", ss.provider.Name(), outcomeErr)
+	}
+	return nil
+}
+
+// smsOutcome decides the dispatch and request statuses of a provider call,
+// and the error to retry or park the message with.
+//
+// An SMS the provider took for at least one recipient is dispatched:
+// retrying it would text the others again. Recipients it rejected are
+// only recorded. An SMS it took for nobody is retried if any rejection may
+// yet succeed, and parked otherwise.
+func smsOutcome(result *SmsResult, sendErr error) (dispatchStatus, requestStatus string, err error) {
+	switch {
+	case resilience.Open(sendErr):
+		return "circuit_open", "circuit_open", sendErr
+	case sendErr != nil:
+		return "failed", "failed", sendErr
+	}
+
+	var rejected []string
+	retryable := false
+	for _, r := range result.Recipients {
+		if r.Accepted {
+			return "sent", "dispatched", nil
+		}
+		rejected = append(rejected, r.Number+": "+r.Status)
+		retryable = retryable || r.Retryable
+	}
+
+	err = fmt.Errorf("every recipient was rejected (%s)", strings.Join(rejected, ", "))
+	if !retryable {
+		err = resilience.Permanent(err)
+	}
+	return "rejected", "failed", err
+}
+
+// validateSms checks what the provider would otherwise reject: a missing
+// body, and numbers that aren't in E.164 form.
+func validateSms(sms Sms) error {
+	if strings.TrimSpace(sms.Body) == "" {
+		return errors.New("sms body is required")
+	}
+	if len(sms.ToNumbers) == 0 {
+		return errors.New("at least one recipient in to_numbers or to_user_ids is required")
+	}
+	for _, number := range sms.ToNumbers {
+		if !e164.MatchString(number) {
+			return fmt.Errorf("recipient %q is not an E.164 phone number", number)
+		}
+	}
+	return nil
+}
+
+// withoutOptedOutNumbers removes from sms's numbers those whose user opted
+// out of its notification_type by SMS, and records them on the SMS
+// request.
+func (ss *smsService) withoutOptedOutNumbers(
+	ctx context.Context,
+	smsReqID uuid.UUID,
+	sms *Sms,
+) error {
+	optedOut, err := ss.repo.ListOptedOutPhoneNumbers(ctx, repository.ListOptedOutPhoneNumbersParams{
+		Numbers:          sms.ToNumbers,
+		NotificationType: derefString(sms.NotificationType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check notification preferences: %w", err)
+	}
+	if len(optedOut) == 0 {
+		return nil
+	}
+
+	if err := ss.repo.SetSmsRequestSuppressedNumbers(ctx, repository.SetSmsRequestSuppressedNumbersParams{
+		ID:                smsReqID,
+		SuppressedNumbers: optedOut,
+	}); err != nil {
+		return fmt.Errorf("failed to record suppressed numbers: %w", err)
+	}
+	sms.ToNumbers = slices.DeleteFunc(slices.Clone(sms.ToNumbers), func(number string) bool {
+		return slices.Contains(optedOut, number)
+	})
+	return nil
+}
+
+// appendNewNumbers appends to list the numbers in more it doesn't already
+// hold.
+func appendNewNumbers(list, more []string) []string {
+	for _, number := range more {
+		if !slices.Contains(list, number) {
+			list = append(list, number)
+		}
+	}
+	return list
+}
+
+// setStatus records status on an SMS request, logging rather than
+// returning a failure: the caller is already reporting a worse one.
+func (ss *smsService) setStatus(ctx context.Context, id uuid.UUID, status string) {
+	if err := ss.repo.UpdateSmsRequestStatusByID(ctx, repository.UpdateSmsRequestStatusByIDParams{
+		ID:     id,
+		Status: status,
+	}); err != nil {
+		ss.logger.Error("failed to update sms request status",
+			"sms_request_id", id,
+			"status", status,
+			"error", err,
+		)
+	}
+}
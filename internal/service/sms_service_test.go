@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/opencrafts-io/gossip-monger/internal/broker"
+	"github.com/opencrafts-io/gossip-monger/internal/repository"
+	"github.com/opencrafts-io/gossip-monger/internal/resilience"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSmsRepo keeps the one SMS request a test sends, and what Send
+// recorded about it.
+type fakeSmsRepo struct {
+	repository.Querier
+	existing   *repository.SmsRequest
+	upserted   repository.UpsertSmsRequestParams
+	dispatches []repository.CreateSmsDispatchParams
+	statuses   []string
+	// users is the user directory to_user_ids are resolved against.
+	users    map[uuid.UUID]repository.User
+	resolved []string
+	// optedOut are the numbers whose user opted out of every SMS.
+	optedOut   []string
+	suppressed []string
+}
+
+func (f *fakeSmsRepo) ListOptedOutPhoneNumbers(
+	_ context.Context,
+	arg repository.ListOptedOutPhoneNumbersParams,
+) ([]string, error) {
+	var optedOut []string
+	for _, number := range arg.Numbers {
+		if slices.Contains(f.optedOut, number) {
+			optedOut = append(optedOut, number)
+		}
+	}
+	return optedOut, nil
+}
+
+func (f *fakeSmsRepo) SetSmsRequestSuppressedNumbers(
+	_ context.Context,
+	arg repository.SetSmsRequestSuppressedNumbersParams,
+) error {
+	f.suppressed = arg.SuppressedNumbers
+	return nil
+}
+
+func (f *fakeSmsRepo) GetUserByID(_ context.Context, id uuid.UUID) (repository.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return repository.User{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (f *fakeSmsRepo) SetSmsRequestResolvedNumbers(
+	_ context.Context,
+	arg repository.SetSmsRequestResolvedNumbersParams,
+) error {
+	f.resolved = arg.ResolvedToNumbers
+	return nil
+}
+
+func (f *fakeSmsRepo) GetSmsRequestByQueueMessageID(context.Context, string) (repository.SmsRequest, error) {
+	if f.existing == nil {
+		return repository.SmsRequest{}, pgx.ErrNoRows
+	}
+	return *f.existing, nil
+}
+
+func (f *fakeSmsRepo) UpsertService(_ context.Context, arg repository.UpsertServiceParams) (repository.Service, error) {
+	return repository.Service{ID: arg.ID, Name: arg.Name}, nil
+}
+
+func (f *fakeSmsRepo) UpsertSmsRequest(
+	_ context.Context,
+	arg repository.UpsertSmsRequestParams,
+) (repository.SmsRequest, error) {
+	f.upserted = arg
+	return repository.SmsRequest{ID: uuid.New(), QueueMessageID: arg.QueueMessageID}, nil
+}
+
+func (f *fakeSmsRepo) CreateSmsDispatch(
+	_ context.Context,
+	arg repository.CreateSmsDispatchParams,
+) (repository.SmsDispatch, error) {
+	f.dispatches = append(f.dispatches, arg)
+	return repository.SmsDispatch{}, nil
+}
+
+func (f *fakeSmsRepo) UpdateSmsRequestStatusByID(
+	_ context.Context,
+	arg repository.UpdateSmsRequestStatusByIDParams,
+) error {
+	f.statuses = append(f.statuses, arg.Status)
+	return nil
+}
+
+func validSmsEvent() SmsEvent {
+	return SmsEvent{
+		Sms: Sms{ToNumbers: []string{"+254711000001"}, Body: "Your code is 1234"},
+		Meta: SmsEventMetadata{
+			EventType:       "sms.send",
+			SourceServiceID: "io.opencrafts.verisafe",
+			RequestID:       "req-1",
+		},
+	}
+}
+
+var smsDelivery = broker.Delivery{
+	Exchange:   "gossip.topic.exchange",
+	RoutingKey: "gossip.sms.send",
+	MessageID:  "msg-1",
+	Attempt:    2,
+}
+
+func newTestSmsService(repo repository.Querier, provider SmsProvider) *smsService {
+	return &smsService{
+		repo:            repo,
+		provider:        provider,
+		breaker:         fakeBreaker[*SmsResult]{},
+		defaultSenderID: "OPENCRAFTS",
+		logger:          testLogger(),
+	}
+}
+
+func TestSmsSend_DispatchesAndRecordsRecipients(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+	repo := &fakeSmsRepo{}
+	event := validSmsEvent()
+	event.Sms.ToNumbers = []string{"+254711000001", "+254711000002"}
+
+	err := newTestSmsService(repo, fake.provider()).Send(context.Background(), event, smsDelivery)
+
+	require.NoError(t, err)
+	assert.Equal(t, "OPENCRAFTS", fake.form["from"])
+	assert.Equal(t, "gossip.sms.send", repo.upserted.RoutingKey)
+	assert.Equal(t, "msg-1", *repo.upserted.AmqpMessageID)
+	assert.Equal(t, int32(2), repo.upserted.AttemptCount)
+
+	require.Len(t, repo.dispatches, 1)
+	dispatch := repo.dispatches[0]
+	assert.Equal(t, "africastalking", dispatch.Provider)
+	assert.Equal(t, "sent", dispatch.Status)
+	assert.Equal(t, int32(http.StatusCreated), *dispatch.HttpStatusCode)
+	var recipients []SmsRecipientResult
+	require.NoError(t, json.Unmarshal(dispatch.Recipients, &recipients))
+	assert.Len(t, recipients, 2)
+	// One accepted recipient is enough: a retry would text it twice.
+	assert.Equal(t, []string{"dispatched"}, repo.statuses)
+}
+
+func TestSmsSend_SkipsDispatchedDuplicate(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+	repo := &fakeSmsRepo{existing: &repository.SmsRequest{Status: "dispatched"}}
+
+	err := newTestSmsService(repo, fake.provider()).Send(context.Background(), validSmsEvent(), smsDelivery)
+
+	require.NoError(t, err)
+	assert.Zero(t, fake.calls)
+	assert.Empty(t, repo.dispatches)
+}
+
+func TestSmsSend_InvalidNumberIsPermanent(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+	repo := &fakeSmsRepo{}
+	event := validSmsEvent()
+	event.Sms.ToNumbers = []string{"0711000001"}
+
+	err := newTestSmsService(repo, fake.provider()).Send(context.Background(), event, smsDelivery)
+
+	require.Error(t, err)
+	assert.True(t, resilience.IsPermanent(err))
+	assert.Zero(t, fake.calls)
+	assert.Equal(t, []string{"invalid"}, repo.statuses)
+}
+
+func TestSmsSend_ResolvesToUserIDs(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+	userID := uuid.New()
+	repo := &fakeSmsRepo{users: map[uuid.UUID]repository.User{
+		userID: {ID: userID, Phone: strPtr("+254711000002")},
+	}}
+	event := validSmsEvent()
+	event.Sms.ToUserIDs = []uuid.UUID{userID, userID}
+
+	err := newTestSmsService(repo, fake.provider()).Send(context.Background(), event, smsDelivery)
+
+	require.NoError(t, err)
+	assert.Equal(t, "+254711000001,+254711000002", fake.form["to"])
+	assert.Equal(t, []uuid.UUID{userID, userID}, repo.upserted.ToUserIds)
+	assert.Equal(t, []string{"+254711000001"}, repo.upserted.ToNumbers)
+	assert.Equal(t, []string{"+254711000002"}, repo.resolved)
+}
+
+func TestSmsSend_UnresolvableUserIsPermanent(t *testing.T) {
+	knownID, unknownID := uuid.New(), uuid.New()
+	for name, tt := range map[string]struct {
+		userID uuid.UUID
+		want   error
+	}{
+		"unknown user":  {unknownID, ErrUnknownRecipientUser},
+		"user no phone": {knownID, ErrRecipientUserUnreachable},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+			repo := &fakeSmsRepo{users: map[uuid.UUID]repository.User{
+				knownID: {ID: knownID, Email: "amina@example.com"},
+			}}
+			event := validSmsEvent()
+			event.Sms.ToUserIDs = []uuid.UUID{tt.userID}
+			delivery := smsDelivery
+			delivery.Attempt = unknownUserAttempts
+
+			err := newTestSmsService(repo, fake.provider()).Send(context.Background(), event, delivery)
+
+			require.ErrorIs(t, err, tt.want)
+			assert.ErrorContains(t, err, tt.userID.String())
+			assert.True(t, resilience.IsPermanent(err))
+			assert.Zero(t, fake.calls)
+			assert.Equal(t, []string{"invalid"}, repo.statuses)
+		})
+	}
+}
+
+func TestSmsSend_UnknownUserIsRetriedWhileTheDirectoryCatchesUp(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+	repo := &fakeSmsRepo{users: map[uuid.UUID]repository.User{}}
+	event := validSmsEvent()
+	event.Sms.ToUserIDs = []uuid.UUID{uuid.New()}
+
+	err := newTestSmsService(repo, fake.provider()).Send(context.Background(), event, smsDelivery)
+
+	require.ErrorIs(t, err, ErrUnknownRecipientUser)
+	assert.False(t, resilience.IsPermanent(err))
+	assert.Zero(t, fake.calls)
+	assert.Empty(t, repo.statuses)
+}
+
+func TestSmsSend_EveryRecipientRejected(t *testing.T) {
+	for name, tt := range map[string]struct {
+		statusCode int
+		status     string
+		permanent  bool
+	}{
+		"invalid number is parked":        {403, "InvalidPhoneNumber", true},
+		"insufficient balance is retried": {405, "InsufficientBalance", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]any{"SMSMessageData": map[string]any{
+				"Recipients": []map[string]any{
+					{"statusCode": tt.statusCode, "number": "+254711000001", "status": tt.status},
+				},
+			}})
+			fake := newFakeAfricasTalking(t, http.StatusCreated, string(body))
+			repo := &fakeSmsRepo{}
+
+			err := newTestSmsService(repo, fake.provider()).Send(context.Background(), validSmsEvent(), smsDelivery)
+
+			require.Error(t, err)
+			assert.Equal(t, tt.permanent, resilience.IsPermanent(err))
+			require.Len(t, repo.dispatches, 1)
+			assert.Equal(t, "rejected", repo.dispatches[0].Status)
+			assert.Equal(t, []string{"failed"}, repo.statuses)
+		})
+	}
+}
+
+func TestSmsSend_ProviderErrorIsRetried(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusInternalServerError, "gateway down")
+	repo := &fakeSmsRepo{}
+
+	err := newTestSmsService(repo, fake.provider()).Send(context.Background(), validSmsEvent(), smsDelivery)
+
+	require.Error(t, err)
+	assert.False(t, resilience.IsPermanent(err))
+	require.Len(t, repo.dispatches, 1)
+	assert.Equal(t, "failed", repo.dispatches[0].Status)
+	assert.Equal(t, int32(http.StatusInternalServerError), *repo.dispatches[0].HttpStatusCode)
+	assert.Equal(t, []string{"failed"}, repo.statuses)
+}
+
+func TestSmsSend_BreakerOpenSkipsProvider(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+	repo := &fakeSmsRepo{}
+	ss := newTestSmsService(repo, fake.provider())
+	ss.breaker = fakeBreaker[*SmsResult]{forcedErr: gobreaker.ErrOpenState}
+
+	err := ss.Send(context.Background(), validSmsEvent(), smsDelivery)
+
+	require.Error(t, err)
+	assert.True(t, resilience.Open(err))
+	assert.False(t, resilience.IsPermanent(err))
+	assert.Zero(t, fake.calls)
+	assert.Equal(t, "circuit_open", repo.dispatches[0].Status)
+	assert.Equal(t, []string{"circuit_open"}, repo.statuses)
+}
+
+func TestSmsSend_LeavesOutOptedOutNumbers(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+	repo := &fakeSmsRepo{optedOut: []string{"+254711000002"}}
+	event := validSmsEvent()
+	event.Sms.ToNumbers = []string{"+254711000001", "+254711000002"}
+
+	err := newTestSmsService(repo, fake.provider()).Send(context.Background(), event, smsDelivery)
+
+	require.NoError(t, err)
+	assert.Equal(t, "+254711000001", fake.form["to"])
+	assert.Equal(t, []string{"+254711000002"}, repo.suppressed)
+	assert.Equal(t, []string{"+254711000001", "+254711000002"}, repo.upserted.ToNumbers)
+}
+
+func TestSmsSend_EveryRecipientOptedOut(t *testing.T) {
+	fake := newFakeAfricasTalking(t, http.StatusCreated, africasTalkingMixedResponse)
+	repo := &fakeSmsRepo{optedOut: []string{"+254711000001"}}
+	event := validSmsEvent()
+	event.Sms.NotificationType = stringPtr("marketing")
+
+	err := newTestSmsService(repo, fake.provider()).Send(context.Background(), event, smsDelivery)
+
+	require.NoError(t, err)
+	assert.Zero(t, fake.calls)
+	assert.Equal(t, []string{"+254711000001"}, repo.suppressed)
+	assert.Equal(t, []string{"suppressed"}, repo.statuses)
+	assert.Equal(t, stringPtr("marketing"), repo.upserted.NotificationType)
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidUnsubscribeToken is returned for an unsubscribe link that
+// wasn't signed by UnsubscribeLinks, or was altered.
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// UnsubscribeLinks signs the one-click unsubscribe links put in
+// non-transactional email, and verifies them when they are followed. A
+// link names the service and the recipient it unsubscribes, and is only
+// valid with the signature made with Secret.
+type UnsubscribeLinks struct {
+	// BaseURL is where gossip-monger is publicly reachable, e.g.
+	// "https://gossip.opencrafts.io".
+	BaseURL string
+	Secret  string
+}
+
+// URL returns the link that unsubscribes recipient from serviceID's
+// non-transactional email.
+func (u UnsubscribeLinks) URL(serviceID, recipient string) (string, error) {
+	if u.BaseURL == "" || u.Secret == "" {
+		return "", errors.New("unsubscribe links are not configured; only transactional email can be sent")
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(serviceID + "\n" + recipient))
+	token := payload + "." + base64.RawURLEncoding.EncodeToString(u.sign(payload))
+	return strings.TrimSuffix(u.BaseURL, "/") + "/unsubscribe?token=" + url.QueryEscape(token), nil
+}
+
+// Verify returns the service and recipient token, from a link made by URL,
+// unsubscribes.
+func (u UnsubscribeLinks) Verify(token string) (serviceID, recipient string, err error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || u.Secret == "" {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	given, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, u.sign(payload)) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	serviceID, recipient, ok = strings.Cut(string(decoded), "\n")
+	if !ok || serviceID == "" || recipient == "" {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return serviceID, recipient, nil
+}
+
+func (u UnsubscribeLinks) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(u.Secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
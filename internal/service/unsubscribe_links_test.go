@@ -0,0 +1,52 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unsubscribeToken(t *testing.T, links UnsubscribeLinks, serviceID, recipient string) string {
+	t.Helper()
+	link, err := links.URL(serviceID, recipient)
+	require.NoError(t, err)
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	return parsed.Query().Get("token")
+}
+
+func TestUnsubscribeLinks_RoundTrip(t *testing.T) {
+	token := unsubscribeToken(t, testUnsubscribeLinks, "io.opencrafts.sherehe", "amina@example.com")
+
+	serviceID, recipient, err := testUnsubscribeLinks.Verify(token)
+
+	require.NoError(t, err)
+	assert.Equal(t, "io.opencrafts.sherehe", serviceID)
+	assert.Equal(t, "amina@example.com", recipient)
+}
+
+func TestUnsubscribeLinks_RejectsForgedTokens(t *testing.T) {
+	token := unsubscribeToken(t, testUnsubscribeLinks, "io.opencrafts.sherehe", "amina@example.com")
+	payload, signature, _ := strings.Cut(token, ".")
+	other := unsubscribeToken(t, testUnsubscribeLinks, "io.opencrafts.sherehe", "otieno@example.com")
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	for name, forged := range map[string]string{
+		"no signature":      payload,
+		"someone else":      otherPayload + "." + signature,
+		"garbled signature": payload + ".!!!",
+		"empty":             "",
+		"signed with another secret": unsubscribeToken(t, UnsubscribeLinks{
+			BaseURL: testUnsubscribeLinks.BaseURL,
+			Secret:  "another-secret",
+		}, "io.opencrafts.sherehe", "amina@example.com"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := testUnsubscribeLinks.Verify(forged)
+			assert.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+		})
+	}
+}
@@ -121,6 +121,9 @@ func (s *userService) Delete(ctx context.Context, user repository.User) error {
 	if err = repo.DeleteUserByID(ctx, user.ID); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	if err = repo.DeleteNotificationPreferencesByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete user's notification preferences: %w", err)
+	}
 
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -0,0 +1,115 @@
+// Package tracing sets up OpenTelemetry tracing: the OTLP exporter, W3C
+// trace context propagation, and the instrumented HTTP client and Postgres
+// tracer the rest of gossip-monger uses.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/exaring/otelpgx"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup installs the global propagator and, when endpoint is set, a tracer
+// provider exporting to that OTLP/HTTP traces URL (e.g.
+// "http://otel-collector:4318/v1/traces"). sampleRatio is the fraction of
+// new traces sampled; traces started upstream keep their sampling
+// decision.
+//
+// With no endpoint, spans are not recorded but trace context is still
+// passed along, so a trace started upstream continues past gossip-monger
+// to the providers. The returned shutdown flushes buffered spans.
+func Setup(
+	ctx context.Context,
+	endpoint, serviceName string,
+	sampleRatio float64,
+) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	// The service name is added without a schema URL: merging it with the
+	// SDK's own attributes fails whenever the SDK's semconv version moves
+	// past the one imported here.
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewHTTPClient returns an HTTP client that records a span for each
+// request and passes the trace context on in its headers. A zero timeout
+// means none.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		Timeout:   timeout,
+	}
+}
+
+// NewPgxTracer returns a pgx tracer recording a span for each query, named
+// after the sqlc query it runs. Query parameters are not recorded: they
+// carry recipients' addresses.
+func NewPgxTracer() *otelpgx.Tracer {
+	return otelpgx.NewTracer(otelpgx.WithSpanNameFunc(SQLSpanName))
+}
+
+// SQLSpanName names a query's span after its sqlc annotation
+// ("-- name: GetEmailRequestByID :one" gives "GetEmailRequestByID"), or
+// after its first keyword for SQL that isn't sqlc-generated.
+func SQLSpanName(stmt string) string {
+	stmt = strings.TrimSpace(stmt)
+	if rest, ok := strings.CutPrefix(stmt, "-- name:"); ok {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	if fields := strings.Fields(stmt); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return "SQL"
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_WithEndpoint(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err := Setup(context.Background(), "http://localhost:4318/v1/traces", "gossip-monger", 1)
+
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSQLSpanName(t *testing.T) {
+	for stmt, want := range map[string]string{
+		"-- name: GetEmailRequestByID :one\nSELECT * FROM email_requests WHERE id = $1": "GetEmailRequestByID",
+		"  -- name: ListDueNotifications :many\nSELECT 1":                               "ListDueNotifications",
+		"select 1": "SELECT",
+		"":         "SQL",
+	} {
+		assert.Equal(t, want, SQLSpanName(stmt), stmt)
+	}
+}